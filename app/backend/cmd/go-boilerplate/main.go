@@ -3,11 +3,13 @@ package main
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
 	"time"
 
+	"github.com/apk471/go-boilerplate/internal/buildinfo"
 	"github.com/apk471/go-boilerplate/internal/config"
 	"github.com/apk471/go-boilerplate/internal/database"
 	"github.com/apk471/go-boilerplate/internal/handler"
@@ -20,7 +22,11 @@ import (
 
 const DefaultContextTimeout = 30
 func main() {
-	
+	if len(os.Args) > 1 && os.Args[1] == "version" {
+		fmt.Println(buildinfo.Get())
+		return
+	}
+
 	cfg , err := config.LoadConfig()
 	if err != nil {
 		panic("failed to load config: " + err.Error())
@@ -30,6 +36,7 @@ func main() {
 	defer loggerService.Shutdown()
 
 	log := logger.NewLoggerWithService(cfg.Observability, loggerService)
+	logger.SetDefault(&log)
 
 	if cfg.Primary.Env != "local" {
 		if err := database.Migrate(context.Background(), &log, cfg); err != nil {
@@ -53,6 +60,8 @@ func main() {
 
 	srv.SetupHTTPServer(r)
 
+	srv.LogStartupSummary()
+
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
 
 	// Start server
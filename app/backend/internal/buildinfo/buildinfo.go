@@ -0,0 +1,102 @@
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+// Set at build time with -ldflags, for example:
+//
+//	go build -ldflags "-X github.com/apk471/go-boilerplate/internal/buildinfo.Version=v1.2.3"
+//
+// Values left empty are filled from the VCS stamp in debug.ReadBuildInfo.
+var (
+	Version   = ""
+	Commit    = ""
+	BuildDate = ""
+	Dirty     = ""
+)
+
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"buildDate"`
+	Dirty     bool   `json:"dirty"`
+	GoVersion string `json:"goVersion"`
+}
+
+var (
+	once sync.Once
+	info Info
+)
+
+// Get returns the build information of the running binary
+func Get() Info {
+	once.Do(func() {
+		info = read()
+	})
+	return info
+}
+
+func read() Info {
+	i := Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		Dirty:     Dirty == "true",
+		GoVersion: runtime.Version(),
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		if i.Version == "" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+			i.Version = bi.Main.Version
+		}
+
+		for _, setting := range bi.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if i.Commit == "" {
+					i.Commit = setting.Value
+				}
+			case "vcs.time":
+				if i.BuildDate == "" {
+					i.BuildDate = setting.Value
+				}
+			case "vcs.modified":
+				if Dirty == "" {
+					i.Dirty = setting.Value == "true"
+				}
+			}
+		}
+	}
+
+	if i.Version == "" {
+		i.Version = "dev"
+	}
+	if i.Commit == "" {
+		i.Commit = "unknown"
+	}
+
+	return i
+}
+
+// ShortCommit returns the first 7 characters of the commit hash
+func (i Info) ShortCommit() string {
+	if len(i.Commit) > 7 {
+		return i.Commit[:7]
+	}
+	return i.Commit
+}
+
+// String formats the build information on a single line
+func (i Info) String() string {
+	s := i.Version + " (commit " + i.ShortCommit()
+	if i.Dirty {
+		s += ", dirty"
+	}
+	if i.BuildDate != "" {
+		s += ", built " + i.BuildDate
+	}
+	return s + ", " + i.GoVersion + ")"
+}
@@ -1,8 +1,10 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	_ "github.com/joho/godotenv/autoload"
@@ -18,6 +20,14 @@ type Config struct {
 	Auth          AuthConfig           `koanf:"auth" validate:"required"`
 	Redis         RedisConfig          `koanf:"redis" validate:"required"`
 	Integration   IntegrationConfig    `koanf:"integration" validate:"required"`
+	Webhooks      WebhookConfig        `koanf:"webhooks"`
+	Notifications NotificationConfig   `koanf:"notifications"`
+	Privacy       PrivacyConfig        `koanf:"privacy"`
+	Retention     RetentionConfig      `koanf:"retention"`
+	Batch         BatchConfig          `koanf:"batch"`
+	Outbound      OutboundConfig       `koanf:"outbound"`
+	Resilience    ResilienceConfig     `koanf:"resilience"`
+	LoadShed      LoadShedConfig       `koanf:"load_shed"`
 	Observability *ObservabilityConfig `koanf:"observability"`
 }
 
@@ -26,11 +36,24 @@ type Primary struct {
 }
 
 type ServerConfig struct {
-	Port               string   `koanf:"port" validate:"required"`
-	ReadTimeout        int      `koanf:"read_timeout" validate:"required"`
-	WriteTimeout       int      `koanf:"write_timeout" validate:"required"`
-	IdleTimeout        int      `koanf:"idle_timeout" validate:"required"`
-	CORSAllowedOrigins []string `koanf:"cors_allowed_origins" validate:"required"`
+	Port               string     `koanf:"port" validate:"required"`
+	ReadTimeout        int        `koanf:"read_timeout" validate:"required"`
+	WriteTimeout       int        `koanf:"write_timeout" validate:"required"`
+	IdleTimeout        int        `koanf:"idle_timeout" validate:"required"`
+	CORSAllowedOrigins []string   `koanf:"cors_allowed_origins" validate:"required"`
+	JSON               JSONConfig `koanf:"json"`
+}
+
+// JSONConfig configures how request and response bodies are encoded, see
+// internal/lib/jsoncodec
+type JSONConfig struct {
+	// Codec is std for encoding/json or go-json for goccy/go-json
+	Codec string `koanf:"codec" validate:"omitempty,oneof=std go-json"`
+	// Strict rejects request bodies with unknown fields or data after the value
+	Strict bool `koanf:"strict"`
+	// UseNumber decodes numbers into interface{} values as json.Number
+	// instead of float64, keeping large integers exact
+	UseNumber bool `koanf:"use_number"`
 }
 
 type DatabaseConfig struct {
@@ -44,7 +67,54 @@ type DatabaseConfig struct {
 	MaxIdleConns    int    `koanf:"max_idle_conns" validate:"required"`
 	ConnMaxLifetime int    `koanf:"conn_max_lifetime" validate:"required"`
 	ConnMaxIdleTime int    `koanf:"conn_max_idle_time" validate:"required"`
+	// QueryExecMode is how pgx sends queries, one of the pgx
+	// default_query_exec_mode values
+	QueryExecMode string `koanf:"query_exec_mode" validate:"omitempty,oneof=cache_statement cache_describe describe_exec exec simple_protocol"`
+	// StatementCacheCapacity bounds the prepared statements of each
+	// connection in cache_statement mode, 0 disables the cache and is
+	// rejected in that mode
+	StatementCacheCapacity int `koanf:"statement_cache_capacity" validate:"min=0"`
+	// DescriptionCacheCapacity bounds the statement descriptions of each
+	// connection in cache_describe mode, 0 disables the cache and is
+	// rejected in that mode
+	DescriptionCacheCapacity int `koanf:"description_cache_capacity" validate:"min=0"`
+	// PgBouncer defaults the mode and caches to ones that work behind
+	// PgBouncer in transaction pooling mode
+	PgBouncer bool `koanf:"pgbouncer"`
+}
+
+// withDefaults fills the query mode and the cache capacities that are not set
+// explicitly. 0 disables a cache, so the capacities are only filled when
+// unset. Behind PgBouncer the mode defaults to exec and the caches to off,
+// except the one an explicit cache mode relies on.
+func (d DatabaseConfig) withDefaults(statementCacheSet, descriptionCacheSet bool) DatabaseConfig {
+	if d.QueryExecMode == "" {
+		d.QueryExecMode = DefaultDatabaseQueryExecMode
+		if d.PgBouncer {
+			d.QueryExecMode = PgBouncerQueryExecMode
+		}
+	}
+	if !statementCacheSet && (!d.PgBouncer || d.QueryExecMode == "cache_statement") {
+		d.StatementCacheCapacity = DefaultDatabaseStatementCacheCapacity
+	}
+	if !descriptionCacheSet && (!d.PgBouncer || d.QueryExecMode == "cache_describe") {
+		d.DescriptionCacheCapacity = DefaultDatabaseDescriptionCacheCapacity
+	}
+	return d
 }
+
+// Validate rejects a cache mode whose cache is disabled, pgx would fail every
+// query with it
+func (d DatabaseConfig) Validate() error {
+	if d.QueryExecMode == "cache_statement" && d.StatementCacheCapacity == 0 {
+		return fmt.Errorf("query_exec_mode cache_statement requires a statement_cache_capacity above 0")
+	}
+	if d.QueryExecMode == "cache_describe" && d.DescriptionCacheCapacity == 0 {
+		return fmt.Errorf("query_exec_mode cache_describe requires a description_cache_capacity above 0")
+	}
+	return nil
+}
+
 type RedisConfig struct {
 	Address string `koanf:"address" validate:"required"`
 }
@@ -54,9 +124,242 @@ type IntegrationConfig struct {
 }
 
 type AuthConfig struct {
-	SecretKey string `koanf:"secret_key" validate:"required"`
+	SecretKey   string           `koanf:"secret_key" validate:"required"`
+	Invitations InvitationConfig `koanf:"invitations"`
+	// ServiceToken authorizes operational endpoints through the
+	// x-service-token header; they are disabled when it is empty
+	ServiceToken string `koanf:"service_token"`
+}
+
+type InvitationConfig struct {
+	// SigningKey signs invitation tokens; the Clerk secret key is used when empty
+	SigningKey string        `koanf:"signing_key"`
+	TTL        time.Duration `koanf:"ttl"`
+	// AcceptURL is the frontend page invitation emails link to, with ?token= appended
+	AcceptURL string `koanf:"accept_url"`
+}
+
+type WebhookConfig struct {
+	// Timeout bounds a single delivery attempt
+	Timeout    time.Duration `koanf:"timeout"`
+	MaxRetries int           `koanf:"max_retries"`
+	// DisableAfterFailures disables an endpoint once this many deliveries in a
+	// row have failed every retry
+	DisableAfterFailures int `koanf:"disable_after_failures"`
+	// AllowPrivateAddresses lets deliveries reach loopback and private
+	// network addresses, for receivers running locally in development
+	AllowPrivateAddresses bool `koanf:"allow_private_addresses"`
+}
+
+type NotificationConfig struct {
+	// DigestHour is the hour of day, in each user's timezone, digest emails go out
+	DigestHour int `koanf:"digest_hour" validate:"min=0,max=23"`
+	// AppURL is the frontend page notification emails link to
+	AppURL string `koanf:"app_url"`
+}
+
+type PrivacyConfig struct {
+	// SigningKey signs export download links; the Clerk secret key is used when empty
+	SigningKey string `koanf:"signing_key"`
+	// ExportTTL is how long a finished export can be downloaded
+	ExportTTL time.Duration `koanf:"export_ttl"`
+	// DownloadURL is the export download endpoint emails link to, with ?token= appended
+	DownloadURL string `koanf:"download_url"`
+}
+
+type RetentionConfig struct {
+	// Schedule is the cron spec or @every interval purges run at
+	Schedule string `koanf:"schedule"`
+	// BatchSize is the number of rows purged per transaction
+	BatchSize int `koanf:"batch_size"`
+	// MaxBatches bounds the batches a policy purges per run; the rest wait
+	// for the next run
+	MaxBatches int `koanf:"max_batches"`
+	// ArchiveDir receives the rows of archiving policies before they are purged
+	ArchiveDir string `koanf:"archive_dir"`
+}
+
+type BatchConfig struct {
+	// MaxRequests bounds the sub-requests of one batch
+	MaxRequests int `koanf:"max_requests"`
+	// MaxBodyBytes bounds the size of the whole batch request body
+	MaxBodyBytes int64 `koanf:"max_body_bytes"`
+}
+
+type OutboundConfig struct {
+	// Timeout bounds a single attempt of an outbound request
+	Timeout time.Duration `koanf:"timeout"`
+	// MaxRetries is how often idempotent requests are retried after a
+	// network error, 429 or 5xx response
+	MaxRetries     int           `koanf:"max_retries"`
+	RetryBaseDelay time.Duration `koanf:"retry_base_delay"`
+	RetryMaxDelay  time.Duration `koanf:"retry_max_delay"`
+	// BreakerFailures is the number of failures in a row that opens the
+	// circuit of a host; requests to it fail fast for BreakerCooldown
+	BreakerFailures int           `koanf:"breaker_failures"`
+	BreakerCooldown time.Duration `koanf:"breaker_cooldown"`
+}
+
+// ResilienceConfig configures the circuit breaker and bulkhead of each
+// dependency, see internal/lib/resilience
+type ResilienceConfig struct {
+	Postgres GuardConfig `koanf:"postgres"`
+	Redis    GuardConfig `koanf:"redis"`
+	Jobs     GuardConfig `koanf:"jobs"`
+	Email    GuardConfig `koanf:"email"`
 }
 
+// GuardConfig configures the guard of one dependency
+type GuardConfig struct {
+	// BreakerFailures is the number of failures in a row that opens the
+	// circuit; calls are rejected for BreakerCooldown
+	BreakerFailures int           `koanf:"breaker_failures"`
+	BreakerCooldown time.Duration `koanf:"breaker_cooldown"`
+	// MaxConcurrent bounds the calls in flight; further calls wait up to
+	// MaxWait for a slot. Postgres is unbounded by default.
+	MaxConcurrent int           `koanf:"max_concurrent"`
+	MaxWait       time.Duration `koanf:"max_wait"`
+	// Fallback is what callers do when a call is rejected: skip the
+	// dependency where they can do without it, or fail
+	Fallback string `koanf:"fallback" validate:"omitempty,oneof=skip fail"`
+}
+
+// LoadShedConfig bounds the requests the server works on at once, so under
+// overload excess requests are rejected fast instead of slowing down all
+type LoadShedConfig struct {
+	// MaxInFlight bounds the requests in flight across the server; 0 disables
+	// load shedding
+	MaxInFlight int `koanf:"max_in_flight"`
+	// ReadMaxInFlight, WriteMaxInFlight and BatchMaxInFlight bound the
+	// requests in flight per route class; 0 leaves a class unbounded
+	ReadMaxInFlight  int `koanf:"read_max_in_flight"`
+	WriteMaxInFlight int `koanf:"write_max_in_flight"`
+	BatchMaxInFlight int `koanf:"batch_max_in_flight"`
+	// TargetLatency adapts the limits: requests slower than it lower them,
+	// down to MinInFlight. 0 keeps the limits fixed.
+	TargetLatency time.Duration `koanf:"target_latency"`
+	MinInFlight   int           `koanf:"min_in_flight"`
+	// AnonymousShare is the percentage of each limit requests without
+	// credentials may use, the rest is kept for authenticated users
+	AnonymousShare int `koanf:"anonymous_share" validate:"omitempty,min=1,max=100"`
+	// RetryAfter is sent with rejected requests
+	RetryAfter time.Duration `koanf:"retry_after"`
+}
+
+// withDefaults fills the unset fields of g from defaults. MaxConcurrent 0
+// means unbounded, so it is only filled when maxConcurrentSet is false.
+func (g GuardConfig) withDefaults(defaults GuardConfig, maxConcurrentSet bool) GuardConfig {
+	if g.BreakerFailures <= 0 {
+		g.BreakerFailures = defaults.BreakerFailures
+	}
+	if g.BreakerCooldown <= 0 {
+		g.BreakerCooldown = defaults.BreakerCooldown
+	}
+	if !maxConcurrentSet {
+		g.MaxConcurrent = defaults.MaxConcurrent
+	}
+	if g.MaxWait <= 0 {
+		g.MaxWait = defaults.MaxWait
+	}
+	if g.Fallback == "" {
+		g.Fallback = defaults.Fallback
+	}
+	return g
+}
+
+const (
+	DefaultDatabaseQueryExecMode            = "cache_statement"
+	DefaultDatabaseStatementCacheCapacity   = 512
+	DefaultDatabaseDescriptionCacheCapacity = 512
+	// PgBouncer in transaction mode can run each query on another server
+	// connection, where statements prepared on the last one do not exist.
+	// exec mode prepares nothing and sends a query in a single round trip.
+	PgBouncerQueryExecMode = "exec"
+)
+
+const (
+	DefaultWebhookTimeout              = 10 * time.Second
+	DefaultWebhookMaxRetries           = 8
+	DefaultWebhookDisableAfterFailures = 5
+)
+
+const (
+	DefaultNotificationDigestHour = 9
+	DefaultNotificationAppURL     = "http://localhost:3000/notifications"
+)
+
+const (
+	DefaultPrivacyExportTTL   = 7 * 24 * time.Hour
+	DefaultPrivacyDownloadURL = "http://localhost:8080/api/v1/privacy/exports/download"
+)
+
+const (
+	DefaultRetentionSchedule   = "@hourly"
+	DefaultRetentionBatchSize  = 1000
+	DefaultRetentionMaxBatches = 100
+	DefaultRetentionArchiveDir = "archive/retention"
+)
+
+const (
+	DefaultBatchMaxRequests  = 20
+	DefaultBatchMaxBodyBytes = 1 << 20
+)
+
+const (
+	DefaultOutboundTimeout         = 10 * time.Second
+	DefaultOutboundMaxRetries      = 2
+	DefaultOutboundRetryBaseDelay  = 200 * time.Millisecond
+	DefaultOutboundRetryMaxDelay   = 5 * time.Second
+	DefaultOutboundBreakerFailures = 5
+	DefaultOutboundBreakerCooldown = 30 * time.Second
+)
+
+// The pool already bounds concurrent Postgres calls. Postgres and Redis guard
+// calls their callers can do without, so they fall back to skipping, while job
+// enqueues and email sends have callers that retry and fail
+var (
+	DefaultResiliencePostgres = GuardConfig{BreakerFailures: 5, BreakerCooldown: 10 * time.Second, Fallback: "skip"}
+	DefaultResilienceRedis    = GuardConfig{
+		BreakerFailures: 5,
+		BreakerCooldown: 10 * time.Second,
+		MaxConcurrent:   100,
+		MaxWait:         50 * time.Millisecond,
+		Fallback:        "skip",
+	}
+	DefaultResilienceJobs = GuardConfig{
+		BreakerFailures: 5,
+		BreakerCooldown: 10 * time.Second,
+		MaxConcurrent:   50,
+		MaxWait:         100 * time.Millisecond,
+		Fallback:        "fail",
+	}
+	DefaultResilienceEmail = GuardConfig{
+		BreakerFailures: 5,
+		BreakerCooldown: 30 * time.Second,
+		MaxConcurrent:   5,
+		MaxWait:         time.Second,
+		Fallback:        "fail",
+	}
+)
+
+const (
+	DefaultLoadShedMaxInFlight      = 1000
+	DefaultLoadShedReadMaxInFlight  = 800
+	DefaultLoadShedWriteMaxInFlight = 400
+	DefaultLoadShedBatchMaxInFlight = 50
+	DefaultLoadShedTargetLatency    = 500 * time.Millisecond
+	DefaultLoadShedMinInFlight      = 20
+	DefaultLoadShedAnonymousShare   = 80
+	DefaultLoadShedRetryAfter       = time.Second
+)
+
+const DefaultJSONCodec = "std"
+
+const (
+	DefaultInvitationTTL       = 7 * 24 * time.Hour
+	DefaultInvitationAcceptURL = "http://localhost:3000/invitations/accept"
+)
+
 func LoadConfig() (*Config, error) {
 	logger := zerolog.New(zerolog.ConsoleWriter{Out: os.Stderr}).With().Timestamp().Logger()
 
@@ -83,15 +386,151 @@ func LoadConfig() (*Config, error) {
 		logger.Fatal().Err(err).Msg("config validation failed")
 	}
 
+	// Explicit settings win over the PgBouncer preset
+	mainConfig.Database = mainConfig.Database.withDefaults(
+		k.Exists("database.statement_cache_capacity"),
+		k.Exists("database.description_cache_capacity"),
+	)
+	if err := mainConfig.Database.Validate(); err != nil {
+		logger.Fatal().Err(err).Msg("invalid database config")
+	}
+
+	if mainConfig.Server.JSON.Codec == "" {
+		mainConfig.Server.JSON.Codec = DefaultJSONCodec
+	}
+
+	if mainConfig.Auth.Invitations.SigningKey == "" {
+		mainConfig.Auth.Invitations.SigningKey = mainConfig.Auth.SecretKey
+	}
+	if mainConfig.Auth.Invitations.TTL <= 0 {
+		mainConfig.Auth.Invitations.TTL = DefaultInvitationTTL
+	}
+	if mainConfig.Auth.Invitations.AcceptURL == "" {
+		mainConfig.Auth.Invitations.AcceptURL = DefaultInvitationAcceptURL
+	}
+
+	if mainConfig.Webhooks.Timeout <= 0 {
+		mainConfig.Webhooks.Timeout = DefaultWebhookTimeout
+	}
+	if mainConfig.Webhooks.MaxRetries <= 0 {
+		mainConfig.Webhooks.MaxRetries = DefaultWebhookMaxRetries
+	}
+	if mainConfig.Webhooks.DisableAfterFailures <= 0 {
+		mainConfig.Webhooks.DisableAfterFailures = DefaultWebhookDisableAfterFailures
+	}
+
+	// 0 is a valid hour, so only apply the default when the variable is unset
+	if !k.Exists("notifications.digest_hour") {
+		mainConfig.Notifications.DigestHour = DefaultNotificationDigestHour
+	}
+	if mainConfig.Notifications.AppURL == "" {
+		mainConfig.Notifications.AppURL = DefaultNotificationAppURL
+	}
+
+	if mainConfig.Privacy.SigningKey == "" {
+		mainConfig.Privacy.SigningKey = mainConfig.Auth.SecretKey
+	}
+	if mainConfig.Privacy.ExportTTL <= 0 {
+		mainConfig.Privacy.ExportTTL = DefaultPrivacyExportTTL
+	}
+	if mainConfig.Privacy.DownloadURL == "" {
+		mainConfig.Privacy.DownloadURL = DefaultPrivacyDownloadURL
+	}
+
+	if mainConfig.Retention.Schedule == "" {
+		mainConfig.Retention.Schedule = DefaultRetentionSchedule
+	}
+	if mainConfig.Retention.BatchSize <= 0 {
+		mainConfig.Retention.BatchSize = DefaultRetentionBatchSize
+	}
+	if mainConfig.Retention.MaxBatches <= 0 {
+		mainConfig.Retention.MaxBatches = DefaultRetentionMaxBatches
+	}
+	if mainConfig.Retention.ArchiveDir == "" {
+		mainConfig.Retention.ArchiveDir = DefaultRetentionArchiveDir
+	}
+
+	if mainConfig.Batch.MaxRequests <= 0 {
+		mainConfig.Batch.MaxRequests = DefaultBatchMaxRequests
+	}
+	if mainConfig.Batch.MaxBodyBytes <= 0 {
+		mainConfig.Batch.MaxBodyBytes = DefaultBatchMaxBodyBytes
+	}
+
+	if mainConfig.Outbound.Timeout <= 0 {
+		mainConfig.Outbound.Timeout = DefaultOutboundTimeout
+	}
+	// 0 disables retries, so only apply the default when the variable is unset
+	if !k.Exists("outbound.max_retries") {
+		mainConfig.Outbound.MaxRetries = DefaultOutboundMaxRetries
+	}
+	if mainConfig.Outbound.RetryBaseDelay <= 0 {
+		mainConfig.Outbound.RetryBaseDelay = DefaultOutboundRetryBaseDelay
+	}
+	if mainConfig.Outbound.RetryMaxDelay <= 0 {
+		mainConfig.Outbound.RetryMaxDelay = DefaultOutboundRetryMaxDelay
+	}
+	if mainConfig.Outbound.BreakerFailures <= 0 {
+		mainConfig.Outbound.BreakerFailures = DefaultOutboundBreakerFailures
+	}
+	if mainConfig.Outbound.BreakerCooldown <= 0 {
+		mainConfig.Outbound.BreakerCooldown = DefaultOutboundBreakerCooldown
+	}
+
+	mainConfig.Resilience.Postgres = mainConfig.Resilience.Postgres.withDefaults(DefaultResiliencePostgres, k.Exists("resilience.postgres.max_concurrent"))
+	mainConfig.Resilience.Redis = mainConfig.Resilience.Redis.withDefaults(DefaultResilienceRedis, k.Exists("resilience.redis.max_concurrent"))
+	mainConfig.Resilience.Jobs = mainConfig.Resilience.Jobs.withDefaults(DefaultResilienceJobs, k.Exists("resilience.jobs.max_concurrent"))
+	mainConfig.Resilience.Email = mainConfig.Resilience.Email.withDefaults(DefaultResilienceEmail, k.Exists("resilience.email.max_concurrent"))
+
+	// 0 disables load shedding, a class limit or adaptation, so only apply
+	// those defaults when the variable is unset
+	if !k.Exists("load_shed.max_in_flight") {
+		mainConfig.LoadShed.MaxInFlight = DefaultLoadShedMaxInFlight
+	}
+	if !k.Exists("load_shed.read_max_in_flight") {
+		mainConfig.LoadShed.ReadMaxInFlight = DefaultLoadShedReadMaxInFlight
+	}
+	if !k.Exists("load_shed.write_max_in_flight") {
+		mainConfig.LoadShed.WriteMaxInFlight = DefaultLoadShedWriteMaxInFlight
+	}
+	if !k.Exists("load_shed.batch_max_in_flight") {
+		mainConfig.LoadShed.BatchMaxInFlight = DefaultLoadShedBatchMaxInFlight
+	}
+	if !k.Exists("load_shed.target_latency") {
+		mainConfig.LoadShed.TargetLatency = DefaultLoadShedTargetLatency
+	}
+	if mainConfig.LoadShed.MinInFlight <= 0 {
+		mainConfig.LoadShed.MinInFlight = DefaultLoadShedMinInFlight
+	}
+	if mainConfig.LoadShed.AnonymousShare <= 0 {
+		mainConfig.LoadShed.AnonymousShare = DefaultLoadShedAnonymousShare
+	}
+	if mainConfig.LoadShed.RetryAfter <= 0 {
+		mainConfig.LoadShed.RetryAfter = DefaultLoadShedRetryAfter
+	}
+
 	// Set default observability config if not provided
 	if mainConfig.Observability == nil {
 		mainConfig.Observability = DefaultObservabilityConfig()
 	}
 
+	defaultQueryStats := DefaultQueryStatsConfig()
+	if mainConfig.Observability.QueryStats.MaxQueries <= 0 {
+		mainConfig.Observability.QueryStats.MaxQueries = defaultQueryStats.MaxQueries
+	}
+	if mainConfig.Observability.QueryStats.MaxRepeats <= 0 {
+		mainConfig.Observability.QueryStats.MaxRepeats = defaultQueryStats.MaxRepeats
+	}
+
 	// Override service name and environment from primary config
 	mainConfig.Observability.ServiceName = "boilerplate"
 	mainConfig.Observability.Environment = mainConfig.Primary.Env
 
+	// Server-Timing exposes internals, so it is only on by default outside production
+	if !k.Exists("observability.server_timing") {
+		mainConfig.Observability.ServerTiming = !mainConfig.Observability.IsProduction()
+	}
+
 	// Validate observability config
 	if err := mainConfig.Observability.Validate(); err != nil {
 		logger.Fatal().Err(err).Msg("invalid observability config")
@@ -0,0 +1,78 @@
+package config
+
+import "testing"
+
+func TestDatabaseConfigWithDefaults(t *testing.T) {
+	tests := []struct {
+		name                string
+		cfg                 DatabaseConfig
+		statementCacheSet   bool
+		descriptionCacheSet bool
+		want                DatabaseConfig
+	}{
+		{
+			name: "unset",
+			want: DatabaseConfig{QueryExecMode: "cache_statement", StatementCacheCapacity: 512, DescriptionCacheCapacity: 512},
+		},
+		{
+			name:              "explicit capacity of 0",
+			cfg:               DatabaseConfig{QueryExecMode: "exec"},
+			statementCacheSet: true,
+			want:              DatabaseConfig{QueryExecMode: "exec", DescriptionCacheCapacity: 512},
+		},
+		{
+			name: "pgbouncer",
+			cfg:  DatabaseConfig{PgBouncer: true},
+			want: DatabaseConfig{PgBouncer: true, QueryExecMode: "exec"},
+		},
+		{
+			name: "pgbouncer with cache_statement",
+			cfg:  DatabaseConfig{PgBouncer: true, QueryExecMode: "cache_statement"},
+			want: DatabaseConfig{PgBouncer: true, QueryExecMode: "cache_statement", StatementCacheCapacity: 512},
+		},
+		{
+			name: "pgbouncer with cache_describe",
+			cfg:  DatabaseConfig{PgBouncer: true, QueryExecMode: "cache_describe"},
+			want: DatabaseConfig{PgBouncer: true, QueryExecMode: "cache_describe", DescriptionCacheCapacity: 512},
+		},
+		{
+			name:                "pgbouncer with explicit capacities",
+			cfg:                 DatabaseConfig{PgBouncer: true, QueryExecMode: "cache_describe", DescriptionCacheCapacity: 64},
+			descriptionCacheSet: true,
+			want:                DatabaseConfig{PgBouncer: true, QueryExecMode: "cache_describe", DescriptionCacheCapacity: 64},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.cfg.withDefaults(tt.statementCacheSet, tt.descriptionCacheSet)
+			if got != tt.want {
+				t.Errorf("withDefaults() = %+v, want %+v", got, tt.want)
+			}
+			if err := got.Validate(); err != nil {
+				t.Errorf("Validate() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestDatabaseConfigValidateRejectsDisabledModeCache(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     DatabaseConfig
+		wantErr bool
+	}{
+		{"cache_statement without a statement cache", DatabaseConfig{QueryExecMode: "cache_statement", DescriptionCacheCapacity: 512}, true},
+		{"cache_describe without a description cache", DatabaseConfig{QueryExecMode: "cache_describe", StatementCacheCapacity: 512}, true},
+		{"cache_statement without a description cache", DatabaseConfig{QueryExecMode: "cache_statement", StatementCacheCapacity: 512}, false},
+		{"exec without caches", DatabaseConfig{QueryExecMode: "exec"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
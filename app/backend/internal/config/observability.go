@@ -2,6 +2,8 @@ package config
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -11,12 +13,74 @@ type ObservabilityConfig struct {
 	Logging      LoggingConfig      `koanf:"logging" validate:"required"`
 	NewRelic     NewRelicConfig     `koanf:"new_relic" validate:"required"`
 	HealthChecks HealthChecksConfig `koanf:"health_checks" validate:"required"`
+	QueryStats   QueryStatsConfig   `koanf:"query_stats"`
+	// ServerTiming sends the time spent per layer in a Server-Timing header,
+	// on by default outside production
+	ServerTiming bool `koanf:"server_timing"`
 }
 
 type LoggingConfig struct {
 	Level              string        `koanf:"level" validate:"required"`
 	Format             string        `koanf:"format" validate:"required"`
 	SlowQueryThreshold time.Duration `koanf:"slow_query_threshold"`
+	// RouteLevels overrides the level for requests on a route, as "route=level" pairs
+	RouteLevels []string `koanf:"route_levels"`
+	// ComponentLevels overrides the level for a component logger, as "component=level" pairs
+	ComponentLevels []string          `koanf:"component_levels"`
+	Sampling        LogSamplingConfig `koanf:"sampling"`
+	Dedupe          LogDedupeConfig   `koanf:"dedupe"`
+	Sinks           LogSinksConfig    `koanf:"sinks"`
+}
+
+// LogSinksConfig configures where logs are written. Stdout is used when no sink is enabled.
+type LogSinksConfig struct {
+	Stdout  LogSinkConfig        `koanf:"stdout"`
+	File    LogFileSinkConfig    `koanf:"file"`
+	Network LogNetworkSinkConfig `koanf:"network"`
+	Async   LogAsyncConfig       `koanf:"async"`
+}
+
+type LogSinkConfig struct {
+	Enabled bool `koanf:"enabled"`
+	// MinLevel is the lowest level written to the sink, defaults to the logger level
+	MinLevel string `koanf:"min_level"`
+	// Format overrides Logging.Format for this sink
+	Format string `koanf:"format"`
+}
+
+type LogFileSinkConfig struct {
+	LogSinkConfig `koanf:",squash"`
+	Path          string `koanf:"path"`
+	MaxSizeMB     int    `koanf:"max_size_mb"`
+	MaxAgeDays    int    `koanf:"max_age_days"`
+	MaxBackups    int    `koanf:"max_backups"`
+	Compress      bool   `koanf:"compress"`
+}
+
+// LogNetworkSinkConfig forwards log lines over tcp or udp, e.g. to a local syslog or vector agent
+type LogNetworkSinkConfig struct {
+	LogSinkConfig `koanf:",squash"`
+	Network       string `koanf:"network"`
+	Address       string `koanf:"address"`
+}
+
+type LogAsyncConfig struct {
+	Enabled bool `koanf:"enabled"`
+	// BufferSize is the number of lines buffered per sink before new lines are dropped
+	BufferSize int `koanf:"buffer_size"`
+}
+
+type LogSamplingConfig struct {
+	// Routes lists "route=N" pairs; successful requests on the route are logged 1 in N times
+	Routes []string `koanf:"routes"`
+}
+
+type LogDedupeConfig struct {
+	Enabled bool `koanf:"enabled"`
+	// Window is how long identical error lines are counted together
+	Window time.Duration `koanf:"window"`
+	// Burst is how many identical error lines are written per window before the rest are dropped
+	Burst int `koanf:"burst"`
 }
 
 type NewRelicConfig struct {
@@ -33,6 +97,15 @@ type HealthChecksConfig struct {
 	Checks   []string      `koanf:"checks"`
 }
 
+// QueryStatsConfig sets when the queries of a request are reported as a
+// likely N+1
+type QueryStatsConfig struct {
+	// MaxQueries is the number of queries a request may run before a warning
+	MaxQueries int `koanf:"max_queries"`
+	// MaxRepeats is how often a request may run the same statement before a warning
+	MaxRepeats int `koanf:"max_repeats"`
+}
+
 func DefaultObservabilityConfig() *ObservabilityConfig {
 	return &ObservabilityConfig{
 		ServiceName: "boilerplate",
@@ -41,6 +114,18 @@ func DefaultObservabilityConfig() *ObservabilityConfig {
 			Level:              "info",
 			Format:             "json",
 			SlowQueryThreshold: 100 * time.Millisecond,
+			Dedupe: LogDedupeConfig{
+				Enabled: true,
+				Window:  10 * time.Second,
+				Burst:   5,
+			},
+			Sinks: LogSinksConfig{
+				Stdout: LogSinkConfig{Enabled: true},
+				Async: LogAsyncConfig{
+					Enabled:    true,
+					BufferSize: 10000,
+				},
+			},
 		},
 		NewRelic: NewRelicConfig{
 			LicenseKey:                "",
@@ -54,6 +139,14 @@ func DefaultObservabilityConfig() *ObservabilityConfig {
 			Timeout:  5 * time.Second,
 			Checks:   []string{"database", "redis"},
 		},
+		QueryStats: DefaultQueryStatsConfig(),
+	}
+}
+
+func DefaultQueryStatsConfig() QueryStatsConfig {
+	return QueryStatsConfig{
+		MaxQueries: 25,
+		MaxRepeats: 5,
 	}
 }
 
@@ -70,14 +163,136 @@ func (c *ObservabilityConfig) Validate() error {
 		return fmt.Errorf("invalid logging level: %s (must be one of: debug, info, warn, error)", c.Logging.Level)
 	}
 
+	// Validate log format
+	if !validFormats[c.Logging.Format] {
+		return fmt.Errorf("invalid logging format: %s (must be one of: json, console)", c.Logging.Format)
+	}
+
 	// Validate slow query threshold
 	if c.Logging.SlowQueryThreshold < 0 {
 		return fmt.Errorf("logging slow_query_threshold must be non-negative")
 	}
 
+	// Validate level overrides
+	for name, entries := range map[string][]string{
+		"route_levels":     c.Logging.RouteLevels,
+		"component_levels": c.Logging.ComponentLevels,
+	} {
+		levels, err := parsePairs(entries)
+		if err != nil {
+			return fmt.Errorf("invalid logging %s: %w", name, err)
+		}
+		for key, level := range levels {
+			if !validLevels[level] {
+				return fmt.Errorf("invalid logging %s level for %s: %s", name, key, level)
+			}
+		}
+	}
+
+	// Validate sampling rates
+	if _, err := c.Logging.RouteSampleRates(); err != nil {
+		return fmt.Errorf("invalid logging sampling routes: %w", err)
+	}
+
+	// Validate sinks
+	if err := c.Logging.Sinks.validate(validLevels); err != nil {
+		return err
+	}
+
+	// Validate dedupe
+	if c.Logging.Dedupe.Enabled && (c.Logging.Dedupe.Window <= 0 || c.Logging.Dedupe.Burst < 1) {
+		return fmt.Errorf("logging dedupe requires a positive window and a burst of at least 1")
+	}
+
 	return nil
 }
 
+var validFormats = map[string]bool{
+	"json": true, "console": true,
+}
+
+func (c LogSinksConfig) validate(validLevels map[string]bool) error {
+	sinks := map[string]LogSinkConfig{
+		"stdout":  c.Stdout,
+		"file":    c.File.LogSinkConfig,
+		"network": c.Network.LogSinkConfig,
+	}
+	for name, sink := range sinks {
+		if !sink.Enabled {
+			continue
+		}
+		if sink.MinLevel != "" && !validLevels[sink.MinLevel] {
+			return fmt.Errorf("invalid logging sinks.%s min_level: %s", name, sink.MinLevel)
+		}
+		if sink.Format != "" && !validFormats[sink.Format] {
+			return fmt.Errorf("invalid logging sinks.%s format: %s", name, sink.Format)
+		}
+	}
+
+	if c.File.Enabled && c.File.Path == "" {
+		return fmt.Errorf("logging sinks.file.path is required when the file sink is enabled")
+	}
+
+	if c.Network.Enabled {
+		if c.Network.Network != "tcp" && c.Network.Network != "udp" {
+			return fmt.Errorf("invalid logging sinks.network.network: %s (must be one of: tcp, udp)", c.Network.Network)
+		}
+		if c.Network.Address == "" {
+			return fmt.Errorf("logging sinks.network.address is required when the network sink is enabled")
+		}
+	}
+
+	if c.Async.Enabled && c.Async.BufferSize < 1 {
+		return fmt.Errorf("logging sinks.async.buffer_size must be positive")
+	}
+
+	return nil
+}
+
+// RouteLevelOverrides returns the configured log level for each route.
+func (c LoggingConfig) RouteLevelOverrides() map[string]string {
+	levels, _ := parsePairs(c.RouteLevels)
+	return levels
+}
+
+// ComponentLevelOverrides returns the configured log level for each component.
+func (c LoggingConfig) ComponentLevelOverrides() map[string]string {
+	levels, _ := parsePairs(c.ComponentLevels)
+	return levels
+}
+
+// RouteSampleRates returns N for each route whose successful requests are logged 1 in N times.
+func (c LoggingConfig) RouteSampleRates() (map[string]int, error) {
+	pairs, err := parsePairs(c.Sampling.Routes)
+	if err != nil {
+		return nil, err
+	}
+
+	rates := make(map[string]int, len(pairs))
+	for route, value := range pairs {
+		rate, err := strconv.Atoi(value)
+		if err != nil || rate < 1 {
+			return nil, fmt.Errorf("rate for %s must be a positive integer, got %q", route, value)
+		}
+		rates[route] = rate
+	}
+
+	return rates, nil
+}
+
+// parsePairs splits "key=value" entries into a map.
+func parsePairs(entries []string) (map[string]string, error) {
+	pairs := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		key, value, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || key == "" || value == "" {
+			return nil, fmt.Errorf("expected key=value, got %q", entry)
+		}
+		pairs[key] = value
+	}
+	return pairs, nil
+}
+
 func (c *ObservabilityConfig) GetLogLevel() string {
 	switch c.Environment {
 	case "production":
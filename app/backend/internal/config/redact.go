@@ -0,0 +1,12 @@
+package config
+
+// Redacted replaces secret values in logs and summaries
+const Redacted = "[redacted]"
+
+// Redact hides a secret while still showing whether it is set
+func Redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return Redacted
+}
@@ -0,0 +1,62 @@
+package database
+
+import (
+	"context"
+	"sync"
+
+	"github.com/apk471/go-boilerplate/internal/logger"
+)
+
+// afterCommit holds the functions AfterCommit deferred until a transaction
+// commits
+type afterCommit struct {
+	mu  sync.Mutex
+	fns []func(ctx context.Context) error
+}
+
+type afterCommitKey struct{}
+
+// WithAfterCommit returns a context in which AfterCommit defers functions
+// instead of running them, for callers that hold one transaction across
+// several service calls, such as atomic batches. Call run once the
+// transaction commits. On rollback, drop the context and the deferred
+// functions with it.
+func WithAfterCommit(ctx context.Context) (context.Context, func(ctx context.Context)) {
+	hooks := &afterCommit{}
+
+	run := func(ctx context.Context) {
+		hooks.mu.Lock()
+		fns := hooks.fns
+		hooks.fns = nil
+		hooks.mu.Unlock()
+
+		for _, fn := range fns {
+			if err := fn(ctx); err != nil {
+				logger.FromContext(ctx).Error().Err(err).Msg("after commit hook failed")
+			}
+		}
+	}
+
+	return context.WithValue(ctx, afterCommitKey{}, hooks), run
+}
+
+// AfterCommit runs fn once the data written with ctx is committed: right away
+// unless ctx comes from WithAfterCommit, in which case fn is deferred until
+// the transaction commits and dropped if it rolls back. Side effects outside
+// Postgres, such as cache writes, enqueued jobs and published messages, go
+// through it so that a rollback does not leave them behind.
+//
+// A deferred fn runs with the context passed to run, outside the
+// transaction, and its error is logged since the caller has returned by then.
+// Otherwise its error is returned.
+func AfterCommit(ctx context.Context, fn func(ctx context.Context) error) error {
+	hooks, ok := ctx.Value(afterCommitKey{}).(*afterCommit)
+	if !ok {
+		return fn(ctx)
+	}
+
+	hooks.mu.Lock()
+	hooks.fns = append(hooks.fns, fn)
+	hooks.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,65 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestAfterCommitRunsRightAwayWithoutHooks(t *testing.T) {
+	ran := false
+	want := errors.New("enqueue failed")
+
+	err := AfterCommit(context.Background(), func(context.Context) error {
+		ran = true
+		return want
+	})
+	if !ran {
+		t.Fatal("fn did not run")
+	}
+	if !errors.Is(err, want) {
+		t.Errorf("err = %v, want %v", err, want)
+	}
+}
+
+func TestAfterCommitDefersUntilRun(t *testing.T) {
+	ctx, run := WithAfterCommit(context.Background())
+
+	var ran []int
+	for i := range 3 {
+		err := AfterCommit(ctx, func(context.Context) error {
+			ran = append(ran, i)
+			return errors.New("logged only")
+		})
+		if err != nil {
+			t.Fatalf("deferred AfterCommit returned %v", err)
+		}
+	}
+	if len(ran) != 0 {
+		t.Fatalf("hooks ran before commit: %v", ran)
+	}
+
+	run(context.Background())
+	if len(ran) != 3 || ran[0] != 0 || ran[2] != 2 {
+		t.Errorf("ran = %v, want [0 1 2]", ran)
+	}
+
+	// Hooks run once
+	run(context.Background())
+	if len(ran) != 3 {
+		t.Errorf("hooks ran again: %v", ran)
+	}
+}
+
+func TestAfterCommitDroppedWithoutRun(t *testing.T) {
+	ctx, _ := WithAfterCommit(context.Background())
+
+	ran := false
+	_ = AfterCommit(ctx, func(context.Context) error {
+		ran = true
+		return nil
+	})
+	if ran {
+		t.Error("hook of a rolled back transaction ran")
+	}
+}
@@ -0,0 +1,277 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/apk471/go-boilerplate/internal/sqlerr"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// DefaultChunkSize is the number of items bulk helpers send per round-trip
+// when they are passed a chunk size of 0
+const DefaultChunkSize = 500
+
+// ExecBatch runs the statement of every item with pgx.Batch, sending
+// chunkSize statements per round-trip. Each chunk runs in a transaction, or
+// a savepoint when ctx or q already is one. When an item fails, its chunk is
+// rolled back and sent again without it, so the returned *sqlerr.BulkError
+// lists exactly the items that failed and the rest are written. It returns
+// the number of rows affected.
+func ExecBatch[T any](
+	ctx context.Context,
+	q Querier,
+	items []T,
+	chunkSize int,
+	statement func(item T) (string, []any),
+) (int64, error) {
+	return bulk(ctx, q, len(items), chunkSize, func(ctx context.Context, chunk []int) (int64, *sqlerr.BulkError, error) {
+		return execRows(ctx, q, chunk, func(i int) (string, []any) {
+			return statement(items[i])
+		})
+	})
+}
+
+// CopyFrom inserts items into table with COPY, chunkSize rows per COPY in a
+// transaction or savepoint. values returns the values of an item in the order
+// of columns. COPY does not tell which row failed, so a failing chunk is
+// inserted again with ExecBatch to find the failed items, which the returned
+// *sqlerr.BulkError lists. It returns the number of rows inserted.
+func CopyFrom[T any](
+	ctx context.Context,
+	q Querier,
+	table string,
+	columns []string,
+	items []T,
+	chunkSize int,
+	values func(item T) []any,
+) (int64, error) {
+	insert := insertStatement(table, columns)
+
+	return bulk(ctx, q, len(items), chunkSize, func(ctx context.Context, chunk []int) (int64, *sqlerr.BulkError, error) {
+		var copied int64
+		err := pgx.BeginFunc(ctx, q, func(tx pgx.Tx) error {
+			var err error
+			copied, err = tx.CopyFrom(ctx, identifier(table), columns, copySource(items, chunk, values))
+			return err
+		})
+		if !rowFailure(err) {
+			return copied, nil, err
+		}
+
+		return execRows(ctx, q, chunk, func(i int) (string, []any) {
+			return insert, values(items[i])
+		})
+	})
+}
+
+// CopyUpsert inserts or updates items in table: each chunk is copied into a
+// temporary staging table and merged with INSERT ... ON CONFLICT (conflict)
+// DO UPDATE of the other columns. Like CopyFrom, a failing chunk is upserted
+// again with ExecBatch to find the failed items. That includes chunks with
+// items sharing a conflict key, where the last of them wins. It returns the
+// number of rows inserted or updated.
+func CopyUpsert[T any](
+	ctx context.Context,
+	q Querier,
+	table string,
+	columns []string,
+	conflict []string,
+	items []T,
+	chunkSize int,
+	values func(item T) []any,
+) (int64, error) {
+	upsert := insertStatement(table, columns) + onConflict(columns, conflict)
+
+	staging := "staging_" + strings.ReplaceAll(table, ".", "_")
+	columnList := identifierList(columns)
+	createStaging := fmt.Sprintf(`CREATE TEMPORARY TABLE %s ON COMMIT DROP AS SELECT %s FROM %s WITH NO DATA`,
+		identifier(staging).Sanitize(), columnList, identifier(table).Sanitize())
+	merge := fmt.Sprintf(`INSERT INTO %s (%s) SELECT %s FROM %s`,
+		identifier(table).Sanitize(), columnList, columnList, identifier(staging).Sanitize()) + onConflict(columns, conflict)
+	// In a surrounding transaction ON COMMIT DROP would only drop the table
+	// at its end, after the next chunk tried to create it again
+	dropStaging := `DROP TABLE ` + identifier(staging).Sanitize()
+
+	return bulk(ctx, q, len(items), chunkSize, func(ctx context.Context, chunk []int) (int64, *sqlerr.BulkError, error) {
+		var merged int64
+		err := pgx.BeginFunc(ctx, q, func(tx pgx.Tx) error {
+			if _, err := tx.Exec(ctx, createStaging); err != nil {
+				return fmt.Errorf("failed to create staging table: %w", err)
+			}
+			if _, err := tx.CopyFrom(ctx, identifier(staging), columns, copySource(items, chunk, values)); err != nil {
+				return err
+			}
+			tag, err := tx.Exec(ctx, merge)
+			if err != nil {
+				return err
+			}
+			merged = tag.RowsAffected()
+			_, err = tx.Exec(ctx, dropStaging)
+			return err
+		})
+		if !rowFailure(err) {
+			return merged, nil, err
+		}
+
+		return execRows(ctx, q, chunk, func(i int) (string, []any) {
+			return upsert, values(items[i])
+		})
+	})
+}
+
+// bulk runs send for the item indexes in chunks, collecting the failed items
+// of all chunks. It stops at the first error that is not an item's.
+func bulk(
+	ctx context.Context,
+	q Querier,
+	n int,
+	chunkSize int,
+	send func(ctx context.Context, chunk []int) (int64, *sqlerr.BulkError, error),
+) (int64, error) {
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+
+	var total int64
+	var failed []sqlerr.RowError
+	for start := 0; start < n; start += chunkSize {
+		chunk := make([]int, 0, min(chunkSize, n-start))
+		for i := start; i < min(start+chunkSize, n); i++ {
+			chunk = append(chunk, i)
+		}
+
+		affected, bulkErr, err := send(ctx, chunk)
+		total += affected
+		if err != nil {
+			return total, err
+		}
+		if bulkErr != nil {
+			failed = append(failed, bulkErr.Rows...)
+		}
+	}
+
+	if len(failed) > 0 {
+		return total, &sqlerr.BulkError{Rows: failed}
+	}
+	return total, nil
+}
+
+// execRows sends the statements of the items at indexes in one batch. A
+// failed statement aborts the batch, so the batch is sent again without the
+// failed item until the remaining items succeed.
+func execRows(
+	ctx context.Context,
+	q Querier,
+	indexes []int,
+	statement func(i int) (string, []any),
+) (int64, *sqlerr.BulkError, error) {
+	pending := slices.Clone(indexes)
+	var failed []sqlerr.RowError
+	var affected int64
+
+	for len(pending) > 0 {
+		affected = 0
+		failedAt := -1
+
+		err := pgx.BeginFunc(ctx, q, func(tx pgx.Tx) error {
+			batch := &pgx.Batch{}
+			for _, i := range pending {
+				sql, args := statement(i)
+				batch.Queue(sql, args...)
+			}
+
+			results := tx.SendBatch(ctx, batch)
+			for pos := range pending {
+				tag, err := results.Exec()
+				if err != nil {
+					// Statements after the failed one are not run, their
+					// errors only repeat the abort
+					if rowFailure(err) {
+						failedAt = pos
+					}
+					_ = results.Close()
+					return err
+				}
+				affected += tag.RowsAffected()
+			}
+			return results.Close()
+		})
+		if err == nil {
+			break
+		}
+		if failedAt < 0 {
+			return 0, nil, fmt.Errorf("failed to execute batch: %w", err)
+		}
+
+		failed = append(failed, sqlerr.NewRowError(pending[failedAt], err))
+		pending = slices.Delete(pending, failedAt, failedAt+1)
+	}
+
+	if len(failed) > 0 {
+		return affected, &sqlerr.BulkError{Rows: failed}, nil
+	}
+	return affected, nil, nil
+}
+
+// rowFailure reports whether err is caused by the values of a row: a data
+// exception (SQLSTATE class 22), such as a value out of range, an integrity
+// constraint violation (class 23), or a cardinality violation (21000), which
+// ON CONFLICT DO UPDATE raises for rows of one statement sharing a conflict
+// key. Other errors, such as a missing table, permissions, serialization
+// failures or a lost connection, would fail every row and are returned as
+// they are.
+func rowFailure(err error) bool {
+	var pgerr *pgconn.PgError
+	if !errors.As(err, &pgerr) {
+		return false
+	}
+	return pgerr.Code == "21000" ||
+		strings.HasPrefix(pgerr.Code, "22") || strings.HasPrefix(pgerr.Code, "23")
+}
+
+func copySource[T any](items []T, indexes []int, values func(item T) []any) pgx.CopyFromSource {
+	return pgx.CopyFromSlice(len(indexes), func(i int) ([]any, error) {
+		return values(items[indexes[i]]), nil
+	})
+}
+
+func insertStatement(table string, columns []string) string {
+	placeholders := make([]string, len(columns))
+	for i := range columns {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+	return fmt.Sprintf(`INSERT INTO %s (%s) VALUES (%s)`,
+		identifier(table).Sanitize(), identifierList(columns), strings.Join(placeholders, ", "))
+}
+
+func onConflict(columns, conflict []string) string {
+	var updates []string
+	for _, column := range columns {
+		if !slices.Contains(conflict, column) {
+			name := identifier(column).Sanitize()
+			updates = append(updates, name+" = excluded."+name)
+		}
+	}
+	if len(updates) == 0 {
+		return fmt.Sprintf(` ON CONFLICT (%s) DO NOTHING`, identifierList(conflict))
+	}
+	return fmt.Sprintf(` ON CONFLICT (%s) DO UPDATE SET %s`, identifierList(conflict), strings.Join(updates, ", "))
+}
+
+// identifier splits schema qualified names such as public.users
+func identifier(name string) pgx.Identifier {
+	return pgx.Identifier(strings.Split(name, "."))
+}
+
+func identifierList(names []string) string {
+	sanitized := make([]string, len(names))
+	for i, name := range names {
+		sanitized[i] = pgx.Identifier{name}.Sanitize()
+	}
+	return strings.Join(sanitized, ", ")
+}
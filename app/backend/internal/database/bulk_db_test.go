@@ -0,0 +1,237 @@
+package database_test
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/apk471/go-boilerplate/internal/database"
+	"github.com/apk471/go-boilerplate/internal/sqlerr"
+	"github.com/apk471/go-boilerplate/internal/testutil"
+	"github.com/jackc/pgx/v5"
+)
+
+type bulkItem struct {
+	ID       int
+	Name     any
+	Quantity int
+}
+
+func (i bulkItem) values() []any {
+	return []any{i.ID, i.Name, i.Quantity}
+}
+
+var bulkColumns = []string{"id", "name", "quantity"}
+
+// newBulkTable creates the table bulk_items in a savepoint of tx with the rows
+// of existing
+func newBulkTable(t *testing.T, tx pgx.Tx, existing ...bulkItem) pgx.Tx {
+	t.Helper()
+	ctx := context.Background()
+
+	sp := testutil.Savepoint(t, tx)
+	_, err := sp.Exec(ctx, `CREATE TABLE bulk_items (
+		id INT PRIMARY KEY,
+		name TEXT NOT NULL,
+		quantity INT NOT NULL CHECK (quantity >= 0)
+	)`)
+	if err != nil {
+		t.Fatalf("failed to create bulk_items: %v", err)
+	}
+	for _, item := range existing {
+		if _, err := sp.Exec(ctx, `INSERT INTO bulk_items (id, name, quantity) VALUES ($1, $2, $3)`, item.values()...); err != nil {
+			t.Fatalf("failed to insert item %d: %v", item.ID, err)
+		}
+	}
+	return sp
+}
+
+// bulkRows returns the name of every row in bulk_items by id
+func bulkRows(t *testing.T, q database.Querier) map[int]string {
+	t.Helper()
+
+	rows, err := q.Query(context.Background(), `SELECT id, name FROM bulk_items`)
+	if err != nil {
+		t.Fatalf("failed to query bulk_items: %v", err)
+	}
+	defer rows.Close()
+
+	names := map[int]string{}
+	for rows.Next() {
+		var id int
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			t.Fatalf("failed to scan bulk_items: %v", err)
+		}
+		names[id] = name
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("failed to read bulk_items: %v", err)
+	}
+	return names
+}
+
+// failedIndexes returns the indexes of the items listed by a *sqlerr.BulkError
+func failedIndexes(t *testing.T, err error) []int {
+	t.Helper()
+
+	var bulkErr *sqlerr.BulkError
+	if !errors.As(err, &bulkErr) {
+		t.Fatalf("error = %v, want a *sqlerr.BulkError", err)
+	}
+	return bulkErr.Indexes()
+}
+
+// Items 1, 3 and 5 fail: a missing name, a negative quantity and an id taken
+// by item 0. With a chunk size of 2 the failures span every chunk.
+var failingItems = []bulkItem{
+	{ID: 1, Name: "one", Quantity: 1},
+	{ID: 2, Name: nil, Quantity: 1},
+	{ID: 3, Name: "three", Quantity: 3},
+	{ID: 4, Name: "four", Quantity: -1},
+	{ID: 5, Name: "five", Quantity: 5},
+	{ID: 1, Name: "one again", Quantity: 1},
+}
+
+func TestBulkWrites(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	tx := testutil.NewTestDatabase(t).Tx(t)
+
+	// Subtests share tx, each creating bulk_items in a savepoint of its own
+	t.Run("ExecBatch writes all items", func(t *testing.T) {
+		q := newBulkTable(t, tx)
+
+		items := []bulkItem{{ID: 1, Name: "one"}, {ID: 2, Name: "two"}, {ID: 3, Name: "three"}}
+		affected, err := database.ExecBatch(ctx, q, items, 2, func(item bulkItem) (string, []any) {
+			return `INSERT INTO bulk_items (id, name, quantity) VALUES ($1, $2, $3)`, item.values()
+		})
+		if err != nil || affected != 3 {
+			t.Fatalf("ExecBatch() = %d, %v, want 3 rows", affected, err)
+		}
+		if got := bulkRows(t, q); len(got) != 3 {
+			t.Errorf("rows = %v, want 3", got)
+		}
+	})
+
+	t.Run("ExecBatch reports the failed items and writes the rest", func(t *testing.T) {
+		q := newBulkTable(t, tx)
+
+		affected, err := database.ExecBatch(ctx, q, failingItems, 2, func(item bulkItem) (string, []any) {
+			return `INSERT INTO bulk_items (id, name, quantity) VALUES ($1, $2, $3)`, item.values()
+		})
+		if got := failedIndexes(t, err); !slices.Equal(got, []int{1, 3, 5}) {
+			t.Errorf("failed items = %v, want [1 3 5]", got)
+		}
+		if affected != 3 {
+			t.Errorf("affected = %d, want 3", affected)
+		}
+
+		want := map[int]string{1: "one", 3: "three", 5: "five"}
+		if got := bulkRows(t, q); !maps.Equal(got, want) {
+			t.Errorf("rows = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("ExecBatch returns errors that fail every item", func(t *testing.T) {
+		q := testutil.Savepoint(t, tx)
+
+		_, err := database.ExecBatch(ctx, q, failingItems, 2, func(item bulkItem) (string, []any) {
+			return `INSERT INTO missing_items (id) VALUES ($1)`, []any{item.ID}
+		})
+		var bulkErr *sqlerr.BulkError
+		if err == nil || errors.As(err, &bulkErr) {
+			t.Errorf("error = %v, want the error of the statement", err)
+		}
+	})
+
+	t.Run("CopyFrom reports the failed items and inserts the rest", func(t *testing.T) {
+		q := newBulkTable(t, tx)
+
+		affected, err := database.CopyFrom(ctx, q, "bulk_items", bulkColumns, failingItems, 2, bulkItem.values)
+		if got := failedIndexes(t, err); !slices.Equal(got, []int{1, 3, 5}) {
+			t.Errorf("failed items = %v, want [1 3 5]", got)
+		}
+		if affected != 3 {
+			t.Errorf("affected = %d, want 3", affected)
+		}
+
+		want := map[int]string{1: "one", 3: "three", 5: "five"}
+		if got := bulkRows(t, q); !maps.Equal(got, want) {
+			t.Errorf("rows = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("CopyFrom reports items conflicting with existing rows", func(t *testing.T) {
+		q := newBulkTable(t, tx, bulkItem{ID: 2, Name: "existing"})
+
+		items := []bulkItem{{ID: 1, Name: "one"}, {ID: 2, Name: "two"}, {ID: 3, Name: "three"}}
+		affected, err := database.CopyFrom(ctx, q, "bulk_items", bulkColumns, items, 0, bulkItem.values)
+		if got := failedIndexes(t, err); !slices.Equal(got, []int{1}) {
+			t.Errorf("failed items = %v, want [1]", got)
+		}
+		if affected != 2 {
+			t.Errorf("affected = %d, want 2", affected)
+		}
+
+		want := map[int]string{1: "one", 2: "existing", 3: "three"}
+		if got := bulkRows(t, q); !maps.Equal(got, want) {
+			t.Errorf("rows = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("CopyUpsert inserts and updates items", func(t *testing.T) {
+		q := newBulkTable(t, tx, bulkItem{ID: 1, Name: "old"})
+
+		items := []bulkItem{{ID: 1, Name: "updated"}, {ID: 2, Name: "two"}, {ID: 3, Name: "three"}}
+		affected, err := database.CopyUpsert(ctx, q, "bulk_items", bulkColumns, []string{"id"}, items, 2, bulkItem.values)
+		if err != nil || affected != 3 {
+			t.Fatalf("CopyUpsert() = %d, %v, want 3 rows", affected, err)
+		}
+
+		want := map[int]string{1: "updated", 2: "two", 3: "three"}
+		if got := bulkRows(t, q); !maps.Equal(got, want) {
+			t.Errorf("rows = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("CopyUpsert keeps the last of items sharing a key", func(t *testing.T) {
+		q := newBulkTable(t, tx, bulkItem{ID: 1, Name: "old"})
+
+		// A single merge of the chunk would update row 1 twice, which
+		// Postgres rejects
+		items := []bulkItem{{ID: 1, Name: "first"}, {ID: 2, Name: "two"}, {ID: 1, Name: "last"}}
+		if _, err := database.CopyUpsert(ctx, q, "bulk_items", bulkColumns, []string{"id"}, items, 0, bulkItem.values); err != nil {
+			t.Fatalf("CopyUpsert() error = %v", err)
+		}
+
+		want := map[int]string{1: "last", 2: "two"}
+		if got := bulkRows(t, q); !maps.Equal(got, want) {
+			t.Errorf("rows = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("CopyUpsert reports the failed items and writes the rest", func(t *testing.T) {
+		q := newBulkTable(t, tx, bulkItem{ID: 3, Name: "old"})
+
+		items := []bulkItem{
+			{ID: 1, Name: "one"},
+			{ID: 2, Name: nil},
+			{ID: 3, Name: "updated", Quantity: -1},
+			{ID: 4, Name: "four"},
+			{ID: 4, Name: "four again"},
+		}
+		// The first chunk fails on its values, the second on its shared key
+		_, err := database.CopyUpsert(ctx, q, "bulk_items", bulkColumns, []string{"id"}, items, 3, bulkItem.values)
+		if got := failedIndexes(t, err); !slices.Equal(got, []int{1, 2}) {
+			t.Errorf("failed items = %v, want [1 2]", got)
+		}
+
+		want := map[int]string{1: "one", 3: "old", 4: "four again"}
+		if got := bulkRows(t, q); !maps.Equal(got, want) {
+			t.Errorf("rows = %v, want %v", got, want)
+		}
+	})
+}
@@ -0,0 +1,37 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestRowFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"unique violation", &pgconn.PgError{Code: "23505"}, true},
+		{"not null violation", &pgconn.PgError{Code: "23502"}, true},
+		{"numeric value out of range", &pgconn.PgError{Code: "22003"}, true},
+		{"cardinality violation", &pgconn.PgError{Code: "21000"}, true},
+		{"invalid text representation", fmt.Errorf("wrapped: %w", &pgconn.PgError{Code: "22P02"}), true},
+		{"undefined table", &pgconn.PgError{Code: "42P01"}, false},
+		{"insufficient privilege", &pgconn.PgError{Code: "42501"}, false},
+		{"serialization failure", &pgconn.PgError{Code: "40001"}, false},
+		{"admin shutdown", &pgconn.PgError{Code: "57P01"}, false},
+		{"connection error", errors.New("connection reset by peer"), false},
+		{"no error", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rowFailure(tt.err); got != tt.want {
+				t.Errorf("rowFailure(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -52,8 +52,64 @@ func (mt *multiTracer) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data p
 	}
 }
 
+// TraceBatchStart implements pgx batch tracer interface
+func (mt *multiTracer) TraceBatchStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	for _, tracer := range mt.tracers {
+		if t, ok := tracer.(pgx.BatchTracer); ok {
+			ctx = t.TraceBatchStart(ctx, conn, data)
+		}
+	}
+	return ctx
+}
+
+// TraceBatchQuery implements pgx batch tracer interface
+func (mt *multiTracer) TraceBatchQuery(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchQueryData) {
+	for _, tracer := range mt.tracers {
+		if t, ok := tracer.(pgx.BatchTracer); ok {
+			t.TraceBatchQuery(ctx, conn, data)
+		}
+	}
+}
+
+// TraceBatchEnd implements pgx batch tracer interface
+func (mt *multiTracer) TraceBatchEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchEndData) {
+	for _, tracer := range mt.tracers {
+		if t, ok := tracer.(pgx.BatchTracer); ok {
+			t.TraceBatchEnd(ctx, conn, data)
+		}
+	}
+}
+
+// TraceCopyFromStart implements pgx copy tracer interface
+func (mt *multiTracer) TraceCopyFromStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceCopyFromStartData) context.Context {
+	for _, tracer := range mt.tracers {
+		if t, ok := tracer.(pgx.CopyFromTracer); ok {
+			ctx = t.TraceCopyFromStart(ctx, conn, data)
+		}
+	}
+	return ctx
+}
+
+// TraceCopyFromEnd implements pgx copy tracer interface
+func (mt *multiTracer) TraceCopyFromEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceCopyFromEndData) {
+	for _, tracer := range mt.tracers {
+		if t, ok := tracer.(pgx.CopyFromTracer); ok {
+			t.TraceCopyFromEnd(ctx, conn, data)
+		}
+	}
+}
+
 const DatabasePingTimeout = 10
 
+// queryExecModes maps config.DatabaseConfig.QueryExecMode to pgx modes
+var queryExecModes = map[string]pgx.QueryExecMode{
+	"cache_statement": pgx.QueryExecModeCacheStatement,
+	"cache_describe":  pgx.QueryExecModeCacheDescribe,
+	"describe_exec":   pgx.QueryExecModeDescribeExec,
+	"exec":            pgx.QueryExecModeExec,
+	"simple_protocol": pgx.QueryExecModeSimpleProtocol,
+}
+
 func New(cfg *config.Config, logger *zerolog.Logger, loggerService *loggerConfig.LoggerService) (*Database, error) {
 	hostPort := net.JoinHostPort(cfg.Database.Host, strconv.Itoa(cfg.Database.Port))
 
@@ -72,13 +128,23 @@ func New(cfg *config.Config, logger *zerolog.Logger, loggerService *loggerConfig
 		return nil, fmt.Errorf("failed to parse pgx pool config: %w", err)
 	}
 
+	if cfg.Database.QueryExecMode != "" {
+		mode, ok := queryExecModes[cfg.Database.QueryExecMode]
+		if !ok {
+			return nil, fmt.Errorf("unknown query exec mode %q", cfg.Database.QueryExecMode)
+		}
+		pgxPoolConfig.ConnConfig.DefaultQueryExecMode = mode
+	}
+	pgxPoolConfig.ConnConfig.StatementCacheCapacity = cfg.Database.StatementCacheCapacity
+	pgxPoolConfig.ConnConfig.DescriptionCacheCapacity = cfg.Database.DescriptionCacheCapacity
+
 	// Add New Relic PostgreSQL instrumentation
 	if loggerService != nil && loggerService.GetApplication() != nil {
 		pgxPoolConfig.ConnConfig.Tracer = nrpgx5.NewTracer()
 	}
 
 	if cfg.Primary.Env == "local" {
-		globalLevel := logger.GetLevel()
+		globalLevel := loggerService.Policy().ComponentLevel("database", logger.GetLevel())
 		pgxLogger := loggerConfig.NewPgxLogger(globalLevel)
 		// Chain tracers - New Relic first, then local logging
 		if pgxPoolConfig.ConnConfig.Tracer != nil {
@@ -98,6 +164,15 @@ func New(cfg *config.Config, logger *zerolog.Logger, loggerService *loggerConfig
 		}
 	}
 
+	// Count queries per request, see WithQueryStats
+	if pgxPoolConfig.ConnConfig.Tracer != nil {
+		pgxPoolConfig.ConnConfig.Tracer = &multiTracer{
+			tracers: []any{statsTracer{}, pgxPoolConfig.ConnConfig.Tracer},
+		}
+	} else {
+		pgxPoolConfig.ConnConfig.Tracer = statsTracer{}
+	}
+
 	pool, err := pgxpool.NewWithConfig(context.Background(), pgxPoolConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create pgx pool: %w", err)
@@ -114,7 +189,12 @@ func New(cfg *config.Config, logger *zerolog.Logger, loggerService *loggerConfig
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	logger.Info().Msg("connected to the database")
+	logger.Info().
+		Str("query_exec_mode", pgxPoolConfig.ConnConfig.DefaultQueryExecMode.String()).
+		Int("statement_cache_capacity", pgxPoolConfig.ConnConfig.StatementCacheCapacity).
+		Int("description_cache_capacity", pgxPoolConfig.ConnConfig.DescriptionCacheCapacity).
+		Bool("pgbouncer", cfg.Database.PgBouncer).
+		Msg("connected to the database")
 
 	return database, nil
 }
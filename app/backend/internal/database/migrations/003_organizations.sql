@@ -0,0 +1,65 @@
+CREATE TABLE organizations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    clerk_org_id TEXT,
+    name TEXT NOT NULL,
+    slug TEXT NOT NULL,
+    created_by UUID REFERENCES users (id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT unique_organizations_clerk_org_id UNIQUE (clerk_org_id),
+    CONSTRAINT unique_organizations_slug UNIQUE (slug)
+);
+
+CREATE TRIGGER organizations_set_updated_at
+    BEFORE UPDATE ON organizations
+    FOR EACH ROW
+    EXECUTE FUNCTION set_updated_at();
+
+CREATE TABLE organization_memberships (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT unique_organization_memberships_user UNIQUE (organization_id, user_id),
+    CONSTRAINT check_organization_memberships_role CHECK (role IN ('owner', 'admin', 'member'))
+);
+
+CREATE INDEX idx_organization_memberships_user_id ON organization_memberships (user_id);
+
+CREATE TRIGGER organization_memberships_set_updated_at
+    BEFORE UPDATE ON organization_memberships
+    FOR EACH ROW
+    EXECUTE FUNCTION set_updated_at();
+
+CREATE TABLE organization_invitations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    email TEXT NOT NULL,
+    role TEXT NOT NULL,
+    invited_by UUID REFERENCES users (id) ON DELETE SET NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    accepted_at TIMESTAMPTZ,
+    accepted_by UUID REFERENCES users (id) ON DELETE SET NULL,
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT check_organization_invitations_role CHECK (role IN ('admin', 'member'))
+);
+
+-- One open invitation per address and organization
+CREATE UNIQUE INDEX unique_organization_invitations_email
+    ON organization_invitations (organization_id, lower(email))
+    WHERE accepted_at IS NULL AND revoked_at IS NULL;
+
+CREATE TRIGGER organization_invitations_set_updated_at
+    BEFORE UPDATE ON organization_invitations
+    FOR EACH ROW
+    EXECUTE FUNCTION set_updated_at();
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS organization_invitations;
+DROP TABLE IF EXISTS organization_memberships;
+DROP TABLE IF EXISTS organizations;
@@ -0,0 +1,20 @@
+-- Domain events published inside a transaction. The event bus relay dispatches
+-- rows to subscribers once the transaction that wrote them has committed.
+CREATE TABLE outbox_events (
+    id UUID PRIMARY KEY,
+    event_type TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    occurred_at TIMESTAMPTZ NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    dispatched_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_outbox_events_pending ON outbox_events (next_attempt_at)
+    WHERE dispatched_at IS NULL;
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS outbox_events;
@@ -0,0 +1,77 @@
+CREATE TABLE notifications (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    organization_id UUID REFERENCES organizations (id) ON DELETE CASCADE,
+    type TEXT NOT NULL,
+    title TEXT NOT NULL,
+    body TEXT NOT NULL,
+    data JSONB NOT NULL DEFAULT '{}',
+    -- Channels are resolved from the recipient's preferences when the
+    -- notification is created
+    in_app BOOLEAN NOT NULL,
+    email_status TEXT,
+    emailed_at TIMESTAMPTZ,
+    read_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT check_notifications_email_status CHECK (email_status IN ('pending', 'digest', 'sent', 'skipped'))
+);
+
+CREATE INDEX idx_notifications_user_id_created_at ON notifications (user_id, created_at DESC)
+    WHERE in_app;
+
+CREATE INDEX idx_notifications_user_id_unread ON notifications (user_id)
+    WHERE in_app AND read_at IS NULL;
+
+CREATE INDEX idx_notifications_user_id_digest ON notifications (user_id)
+    WHERE email_status = 'digest';
+
+CREATE TRIGGER notifications_set_updated_at
+    BEFORE UPDATE ON notifications
+    FOR EACH ROW
+    EXECUTE FUNCTION set_updated_at();
+
+-- Rows exist only for types whose channels the user changed
+CREATE TABLE notification_preferences (
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    type TEXT NOT NULL,
+    in_app BOOLEAN NOT NULL,
+    email BOOLEAN NOT NULL,
+    webhook BOOLEAN NOT NULL,
+    -- Collect emails of this type into the daily digest instead of sending them one by one
+    email_digest BOOLEAN NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, type)
+);
+
+CREATE TRIGGER notification_preferences_set_updated_at
+    BEFORE UPDATE ON notification_preferences
+    FOR EACH ROW
+    EXECUTE FUNCTION set_updated_at();
+
+-- Quiet hours are wall-clock times in the user's timezone; start after end
+-- wraps past midnight
+CREATE TABLE notification_settings (
+    user_id UUID PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    quiet_hours_start TEXT,
+    quiet_hours_end TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT check_notification_settings_quiet_hours CHECK (
+        (quiet_hours_start IS NULL AND quiet_hours_end IS NULL)
+        OR (quiet_hours_start ~ '^([01][0-9]|2[0-3]):[0-5][0-9]$'
+            AND quiet_hours_end ~ '^([01][0-9]|2[0-3]):[0-5][0-9]$')
+    )
+);
+
+CREATE TRIGGER notification_settings_set_updated_at
+    BEFORE UPDATE ON notification_settings
+    FOR EACH ROW
+    EXECUTE FUNCTION set_updated_at();
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS notification_settings;
+DROP TABLE IF EXISTS notification_preferences;
+DROP TABLE IF EXISTS notifications;
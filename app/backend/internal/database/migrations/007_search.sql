@@ -0,0 +1,30 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Search columns are generated so they never drift from their sources. The
+-- fields and weights must match the search.Index in the repository using them.
+ALTER TABLE notifications ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple'::regconfig, title), 'A') ||
+    setweight(to_tsvector('simple'::regconfig, body), 'B')
+) STORED;
+
+CREATE INDEX idx_notifications_search_vector ON notifications USING GIN (search_vector);
+
+CREATE INDEX idx_notifications_title_trgm ON notifications USING GIN (title gin_trgm_ops);
+
+ALTER TABLE users ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple'::regconfig, coalesce(display_name, '')), 'A')
+) STORED;
+
+CREATE INDEX idx_users_search_vector ON users USING GIN (search_vector);
+
+CREATE INDEX idx_users_display_name_trgm ON users USING GIN (display_name gin_trgm_ops);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS idx_users_display_name_trgm;
+DROP INDEX IF EXISTS idx_users_search_vector;
+ALTER TABLE users DROP COLUMN IF EXISTS search_vector;
+DROP INDEX IF EXISTS idx_notifications_title_trgm;
+DROP INDEX IF EXISTS idx_notifications_search_vector;
+ALTER TABLE notifications DROP COLUMN IF EXISTS search_vector;
+DROP EXTENSION IF EXISTS pg_trgm;
@@ -0,0 +1,67 @@
+-- Requests outlive the user they belong to, so erasures stay on record after
+-- the account is gone; user_id is deliberately not a foreign key
+CREATE TABLE privacy_requests (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL,
+    kind TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    -- The ZIP export, kept until expires_at
+    archive BYTEA,
+    archive_size BIGINT,
+    expires_at TIMESTAMPTZ,
+    error TEXT,
+    completed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT check_privacy_requests_kind CHECK (kind IN ('export', 'erasure')),
+    CONSTRAINT check_privacy_requests_status CHECK (status IN ('pending', 'processing', 'completed', 'failed', 'blocked'))
+);
+
+CREATE INDEX idx_privacy_requests_user_id ON privacy_requests (user_id, created_at DESC);
+
+-- One export and one erasure in flight per user
+CREATE UNIQUE INDEX unique_privacy_requests_open ON privacy_requests (user_id, kind)
+    WHERE status IN ('pending', 'processing');
+
+CREATE TRIGGER privacy_requests_set_updated_at
+    BEFORE UPDATE ON privacy_requests
+    FOR EACH ROW
+    EXECUTE FUNCTION set_updated_at();
+
+CREATE TABLE privacy_audit_log (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    request_id UUID NOT NULL REFERENCES privacy_requests (id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    action TEXT NOT NULL,
+    module TEXT,
+    detail TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_privacy_audit_log_request_id ON privacy_audit_log (request_id, created_at);
+
+-- Placed by operators when data must be preserved, e.g. for litigation. An
+-- active hold blocks erasure of the whole account.
+CREATE TABLE legal_holds (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    reason TEXT NOT NULL,
+    placed_by TEXT NOT NULL,
+    released_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_legal_holds_user_id_active ON legal_holds (user_id)
+    WHERE released_at IS NULL;
+
+CREATE TRIGGER legal_holds_set_updated_at
+    BEFORE UPDATE ON legal_holds
+    FOR EACH ROW
+    EXECUTE FUNCTION set_updated_at();
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS legal_holds;
+DROP TABLE IF EXISTS privacy_audit_log;
+DROP TABLE IF EXISTS privacy_requests;
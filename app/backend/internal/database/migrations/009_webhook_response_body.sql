@@ -0,0 +1,7 @@
+-- Response bodies of webhook receivers are no longer stored, since endpoint
+-- URLs are user input and the delivery log would read responses back
+ALTER TABLE webhook_deliveries DROP COLUMN response_body;
+
+---- create above / drop below ----
+
+ALTER TABLE webhook_deliveries ADD COLUMN response_body TEXT;
@@ -0,0 +1,7 @@
+-- Subscribers an outbox event was delivered to, so a retry after a partial
+-- failure only dispatches to the subscribers that failed
+ALTER TABLE outbox_events ADD COLUMN dispatched_to TEXT[] NOT NULL DEFAULT '{}';
+
+---- create above / drop below ----
+
+ALTER TABLE outbox_events DROP COLUMN dispatched_to;
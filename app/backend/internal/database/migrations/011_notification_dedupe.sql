@@ -0,0 +1,11 @@
+-- Notifications created for a domain event carry a key per event, so a
+-- retried subscriber does not notify a recipient twice
+ALTER TABLE notifications ADD COLUMN dedupe_key TEXT;
+
+CREATE UNIQUE INDEX unique_notifications_user_id_dedupe_key ON notifications (user_id, dedupe_key)
+    WHERE dedupe_key IS NOT NULL;
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS unique_notifications_user_id_dedupe_key;
+ALTER TABLE notifications DROP COLUMN dedupe_key;
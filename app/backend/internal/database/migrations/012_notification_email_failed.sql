@@ -0,0 +1,12 @@
+-- Emails whose dispatch could not be scheduled after the notification was
+-- committed are marked failed instead of staying pending forever
+ALTER TABLE notifications DROP CONSTRAINT check_notifications_email_status;
+ALTER TABLE notifications ADD CONSTRAINT check_notifications_email_status
+    CHECK (email_status IN ('pending', 'digest', 'sent', 'skipped', 'failed'));
+
+---- create above / drop below ----
+
+UPDATE notifications SET email_status = 'skipped' WHERE email_status = 'failed';
+ALTER TABLE notifications DROP CONSTRAINT check_notifications_email_status;
+ALTER TABLE notifications ADD CONSTRAINT check_notifications_email_status
+    CHECK (email_status IN ('pending', 'digest', 'sent', 'skipped'));
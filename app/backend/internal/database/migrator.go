@@ -37,6 +37,11 @@ func Migrate(ctx context.Context, logger *zerolog.Logger, cfg *config.Config) er
 	}
 	defer conn.Close(ctx)
 
+	return MigrateConn(ctx, logger, conn)
+}
+
+// MigrateConn applies the embedded migrations using an already open connection.
+func MigrateConn(ctx context.Context, logger *zerolog.Logger, conn *pgx.Conn) error {
 	m, err := tern.NewMigrator(ctx, conn, "schema_version")
 	if err != nil {
 		return fmt.Errorf("constructing database migrator: %w", err)
@@ -0,0 +1,88 @@
+package database
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Querier is the subset of pgx shared by *pgxpool.Pool, *pgx.Conn and pgx.Tx.
+// Repositories should depend on it instead of the pool so that callers can run
+// them inside a transaction.
+type Querier interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// Querier returns the pool as a Querier. Queries whose context carries a
+// transaction from WithTx run in that transaction instead, and Begin starts a
+// savepoint in it.
+func (db *Database) Querier() Querier {
+	return contextQuerier{pool: db.Pool}
+}
+
+type txKey struct{}
+
+// WithTx returns a context whose queries through Database.Querier run in tx.
+// A pgx.Tx is not safe for concurrent use, so the context must not be shared
+// between goroutines querying at the same time.
+func WithTx(ctx context.Context, tx pgx.Tx) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// TxFromContext returns the transaction set by WithTx, if any
+func TxFromContext(ctx context.Context) (pgx.Tx, bool) {
+	tx, ok := ctx.Value(txKey{}).(pgx.Tx)
+	return tx, ok
+}
+
+// contextQuerier runs queries in the context's transaction when there is one
+// and on the pool otherwise
+type contextQuerier struct {
+	pool Querier
+}
+
+func (q contextQuerier) querier(ctx context.Context) Querier {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx
+	}
+	return q.pool
+}
+
+func (q contextQuerier) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	return q.querier(ctx).Exec(ctx, sql, arguments...)
+}
+
+func (q contextQuerier) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	return q.querier(ctx).Query(ctx, sql, args...)
+}
+
+func (q contextQuerier) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return q.querier(ctx).QueryRow(ctx, sql, args...)
+}
+
+func (q contextQuerier) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	return q.querier(ctx).SendBatch(ctx, b)
+}
+
+func (q contextQuerier) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	return q.querier(ctx).CopyFrom(ctx, tableName, columnNames, rowSrc)
+}
+
+func (q contextQuerier) Begin(ctx context.Context) (pgx.Tx, error) {
+	return q.querier(ctx).Begin(ctx)
+}
+
+// Unavailable reports whether a query error means Postgres could not be
+// reached. Errors returned by the server, such as constraint violations, and
+// empty results come from a healthy database.
+func Unavailable(err error) bool {
+	var pgErr *pgconn.PgError
+	return !errors.As(err, &pgErr) && !errors.Is(err, pgx.ErrNoRows)
+}
@@ -0,0 +1,148 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apk471/go-boilerplate/internal/lib/timing"
+	"github.com/jackc/pgx/v5"
+)
+
+// QueryStats counts the queries run with a context, e.g. those of one API
+// request. It is safe for concurrent use.
+type QueryStats struct {
+	mu         sync.Mutex
+	queries    int
+	duration   time.Duration
+	statements map[string]int
+}
+
+// QueryStatsSummary is a snapshot of QueryStats
+type QueryStatsSummary struct {
+	Queries  int
+	Duration time.Duration
+	// Repeats is how often the most repeated statement ran, and Statement is
+	// that statement. Statements are compared without their arguments, so a
+	// statement repeated once per item of a list is the sign of an N+1.
+	Repeats   int
+	Statement string
+}
+
+type queryStatsKey struct{}
+
+// WithQueryStats returns a context whose queries are counted in the returned
+// QueryStats
+func WithQueryStats(ctx context.Context) (context.Context, *QueryStats) {
+	stats := &QueryStats{statements: make(map[string]int)}
+	return context.WithValue(ctx, queryStatsKey{}, stats), stats
+}
+
+// QueryStatsFromContext returns the QueryStats set by WithQueryStats, if any
+func QueryStatsFromContext(ctx context.Context) (*QueryStats, bool) {
+	stats, ok := ctx.Value(queryStatsKey{}).(*QueryStats)
+	return stats, ok
+}
+
+// Summary returns the numbers counted so far
+func (s *QueryStats) Summary() QueryStatsSummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	summary := QueryStatsSummary{Queries: s.queries, Duration: s.duration}
+	for statement, count := range s.statements {
+		if count > summary.Repeats {
+			summary.Repeats, summary.Statement = count, statement
+		}
+	}
+	return summary
+}
+
+func (s *QueryStats) record(sql string, duration time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.queries++
+	s.duration += duration
+	if statement := normalizeStatement(sql); statement != "" && !transactionControl(statement) {
+		s.statements[statement]++
+	}
+}
+
+// normalizeStatement collapses whitespace, so the same statement formatted
+// differently counts as one
+func normalizeStatement(sql string) string {
+	return strings.Join(strings.Fields(sql), " ")
+}
+
+// transactionControl reports whether statement begins or ends a transaction
+// or savepoint, which requests may repeat without being an N+1
+func transactionControl(statement string) bool {
+	keyword, _, _ := strings.Cut(strings.ToLower(statement), " ")
+	switch keyword {
+	case "begin", "commit", "rollback", "savepoint", "release":
+		return true
+	}
+	return false
+}
+
+// statsTracer records queries, batches and copies in the QueryStats of their
+// context and adds their time to its db timing span. Queries of contexts
+// without either are not timed.
+type statsTracer struct{}
+
+type queryStartKey struct{}
+
+type queryStart struct {
+	sql   string
+	start time.Time
+}
+
+func (statsTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return startQuery(ctx, data.SQL)
+}
+
+func (statsTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, _ pgx.TraceQueryEndData) {
+	endQuery(ctx)
+}
+
+// A batch is one round-trip, so it is counted as one query and not compared
+// for repeats
+func (statsTracer) TraceBatchStart(ctx context.Context, _ *pgx.Conn, _ pgx.TraceBatchStartData) context.Context {
+	return startQuery(ctx, "")
+}
+
+func (statsTracer) TraceBatchQuery(context.Context, *pgx.Conn, pgx.TraceBatchQueryData) {}
+
+func (statsTracer) TraceBatchEnd(ctx context.Context, _ *pgx.Conn, _ pgx.TraceBatchEndData) {
+	endQuery(ctx)
+}
+
+func (statsTracer) TraceCopyFromStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceCopyFromStartData) context.Context {
+	return startQuery(ctx, "COPY "+data.TableName.Sanitize())
+}
+
+func (statsTracer) TraceCopyFromEnd(ctx context.Context, _ *pgx.Conn, _ pgx.TraceCopyFromEndData) {
+	endQuery(ctx)
+}
+
+func startQuery(ctx context.Context, sql string) context.Context {
+	if _, ok := QueryStatsFromContext(ctx); !ok && timing.FromContext(ctx) == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, queryStartKey{}, queryStart{sql: sql, start: time.Now()})
+}
+
+func endQuery(ctx context.Context) {
+	start, ok := ctx.Value(queryStartKey{}).(queryStart)
+	if !ok {
+		return
+	}
+
+	duration := time.Since(start.start)
+	if stats, ok := QueryStatsFromContext(ctx); ok {
+		stats.record(start.sql, duration)
+	}
+	timing.FromContext(ctx).Add("db", duration)
+}
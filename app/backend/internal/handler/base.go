@@ -1,8 +1,13 @@
 package handler
 
 import (
+	"errors"
+	"reflect"
 	"time"
 
+	"github.com/apk471/go-boilerplate/internal/errs"
+	"github.com/apk471/go-boilerplate/internal/lib/partial"
+	"github.com/apk471/go-boilerplate/internal/lib/timing"
 	"github.com/apk471/go-boilerplate/internal/middleware"
 	"github.com/apk471/go-boilerplate/internal/server"
 	"github.com/apk471/go-boilerplate/internal/validation"
@@ -37,9 +42,22 @@ type ResponseHandler interface {
 // JSONResponseHandler handles JSON responses
 type JSONResponseHandler struct {
 	status int
+	// selection prunes and expands the response per ?fields= and ?expand=
+	selection *partial.Selection
 }
 
 func (h JSONResponseHandler) Handle(c echo.Context, result interface{}) error {
+	if version := versionOf(result); version != "" {
+		c.Response().Header().Set("ETag", etag(version))
+	}
+
+	if h.selection != nil {
+		selected, err := h.selection.Apply(c.Request().Context(), result)
+		if err != nil {
+			return err
+		}
+		result = selected
+	}
 	return c.JSON(h.status, result)
 }
 
@@ -96,14 +114,23 @@ func (h FileResponseHandler) AddAttributes(txn *newrelic.Transaction, result int
 	}
 }
 
+// bindFunc binds and validates a request payload
+type bindFunc[Req validation.Validatable] func(c echo.Context, req Req) error
+
+func bindAndValidate[Req validation.Validatable](c echo.Context, req Req) error {
+	return validation.BindAndValidate(c, req)
+}
+
 // handleRequest is the unified handler function that eliminates code duplication
 func handleRequest[Req validation.Validatable](
 	c echo.Context,
 	req Req,
+	bind bindFunc[Req],
 	handler func(c echo.Context, req Req) (interface{}, error),
 	responseHandler ResponseHandler,
 ) error {
 	start := time.Now()
+	req = newRequest(req)
 	method := c.Request().Method
 	path := c.Path()
 	route := path
@@ -138,7 +165,7 @@ func handleRequest[Req validation.Validatable](
 
 	// Validation with observability
 	validationStart := time.Now()
-	if err := validation.BindAndValidate(c, req); err != nil {
+	if err := bind(c, req); err != nil {
 		validationDuration := time.Since(validationStart)
 
 		logger.Error().
@@ -155,6 +182,8 @@ func handleRequest[Req validation.Validatable](
 	}
 
 	validationDuration := time.Since(validationStart)
+	timings := timing.FromContext(c.Request().Context())
+	timings.Add("validation", validationDuration)
 	if txn != nil {
 		txn.AddAttribute("validation.status", "success")
 		txn.AddAttribute("validation.duration_ms", validationDuration.Milliseconds())
@@ -168,6 +197,7 @@ func handleRequest[Req validation.Validatable](
 	handlerStart := time.Now()
 	result, err := handler(c, req)
 	handlerDuration := time.Since(handlerStart)
+	timings.Add("handler", handlerDuration)
 
 	if err != nil {
 		totalDuration := time.Since(start)
@@ -206,6 +236,17 @@ func handleRequest[Req validation.Validatable](
 	return responseHandler.Handle(c, result)
 }
 
+// newRequest returns a zero value of the same type as template, so that
+// concurrent requests never bind into a shared payload
+func newRequest[Req any](template Req) Req {
+	t := reflect.TypeOf(template)
+	if t != nil && t.Kind() == reflect.Ptr {
+		return reflect.New(t.Elem()).Interface().(Req)
+	}
+	var zero Req
+	return zero
+}
+
 // Handle wraps a handler with validation, error handling, logging, metrics, and tracing
 func Handle[Req validation.Validatable, Res any](
 	h Handler,
@@ -213,13 +254,32 @@ func Handle[Req validation.Validatable, Res any](
 	status int,
 	req Req,
 ) echo.HandlerFunc {
+	resultType := reflect.TypeFor[Res]()
+
 	return func(c echo.Context) error {
-		return handleRequest(c, req, func(c echo.Context, req Req) (interface{}, error) {
+		// Validated before the handler runs, so a bad parameter has no side effects
+		selection, err := h.server.Partial.Parse(resultType, c.QueryParam("fields"), c.QueryParam("expand"))
+		if err != nil {
+			return partialError(err)
+		}
+
+		return handleRequest(c, req, bindAndValidate[Req], func(c echo.Context, req Req) (interface{}, error) {
 			return handler(c, req)
-		}, JSONResponseHandler{status: status})
+		}, JSONResponseHandler{status: status, selection: selection})
 	}
 }
 
+func partialError(err error) error {
+	var invalid *partial.InvalidError
+	if !errors.As(err, &invalid) {
+		return err
+	}
+
+	code := "INVALID_FIELD_SELECTION"
+	return errs.NewBadRequestError("Invalid field selection", true, &code,
+		[]errs.FieldError{{Field: invalid.Param, Error: invalid.Message}}, nil)
+}
+
 func HandleFile[Req validation.Validatable](
 	h Handler,
 	handler HandlerFunc[Req, []byte],
@@ -229,7 +289,7 @@ func HandleFile[Req validation.Validatable](
 	contentType string,
 ) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		return handleRequest(c, req, func(c echo.Context, req Req) (interface{}, error) {
+		return handleRequest(c, req, bindAndValidate[Req], func(c echo.Context, req Req) (interface{}, error) {
 			return handler(c, req)
 		}, FileResponseHandler{
 			status:      status,
@@ -247,7 +307,7 @@ func HandleNoContent[Req validation.Validatable](
 	req Req,
 ) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		return handleRequest(c, req, func(c echo.Context, req Req) (interface{}, error) {
+		return handleRequest(c, req, bindAndValidate[Req], func(c echo.Context, req Req) (interface{}, error) {
 			err := handler(c, req)
 			return nil, err
 		}, NoContentResponseHandler{status: status})
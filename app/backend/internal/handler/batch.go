@@ -0,0 +1,216 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+
+	"github.com/apk471/go-boilerplate/internal/database"
+	"github.com/apk471/go-boilerplate/internal/errs"
+	"github.com/apk471/go-boilerplate/internal/middleware"
+	"github.com/apk471/go-boilerplate/internal/model"
+	"github.com/apk471/go-boilerplate/internal/server"
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+)
+
+// errBatchItemFailed rolls back the transaction of an atomic batch
+var errBatchItemFailed = errors.New("batch sub-request failed")
+
+// errBatchFlush is returned to handlers that flush a sub-request's response
+var errBatchFlush = errors.New("batch sub-responses cannot be streamed")
+
+// streamingPaths are the routes that keep their response open, which a batch
+// cannot record
+var streamingPaths = []string{"/api/v1/notifications/stream"}
+
+// IsStreamingPath reports whether the route path streams its response
+func IsStreamingPath(p string) bool {
+	return slices.Contains(streamingPaths, p)
+}
+
+type batchItemKey struct{}
+
+// IsBatchItem reports whether r is a sub-request dispatched by a batch
+func IsBatchItem(r *http.Request) bool {
+	_, ok := r.Context().Value(batchItemKey{}).(bool)
+	return ok
+}
+
+// BatchHandler dispatches the sub-requests of a batch through the router, so
+// they pass the same middleware, validation and handlers as on their own
+type BatchHandler struct {
+	Handler
+	router http.Handler
+}
+
+func NewBatchHandler(s *server.Server) *BatchHandler {
+	return &BatchHandler{
+		Handler: NewHandler(s),
+	}
+}
+
+// SetRouter sets the router sub-requests are dispatched through. The router
+// is built after the handlers, so it cannot be passed to NewBatchHandler.
+func (h *BatchHandler) SetRouter(router http.Handler) {
+	h.router = router
+}
+
+func (h *BatchHandler) Execute(c echo.Context, req *model.ExecuteBatchPayload) (*model.BatchResponse, error) {
+	if limit := h.server.Config.Batch.MaxRequests; len(req.Requests) > limit {
+		code := "BATCH_TOO_LARGE"
+		return nil, errs.NewBadRequestError(
+			fmt.Sprintf("A batch can contain at most %d requests", limit), true, &code, nil, nil)
+	}
+
+	response := &model.BatchResponse{
+		Atomic:  req.Atomic,
+		Results: make([]model.BatchResult, 0, len(req.Requests)),
+	}
+	ctx := c.Request().Context()
+
+	if !req.Atomic {
+		for i, item := range req.Requests {
+			response.Results = append(response.Results, h.dispatch(ctx, c, i, item))
+		}
+		return response, nil
+	}
+
+	// Repositories query through database.Querier, which uses the transaction
+	// carried by the context, so every sub-request shares this one. Sub-requests
+	// run one at a time since a transaction is not safe for concurrent use.
+	// Their cache writes, jobs and messages are deferred until it commits, see
+	// database.AfterCommit.
+	hookCtx, runAfterCommit := database.WithAfterCommit(ctx)
+	err := pgx.BeginFunc(ctx, h.server.DB.Querier(), func(tx pgx.Tx) error {
+		txCtx := database.WithTx(hookCtx, tx)
+		for i, item := range req.Requests {
+			result := h.dispatch(txCtx, c, i, item)
+			response.Results = append(response.Results, result)
+			if result.Status >= http.StatusBadRequest {
+				return errBatchItemFailed
+			}
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errBatchItemFailed) {
+		return nil, err
+	}
+
+	if err == nil {
+		runAfterCommit(ctx)
+	} else {
+		response.RolledBack = true
+		skipped := errorBody(&errs.HTTPError{
+			Code:    "BATCH_ABORTED",
+			Message: "Not run because an earlier request of the atomic batch failed",
+			Status:  http.StatusFailedDependency,
+		})
+		for _, item := range req.Requests[len(response.Results):] {
+			response.Results = append(response.Results, model.BatchResult{
+				ID:     item.ID,
+				Status: http.StatusFailedDependency,
+				Body:   skipped,
+			})
+		}
+	}
+
+	return response, nil
+}
+
+// dispatch runs one sub-request through the router and records its response.
+// The sub-request carries no Authorization header: its context derives from
+// the batch request, whose verified session claims the auth middleware uses
+// when there is no token, so every sub-request runs as the caller.
+func (h *BatchHandler) dispatch(ctx context.Context, c echo.Context, index int, item model.BatchRequest) model.BatchResult {
+	result := model.BatchResult{ID: item.ID}
+
+	r, err := http.NewRequestWithContext(context.WithValue(ctx, batchItemKey{}, true), item.Method, item.Path, bytes.NewReader(item.Body))
+	if err != nil {
+		result.Status = http.StatusBadRequest
+		result.Body = errorBody(errs.NewBadRequestError("Invalid request path", true, nil, nil, nil))
+		return result
+	}
+
+	if path.Clean(r.URL.Path) == c.Path() {
+		result.Status = http.StatusBadRequest
+		result.Body = errorBody(errs.NewBadRequestError("Batches cannot be nested", true, nil, nil, nil))
+		return result
+	}
+
+	if IsStreamingPath(path.Clean(r.URL.Path)) {
+		result.Status = http.StatusBadRequest
+		result.Body = errorBody(errs.NewBadRequestError("Streaming endpoints cannot be batched", true, nil, nil, nil))
+		return result
+	}
+
+	parent := c.Request()
+	r.Header = parent.Header.Clone()
+	for name, value := range item.Headers {
+		r.Header.Set(name, value)
+	}
+	r.Header.Del(echo.HeaderAuthorization)
+	r.Header.Del(echo.HeaderContentLength)
+	r.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	r.Header.Set(middleware.RequestIDHeader, fmt.Sprintf("%s-%d", middleware.GetRequestID(c), index))
+	r.Host = parent.Host
+	r.RemoteAddr = parent.RemoteAddr
+
+	rec := &batchRecorder{header: make(http.Header)}
+	h.router.ServeHTTP(rec, r)
+
+	result.Status = rec.status
+	if result.Status == 0 {
+		result.Status = http.StatusOK
+	}
+
+	if out := bytes.TrimSpace(rec.body.Bytes()); len(out) > 0 {
+		if json.Valid(out) {
+			result.Body = out
+		} else {
+			result.Body, _ = json.Marshal(string(out))
+		}
+	}
+
+	return result
+}
+
+func errorBody(err *errs.HTTPError) json.RawMessage {
+	body, _ := json.Marshal(err)
+	return body
+}
+
+// batchRecorder is the http.ResponseWriter a sub-request writes to. Flushing
+// fails rather than panicking in echo.Response.Flush, since the response is
+// only returned once the sub-request ends.
+type batchRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *batchRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *batchRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+func (r *batchRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.body.Write(b)
+}
+
+func (r *batchRecorder) FlushError() error {
+	return errBatchFlush
+}
@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/apk471/go-boilerplate/internal/model"
+	"github.com/labstack/echo/v4"
+)
+
+func TestBatchRecorderFlushDoesNotPanic(t *testing.T) {
+	rec := &batchRecorder{header: make(http.Header)}
+	res := echo.NewResponse(rec, echo.New())
+
+	if err := http.NewResponseController(rec).Flush(); err == nil {
+		t.Error("flushing a batch sub-response succeeded")
+	}
+	// echo.Response.Flush panics only if flushing is not supported
+	res.Flush()
+}
+
+func TestStreamingPathsAreRejected(t *testing.T) {
+	e := echo.New()
+	c := e.NewContext(httptest.NewRequest(http.MethodPost, "/api/v1/batch", nil), httptest.NewRecorder())
+	c.SetPath("/api/v1/batch")
+
+	h := &BatchHandler{}
+	result := h.dispatch(c.Request().Context(), c, 0, model.BatchRequest{
+		ID:     "stream",
+		Method: http.MethodGet,
+		Path:   "/api/v1/notifications//stream",
+	})
+	if result.Status != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", result.Status, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,253 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/apk471/go-boilerplate/internal/config"
+	"github.com/apk471/go-boilerplate/internal/database"
+	"github.com/apk471/go-boilerplate/internal/handler"
+	"github.com/apk471/go-boilerplate/internal/lib/events"
+	"github.com/apk471/go-boilerplate/internal/lib/jsoncodec"
+	"github.com/apk471/go-boilerplate/internal/lib/partial"
+	"github.com/apk471/go-boilerplate/internal/logger"
+	"github.com/apk471/go-boilerplate/internal/middleware"
+	"github.com/apk471/go-boilerplate/internal/model"
+	"github.com/apk471/go-boilerplate/internal/repository"
+	"github.com/apk471/go-boilerplate/internal/server"
+	"github.com/apk471/go-boilerplate/internal/service"
+	"github.com/apk471/go-boilerplate/internal/testutil"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+)
+
+const testClerkUserID = "user_contract_test"
+
+// newServer returns a server without infrastructure, for handlers that only
+// reach the dependencies a test sets on it
+func newServer() *server.Server {
+	log := zerolog.Nop()
+	return &server.Server{
+		Config: &config.Config{
+			Batch: config.BatchConfig{
+				MaxRequests:  config.DefaultBatchMaxRequests,
+				MaxBodyBytes: config.DefaultBatchMaxBodyBytes,
+			},
+		},
+		Logger:        &log,
+		LoggerService: &logger.LoggerService{},
+		Partial:       partial.NewRegistry(),
+	}
+}
+
+// newRouter registers one or more handlers of each route group the way the
+// router does, minus authentication: every request is made by the same user.
+func newRouter(s *server.Server, services *service.Services) *echo.Echo {
+	h := handler.NewHandlers(s, services)
+
+	e := echo.New()
+	e.HTTPErrorHandler = middleware.NewGlobalMiddlewares(s).GlobalErrorHandler
+	e.JSONSerializer = jsoncodec.New(config.JSONConfig{Codec: jsoncodec.CodecStd})
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set(middleware.UserIDKey, testClerkUserID)
+			// Like RequireAuth, when there is a user service to load the caller
+			if services.User != nil {
+				user, err := services.User.Load(c.Request().Context(), testClerkUserID)
+				if err != nil {
+					return err
+				}
+				middleware.SetUser(c, user)
+			}
+			return next(c)
+		}
+	})
+	h.Batch.SetRouter(e)
+
+	e.GET("/version", h.Version.GetVersion)
+
+	v1 := e.Group("/api/v1")
+	v1.GET("/me", handler.Handle(h.User.Handler, h.User.GetMe, http.StatusOK, &model.GetMePayload{}))
+	v1.PATCH("/me", handler.HandlePatch(h.User.Handler, h.User.CurrentMe, h.User.UpdateMe, http.StatusOK,
+		&model.UpdateMePayload{}))
+	v1.GET("/organizations", handler.Handle(h.Organization.Handler, h.Organization.ListOrganizations,
+		http.StatusOK, &model.ListOrganizationsPayload{}))
+	v1.POST("/organizations", handler.Handle(h.Organization.Handler, h.Organization.CreateOrganization,
+		http.StatusCreated, &model.CreateOrganizationPayload{}))
+	v1.GET("/organizations/:id/webhooks", handler.Handle(h.Webhook.Handler, h.Webhook.ListEndpoints,
+		http.StatusOK, &model.ListWebhookEndpointsPayload{}))
+	v1.POST("/organizations/:id/webhooks", handler.Handle(h.Webhook.Handler, h.Webhook.CreateEndpoint,
+		http.StatusCreated, &model.CreateWebhookEndpointPayload{}))
+	v1.GET("/notifications", handler.Handle(h.Notification.Handler, h.Notification.List,
+		http.StatusOK, &model.ListNotificationsPayload{}))
+	v1.GET("/privacy/exports", handler.Handle(h.Privacy.Handler, h.Privacy.ListExports,
+		http.StatusOK, &model.ListDataExportsPayload{}))
+	v1.GET("/privacy/exports/download", handler.HandleFile(h.Privacy.Handler, h.Privacy.DownloadExport,
+		http.StatusOK, &model.DownloadDataExportPayload{}, "data-export.zip", "application/zip"))
+	v1.GET("/retention/report", handler.Handle(h.Retention.Handler, h.Retention.GetReport,
+		http.StatusOK, &model.GetRetentionReportPayload{}))
+	v1.POST("/batch", handler.Handle(h.Batch.Handler, h.Batch.Execute, http.StatusOK, &model.ExecuteBatchPayload{}))
+
+	return e
+}
+
+// newRequest builds a test request with a body of contentType, if any
+func newRequest(method, target, contentType, body string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set(echo.HeaderContentType, contentType)
+	}
+	return req
+}
+
+func TestHandlerContracts(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		contentType string
+		body        string
+		opts        []testutil.GoldenOption
+	}{
+		{
+			name:   "system_version",
+			method: http.MethodGet,
+			target: "/version",
+			opts:   []testutil.GoldenOption{testutil.WithVolatileFields("version", "commit", "buildDate", "dirty", "goVersion")},
+		},
+		{
+			name:        "user_update_me_unsupported_media_type",
+			method:      http.MethodPatch,
+			target:      "/api/v1/me",
+			contentType: "text/plain",
+			body:        "locale=fr",
+		},
+		{
+			name:        "organization_create_invalid_slug",
+			method:      http.MethodPost,
+			target:      "/api/v1/organizations",
+			contentType: echo.MIMEApplicationJSON,
+			body:        `{"name":"Acme","slug":"Not A Slug"}`,
+		},
+		{
+			name:        "webhook_create_invalid_url",
+			method:      http.MethodPost,
+			target:      "/api/v1/organizations/0b4c7f0e-3f0a-4d2b-9a53-6f1e2d1c9b8a/webhooks",
+			contentType: echo.MIMEApplicationJSON,
+			body:        `{"url":"not a url"}`,
+		},
+		{
+			name:   "notification_list_invalid_limit",
+			method: http.MethodGet,
+			target: "/api/v1/notifications?limit=500",
+		},
+		{
+			name:   "privacy_download_missing_token",
+			method: http.MethodGet,
+			target: "/api/v1/privacy/exports/download",
+		},
+		{
+			name:   "retention_report_invalid_fields",
+			method: http.MethodGet,
+			target: "/api/v1/retention/report?fields=nope",
+		},
+		{
+			name:        "batch_execute_empty",
+			method:      http.MethodPost,
+			target:      "/api/v1/batch",
+			contentType: echo.MIMEApplicationJSON,
+			body:        `{"requests":[]}`,
+		},
+		{
+			name:        "batch_execute",
+			method:      http.MethodPost,
+			target:      "/api/v1/batch",
+			contentType: echo.MIMEApplicationJSON,
+			body:        `{"requests":[{"id":"page","method":"GET","path":"/api/v1/notifications?limit=500"}]}`,
+		},
+	}
+
+	// The handlers have no services, so only requests rejected before the
+	// service is called can be served
+	e := newRouter(newServer(), &service.Services{})
+	contract := testutil.NewContract(t)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newRequest(tt.method, tt.target, tt.contentType, tt.body)
+			contract.AssertContract(t, tt.name, e, req, tt.opts...)
+		})
+	}
+}
+
+// TestHandlerContractsWithDatabase covers the success path of each route group
+// backed by services. Rows differ on every run, so responses are validated
+// against the spec without golden files.
+func TestHandlerContractsWithDatabase(t *testing.T) {
+	s := newServer()
+	s.DB = &database.Database{Pool: testutil.NewTestDatabase(t).Clone(t)}
+	// The relay is not started, so published events stay in the outbox
+	s.Events = events.NewBus(s.Logger, nil, s.DB.Querier(), nil)
+	s.Config.Retention = config.RetentionConfig{
+		BatchSize:  config.DefaultRetentionBatchSize,
+		MaxBatches: config.DefaultRetentionMaxBatches,
+		ArchiveDir: t.TempDir(),
+	}
+
+	repos := repository.NewRepositories(s)
+	users := service.NewUserService(s, repos.User)
+	organizations := service.NewOrganizationService(s, repos.Organization, users)
+	retention, err := service.NewRetentionService(s)
+	if err != nil {
+		t.Fatalf("NewRetentionService() error = %v", err)
+	}
+
+	e := newRouter(s, &service.Services{
+		User:         users,
+		Organization: organizations,
+		Webhook:      service.NewWebhookService(s, repos.Webhook, users, organizations),
+		Notification: service.NewNotificationService(s, repos.Notification, users, organizations),
+		Privacy:      service.NewPrivacyService(s, repos.Privacy, users),
+		Retention:    retention,
+	})
+	contract := testutil.NewContract(t)
+
+	request := func(t *testing.T, req *http.Request, wantStatus int) *httptest.ResponseRecorder {
+		t.Helper()
+
+		rec := testutil.Record(e, req)
+		if rec.Code != wantStatus {
+			t.Fatalf("status = %d, want %d: %s", rec.Code, wantStatus, rec.Body.String())
+		}
+		contract.Validate(t, req, rec)
+		return rec
+	}
+
+	rec := request(t, newRequest(http.MethodPost, "/api/v1/organizations", echo.MIMEApplicationJSON,
+		`{"name":"Acme","slug":"acme-contract"}`), http.StatusCreated)
+	var organization model.OrganizationWithRole
+	if err := json.Unmarshal(rec.Body.Bytes(), &organization); err != nil {
+		t.Fatalf("failed to decode organization: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		target string
+	}{
+		{"user_get_me", "/api/v1/me"},
+		{"organization_list", "/api/v1/organizations"},
+		{"webhook_list_endpoints", "/api/v1/organizations/" + organization.ID.String() + "/webhooks"},
+		{"notification_list", "/api/v1/notifications?unread=true"},
+		{"privacy_list_exports", "/api/v1/privacy/exports"},
+		{"retention_report", "/api/v1/retention/report"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request(t, newRequest(http.MethodGet, tt.target, "", ""), http.StatusOK)
+		})
+	}
+}
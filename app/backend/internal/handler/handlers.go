@@ -6,13 +6,29 @@ import (
 )
 
 type Handlers struct {
-	Health  *HealthHandler
-	OpenAPI *OpenAPIHandler
+	Health       *HealthHandler
+	Version      *VersionHandler
+	User         *UserHandler
+	Organization *OrganizationHandler
+	Webhook      *WebhookHandler
+	Notification *NotificationHandler
+	Privacy      *PrivacyHandler
+	Retention    *RetentionHandler
+	Batch        *BatchHandler
+	OpenAPI      *OpenAPIHandler
 }
 
 func NewHandlers(s *server.Server, services *service.Services) *Handlers {
 	return &Handlers{
-		Health:  NewHealthHandler(s),
-		OpenAPI: NewOpenAPIHandler(s),
+		Health:       NewHealthHandler(s),
+		Version:      NewVersionHandler(s),
+		User:         NewUserHandler(s, services.User),
+		Organization: NewOrganizationHandler(s, services.Organization),
+		Webhook:      NewWebhookHandler(s, services.Webhook),
+		Notification: NewNotificationHandler(s, services.Notification),
+		Privacy:      NewPrivacyHandler(s, services.Privacy),
+		Retention:    NewRetentionHandler(s, services.Retention),
+		Batch:        NewBatchHandler(s),
+		OpenAPI:      NewOpenAPIHandler(s),
 	}
 }
@@ -6,6 +6,8 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/apk471/go-boilerplate/internal/buildinfo"
+	"github.com/apk471/go-boilerplate/internal/lib/resilience"
 	"github.com/apk471/go-boilerplate/internal/middleware"
 	"github.com/apk471/go-boilerplate/internal/server"
 
@@ -32,6 +34,7 @@ func (h *HealthHandler) CheckHealth(c echo.Context) error {
 		"status":      "healthy",
 		"timestamp":   time.Now().UTC(),
 		"environment": h.server.Config.Primary.Env,
+		"build":       buildinfo.Get(),
 		"checks":      make(map[string]interface{}),
 	}
 
@@ -103,6 +106,19 @@ func (h *HealthHandler) CheckHealth(c echo.Context) error {
 		}
 	}
 
+	// Report the circuit breakers of the dependencies. An open circuit does
+	// not make the instance unhealthy by itself, the checks above decide that.
+	if h.server.Resilience != nil {
+		circuits := make(map[string]interface{})
+		for _, status := range h.server.Resilience.Statuses() {
+			circuits[status.Name] = status
+			if status.State != resilience.StateClosed {
+				logger.Warn().Str("dependency", status.Name).Str("state", string(status.State)).Msg("circuit not closed")
+			}
+		}
+		checks["circuits"] = circuits
+	}
+
 	// Set overall status
 	if !isHealthy {
 		response["status"] = "unhealthy"
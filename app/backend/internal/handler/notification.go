@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/apk471/go-boilerplate/internal/logger"
+	"github.com/apk471/go-boilerplate/internal/middleware"
+	"github.com/apk471/go-boilerplate/internal/model"
+	"github.com/apk471/go-boilerplate/internal/server"
+	"github.com/apk471/go-boilerplate/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// notificationStreamHeartbeat keeps idle streams alive through proxies
+	notificationStreamHeartbeat = 25 * time.Second
+	// notificationStreamMargin ends a stream this long before the server's
+	// write timeout when the deadline cannot be lifted, so clients reconnect
+	// cleanly instead of seeing a reset connection
+	notificationStreamMargin = 5 * time.Second
+	// notificationStreamRetry is the reconnect delay sent to clients, in ms
+	notificationStreamRetry = 3000
+)
+
+type NotificationHandler struct {
+	Handler
+	notificationService *service.NotificationService
+}
+
+func NewNotificationHandler(s *server.Server, notificationService *service.NotificationService) *NotificationHandler {
+	return &NotificationHandler{
+		Handler:             NewHandler(s),
+		notificationService: notificationService,
+	}
+}
+
+func (h *NotificationHandler) List(
+	c echo.Context,
+	req *model.ListNotificationsPayload,
+) (*model.PaginatedResponse[model.NotificationWithHighlights], error) {
+	return h.notificationService.List(c.Request().Context(), middleware.GetUserID(c), req)
+}
+
+func (h *NotificationHandler) UnreadCount(
+	c echo.Context,
+	req *model.GetUnreadNotificationCountPayload,
+) (*model.NotificationUnreadCount, error) {
+	return h.notificationService.UnreadCount(c.Request().Context(), middleware.GetUserID(c))
+}
+
+func (h *NotificationHandler) MarkRead(c echo.Context, req *model.MarkNotificationReadPayload) (*model.Notification, error) {
+	return h.notificationService.MarkRead(c.Request().Context(), middleware.GetUserID(c), req.ID)
+}
+
+func (h *NotificationHandler) MarkAllRead(c echo.Context, req *model.MarkAllNotificationsReadPayload) error {
+	return h.notificationService.MarkAllRead(c.Request().Context(), middleware.GetUserID(c))
+}
+
+func (h *NotificationHandler) GetPreferences(
+	c echo.Context,
+	req *model.GetNotificationPreferencesPayload,
+) (*model.NotificationPreferences, error) {
+	return h.notificationService.GetPreferences(c.Request().Context(), middleware.GetUserID(c))
+}
+
+func (h *NotificationHandler) UpdatePreferences(
+	c echo.Context,
+	req *model.UpdateNotificationPreferencesPayload,
+) (*model.NotificationPreferences, error) {
+	return h.notificationService.UpdatePreferences(c.Request().Context(), middleware.GetUserID(c), req)
+}
+
+// Stream sends the caller's realtime updates as server-sent events. Each
+// event is named after NotificationStreamMessage.Type.
+func (h *NotificationHandler) Stream(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	messages, err := h.notificationService.Stream(ctx, middleware.GetUserID(c))
+	if err != nil {
+		return err
+	}
+
+	res := c.Response()
+
+	// The server's write timeout would cut the stream off. Lift it if the
+	// response writer allows, otherwise end the stream just before it.
+	var deadline <-chan time.Time
+	if err := http.NewResponseController(res).SetWriteDeadline(time.Time{}); err != nil {
+		writeTimeout := time.Duration(h.server.Config.Server.WriteTimeout) * time.Second
+		timer := time.NewTimer(max(writeTimeout-notificationStreamMargin, time.Second))
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	res.Header().Set(echo.HeaderConnection, "keep-alive")
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+
+	if _, err := fmt.Fprintf(res, "retry: %d\n\n", notificationStreamRetry); err != nil {
+		return nil
+	}
+	res.Flush()
+
+	heartbeat := time.NewTicker(notificationStreamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-deadline:
+			return nil
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(res, ": heartbeat\n\n"); err != nil {
+				return nil
+			}
+		case message, ok := <-messages:
+			if !ok {
+				return nil
+			}
+
+			data, err := json.Marshal(message)
+			if err != nil {
+				logger.FromContext(ctx).Error().Err(err).Msg("failed to encode notification stream message")
+				continue
+			}
+
+			if _, err := fmt.Fprintf(res, "event: %s\ndata: %s\n\n", message.Type, data); err != nil {
+				logger.FromContext(ctx).Debug().Err(err).Msg("notification stream closed")
+				return nil
+			}
+		}
+		res.Flush()
+	}
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"github.com/apk471/go-boilerplate/internal/middleware"
+	"github.com/apk471/go-boilerplate/internal/model"
+	"github.com/apk471/go-boilerplate/internal/server"
+	"github.com/apk471/go-boilerplate/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+type OrganizationHandler struct {
+	Handler
+	organizationService *service.OrganizationService
+}
+
+func NewOrganizationHandler(s *server.Server, organizationService *service.OrganizationService) *OrganizationHandler {
+	return &OrganizationHandler{
+		Handler:             NewHandler(s),
+		organizationService: organizationService,
+	}
+}
+
+func (h *OrganizationHandler) ListOrganizations(
+	c echo.Context,
+	req *model.ListOrganizationsPayload,
+) ([]model.OrganizationWithRole, error) {
+	return h.organizationService.List(c.Request().Context(), middleware.GetUserID(c))
+}
+
+func (h *OrganizationHandler) CreateOrganization(
+	c echo.Context,
+	req *model.CreateOrganizationPayload,
+) (*model.OrganizationWithRole, error) {
+	return h.organizationService.Create(c.Request().Context(), middleware.GetUserID(c), req)
+}
+
+func (h *OrganizationHandler) GetOrganization(
+	c echo.Context,
+	req *model.GetOrganizationPayload,
+) (*model.OrganizationWithRole, error) {
+	return h.organizationService.Get(c.Request().Context(), middleware.GetUserID(c), req.ID)
+}
+
+func (h *OrganizationHandler) ListMembers(
+	c echo.Context,
+	req *model.ListOrganizationMembersPayload,
+) ([]model.OrganizationMember, error) {
+	return h.organizationService.ListMembers(c.Request().Context(), middleware.GetUserID(c), req.ID, req.Q)
+}
+
+func (h *OrganizationHandler) UpdateMember(
+	c echo.Context,
+	req *model.UpdateOrganizationMemberPayload,
+) (*model.OrganizationMember, error) {
+	return h.organizationService.UpdateMemberRole(c.Request().Context(), middleware.GetUserID(c), req)
+}
+
+func (h *OrganizationHandler) RemoveMember(c echo.Context, req *model.RemoveOrganizationMemberPayload) error {
+	return h.organizationService.RemoveMember(c.Request().Context(), middleware.GetUserID(c), req.ID, req.UserID)
+}
+
+func (h *OrganizationHandler) ListInvitations(
+	c echo.Context,
+	req *model.ListOrganizationInvitationsPayload,
+) ([]model.OrganizationInvitation, error) {
+	return h.organizationService.ListInvitations(c.Request().Context(), middleware.GetUserID(c), req.ID)
+}
+
+func (h *OrganizationHandler) CreateInvitation(
+	c echo.Context,
+	req *model.CreateOrganizationInvitationPayload,
+) (*model.OrganizationInvitation, error) {
+	return h.organizationService.Invite(c.Request().Context(), middleware.GetUserID(c), req)
+}
+
+func (h *OrganizationHandler) RevokeInvitation(c echo.Context, req *model.RevokeOrganizationInvitationPayload) error {
+	return h.organizationService.RevokeInvitation(c.Request().Context(), middleware.GetUserID(c), req.ID, req.InvitationID)
+}
+
+func (h *OrganizationHandler) AcceptInvitation(
+	c echo.Context,
+	req *model.AcceptInvitationPayload,
+) (*model.OrganizationWithRole, error) {
+	return h.organizationService.AcceptInvitation(c.Request().Context(), middleware.GetUserID(c), req.Token)
+}
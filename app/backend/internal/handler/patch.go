@@ -0,0 +1,197 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"reflect"
+	"strings"
+
+	"github.com/apk471/go-boilerplate/internal/errs"
+	"github.com/apk471/go-boilerplate/internal/lib/patch"
+	"github.com/apk471/go-boilerplate/internal/validation"
+	"github.com/labstack/echo/v4"
+)
+
+// PatchHandlerFunc stores a patched resource. req holds only the changed
+// fields; p lists them with their old and new values and the version of the
+// resource the patch was applied to.
+type PatchHandlerFunc[Req validation.Validatable, Res any] func(c echo.Context, req Req, p patch.Patch) (Res, error)
+
+// HandlePatch serves a partial update. The body is a merge patch
+// (application/merge-patch+json, or application/json) or a JSON Patch
+// (application/json-patch+json) applied to the resource returned by current.
+// The changed fields are decoded into req and validated; changing a field req
+// does not have is rejected. If-Match must name the current version when sent.
+// handler is not called when the patch changes nothing.
+func HandlePatch[Req validation.Validatable, Res any](
+	h Handler,
+	current HandlerFunc[Req, Res],
+	handler PatchHandlerFunc[Req, Res],
+	status int,
+	req Req,
+) echo.HandlerFunc {
+	resultType := reflect.TypeFor[Res]()
+
+	return func(c echo.Context) error {
+		selection, err := h.server.Partial.Parse(resultType, c.QueryParam("fields"), c.QueryParam("expand"))
+		if err != nil {
+			return partialError(err)
+		}
+
+		var (
+			base    Res
+			applied patch.Patch
+		)
+		bind := func(c echo.Context, req Req) error {
+			base, applied, err = bindPatch(c, req, current)
+			return err
+		}
+
+		return handleRequest(c, req, bind, func(c echo.Context, req Req) (interface{}, error) {
+			if len(applied.Changes) == 0 {
+				return base, nil
+			}
+			return handler(c, req, applied)
+		}, JSONResponseHandler{status: status, selection: selection})
+	}
+}
+
+func bindPatch[Req validation.Validatable, Res any](
+	c echo.Context,
+	req Req,
+	current HandlerFunc[Req, Res],
+) (Res, patch.Patch, error) {
+	var zero Res
+
+	if err := (&echo.DefaultBinder{}).BindPathParams(c, req); err != nil {
+		return zero, patch.Patch{}, errs.NewBadRequestError("Invalid path parameters", false, nil, nil, nil)
+	}
+
+	var apply func(doc, patch []byte) ([]byte, error)
+	mediaType, _, _ := mime.ParseMediaType(c.Request().Header.Get(echo.HeaderContentType))
+	switch mediaType {
+	case patch.MediaTypeMergePatch, echo.MIMEApplicationJSON:
+		apply = patch.MergePatch
+	case patch.MediaTypeJSONPatch:
+		apply = patch.JSONPatch
+	default:
+		return zero, patch.Patch{}, echo.ErrUnsupportedMediaType
+	}
+
+	base, err := current(c, req)
+	if err != nil {
+		return zero, patch.Patch{}, err
+	}
+
+	version := versionOf(base)
+	if ifMatch := c.Request().Header.Get("If-Match"); ifMatch != "" && !etagMatches(ifMatch, version) {
+		return zero, patch.Patch{}, errs.NewPreconditionFailedError(
+			"The resource was modified since it was read, fetch it again and retry", true)
+	}
+
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return zero, patch.Patch{}, errs.NewBadRequestError("Failed to read request body", false, nil, nil, nil)
+	}
+
+	doc, err := json.Marshal(base)
+	if err != nil {
+		return zero, patch.Patch{}, err
+	}
+
+	patched, err := apply(doc, body)
+	if err != nil {
+		return zero, patch.Patch{}, patchError(err)
+	}
+
+	changes, err := patch.Diff(doc, patched)
+	if err != nil {
+		return zero, patch.Patch{}, patchError(err)
+	}
+
+	if err := decodeChanges(req, changes); err != nil {
+		return zero, patch.Patch{}, err
+	}
+	if err := validation.Validate(req); err != nil {
+		return zero, patch.Patch{}, err
+	}
+
+	return base, patch.Patch{Version: version, Changes: changes}, nil
+}
+
+func patchError(err error) error {
+	switch {
+	case errors.Is(err, patch.ErrTestFailed):
+		code := "PATCH_TEST_FAILED"
+		return errs.NewConflictError(err.Error(), true, &code)
+	case errors.Is(err, patch.ErrInvalidPatch):
+		code := "INVALID_PATCH"
+		return errs.NewBadRequestError(err.Error(), true, &code, nil, nil)
+	default:
+		return err
+	}
+}
+
+// decodeChanges decodes the new value of each changed field into req,
+// rejecting fields req does not have and removed fields
+func decodeChanges(req any, changes patch.Changes) error {
+	var fieldErrors []errs.FieldError
+	for _, change := range changes {
+		if change.To == nil || string(change.To) == "null" {
+			fieldErrors = append(fieldErrors, errs.FieldError{Field: change.Field, Error: "cannot be removed"})
+			continue
+		}
+
+		field, _ := json.Marshal(map[string]json.RawMessage{change.Field: change.To})
+		decoder := json.NewDecoder(bytes.NewReader(field))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(req); err != nil {
+			message := "cannot be changed"
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) {
+				message = "has the wrong type"
+			}
+			fieldErrors = append(fieldErrors, errs.FieldError{Field: change.Field, Error: message})
+		}
+	}
+
+	if fieldErrors != nil {
+		return errs.NewBadRequestError("Validation failed", true, nil, fieldErrors, nil)
+	}
+	return nil
+}
+
+// versionOf returns the version of versioned resources, see model.BaseWithUpdatedAt
+func versionOf(result any) string {
+	v := reflect.ValueOf(result)
+	if !v.IsValid() || (v.Kind() == reflect.Pointer && v.IsNil()) {
+		return ""
+	}
+
+	if versioned, ok := result.(interface{ Version() string }); ok {
+		return versioned.Version()
+	}
+	return ""
+}
+
+func etag(version string) string {
+	return `"` + version + `"`
+}
+
+// etagMatches evaluates an If-Match header against the current version
+func etagMatches(header, version string) bool {
+	if version == "" {
+		return false
+	}
+
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == etag(version) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"github.com/apk471/go-boilerplate/internal/middleware"
+	"github.com/apk471/go-boilerplate/internal/model"
+	"github.com/apk471/go-boilerplate/internal/server"
+	"github.com/apk471/go-boilerplate/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+type PrivacyHandler struct {
+	Handler
+	privacyService *service.PrivacyService
+}
+
+func NewPrivacyHandler(s *server.Server, privacyService *service.PrivacyService) *PrivacyHandler {
+	return &PrivacyHandler{
+		Handler:        NewHandler(s),
+		privacyService: privacyService,
+	}
+}
+
+func (h *PrivacyHandler) RequestExport(c echo.Context, req *model.RequestDataExportPayload) (*model.PrivacyRequest, error) {
+	return h.privacyService.RequestExport(c.Request().Context(), middleware.GetUserID(c))
+}
+
+func (h *PrivacyHandler) ListExports(c echo.Context, req *model.ListDataExportsPayload) ([]model.PrivacyRequest, error) {
+	return h.privacyService.ListExports(c.Request().Context(), middleware.GetUserID(c))
+}
+
+func (h *PrivacyHandler) DownloadExport(c echo.Context, req *model.DownloadDataExportPayload) ([]byte, error) {
+	return h.privacyService.DownloadExport(c.Request().Context(), req.Token)
+}
+
+func (h *PrivacyHandler) RequestErasure(c echo.Context, req *model.DeleteMePayload) error {
+	return h.privacyService.RequestErasure(c.Request().Context(), middleware.GetUserID(c))
+}
@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"github.com/apk471/go-boilerplate/internal/model"
+	"github.com/apk471/go-boilerplate/internal/server"
+	"github.com/apk471/go-boilerplate/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+type RetentionHandler struct {
+	Handler
+	retentionService *service.RetentionService
+}
+
+func NewRetentionHandler(s *server.Server, retentionService *service.RetentionService) *RetentionHandler {
+	return &RetentionHandler{
+		Handler:          NewHandler(s),
+		retentionService: retentionService,
+	}
+}
+
+func (h *RetentionHandler) GetReport(c echo.Context, req *model.GetRetentionReportPayload) (*model.RetentionReport, error) {
+	return h.retentionService.Report(c.Request().Context())
+}
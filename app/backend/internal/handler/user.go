@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"github.com/apk471/go-boilerplate/internal/lib/patch"
+	"github.com/apk471/go-boilerplate/internal/middleware"
+	"github.com/apk471/go-boilerplate/internal/model"
+	"github.com/apk471/go-boilerplate/internal/server"
+	"github.com/apk471/go-boilerplate/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+type UserHandler struct {
+	Handler
+	userService *service.UserService
+}
+
+func NewUserHandler(s *server.Server, userService *service.UserService) *UserHandler {
+	return &UserHandler{
+		Handler:     NewHandler(s),
+		userService: userService,
+	}
+}
+
+func (h *UserHandler) GetMe(c echo.Context, req *model.GetMePayload) (*model.User, error) {
+	return middleware.GetUser(c), nil
+}
+
+// CurrentMe returns the profile UpdateMe patches
+func (h *UserHandler) CurrentMe(c echo.Context, req *model.UpdateMePayload) (*model.User, error) {
+	return middleware.GetUser(c), nil
+}
+
+func (h *UserHandler) UpdateMe(c echo.Context, req *model.UpdateMePayload, p patch.Patch) (*model.User, error) {
+	return h.userService.UpdateProfile(c.Request().Context(), middleware.GetUserID(c), req, p)
+}
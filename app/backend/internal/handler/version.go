@@ -0,0 +1,24 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/apk471/go-boilerplate/internal/buildinfo"
+	"github.com/apk471/go-boilerplate/internal/server"
+
+	"github.com/labstack/echo/v4"
+)
+
+type VersionHandler struct {
+	Handler
+}
+
+func NewVersionHandler(s *server.Server) *VersionHandler {
+	return &VersionHandler{
+		Handler: NewHandler(s),
+	}
+}
+
+func (h *VersionHandler) GetVersion(c echo.Context) error {
+	return c.JSON(http.StatusOK, buildinfo.Get())
+}
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"github.com/apk471/go-boilerplate/internal/middleware"
+	"github.com/apk471/go-boilerplate/internal/model"
+	"github.com/apk471/go-boilerplate/internal/server"
+	"github.com/apk471/go-boilerplate/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+type WebhookHandler struct {
+	Handler
+	webhookService *service.WebhookService
+}
+
+func NewWebhookHandler(s *server.Server, webhookService *service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		Handler:        NewHandler(s),
+		webhookService: webhookService,
+	}
+}
+
+func (h *WebhookHandler) ListEndpoints(
+	c echo.Context,
+	req *model.ListWebhookEndpointsPayload,
+) ([]model.WebhookEndpoint, error) {
+	return h.webhookService.ListEndpoints(c.Request().Context(), middleware.GetUserID(c), req.ID)
+}
+
+func (h *WebhookHandler) CreateEndpoint(
+	c echo.Context,
+	req *model.CreateWebhookEndpointPayload,
+) (*model.WebhookEndpointWithSecret, error) {
+	return h.webhookService.CreateEndpoint(c.Request().Context(), middleware.GetUserID(c), req)
+}
+
+func (h *WebhookHandler) GetEndpoint(
+	c echo.Context,
+	req *model.GetWebhookEndpointPayload,
+) (*model.WebhookEndpoint, error) {
+	return h.webhookService.GetEndpoint(c.Request().Context(), middleware.GetUserID(c), req.ID, req.EndpointID)
+}
+
+func (h *WebhookHandler) UpdateEndpoint(
+	c echo.Context,
+	req *model.UpdateWebhookEndpointPayload,
+) (*model.WebhookEndpoint, error) {
+	return h.webhookService.UpdateEndpoint(c.Request().Context(), middleware.GetUserID(c), req)
+}
+
+func (h *WebhookHandler) DeleteEndpoint(c echo.Context, req *model.DeleteWebhookEndpointPayload) error {
+	return h.webhookService.DeleteEndpoint(c.Request().Context(), middleware.GetUserID(c), req.ID, req.EndpointID)
+}
+
+func (h *WebhookHandler) RotateSecret(
+	c echo.Context,
+	req *model.RotateWebhookSecretPayload,
+) (*model.WebhookEndpointWithSecret, error) {
+	return h.webhookService.RotateSecret(c.Request().Context(), middleware.GetUserID(c), req.ID, req.EndpointID)
+}
+
+func (h *WebhookHandler) PingEndpoint(
+	c echo.Context,
+	req *model.PingWebhookEndpointPayload,
+) (*model.WebhookDelivery, error) {
+	return h.webhookService.Ping(c.Request().Context(), middleware.GetUserID(c), req.ID, req.EndpointID)
+}
+
+func (h *WebhookHandler) ListDeliveries(
+	c echo.Context,
+	req *model.ListWebhookDeliveriesPayload,
+) (*model.PaginatedResponse[model.WebhookDelivery], error) {
+	return h.webhookService.ListDeliveries(c.Request().Context(), middleware.GetUserID(c), req)
+}
+
+func (h *WebhookHandler) Redeliver(
+	c echo.Context,
+	req *model.RedeliverWebhookPayload,
+) (*model.WebhookDelivery, error) {
+	return h.webhookService.Redeliver(c.Request().Context(), middleware.GetUserID(c), req)
+}
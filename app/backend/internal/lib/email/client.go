@@ -2,28 +2,92 @@ package email
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"html/template"
+	"net/http"
+	"net/url"
 
 	"github.com/apk471/go-boilerplate/internal/config"
+	"github.com/apk471/go-boilerplate/internal/lib/resilience"
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
 	"github.com/pkg/errors"
 	"github.com/resend/resend-go/v2"
 	"github.com/rs/zerolog"
 )
 
+// StatusError is a send Resend answered with an error status
+type StatusError struct {
+	StatusCode int
+	Err        error
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("resend responded %d: %v", e.StatusCode, e.Err)
+}
+
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
+
+// Failure reports whether a send error means Resend is unavailable: the
+// request got no response, was rate limited or failed with a 5xx. Sends
+// Resend rejects, e.g. for an invalid address, and template errors come from
+// a healthy service. It is the failure predicate of the email guard.
+func Failure(err error) bool {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= http.StatusInternalServerError
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+type statusKey struct{}
+
+// statusTransport stores the status of the response in the *int the request
+// context carries, since Resend's errors do not include it
+type statusTransport struct {
+	next http.RoundTripper
+}
+
+func (t statusTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if status, ok := req.Context().Value(statusKey{}).(*int); ok && err == nil {
+		*status = resp.StatusCode
+	}
+	return resp, err
+}
+
 type Client struct {
 	client *resend.Client
 	logger *zerolog.Logger
+	guard  *resilience.Guard
 }
 
-func NewClient(cfg *config.Config, logger *zerolog.Logger) *Client {
+// NewClient sends emails through Resend with httpClient, usually an
+// httpclient client so sends are traced, logged and retried. guard bounds
+// concurrent sends and fails them fast while Resend is failing; it may be nil.
+func NewClient(cfg *config.Config, logger *zerolog.Logger, httpClient *http.Client, guard *resilience.Guard) *Client {
+	next := httpClient.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	client := *httpClient
+	client.Transport = statusTransport{next: next}
+
 	return &Client{
-		client: resend.NewClient(cfg.Integration.ResendAPIKey),
+		client: resend.NewCustomClient(&client, cfg.Integration.ResendAPIKey),
 		logger: logger,
+		guard:  guard,
 	}
 }
 
-func (c *Client) SendEmail(to, subject string, templateName Template, data map[string]string) error {
+// SendEmail renders templateName with data, usually a map of strings or a
+// struct for templates that range over lists. Sends carry an idempotency key,
+// see idempotencyKey, so they can be retried without sending the email twice.
+func (c *Client) SendEmail(ctx context.Context, to, subject string, templateName Template, data any) error {
 	tmplPath := fmt.Sprintf("%s/%s.html", "templates/emails", templateName)
 
 	tmpl, err := template.ParseFiles(tmplPath)
@@ -43,10 +107,35 @@ func (c *Client) SendEmail(to, subject string, templateName Template, data map[s
 		Html:    body.String(),
 	}
 
-	_, err = c.client.Emails.Send(params)
+	err = c.guard.Do(ctx, func(ctx context.Context) error {
+		var status int
+		_, err := c.client.Emails.SendWithOptions(context.WithValue(ctx, statusKey{}, &status), params, &resend.SendEmailOptions{
+			IdempotencyKey: idempotencyKey(ctx, templateName),
+		})
+		if err != nil && status != 0 {
+			return &StatusError{StatusCode: status, Err: err}
+		}
+		return err
+	})
+	if resilience.Skipped(err) {
+		c.logger.Warn().Err(err).Str("template", string(templateName)).Msg("Skipping email")
+		return nil
+	}
 	if err != nil {
 		return fmt.Errorf("failed to send email: %w", err)
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// idempotencyKey returns the key Resend deduplicates a send by for 24 hours.
+// Within a job it is derived from the task ID, which stays the same across
+// the task's retries, so a retry after a lost response does not send the
+// email again. Other sends get a key of their own, covering only the retries
+// of the HTTP client.
+func idempotencyKey(ctx context.Context, templateName Template) string {
+	if taskID, ok := asynq.GetTaskID(ctx); ok {
+		return fmt.Sprintf("%s/%s", templateName, taskID)
+	}
+	return uuid.NewString()
+}
@@ -0,0 +1,123 @@
+package email
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/apk471/go-boilerplate/internal/config"
+	"github.com/apk471/go-boilerplate/internal/lib/httpclient"
+	"github.com/apk471/go-boilerplate/internal/testutil"
+	"github.com/rs/zerolog"
+)
+
+// testTemplate is written to a temporary templates directory, so cassettes
+// hold a short body instead of a full email layout
+const testTemplate Template = "test"
+
+// recipient is Resend's test address, which accepts sends without delivering
+const recipient = "delivered@resend.dev"
+
+// keyRecorder passes requests on and remembers their idempotency keys
+type keyRecorder struct {
+	next http.RoundTripper
+
+	mu   sync.Mutex
+	keys []string
+}
+
+func (r *keyRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.mu.Lock()
+	r.keys = append(r.keys, req.Header.Get("Idempotency-Key"))
+	r.mu.Unlock()
+	return r.next.RoundTrip(req)
+}
+
+func newTestClient(t *testing.T, cassette string) (*Client, *keyRecorder) {
+	t.Helper()
+
+	// Recording sends real emails, with the API key from the environment
+	apiKey := "re_test"
+	if testutil.Recording() {
+		apiKey = os.Getenv("BOILERPLATE_INTEGRATION_RESEND_API_KEY")
+	}
+
+	recorder := &keyRecorder{next: testutil.NewCassette(t, cassette)}
+
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "templates", "emails"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	err := os.WriteFile(filepath.Join(dir, "templates", "emails", string(testTemplate)+".html"),
+		[]byte(`<p>Hi {{.Name}}</p>`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(dir)
+
+	cfg := &config.Config{Integration: config.IntegrationConfig{ResendAPIKey: apiKey}}
+	outbound := config.OutboundConfig{MaxRetries: 2}
+	logger := zerolog.Nop()
+
+	client := NewClient(cfg, &logger, httpclient.New("resend", outbound, nil, httpclient.WithTransport(recorder)), nil)
+	return client, recorder
+}
+
+func TestSendEmail(t *testing.T) {
+	client, recorder := newTestClient(t, "send")
+
+	err := client.SendEmail(t.Context(), recipient, "Hello", testTemplate, map[string]string{"Name": "<Ada>"})
+	if err != nil {
+		t.Fatalf("SendEmail() error = %v", err)
+	}
+
+	if len(recorder.keys) != 1 || recorder.keys[0] == "" {
+		t.Errorf("idempotency keys = %q, want one key", recorder.keys)
+	}
+}
+
+func TestSendEmailValidationError(t *testing.T) {
+	// Resend rejects the address with 422, which is not retried
+	client, _ := newTestClient(t, "send_validation_error")
+
+	err := client.SendEmail(t.Context(), "not-an-email", "Hello", testTemplate, map[string]string{"Name": "Ada"})
+	if err == nil {
+		t.Fatal("SendEmail() succeeded for an invalid address")
+	}
+
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("error = %v, want a StatusError with %d", err, http.StatusUnprocessableEntity)
+	}
+	if Failure(err) {
+		t.Error("a rejected address counts as a Resend failure")
+	}
+}
+
+func TestFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"server error", &StatusError{StatusCode: http.StatusBadGateway, Err: errors.New("bad gateway")}, true},
+		{"rate limited", &StatusError{StatusCode: http.StatusTooManyRequests, Err: errors.New("rate limit exceeded")}, true},
+		{"validation error", &StatusError{StatusCode: http.StatusUnprocessableEntity, Err: errors.New("invalid to")}, false},
+		{"unauthorized", &StatusError{StatusCode: http.StatusUnauthorized, Err: errors.New("invalid API key")}, false},
+		{"transport error", fmt.Errorf("failed to send email: %w", &url.Error{Op: "Post", URL: "https://api.resend.com/emails", Err: errors.New("connection refused")}), true},
+		{"template error", errors.New("failed to parse email template welcome"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Failure(tt.err); got != tt.want {
+				t.Errorf("Failure(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,14 +1,95 @@
 package email
 
-func (c *Client) SendWelcomeEmail(to, firstName string) error {
+import (
+	"context"
+	"fmt"
+)
+
+func (c *Client) SendWelcomeEmail(ctx context.Context, to, firstName string) error {
 	data := map[string]string{
 		"UserFirstName": firstName,
 	}
 
 	return c.SendEmail(
+		ctx,
 		to,
 		"Welcome to Boilerplate!",
 		TemplateWelcome,
 		data,
 	)
-}
\ No newline at end of file
+}
+
+func (c *Client) SendInvitationEmail(ctx context.Context, to, organizationName, inviterName, acceptURL, expiresAt string) error {
+	data := map[string]string{
+		"OrganizationName": organizationName,
+		"InviterName":      inviterName,
+		"AcceptURL":        acceptURL,
+		"ExpiresAt":        expiresAt,
+	}
+
+	return c.SendEmail(
+		ctx,
+		to,
+		"You have been invited to join "+organizationName,
+		TemplateInvitation,
+		data,
+	)
+}
+
+func (c *Client) SendNotificationEmail(ctx context.Context, to, title, body, appURL string) error {
+	data := map[string]string{
+		"Title":  title,
+		"Body":   body,
+		"AppURL": appURL,
+	}
+
+	return c.SendEmail(
+		ctx,
+		to,
+		title,
+		TemplateNotification,
+		data,
+	)
+}
+
+type NotificationDigestItem struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+type NotificationDigestData struct {
+	Count  int
+	Items  []NotificationDigestItem
+	AppURL string
+}
+
+func (c *Client) SendNotificationDigestEmail(ctx context.Context, to string, items []NotificationDigestItem, appURL string) error {
+	data := NotificationDigestData{
+		Count:  len(items),
+		Items:  items,
+		AppURL: appURL,
+	}
+
+	return c.SendEmail(
+		ctx,
+		to,
+		fmt.Sprintf("You have %d new notifications", len(items)),
+		TemplateNotificationDigest,
+		data,
+	)
+}
+
+func (c *Client) SendDataExportEmail(ctx context.Context, to, downloadURL, expiresAt string) error {
+	data := map[string]string{
+		"DownloadURL": downloadURL,
+		"ExpiresAt":   expiresAt,
+	}
+
+	return c.SendEmail(
+		ctx,
+		to,
+		"Your data export is ready",
+		TemplateDataExport,
+		data,
+	)
+}
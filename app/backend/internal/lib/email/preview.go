@@ -1,7 +1,30 @@
 package email
 
-var PreviewData = map[string]map[string]string{
-	"welcome": {
+var PreviewData = map[string]any{
+	"welcome": map[string]string{
 		"UserFirstName": "John",
 	},
+	"invitation": map[string]string{
+		"OrganizationName": "Acme",
+		"InviterName":      "Jane",
+		"AcceptURL":        "http://localhost:3000/invitations/accept?token=preview",
+		"ExpiresAt":        "January 2, 2026",
+	},
+	"notification": map[string]string{
+		"Title":  "Your role in Acme changed",
+		"Body":   "You are now an admin.",
+		"AppURL": "http://localhost:3000/notifications",
+	},
+	"notification_digest": NotificationDigestData{
+		Count: 2,
+		Items: []NotificationDigestItem{
+			{Title: "Jane joined Acme", Body: "They joined as a member."},
+			{Title: "John joined Acme", Body: "They joined as an admin."},
+		},
+		AppURL: "http://localhost:3000/notifications",
+	},
+	"data_export": map[string]string{
+		"DownloadURL": "http://localhost:8080/api/v1/privacy/exports/download?token=preview",
+		"ExpiresAt":   "January 2, 2026",
+	},
 }
\ No newline at end of file
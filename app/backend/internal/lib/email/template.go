@@ -3,5 +3,11 @@ package email
 type Template string
 
 const (
-	TemplateWelcome Template = "welcome"
+	TemplateWelcome    Template = "welcome"
+	TemplateInvitation Template = "invitation"
+
+	TemplateNotification       Template = "notification"
+	TemplateNotificationDigest Template = "notification_digest"
+
+	TemplateDataExport Template = "data_export"
 )
\ No newline at end of file
@@ -0,0 +1,277 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/apk471/go-boilerplate/internal/database"
+	job "github.com/apk471/go-boilerplate/internal/lib/jobs"
+	"github.com/apk471/go-boilerplate/internal/logger"
+	"github.com/hibiken/asynq"
+	"github.com/newrelic/go-agent/v3/newrelic"
+	"github.com/rs/zerolog"
+)
+
+// DefaultMaxRetry is how often an asynchronous subscriber is retried unless
+// it registers with WithMaxRetry
+const DefaultMaxRetry = 10
+
+// Handler receives events of the type it was subscribed to
+type Handler func(ctx context.Context, envelope Envelope) error
+
+type subscriber struct {
+	name      string
+	eventType string
+	async     bool
+	maxRetry  int
+	handler   Handler
+}
+
+type SubscribeOption func(*subscriber)
+
+// WithMaxRetry sets how often an asynchronous subscriber is retried before
+// the event is dropped for it
+func WithMaxRetry(n int) SubscribeOption {
+	return func(s *subscriber) {
+		s.maxRetry = n
+	}
+}
+
+// Bus dispatches domain events to subscribers in the same process.
+// Synchronous subscribers run inside Publish; asynchronous subscribers each
+// get their own job so a slow or failing subscriber does not hold up others.
+type Bus struct {
+	logger *zerolog.Logger
+	jobs   *job.JobService
+	db     database.Querier
+	nrApp  *newrelic.Application
+
+	mu          sync.RWMutex
+	subscribers map[string][]*subscriber
+	byName      map[string]*subscriber
+
+	relayCancel context.CancelFunc
+	relayDone   chan struct{}
+}
+
+func NewBus(
+	logger *zerolog.Logger,
+	jobs *job.JobService,
+	db database.Querier,
+	nrApp *newrelic.Application,
+) *Bus {
+	b := &Bus{
+		logger:      logger,
+		jobs:        jobs,
+		db:          db,
+		nrApp:       nrApp,
+		subscribers: make(map[string][]*subscriber),
+		byName:      make(map[string]*subscriber),
+	}
+
+	if jobs != nil {
+		jobs.RegisterHandler(job.TaskEventDelivery, b.handleDeliveryTask)
+	}
+
+	return b
+}
+
+// Subscribe registers a synchronous subscriber. It runs in the publisher's
+// goroutine and its error is returned from Publish. Names identify the
+// subscriber in logs, metrics and jobs and must be unique.
+func Subscribe[E Event](b *Bus, name string, fn func(context.Context, E) error) {
+	b.register(newSubscriber(name, false, fn))
+}
+
+// SubscribeAsync registers a subscriber that runs in a background job and is
+// retried on failure
+func SubscribeAsync[E Event](b *Bus, name string, fn func(context.Context, E) error, opts ...SubscribeOption) {
+	s := newSubscriber(name, true, fn)
+	for _, opt := range opts {
+		opt(s)
+	}
+	b.register(s)
+}
+
+func newSubscriber[E Event](name string, async bool, fn func(context.Context, E) error) *subscriber {
+	var zero E
+	return &subscriber{
+		name:      name,
+		eventType: zero.EventType(),
+		async:     async,
+		maxRetry:  DefaultMaxRetry,
+		handler: func(ctx context.Context, envelope Envelope) error {
+			var event E
+			if err := json.Unmarshal(envelope.Payload, &event); err != nil {
+				return fmt.Errorf("failed to unmarshal event %s: %w", envelope.Type, err)
+			}
+			return fn(ctx, event)
+		},
+	}
+}
+
+// register panics on duplicate names, like http.ServeMux, since subscribers
+// are registered once at startup
+func (b *Bus) register(s *subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.byName[s.name]; ok {
+		panic("events: duplicate subscriber " + s.name)
+	}
+
+	b.byName[s.name] = s
+	b.subscribers[s.eventType] = append(b.subscribers[s.eventType], s)
+}
+
+// Publish dispatches event to its subscribers now. Use PublishTx for events
+// that must only be seen once a transaction commits.
+func (b *Bus) Publish(ctx context.Context, event Event) error {
+	envelope, err := newEnvelope(event)
+	if err != nil {
+		return err
+	}
+
+	return b.dispatch(ctx, envelope)
+}
+
+// dispatch runs the synchronous subscribers and enqueues a job per
+// asynchronous subscriber. Every subscriber is attempted; errors are joined.
+func (b *Bus) dispatch(ctx context.Context, envelope Envelope) error {
+	_, err := b.dispatchExcept(ctx, envelope, nil)
+	return err
+}
+
+// dispatchExcept dispatches to the subscribers not named in done and returns
+// the names of those that succeeded
+func (b *Bus) dispatchExcept(ctx context.Context, envelope Envelope, done []string) ([]string, error) {
+	b.mu.RLock()
+	subscribers := b.subscribers[envelope.Type]
+	b.mu.RUnlock()
+
+	var succeeded []string
+	var failures []error
+	for _, s := range subscribers {
+		if slices.Contains(done, s.name) {
+			continue
+		}
+
+		var err error
+		if s.async {
+			err = b.enqueue(ctx, s, envelope)
+		} else {
+			err = b.invoke(ctx, s, envelope)
+		}
+		if err != nil {
+			failures = append(failures, fmt.Errorf("subscriber %s: %w", s.name, err))
+			continue
+		}
+		succeeded = append(succeeded, s.name)
+	}
+
+	return succeeded, errors.Join(failures...)
+}
+
+func (b *Bus) enqueue(ctx context.Context, s *subscriber, envelope Envelope) error {
+	if b.jobs == nil {
+		return errors.New("job service is not available")
+	}
+
+	encoded, err := json.Marshal(envelope)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event envelope: %w", err)
+	}
+
+	task, err := job.NewEventDeliveryTask(s.name, envelope.ID.String(), encoded, s.maxRetry)
+	if err != nil {
+		return fmt.Errorf("failed to create event delivery task: %w", err)
+	}
+
+	// A conflict means this event was already enqueued for the subscriber,
+	// e.g. when the outbox relay retries a partially dispatched event
+	if err := b.jobs.Enqueue(ctx, task); err != nil && !errors.Is(err, asynq.ErrTaskIDConflict) {
+		return fmt.Errorf("failed to enqueue event delivery task: %w", err)
+	}
+
+	return nil
+}
+
+func (b *Bus) handleDeliveryTask(ctx context.Context, t *asynq.Task) error {
+	var p job.EventDeliveryPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("failed to unmarshal event delivery payload: %w: %w", err, asynq.SkipRetry)
+	}
+
+	var envelope Envelope
+	if err := json.Unmarshal(p.Envelope, &envelope); err != nil {
+		return fmt.Errorf("failed to unmarshal event envelope: %w: %w", err, asynq.SkipRetry)
+	}
+
+	b.mu.RLock()
+	s, ok := b.byName[p.Subscriber]
+	b.mu.RUnlock()
+	if !ok {
+		// The subscriber was removed in a release after the event was published
+		b.logger.Warn().
+			Str("subscriber", p.Subscriber).
+			Str("event_id", envelope.ID.String()).
+			Str("event_type", envelope.Type).
+			Msg("Dropping event for unknown subscriber")
+		return nil
+	}
+
+	if b.nrApp != nil {
+		txn := b.nrApp.StartTransaction("event/" + s.name)
+		defer txn.End()
+		txn.AddAttribute("event.id", envelope.ID.String())
+		txn.AddAttribute("event.type", envelope.Type)
+		ctx = newrelic.NewContext(ctx, txn)
+	}
+
+	err := b.invoke(ctx, s, envelope)
+	if err != nil {
+		if txn := newrelic.FromContext(ctx); txn != nil {
+			txn.NoticeError(err)
+		}
+	}
+	return err
+}
+
+// invoke runs one subscriber with a trace segment, a context logger and
+// per-subscriber duration and error metrics
+func (b *Bus) invoke(ctx context.Context, s *subscriber, envelope Envelope) error {
+	if txn := newrelic.FromContext(ctx); txn != nil {
+		defer txn.StartSegment("event/" + s.name).End()
+	}
+
+	log := logger.FromContext(ctx).With().
+		Str("subscriber", s.name).
+		Str("event_id", envelope.ID.String()).
+		Str("event_type", envelope.Type).
+		Logger()
+	ctx = withEnvelope(logger.WithContext(ctx, &log), envelope)
+
+	start := time.Now()
+	err := s.handler(ctx, envelope)
+	duration := time.Since(start)
+
+	if b.nrApp != nil {
+		b.nrApp.RecordCustomMetric("Events/"+s.name+"/Duration", float64(duration.Milliseconds()))
+		if err != nil {
+			b.nrApp.RecordCustomMetric("Events/"+s.name+"/Errors", 1)
+		}
+	}
+
+	if err != nil {
+		log.Error().Err(err).Dur("duration", duration).Msg("Event subscriber failed")
+		return err
+	}
+
+	log.Debug().Dur("duration", duration).Msg("Event subscriber succeeded")
+	return nil
+}
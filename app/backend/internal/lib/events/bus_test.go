@@ -0,0 +1,61 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+type testEvent struct {
+	Name string `json:"name"`
+}
+
+func (testEvent) EventType() string { return "test.event" }
+
+func TestDispatchExceptSkipsDeliveredSubscribers(t *testing.T) {
+	log := zerolog.Nop()
+	bus := NewBus(&log, nil, nil, nil)
+
+	calls := map[string]int{}
+	failing := true
+	Subscribe(bus, "first", func(ctx context.Context, e testEvent) error {
+		calls["first"]++
+		return nil
+	})
+	Subscribe(bus, "second", func(ctx context.Context, e testEvent) error {
+		calls["second"]++
+		if failing {
+			return errors.New("unavailable")
+		}
+		return nil
+	})
+
+	envelope, err := newEnvelope(testEvent{Name: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	succeeded, err := bus.dispatchExcept(context.Background(), envelope, nil)
+	if err == nil {
+		t.Fatal("expected the failing subscriber's error")
+	}
+	if !slices.Equal(succeeded, []string{"first"}) {
+		t.Fatalf("succeeded = %v, want [first]", succeeded)
+	}
+
+	// The relay retries with the subscribers that already succeeded
+	failing = false
+	succeeded, err = bus.dispatchExcept(context.Background(), envelope, succeeded)
+	if err != nil {
+		t.Fatalf("retry failed: %v", err)
+	}
+	if !slices.Equal(succeeded, []string{"second"}) {
+		t.Fatalf("succeeded on retry = %v, want [second]", succeeded)
+	}
+	if calls["first"] != 1 || calls["second"] != 2 {
+		t.Errorf("calls = %v, want first once and second twice", calls)
+	}
+}
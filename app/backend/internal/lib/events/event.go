@@ -0,0 +1,53 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event is a typed domain event. Events are plain value types whose
+// EventType does not depend on their fields, so the bus can read the type of
+// an event from its zero value when subscribers register.
+type Event interface {
+	EventType() string
+}
+
+// Envelope is an event as it travels through the bus, the outbox and the job
+// queue. ID is stable across retries so subscribers can deduplicate.
+type Envelope struct {
+	ID         uuid.UUID       `json:"id" db:"id"`
+	Type       string          `json:"type" db:"type"`
+	OccurredAt time.Time       `json:"occurred_at" db:"occurred_at"`
+	Payload    json.RawMessage `json:"payload" db:"payload"`
+}
+
+func newEnvelope(event Event) (Envelope, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return Envelope{}, fmt.Errorf("failed to marshal event %s: %w", event.EventType(), err)
+	}
+
+	return Envelope{
+		ID:         uuid.New(),
+		Type:       event.EventType(),
+		OccurredAt: time.Now().UTC(),
+		Payload:    payload,
+	}, nil
+}
+
+type envelopeKey struct{}
+
+// EnvelopeFromContext returns the envelope of the event being handled. It is
+// set for every subscriber call.
+func EnvelopeFromContext(ctx context.Context) (Envelope, bool) {
+	envelope, ok := ctx.Value(envelopeKey{}).(Envelope)
+	return envelope, ok
+}
+
+func withEnvelope(ctx context.Context, envelope Envelope) context.Context {
+	return context.WithValue(ctx, envelopeKey{}, envelope)
+}
@@ -0,0 +1,151 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/apk471/go-boilerplate/internal/database"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	relayInterval  = time.Second
+	relayBatchSize = 100
+	// Failed outbox rows back off exponentially up to this delay
+	relayMaxBackoff = time.Hour
+)
+
+// PublishTx records event in the outbox through tx, so the event exists if
+// and only if the transaction commits. The relay dispatches it to every
+// subscriber afterwards, which means synchronous subscribers also run in the
+// background for events published this way.
+func (b *Bus) PublishTx(ctx context.Context, tx database.Querier, event Event) error {
+	envelope, err := newEnvelope(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO outbox_events (id, event_type, payload, occurred_at)
+		VALUES ($1, $2, $3, $4)`, envelope.ID, envelope.Type, envelope.Payload, envelope.OccurredAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert into table:outbox_events: %w", err)
+	}
+
+	return nil
+}
+
+// StartRelay starts dispatching outbox events in the background. Call it once
+// every subscriber has registered.
+func (b *Bus) StartRelay() {
+	ctx, cancel := context.WithCancel(context.Background())
+	b.relayCancel = cancel
+	b.relayDone = make(chan struct{})
+
+	go func() {
+		defer close(b.relayDone)
+
+		ticker := time.NewTicker(relayInterval)
+		defer ticker.Stop()
+
+		for {
+			// Keep draining while batches come back full
+			for {
+				n, err := b.relayOnce(ctx)
+				if err != nil {
+					if ctx.Err() == nil {
+						b.logger.Error().Err(err).Msg("Failed to relay outbox events")
+					}
+					break
+				}
+				if n < relayBatchSize {
+					break
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	b.logger.Info().Msg("Outbox relay started")
+}
+
+// StopRelay stops the relay and waits for the batch in flight
+func (b *Bus) StopRelay() {
+	if b.relayCancel == nil {
+		return
+	}
+
+	b.relayCancel()
+	<-b.relayDone
+	b.relayCancel = nil
+
+	b.logger.Info().Msg("Outbox relay stopped")
+}
+
+// outboxRow is an outbox event with the subscribers it was delivered to
+type outboxRow struct {
+	Envelope
+	DispatchedTo []string `db:"dispatched_to"`
+}
+
+// relayOnce dispatches one batch of due events. SKIP LOCKED lets several
+// instances relay concurrently without dispatching the same row twice. The
+// subscribers an event reached are recorded, so a retry after a partial
+// failure only dispatches to the ones that failed.
+func (b *Bus) relayOnce(ctx context.Context) (int, error) {
+	var dispatched int
+
+	err := pgx.BeginFunc(ctx, b.db, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT id, event_type AS type, occurred_at, payload, dispatched_to
+			FROM outbox_events
+			WHERE dispatched_at IS NULL AND next_attempt_at <= now()
+			ORDER BY created_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED`, relayBatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to query table:outbox_events: %w", err)
+		}
+
+		events, err := pgx.CollectRows(rows, pgx.RowToStructByName[outboxRow])
+		if err != nil {
+			return fmt.Errorf("failed to collect rows from table:outbox_events: %w", err)
+		}
+
+		for _, event := range events {
+			succeeded, err := b.dispatchExcept(ctx, event.Envelope, event.DispatchedTo)
+			if err != nil {
+				_, err = tx.Exec(ctx, `
+					UPDATE outbox_events
+					SET attempts = attempts + 1,
+						dispatched_to = dispatched_to || $4::text[],
+						last_error = $2,
+						next_attempt_at = now() + least(
+							make_interval(secs => power(2, attempts + 1)),
+							make_interval(secs => $3)
+						)
+					WHERE id = $1`, event.ID, err.Error(), relayMaxBackoff.Seconds(), succeeded)
+			} else {
+				_, err = tx.Exec(ctx, `
+					UPDATE outbox_events
+					SET dispatched_at = now(), attempts = attempts + 1, last_error = NULL,
+						dispatched_to = dispatched_to || $2::text[]
+					WHERE id = $1`, event.ID, succeeded)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to update table:outbox_events: %w", err)
+			}
+		}
+
+		dispatched = len(events)
+		return nil
+	})
+
+	return dispatched, err
+}
@@ -0,0 +1,38 @@
+package httpclient
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/apk471/go-boilerplate/internal/lib/resilience"
+)
+
+// ErrCircuitOpen is returned without calling a host whose circuit is open
+var ErrCircuitOpen = resilience.ErrOpen
+
+// breakers holds a breaker per host, so one failing host does not stop calls
+// to others
+type breakers struct {
+	failures int
+	cooldown time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]*resilience.Breaker
+}
+
+func (b *breakers) get(host string) *resilience.Breaker {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if br, ok := b.hosts[host]; ok {
+		return br
+	}
+	br := resilience.NewBreaker(b.failures, b.cooldown, nil)
+	b.hosts[host] = br
+	return br
+}
+
+func circuitOpenError(host string) error {
+	return fmt.Errorf("%w for %s", ErrCircuitOpen, host)
+}
@@ -0,0 +1,290 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/apk471/go-boilerplate/internal/config"
+	"github.com/apk471/go-boilerplate/internal/lib/resilience"
+	"github.com/apk471/go-boilerplate/internal/lib/timing"
+	"github.com/apk471/go-boilerplate/internal/logger"
+	"github.com/newrelic/go-agent/v3/newrelic"
+	"github.com/rs/zerolog"
+)
+
+// RequestIDHeader carries the ID of the request an outbound call is made for
+const RequestIDHeader = "X-Request-ID"
+
+// drainLimit bounds how much of a response body is read before it is
+// discarded for a retry, so the connection can be reused
+const drainLimit = 64 << 10
+
+type options struct {
+	timeout       time.Duration
+	maxRetries    int
+	transport     http.RoundTripper
+	checkRedirect func(req *http.Request, via []*http.Request) error
+}
+
+// Option customizes a client
+type Option func(*options)
+
+// WithTimeout overrides the timeout of a single attempt
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+// WithRetries overrides the number of retries, 0 disables them
+func WithRetries(maxRetries int) Option {
+	return func(o *options) {
+		o.maxRetries = maxRetries
+	}
+}
+
+// WithTransport replaces the transport requests are sent with, e.g. with a
+// testutil cassette
+func WithTransport(transport http.RoundTripper) Option {
+	return func(o *options) {
+		o.transport = transport
+	}
+}
+
+// WithCheckRedirect sets the redirect policy of the client
+func WithCheckRedirect(fn func(req *http.Request, via []*http.Request) error) Option {
+	return func(o *options) {
+		o.checkRedirect = fn
+	}
+}
+
+// New returns an HTTP client for the integration name. Requests carry the
+// X-Request-ID and New Relic trace headers of their context and are logged
+// with the context logger. Idempotent requests are retried with backoff, and
+// each host gets a circuit breaker. Metrics are recorded under
+// HTTPClient/<name>/. nrApp may be nil.
+func New(name string, cfg config.OutboundConfig, nrApp *newrelic.Application, opts ...Option) *http.Client {
+	o := options{
+		timeout:    cfg.Timeout,
+		maxRetries: cfg.MaxRetries,
+		transport:  http.DefaultTransport,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return &http.Client{
+		Transport: &transport{
+			name:       name,
+			next:       newrelic.NewRoundTripper(o.transport),
+			timeout:    o.timeout,
+			maxRetries: o.maxRetries,
+			baseDelay:  cfg.RetryBaseDelay,
+			maxDelay:   cfg.RetryMaxDelay,
+			breakers: &breakers{
+				failures: cfg.BreakerFailures,
+				cooldown: cfg.BreakerCooldown,
+				hosts:    make(map[string]*resilience.Breaker),
+			},
+			nrApp: nrApp,
+		},
+		CheckRedirect: o.checkRedirect,
+	}
+}
+
+type transport struct {
+	name       string
+	next       http.RoundTripper
+	timeout    time.Duration
+	maxRetries int
+	baseDelay  time.Duration
+	maxDelay   time.Duration
+	breakers   *breakers
+	nrApp      *newrelic.Application
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	// A RoundTripper must not modify the request it was given
+	req = req.Clone(ctx)
+	if requestID := logger.RequestIDFromContext(ctx); requestID != "" && req.Header.Get(RequestIDHeader) == "" {
+		req.Header.Set(RequestIDHeader, requestID)
+	}
+
+	log := logger.FromContext(ctx).With().
+		Str("client", t.name).
+		Str("method", req.Method).
+		Str("url", RedactURL(req.URL)).
+		Logger()
+	log.Debug().Interface("headers", RedactHeaders(req.Header)).Msg("outbound request")
+
+	// Up to the response headers, reading the body is up to the caller
+	defer timing.Start(ctx, "http."+t.name)()
+
+	retryable := t.maxRetries > 0 && idempotent(req) && (req.Body == nil || req.Body == http.NoBody || req.GetBody != nil)
+	br := t.breakers.get(req.URL.Host)
+	start := time.Now()
+
+	for attempt := 1; ; attempt++ {
+		if !br.Allow() {
+			err := circuitOpenError(req.URL.Host)
+			t.finish(&log, start, attempt, nil, err)
+			return nil, err
+		}
+
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("failed to rewind request body: %w", err)
+			}
+			req.Body = body
+		}
+
+		resp, err := t.attempt(req)
+		// Calls the caller gave up on say nothing about the host
+		if ctx.Err() == nil {
+			br.Record(failed(resp, err))
+		} else {
+			br.Abandon()
+		}
+
+		if !retryable || attempt > t.maxRetries || ctx.Err() != nil || !shouldRetry(resp, err) {
+			t.finish(&log, start, attempt, resp, err)
+			return resp, err
+		}
+
+		delay := t.backoff(attempt, resp)
+		event := log.Debug().Int("attempt", attempt).Dur("delay", delay)
+		if err != nil {
+			event = event.Err(err)
+		} else {
+			event = event.Int("status", resp.StatusCode)
+			discard(resp)
+		}
+		event.Msg("retrying outbound request")
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			t.finish(&log, start, attempt, nil, ctx.Err())
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// attempt sends req once within the attempt timeout. The timeout also covers
+// reading the response body, so it is released when the body is closed.
+func (t *transport) attempt(req *http.Request) (*http.Response, error) {
+	if t.timeout <= 0 {
+		return t.next.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+func (t *transport) finish(log *zerolog.Logger, start time.Time, attempts int, resp *http.Response, err error) {
+	duration := time.Since(start)
+
+	var event *zerolog.Event
+	switch {
+	case err != nil:
+		event = log.Warn().Err(err)
+	case resp.StatusCode >= http.StatusInternalServerError:
+		event = log.Warn().Int("status", resp.StatusCode)
+	default:
+		event = log.Info().Int("status", resp.StatusCode)
+	}
+	event.Int("attempts", attempts).Dur("duration", duration).Msg("outbound request completed")
+
+	if t.nrApp == nil {
+		return
+	}
+
+	prefix := "HTTPClient/" + t.name + "/"
+	t.nrApp.RecordCustomMetric(prefix+"Duration", float64(duration.Milliseconds()))
+	if attempts > 1 {
+		t.nrApp.RecordCustomMetric(prefix+"Retries", float64(attempts-1))
+	}
+	if errors.Is(err, ErrCircuitOpen) {
+		t.nrApp.RecordCustomMetric(prefix+"CircuitOpen", 1)
+	}
+	if failed(resp, err) {
+		t.nrApp.RecordCustomMetric(prefix+"Errors", 1)
+	}
+}
+
+// backoff returns the delay before the next attempt: the Retry-After of the
+// response when it sent one, otherwise exponential backoff with full jitter
+func (t *transport) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			return min(time.Duration(seconds)*time.Second, t.maxDelay)
+		}
+	}
+
+	ceiling := t.maxDelay
+	if shift := attempt - 1; shift < 30 {
+		ceiling = min(t.baseDelay<<shift, t.maxDelay)
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return rand.N(ceiling) + 1
+}
+
+// idempotent reports whether req can be sent again without side effects. POST
+// and PATCH are when they carry an Idempotency-Key.
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return req.Header.Get("Idempotency-Key") != ""
+	}
+}
+
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, ErrCircuitOpen)
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+}
+
+// failed reports whether a call counts against the host's circuit. Client
+// errors, including 429, are answers from a healthy host.
+func failed(resp *http.Response, err error) bool {
+	return err != nil || resp.StatusCode >= http.StatusInternalServerError
+}
+
+func discard(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, drainLimit))
+	_ = resp.Body.Close()
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
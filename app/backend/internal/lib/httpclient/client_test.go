@@ -0,0 +1,116 @@
+package httpclient_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apk471/go-boilerplate/internal/config"
+	"github.com/apk471/go-boilerplate/internal/lib/httpclient"
+	"github.com/apk471/go-boilerplate/internal/testutil"
+)
+
+var outbound = config.OutboundConfig{
+	Timeout:         10 * time.Second,
+	MaxRetries:      2,
+	RetryBaseDelay:  time.Millisecond,
+	RetryMaxDelay:   10 * time.Millisecond,
+	BreakerFailures: 3,
+	BreakerCooldown: time.Minute,
+}
+
+func send(t *testing.T, ctx context.Context, client *http.Client, method, url string) (*http.Response, error) {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		t.Fatalf("failed to build request: %v", err)
+	}
+	resp, err := client.Do(req)
+	if err == nil {
+		t.Cleanup(func() { _ = resp.Body.Close() })
+	}
+	return resp, err
+}
+
+func TestClientRetriesServerErrors(t *testing.T) {
+	// The cassette holds the first attempt and both retries
+	client := httpclient.New("test", outbound, nil,
+		httpclient.WithTransport(testutil.NewCassette(t, "retry_server_error")))
+
+	resp, err := send(t, context.Background(), client, http.MethodGet, "https://httpbin.org/status/503")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want the last attempt's %d", resp.StatusCode, http.StatusServiceUnavailable)
+	}
+}
+
+func TestClientDoesNotRetryNonIdempotentRequests(t *testing.T) {
+	// A POST without an Idempotency-Key is sent once, the cassette has no
+	// interaction for a retry
+	client := httpclient.New("test", outbound, nil,
+		httpclient.WithTransport(testutil.NewCassette(t, "no_retry_post")))
+
+	resp, err := send(t, context.Background(), client, http.MethodPost, "https://httpbin.org/status/503")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusServiceUnavailable)
+	}
+}
+
+func TestClientOpensBreakerAfterFailures(t *testing.T) {
+	client := httpclient.New("test", outbound, nil,
+		httpclient.WithRetries(0),
+		httpclient.WithTransport(testutil.NewCassette(t, "breaker")))
+
+	for i := range outbound.BreakerFailures {
+		resp, err := send(t, context.Background(), client, http.MethodGet, "https://httpbin.org/status/500")
+		if err != nil {
+			t.Fatalf("request %d failed: %v", i, err)
+		}
+		if resp.StatusCode != http.StatusInternalServerError {
+			t.Fatalf("request %d: status = %d, want %d", i, resp.StatusCode, http.StatusInternalServerError)
+		}
+	}
+
+	// The circuit is open, so this request fails without reaching the cassette
+	_, err := send(t, context.Background(), client, http.MethodGet, "https://httpbin.org/status/500")
+	if !errors.Is(err, httpclient.ErrCircuitOpen) {
+		t.Errorf("error = %v, want ErrCircuitOpen", err)
+	}
+}
+
+func TestClientRedactsSecretsInLogs(t *testing.T) {
+	client := httpclient.New("test", outbound, nil,
+		httpclient.WithTransport(testutil.NewCassette(t, "redaction")))
+
+	ctx, logs := testutil.CaptureLogs(context.Background())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://httpbin.org/status/204?page=2&api_key=secret-key", nil)
+	if err != nil {
+		t.Fatalf("failed to build request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer secret-token")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	output := logs.String()
+	for _, secret := range []string{"secret-key", "secret-token"} {
+		if strings.Contains(output, secret) {
+			t.Errorf("logs contain %q:\n%s", secret, output)
+		}
+	}
+	if !strings.Contains(output, httpclient.Redacted) {
+		t.Errorf("logs do not contain %s:\n%s", httpclient.Redacted, output)
+	}
+}
@@ -0,0 +1,58 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Redacted replaces secret header and query parameter values in logs and
+// recordings
+const Redacted = "REDACTED"
+
+// sensitiveNames are substrings of header and query parameter names whose
+// values are secrets
+var sensitiveNames = []string{"auth", "cookie", "token", "secret", "key", "signature", "password"}
+
+func sensitive(name string) bool {
+	name = strings.ToLower(name)
+	for _, s := range sensitiveNames {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactHeaders returns a copy of h with the values of secret headers, such
+// as Authorization, cookies and API keys, replaced by Redacted
+func RedactHeaders(h http.Header) http.Header {
+	redacted := make(http.Header, len(h))
+	for name, values := range h {
+		if sensitive(name) {
+			redacted[name] = []string{Redacted}
+			continue
+		}
+		redacted[name] = append([]string(nil), values...)
+	}
+	return redacted
+}
+
+// RedactURL returns u without user info and with the values of secret query
+// parameters replaced by Redacted
+func RedactURL(u *url.URL) string {
+	redacted := *u
+	redacted.User = nil
+
+	if u.RawQuery != "" {
+		query := u.Query()
+		for name := range query {
+			if sensitive(name) {
+				query[name] = []string{Redacted}
+			}
+		}
+		redacted.RawQuery = query.Encode()
+	}
+
+	return redacted.String()
+}
@@ -4,11 +4,19 @@ import (
 	"encoding/json"
 	"time"
 
+	"github.com/apk471/go-boilerplate/internal/lib/email"
+	"github.com/google/uuid"
 	"github.com/hibiken/asynq"
 )
 
 const (
-	TaskWelcome = "email:welcome"
+	TaskWelcome    = "email:welcome"
+	TaskInvitation = "email:invitation"
+
+	TaskNotificationEmail       = "email:notification"
+	TaskNotificationDigestEmail = "email:notification_digest"
+
+	TaskDataExportEmail = "email:data_export"
 )
 
 type WelcomeEmailPayload struct {
@@ -29,4 +37,109 @@ func NewWelcomeEmailTask(to, firstName string) (*asynq.Task, error) {
 		asynq.MaxRetry(3),
 		asynq.Queue("default"),
 		asynq.Timeout(30*time.Second)), nil
-}
\ No newline at end of file
+}
+
+type InvitationEmailPayload struct {
+	To               string `json:"to"`
+	OrganizationName string `json:"organization_name"`
+	InviterName      string `json:"inviter_name"`
+	AcceptURL        string `json:"accept_url"`
+	ExpiresAt        string `json:"expires_at"`
+}
+
+func NewInvitationEmailTask(to, organizationName, inviterName, acceptURL, expiresAt string) (*asynq.Task, error) {
+	payload, err := json.Marshal(InvitationEmailPayload{
+		To:               to,
+		OrganizationName: organizationName,
+		InviterName:      inviterName,
+		AcceptURL:        acceptURL,
+		ExpiresAt:        expiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return asynq.NewTask(TaskInvitation, payload,
+		asynq.MaxRetry(3),
+		asynq.Queue("default"),
+		asynq.Timeout(30*time.Second)), nil
+}
+
+type NotificationEmailPayload struct {
+	To     string `json:"to"`
+	Title  string `json:"title"`
+	Body   string `json:"body"`
+	AppURL string `json:"app_url"`
+}
+
+// NewNotificationEmailTask builds the email for one notification. The
+// notification ID makes the task unique.
+func NewNotificationEmailTask(notificationID uuid.UUID, to, title, body, appURL string) (*asynq.Task, error) {
+	payload, err := json.Marshal(NotificationEmailPayload{
+		To:     to,
+		Title:  title,
+		Body:   body,
+		AppURL: appURL,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return asynq.NewTask(TaskNotificationEmail, payload,
+		asynq.MaxRetry(3),
+		asynq.Queue("default"),
+		asynq.Timeout(30*time.Second),
+		asynq.TaskID(TaskNotificationEmail+":"+notificationID.String())), nil
+}
+
+type NotificationDigestEmailPayload struct {
+	To     string                         `json:"to"`
+	Items  []email.NotificationDigestItem `json:"items"`
+	AppURL string                         `json:"app_url"`
+}
+
+// NewNotificationDigestEmailTask sends the digest claimed by the digest task
+// digestID. The task ID is derived from it and kept for a day after the email
+// is sent, so the digest task can be retried without sending it twice.
+func NewNotificationDigestEmailTask(digestID, to string, items []email.NotificationDigestItem, appURL string) (*asynq.Task, error) {
+	payload, err := json.Marshal(NotificationDigestEmailPayload{
+		To:     to,
+		Items:  items,
+		AppURL: appURL,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return asynq.NewTask(TaskNotificationDigestEmail, payload,
+		asynq.MaxRetry(3),
+		asynq.Queue("low"),
+		asynq.Timeout(30*time.Second),
+		asynq.TaskID(TaskNotificationDigestEmail+":"+digestID),
+		asynq.Retention(24*time.Hour)), nil
+}
+
+type DataExportEmailPayload struct {
+	To          string `json:"to"`
+	DownloadURL string `json:"download_url"`
+	ExpiresAt   string `json:"expires_at"`
+}
+
+// NewDataExportEmailTask sends the download link for an export. The request ID
+// makes the task unique.
+func NewDataExportEmailTask(requestID uuid.UUID, to, downloadURL, expiresAt string) (*asynq.Task, error) {
+	payload, err := json.Marshal(DataExportEmailPayload{
+		To:          to,
+		DownloadURL: downloadURL,
+		ExpiresAt:   expiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return asynq.NewTask(TaskDataExportEmail, payload,
+		asynq.MaxRetry(3),
+		asynq.Queue("critical"),
+		asynq.Timeout(30*time.Second),
+		asynq.TaskID(TaskDataExportEmail+":"+requestID.String())), nil
+}
@@ -0,0 +1,37 @@
+package jobs
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+const (
+	TaskEventDelivery = "event:deliver"
+)
+
+// EventDeliveryPayload carries one domain event to one asynchronous
+// subscriber. Envelope is the event as encoded by the events package.
+type EventDeliveryPayload struct {
+	Subscriber string          `json:"subscriber"`
+	Envelope   json.RawMessage `json:"envelope"`
+}
+
+// NewEventDeliveryTask builds the task for subscriber. eventID makes the task
+// unique so that publishing the same event twice enqueues it once.
+func NewEventDeliveryTask(subscriber, eventID string, envelope json.RawMessage, maxRetry int) (*asynq.Task, error) {
+	payload, err := json.Marshal(EventDeliveryPayload{
+		Subscriber: subscriber,
+		Envelope:   envelope,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return asynq.NewTask(TaskEventDelivery, payload,
+		asynq.MaxRetry(maxRetry),
+		asynq.Queue("default"),
+		asynq.Timeout(time.Minute),
+		asynq.TaskID(TaskEventDelivery+":"+eventID+":"+subscriber)), nil
+}
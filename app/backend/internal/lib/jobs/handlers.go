@@ -7,14 +7,19 @@ import (
 
 	"github.com/apk471/go-boilerplate/internal/config"
 	"github.com/apk471/go-boilerplate/internal/lib/email"
+	"github.com/apk471/go-boilerplate/internal/lib/httpclient"
+	"github.com/apk471/go-boilerplate/internal/lib/resilience"
+	loggerPkg "github.com/apk471/go-boilerplate/internal/logger"
 	"github.com/hibiken/asynq"
+	"github.com/newrelic/go-agent/v3/newrelic"
 	"github.com/rs/zerolog"
 )
 
 var emailClient *email.Client
 
-func (j *JobService) InitHandlers(cfg *config.Config, logger *zerolog.Logger){
-	emailClient = email.NewClient(cfg , logger)
+func (j *JobService) InitHandlers(cfg *config.Config, logger *zerolog.Logger, nrApp *newrelic.Application, guards *resilience.Registry) {
+	emailClient = email.NewClient(cfg, logger, httpclient.New("resend", cfg.Outbound, nrApp),
+		guards.Register(resilience.Email, cfg.Resilience.Email, email.Failure))
 }
 
 
@@ -25,17 +30,20 @@ func (j *JobService) handleWelcomeEmailTask(ctx context.Context, t *asynq.Task)
 		return fmt.Errorf("failed to unmarshal welcome email payload: %w", err)
 	}
 
-	j.logger.Info().
+	logger := loggerPkg.FromContext(ctx)
+
+	logger.Info().
 		Str("type", "welcome").
 		Str("to", p.To).
 		Msg("Processing welcome email task")
 
 	err := emailClient.SendWelcomeEmail(
+		ctx,
 		p.To,
 		p.FirstName,
 	)
 	if err != nil {
-		j.logger.Error().
+		logger.Error().
 			Str("type", "welcome").
 			Str("to", p.To).
 			Err(err).
@@ -43,9 +51,152 @@ func (j *JobService) handleWelcomeEmailTask(ctx context.Context, t *asynq.Task)
 		return err
 	}
 
-	j.logger.Info().
+	logger.Info().
 		Str("type", "welcome").
 		Str("to", p.To).
 		Msg("Successfully sent welcome email")
 	return nil
-}
\ No newline at end of file
+}
+
+func (j *JobService) handleInvitationEmailTask(ctx context.Context, t *asynq.Task) error {
+	var p InvitationEmailPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("failed to unmarshal invitation email payload: %w", err)
+	}
+
+	logger := loggerPkg.FromContext(ctx)
+
+	logger.Info().
+		Str("type", "invitation").
+		Str("to", p.To).
+		Msg("Processing invitation email task")
+
+	err := emailClient.SendInvitationEmail(
+		ctx,
+		p.To,
+		p.OrganizationName,
+		p.InviterName,
+		p.AcceptURL,
+		p.ExpiresAt,
+	)
+	if err != nil {
+		logger.Error().
+			Str("type", "invitation").
+			Str("to", p.To).
+			Err(err).
+			Msg("Failed to send invitation email")
+		return err
+	}
+
+	logger.Info().
+		Str("type", "invitation").
+		Str("to", p.To).
+		Msg("Successfully sent invitation email")
+	return nil
+}
+func (j *JobService) handleNotificationEmailTask(ctx context.Context, t *asynq.Task) error {
+	var p NotificationEmailPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("failed to unmarshal notification email payload: %w", err)
+	}
+
+	logger := loggerPkg.FromContext(ctx)
+
+	logger.Info().
+		Str("type", "notification").
+		Str("to", p.To).
+		Msg("Processing notification email task")
+
+	err := emailClient.SendNotificationEmail(
+		ctx,
+		p.To,
+		p.Title,
+		p.Body,
+		p.AppURL,
+	)
+	if err != nil {
+		logger.Error().
+			Str("type", "notification").
+			Str("to", p.To).
+			Err(err).
+			Msg("Failed to send notification email")
+		return err
+	}
+
+	logger.Info().
+		Str("type", "notification").
+		Str("to", p.To).
+		Msg("Successfully sent notification email")
+	return nil
+}
+
+func (j *JobService) handleNotificationDigestEmailTask(ctx context.Context, t *asynq.Task) error {
+	var p NotificationDigestEmailPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("failed to unmarshal notification digest email payload: %w", err)
+	}
+
+	logger := loggerPkg.FromContext(ctx)
+
+	logger.Info().
+		Str("type", "notification_digest").
+		Str("to", p.To).
+		Int("items", len(p.Items)).
+		Msg("Processing notification digest email task")
+
+	err := emailClient.SendNotificationDigestEmail(
+		ctx,
+		p.To,
+		p.Items,
+		p.AppURL,
+	)
+	if err != nil {
+		logger.Error().
+			Str("type", "notification_digest").
+			Str("to", p.To).
+			Err(err).
+			Msg("Failed to send notification digest email")
+		return err
+	}
+
+	logger.Info().
+		Str("type", "notification_digest").
+		Str("to", p.To).
+		Msg("Successfully sent notification digest email")
+	return nil
+}
+
+func (j *JobService) handleDataExportEmailTask(ctx context.Context, t *asynq.Task) error {
+	var p DataExportEmailPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("failed to unmarshal data export email payload: %w", err)
+	}
+
+	logger := loggerPkg.FromContext(ctx)
+
+	logger.Info().
+		Str("type", "data_export").
+		Str("to", p.To).
+		Msg("Processing data export email task")
+
+	err := emailClient.SendDataExportEmail(
+		ctx,
+		p.To,
+		p.DownloadURL,
+		p.ExpiresAt,
+	)
+	if err != nil {
+		logger.Error().
+			Str("type", "data_export").
+			Str("to", p.To).
+			Err(err).
+			Msg("Failed to send data export email")
+		return err
+	}
+
+	logger.Info().
+		Str("type", "data_export").
+		Str("to", p.To).
+		Msg("Successfully sent data export email")
+	return nil
+}
@@ -1,25 +1,49 @@
 package jobs
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
 	"github.com/apk471/go-boilerplate/internal/config"
+	"github.com/apk471/go-boilerplate/internal/lib/resilience"
+	loggerPkg "github.com/apk471/go-boilerplate/internal/logger"
 	"github.com/hibiken/asynq"
 	"github.com/rs/zerolog"
 )
 
 type JobService struct {
-	Client *asynq.Client
-	server *asynq.Server
-	logger *zerolog.Logger
+	Client    *asynq.Client
+	server    *asynq.Server
+	scheduler *asynq.Scheduler
+	mux       *asynq.ServeMux
+	logger    *zerolog.Logger
+	guard     *resilience.Guard
+
+	retryDelaysMu sync.RWMutex
+	retryDelays   map[string]asynq.RetryDelayFunc
 }
 
-func NewJobService(logger *zerolog.Logger, cfg *config.Config) *JobService {
+// NewJobService creates the job client, server and scheduler, and registers
+// the guard protecting enqueues in guards
+func NewJobService(logger *zerolog.Logger, cfg *config.Config, guards *resilience.Registry) *JobService {
 	redisAddr := cfg.Redis.Address
 
 	client := asynq.NewClient(asynq.RedisClientOpt{
 		Addr: redisAddr,
 	})
 
-	server := asynq.NewServer(
+	j := &JobService{
+		Client:      client,
+		mux:         asynq.NewServeMux(),
+		logger:      logger,
+		guard:       guards.Register(resilience.Jobs, cfg.Resilience.Jobs, enqueueFailure),
+		retryDelays: make(map[string]asynq.RetryDelayFunc),
+	}
+
+	j.server = asynq.NewServer(
 		asynq.RedisClientOpt{Addr: redisAddr},
 		asynq.Config{
 			Concurrency: 10,
@@ -28,23 +52,82 @@ func NewJobService(logger *zerolog.Logger, cfg *config.Config) *JobService {
 				"default":  3, // Default priority for most emails
 				"low":      1, // Lower priority for non-urgent emails
 			},
+			RetryDelayFunc: j.retryDelay,
 		},
 	)
 
-	return &JobService{
-		Client: client,
-		server: server,
-		logger: logger,
+	// Every instance runs a scheduler, so periodic tasks should be unique
+	j.scheduler = asynq.NewScheduler(asynq.RedisClientOpt{Addr: redisAddr}, nil)
+
+	return j
+}
+
+// RegisterHandler routes tasks of taskType to handler. Modules that cannot be
+// imported by this package, such as services, register their handlers here.
+// Handlers may be registered after Start.
+func (j *JobService) RegisterHandler(taskType string, handler func(context.Context, *asynq.Task) error) {
+	j.mux.HandleFunc(taskType, handler)
+}
+
+// RegisterPeriodicTask enqueues task on the cron spec or @every interval.
+// Tasks may be registered after Start.
+func (j *JobService) RegisterPeriodicTask(spec string, task *asynq.Task) error {
+	if _, err := j.scheduler.Register(spec, task); err != nil {
+		return fmt.Errorf("failed to register periodic task %s: %w", task.Type(), err)
+	}
+	return nil
+}
+
+// RegisterRetryDelay overrides the delay before retrying failed tasks of
+// taskType. Other task types use asynq's default backoff.
+func (j *JobService) RegisterRetryDelay(taskType string, delay asynq.RetryDelayFunc) {
+	j.retryDelaysMu.Lock()
+	defer j.retryDelaysMu.Unlock()
+	j.retryDelays[taskType] = delay
+}
+
+// Enqueue enqueues task through the jobs guard, so enqueues fail fast while
+// Redis is unavailable. Rejected enqueues always fail, whatever the fallback,
+// since callers such as the outbox relay rely on them to retry. A duplicate
+// task ID is not counted as a failure.
+func (j *JobService) Enqueue(ctx context.Context, task *asynq.Task, opts ...asynq.Option) error {
+	return j.guard.Do(ctx, func(ctx context.Context) error {
+		_, err := j.Client.EnqueueContext(ctx, task, opts...)
+		return err
+	})
+}
+
+// enqueueFailure reports whether an enqueue error means Redis is unavailable
+func enqueueFailure(err error) bool {
+	return !errors.Is(err, asynq.ErrTaskIDConflict) && !errors.Is(err, asynq.ErrDuplicateTask)
+}
+
+func (j *JobService) retryDelay(n int, err error, t *asynq.Task) time.Duration {
+	j.retryDelaysMu.RLock()
+	delay, ok := j.retryDelays[t.Type()]
+	j.retryDelaysMu.RUnlock()
+
+	if ok {
+		return delay(n, err, t)
 	}
+	return asynq.DefaultRetryDelayFunc(n, err, t)
 }
 
 func (j *JobService) Start() error {
 	// Register task handlers
-	mux := asynq.NewServeMux()
-	mux.HandleFunc(TaskWelcome, j.handleWelcomeEmailTask)
+	j.mux.Use(j.contextLogger)
+	j.mux.HandleFunc(TaskWelcome, j.handleWelcomeEmailTask)
+	j.mux.HandleFunc(TaskInvitation, j.handleInvitationEmailTask)
+	j.mux.HandleFunc(TaskNotificationEmail, j.handleNotificationEmailTask)
+	j.mux.HandleFunc(TaskNotificationDigestEmail, j.handleNotificationDigestEmailTask)
+	j.mux.HandleFunc(TaskDataExportEmail, j.handleDataExportEmailTask)
 
 	j.logger.Info().Msg("Starting background job server")
-	if err := j.server.Start(mux); err != nil {
+	if err := j.server.Start(j.mux); err != nil {
+		return err
+	}
+
+	if err := j.scheduler.Start(); err != nil {
 		return err
 	}
 
@@ -53,6 +136,19 @@ func (j *JobService) Start() error {
 
 func (j *JobService) Stop() {
 	j.logger.Info().Msg("Stopping background job server")
+	j.scheduler.Shutdown()
 	j.server.Shutdown()
 	j.Client.Close()
-}
\ No newline at end of file
+}
+
+// contextLogger stores a logger tagged with the task type and ID in the task context
+func (j *JobService) contextLogger(next asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+		taskLogger := j.logger.With().Str("task_type", t.Type()).Logger()
+		if taskID, ok := asynq.GetTaskID(ctx); ok {
+			taskLogger = taskLogger.With().Str("task_id", taskID).Logger()
+		}
+
+		return next.ProcessTask(loggerPkg.WithContext(ctx, &taskLogger), t)
+	})
+}
@@ -0,0 +1,60 @@
+package jobs
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+)
+
+const (
+	TaskNotificationDispatch = "notification:dispatch"
+	TaskNotificationDigest   = "notification:digest"
+)
+
+type NotificationDispatchPayload struct {
+	NotificationID uuid.UUID `json:"notification_id"`
+}
+
+// NewNotificationDispatchTask emails a notification at processAt, which is
+// after the recipient's quiet hours
+func NewNotificationDispatchTask(notificationID uuid.UUID, processAt time.Time) (*asynq.Task, error) {
+	payload, err := json.Marshal(NotificationDispatchPayload{
+		NotificationID: notificationID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return asynq.NewTask(TaskNotificationDispatch, payload,
+		asynq.MaxRetry(5),
+		asynq.Queue("default"),
+		asynq.Timeout(30*time.Second),
+		asynq.ProcessAt(processAt),
+		asynq.TaskID(TaskNotificationDispatch+":"+notificationID.String())), nil
+}
+
+type NotificationDigestPayload struct {
+	UserID uuid.UUID `json:"user_id"`
+}
+
+// NewNotificationDigestTask sends the user's digest at processAt. Notifications
+// that fall into the same digest enqueue the same task ID, so each digest is
+// scheduled once.
+func NewNotificationDigestTask(userID uuid.UUID, processAt time.Time) (*asynq.Task, error) {
+	payload, err := json.Marshal(NotificationDigestPayload{
+		UserID: userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return asynq.NewTask(TaskNotificationDigest, payload,
+		asynq.MaxRetry(5),
+		asynq.Queue("low"),
+		asynq.Timeout(time.Minute),
+		asynq.ProcessAt(processAt),
+		asynq.TaskID(TaskNotificationDigest+":"+userID.String()+":"+strconv.FormatInt(processAt.Unix(), 10))), nil
+}
@@ -0,0 +1,54 @@
+package jobs
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+)
+
+const (
+	TaskDataExport  = "privacy:export"
+	TaskDataErasure = "privacy:erasure"
+)
+
+type DataExportPayload struct {
+	RequestID uuid.UUID `json:"request_id"`
+}
+
+func NewDataExportTask(requestID uuid.UUID) (*asynq.Task, error) {
+	payload, err := json.Marshal(DataExportPayload{
+		RequestID: requestID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return asynq.NewTask(TaskDataExport, payload,
+		asynq.MaxRetry(5),
+		asynq.Queue("low"),
+		asynq.Timeout(10*time.Minute),
+		asynq.TaskID(TaskDataExport+":"+requestID.String())), nil
+}
+
+type DataErasurePayload struct {
+	RequestID uuid.UUID `json:"request_id"`
+}
+
+// NewDataErasureTask erases a user's data. Retries run every eraser again from
+// the first.
+func NewDataErasureTask(requestID uuid.UUID) (*asynq.Task, error) {
+	payload, err := json.Marshal(DataErasurePayload{
+		RequestID: requestID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return asynq.NewTask(TaskDataErasure, payload,
+		asynq.MaxRetry(10),
+		asynq.Queue("default"),
+		asynq.Timeout(10*time.Minute),
+		asynq.TaskID(TaskDataErasure+":"+requestID.String())), nil
+}
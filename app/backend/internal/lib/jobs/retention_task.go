@@ -0,0 +1,21 @@
+package jobs
+
+import (
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+const (
+	TaskRetentionPurge = "retention:purge"
+)
+
+// NewRetentionPurgeTask runs every retention policy. It is unique while queued
+// or running, so the schedulers of several instances enqueue one purge.
+func NewRetentionPurgeTask() *asynq.Task {
+	return asynq.NewTask(TaskRetentionPurge, nil,
+		asynq.MaxRetry(3),
+		asynq.Queue("low"),
+		asynq.Timeout(time.Hour),
+		asynq.Unique(time.Hour))
+}
@@ -0,0 +1,32 @@
+package jobs
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+)
+
+const (
+	TaskWebhookDelivery = "webhook:deliver"
+)
+
+type WebhookDeliveryPayload struct {
+	DeliveryID uuid.UUID `json:"delivery_id"`
+}
+
+func NewWebhookDeliveryTask(deliveryID uuid.UUID, maxRetry int) (*asynq.Task, error) {
+	payload, err := json.Marshal(WebhookDeliveryPayload{
+		DeliveryID: deliveryID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return asynq.NewTask(TaskWebhookDelivery, payload,
+		asynq.MaxRetry(maxRetry),
+		asynq.Queue("default"),
+		asynq.Timeout(time.Minute),
+		asynq.TaskID(TaskWebhookDelivery+":"+deliveryID.String())), nil
+}
@@ -0,0 +1,141 @@
+package jsoncodec
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/apk471/go-boilerplate/internal/config"
+	gojson "github.com/goccy/go-json"
+	"github.com/labstack/echo/v4"
+)
+
+// Codecs the serializer can encode and decode with
+const (
+	// CodecStd is encoding/json, as used by echo by default
+	CodecStd = "std"
+	// CodecGoJSON is github.com/goccy/go-json, a faster drop-in replacement
+	// for encoding/json
+	CodecGoJSON = "go-json"
+)
+
+type encoder interface {
+	Encode(v any) error
+	SetIndent(prefix, indent string)
+}
+
+type decoder interface {
+	Decode(v any) error
+	DisallowUnknownFields()
+	UseNumber()
+}
+
+type codec struct {
+	newEncoder func(w io.Writer) encoder
+	newDecoder func(r io.Reader) decoder
+}
+
+var codecs = map[string]codec{
+	CodecStd: {
+		newEncoder: func(w io.Writer) encoder { return json.NewEncoder(w) },
+		newDecoder: func(r io.Reader) decoder { return json.NewDecoder(r) },
+	},
+	CodecGoJSON: {
+		newEncoder: func(w io.Writer) encoder { return gojson.NewEncoder(w) },
+		newDecoder: func(r io.Reader) decoder { return gojson.NewDecoder(r) },
+	},
+}
+
+// Serializer is the echo.JSONSerializer behind c.JSON and c.Bind, with the
+// codec and decoding rules of config.JSONConfig
+type Serializer struct {
+	codec     codec
+	strict    bool
+	useNumber bool
+}
+
+// New returns a serializer for cfg. Unknown codecs fall back to CodecStd.
+func New(cfg config.JSONConfig) *Serializer {
+	c, ok := codecs[cfg.Codec]
+	if !ok {
+		c = codecs[CodecStd]
+	}
+	return &Serializer{
+		codec:     c,
+		strict:    cfg.Strict,
+		useNumber: cfg.UseNumber,
+	}
+}
+
+func (s *Serializer) Serialize(c echo.Context, i interface{}, indent string) error {
+	enc := s.codec.newEncoder(c.Response())
+	if indent != "" {
+		enc.SetIndent("", indent)
+	}
+	return enc.Encode(i)
+}
+
+// Deserialize decodes the request body into i. Strict serializers reject
+// unknown fields and data after the JSON value. Errors in the body are 400s
+// worded like echo's.
+func (s *Serializer) Deserialize(c echo.Context, i interface{}) error {
+	dec := s.codec.newDecoder(c.Request().Body)
+	if s.strict {
+		dec.DisallowUnknownFields()
+	}
+	if s.useNumber {
+		dec.UseNumber()
+	}
+
+	if err := dec.Decode(i); err != nil {
+		return decodeError(err)
+	}
+	if s.strict {
+		// Anything but the end of the body after the value, including a
+		// stray closing bracket, is trailing data
+		var trailing json.RawMessage
+		if err := dec.Decode(&trailing); !errors.Is(err, io.EOF) {
+			return echo.NewHTTPError(http.StatusBadRequest, "Syntax error: unexpected data after the JSON value")
+		}
+	}
+	return nil
+}
+
+func decodeError(err error) error {
+	var stdType *json.UnmarshalTypeError
+	var goType *gojson.UnmarshalTypeError
+	var stdSyntax *json.SyntaxError
+	var goSyntax *gojson.SyntaxError
+
+	switch {
+	case errors.As(err, &stdType):
+		return typeError(err, stdType.Value, stdType.Type, stdType.Field, stdType.Offset)
+	case errors.As(err, &goType):
+		return typeError(err, goType.Value, goType.Type, goType.Field, goType.Offset)
+	case errors.As(err, &stdSyntax):
+		return syntaxError(err, stdSyntax.Offset)
+	case errors.As(err, &goSyntax):
+		return syntaxError(err, goSyntax.Offset)
+	// Both codecs word it the same and have no type for it
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.TrimPrefix(err.Error(), "json: unknown field ")
+		return echo.NewHTTPError(http.StatusBadRequest, "Unknown field: "+field).SetInternal(err)
+	default:
+		return err
+	}
+}
+
+func typeError(err error, value string, expected fmt.Stringer, field string, offset int64) error {
+	return echo.NewHTTPError(http.StatusBadRequest,
+		fmt.Sprintf("Unmarshal type error: expected=%v, got=%v, field=%v, offset=%v", expected, value, field, offset),
+	).SetInternal(err)
+}
+
+func syntaxError(err error, offset int64) error {
+	return echo.NewHTTPError(http.StatusBadRequest,
+		fmt.Sprintf("Syntax error: offset=%v, error=%v", offset, err.Error()),
+	).SetInternal(err)
+}
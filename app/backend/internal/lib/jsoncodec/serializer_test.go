@@ -0,0 +1,267 @@
+package jsoncodec
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apk471/go-boilerplate/internal/config"
+	"github.com/apk471/go-boilerplate/internal/model"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+var testCodecs = []string{CodecStd, CodecGoJSON}
+
+type payload struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func deserialize(t testing.TB, s *Serializer, body string, v any) error {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	return s.Deserialize(echo.New().NewContext(req, nil), v)
+}
+
+// badRequest returns the message of a 400 error, failing for other errors
+func badRequest(t *testing.T, err error) string {
+	t.Helper()
+
+	var httpErr *echo.HTTPError
+	if !errors.As(err, &httpErr) || httpErr.Code != http.StatusBadRequest {
+		t.Fatalf("error = %v, want a 400 echo.HTTPError", err)
+	}
+	return httpErr.Message.(string)
+}
+
+func TestDeserializeStrictRejectsUnknownFields(t *testing.T) {
+	for _, codec := range testCodecs {
+		t.Run(codec, func(t *testing.T) {
+			s := New(config.JSONConfig{Codec: codec, Strict: true})
+
+			var p payload
+			err := deserialize(t, s, `{"name":"a","extra":1}`, &p)
+			if msg := badRequest(t, err); !strings.Contains(msg, `Unknown field: "extra"`) {
+				t.Errorf("message = %q, want the unknown field named", msg)
+			}
+
+			lenient := New(config.JSONConfig{Codec: codec})
+			if err := deserialize(t, lenient, `{"name":"a","extra":1}`, &p); err != nil {
+				t.Errorf("lenient Deserialize() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestDeserializeStrictRejectsTrailingData(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr bool
+	}{
+		{"only the value", `{"name":"a"}`, false},
+		{"trailing whitespace", "{\"name\":\"a\"}\n\t ", false},
+		{"stray closing brace", `{"name":"a"}}`, true},
+		{"stray closing bracket", `{"name":"a"}]`, true},
+		{"second value", `{"name":"a"} {"name":"b"}`, true},
+		{"trailing garbage", `{"name":"a"}x`, true},
+		{"trailing comma", `{"name":"a"},`, true},
+	}
+
+	for _, codec := range testCodecs {
+		s := New(config.JSONConfig{Codec: codec, Strict: true})
+
+		for _, tt := range tests {
+			t.Run(codec+"/"+tt.name, func(t *testing.T) {
+				var p payload
+				err := deserialize(t, s, tt.body, &p)
+				if !tt.wantErr {
+					if err != nil {
+						t.Fatalf("Deserialize() error = %v", err)
+					}
+					if p.Name != "a" {
+						t.Errorf("name = %q, want a", p.Name)
+					}
+					return
+				}
+				if msg := badRequest(t, err); !strings.Contains(msg, "unexpected data after the JSON value") {
+					t.Errorf("message = %q, want a trailing data error", msg)
+				}
+			})
+		}
+	}
+}
+
+func TestDeserializeLenientIgnoresTrailingData(t *testing.T) {
+	for _, codec := range testCodecs {
+		t.Run(codec, func(t *testing.T) {
+			s := New(config.JSONConfig{Codec: codec})
+
+			var p payload
+			if err := deserialize(t, s, `{"name":"a"}}`, &p); err != nil {
+				t.Fatalf("Deserialize() error = %v", err)
+			}
+			if p.Name != "a" {
+				t.Errorf("name = %q, want a", p.Name)
+			}
+		})
+	}
+}
+
+func TestDeserializeUseNumber(t *testing.T) {
+	// 2^53 + 1 loses its last digit as a float64
+	const body = `{"id":9007199254740993}`
+
+	for _, codec := range testCodecs {
+		t.Run(codec, func(t *testing.T) {
+			var withNumber map[string]any
+			if err := deserialize(t, New(config.JSONConfig{Codec: codec, UseNumber: true}), body, &withNumber); err != nil {
+				t.Fatalf("Deserialize() error = %v", err)
+			}
+			if n, ok := withNumber["id"].(json.Number); !ok || n.String() != "9007199254740993" {
+				t.Errorf("id = %#v, want json.Number 9007199254740993", withNumber["id"])
+			}
+
+			var withFloat map[string]any
+			if err := deserialize(t, New(config.JSONConfig{Codec: codec}), body, &withFloat); err != nil {
+				t.Fatalf("Deserialize() error = %v", err)
+			}
+			if _, ok := withFloat["id"].(float64); !ok {
+				t.Errorf("id = %#v, want a float64 without use_number", withFloat["id"])
+			}
+		})
+	}
+}
+
+func TestDeserializeErrorsAreBadRequests(t *testing.T) {
+	for _, codec := range testCodecs {
+		s := New(config.JSONConfig{Codec: codec})
+
+		t.Run(codec+"/type", func(t *testing.T) {
+			var p payload
+			msg := badRequest(t, deserialize(t, s, `{"count":"many"}`, &p))
+			if !strings.HasPrefix(msg, "Unmarshal type error") {
+				t.Errorf("message = %q, want a type error", msg)
+			}
+		})
+
+		t.Run(codec+"/syntax", func(t *testing.T) {
+			var p payload
+			msg := badRequest(t, deserialize(t, s, `{"name":}`, &p))
+			if !strings.HasPrefix(msg, "Syntax error") {
+				t.Errorf("message = %q, want a syntax error", msg)
+			}
+		})
+	}
+}
+
+func TestSerialize(t *testing.T) {
+	for _, codec := range testCodecs {
+		t.Run(codec, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+
+			if err := New(config.JSONConfig{Codec: codec}).Serialize(c, payload{Name: "a", Count: 2}, ""); err != nil {
+				t.Fatalf("Serialize() error = %v", err)
+			}
+			if got := strings.TrimSpace(rec.Body.String()); got != `{"name":"a","count":2}` {
+				t.Errorf("body = %s", got)
+			}
+		})
+	}
+}
+
+// The benchmarks compare the codecs on a page of notifications, as served by
+// GET /api/v1/notifications:
+//
+//	go test -run '^$' -bench . -benchmem ./internal/lib/jsoncodec
+const benchItems = 50
+
+func BenchmarkSerialize(b *testing.B) {
+	page := newPage(benchItems)
+	body, err := json.Marshal(page)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for _, codec := range testCodecs {
+		b.Run(codec, func(b *testing.B) {
+			s := New(config.JSONConfig{Codec: codec})
+			e := echo.New()
+			b.SetBytes(int64(len(body)))
+			b.ReportAllocs()
+
+			for b.Loop() {
+				c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+				if err := s.Serialize(c, page, ""); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkDeserialize(b *testing.B) {
+	body, err := json.Marshal(newPage(benchItems))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for _, codec := range testCodecs {
+		b.Run(codec, func(b *testing.B) {
+			s := New(config.JSONConfig{Codec: codec})
+			e := echo.New()
+			b.SetBytes(int64(len(body)))
+			b.ReportAllocs()
+
+			for b.Loop() {
+				c := e.NewContext(httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body)), nil)
+				var page model.PaginatedResponse[model.NotificationWithHighlights]
+				if err := s.Deserialize(c, &page); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func newPage(n int) model.PaginatedResponse[model.NotificationWithHighlights] {
+	now := time.Now().UTC()
+	organizationID := uuid.New()
+
+	data := make([]model.NotificationWithHighlights, n)
+	for i := range data {
+		data[i] = model.NotificationWithHighlights{
+			Notification: model.Notification{
+				Base: model.Base{
+					BaseWithId:        model.BaseWithId{ID: uuid.New()},
+					BaseWithCreatedAt: model.BaseWithCreatedAt{CreatedAt: now},
+					BaseWithUpdatedAt: model.BaseWithUpdatedAt{UpdatedAt: now},
+				},
+				UserID:         uuid.New(),
+				OrganizationID: &organizationID,
+				Type:           model.NotificationTypeOrganizationMemberJoined,
+				Title:          fmt.Sprintf("Member %d joined Acme", i),
+				Body:           "They joined as an admin.",
+				Data:           json.RawMessage(`{"organizationId":"` + organizationID.String() + `","role":"admin"}`),
+			},
+			Highlights: map[string]string{"title": "Member <mark>joined</mark> Acme"},
+		}
+	}
+
+	return model.PaginatedResponse[model.NotificationWithHighlights]{
+		Data:       data,
+		Page:       1,
+		Limit:      n,
+		Total:      n * 10,
+		TotalPages: 10,
+	}
+}
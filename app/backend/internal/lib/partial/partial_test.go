@@ -0,0 +1,416 @@
+package partial
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testOwner struct {
+	ID   string `json:"id"`
+	Role string `json:"role"`
+}
+
+// Base is exported, so its expanders apply to the structs embedding it
+type Base struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type testOrganization struct {
+	Base
+	Name   string            `json:"name"`
+	Owners []testOwner       `json:"owners"`
+	Meta   json.RawMessage   `json:"meta"`
+	Labels map[string]string `json:"labels"`
+	Hidden string            `json:"-"`
+	secret string
+}
+
+type testPage struct {
+	Data  []testOrganization `json:"data"`
+	Total int                `json:"total"`
+}
+
+func (testPage) ItemsField() string { return "data" }
+
+type testPointerPage struct {
+	Items []*testOrganization `json:"items"`
+}
+
+func (*testPointerPage) ItemsField() string { return "items" }
+
+type testCreator struct {
+	Name string `json:"name"`
+}
+
+// newTestRegistry registers creator on the embedded base and memberCount on
+// the organization itself
+func newTestRegistry() *Registry {
+	r := NewRegistry()
+	Register(r, "creator", func(ctx context.Context, items []Base) ([]testCreator, error) {
+		creators := make([]testCreator, len(items))
+		for i, item := range items {
+			creators[i] = testCreator{Name: "creator of " + item.ID}
+		}
+		return creators, nil
+	})
+	Register(r, "memberCount", func(ctx context.Context, items []testOrganization) ([]int, error) {
+		counts := make([]int, len(items))
+		for i, item := range items {
+			counts[i] = len(item.Owners)
+		}
+		return counts, nil
+	})
+	return r
+}
+
+func TestParse(t *testing.T) {
+	organization := reflect.TypeFor[testOrganization]()
+
+	tests := []struct {
+		name      string
+		t         reflect.Type
+		fields    string
+		expand    string
+		wantParam string
+		wantError string
+	}{
+		{name: "fields", t: organization, fields: "id, name"},
+		{name: "embedded field", t: organization, fields: "createdAt"},
+		{name: "field of list elements", t: organization, fields: "owners.role"},
+		{name: "below raw JSON", t: organization, fields: "meta.anything.at.all"},
+		{name: "below a map", t: organization, fields: "labels.team"},
+		{name: "expansion", t: organization, expand: "creator,memberCount", fields: "creator.name,memberCount"},
+		{name: "duplicate expansion", t: organization, expand: "creator,creator"},
+		{name: "list response", t: reflect.TypeFor[[]*testOrganization](), fields: "id"},
+		{name: "envelope", t: reflect.TypeFor[*testPage](), fields: "name", expand: "creator"},
+		{name: "envelope with pointer receiver", t: reflect.TypeFor[testPointerPage](), fields: "owners.id"},
+		{
+			name: "unknown field", t: organization, fields: "id,nope",
+			wantParam: "fields", wantError: `unknown field "nope"`,
+		},
+		{
+			name: "unknown nested field", t: organization, fields: "owners.nope",
+			wantParam: "fields", wantError: `unknown field "owners.nope"`,
+		},
+		{
+			name: "empty segment", t: organization, fields: "owners.",
+			wantParam: "fields", wantError: `unknown field "owners."`,
+		},
+		{
+			name: "below a scalar", t: organization, fields: "name.first",
+			wantParam: "fields", wantError: `unknown field "name.first"`,
+		},
+		{
+			name: "below a JSON marshaler", t: organization, fields: "createdAt.year",
+			wantParam: "fields", wantError: `unknown field "createdAt.year"`,
+		},
+		{
+			name: "skipped field", t: organization, fields: "Hidden",
+			wantParam: "fields", wantError: `unknown field "Hidden"`,
+		},
+		{
+			name: "unexported field", t: organization, fields: "secret",
+			wantParam: "fields", wantError: `unknown field "secret"`,
+		},
+		{
+			name: "expansion field without expand", t: organization, fields: "creator.name",
+			wantParam: "fields", wantError: `"creator.name" requires expand=creator`,
+		},
+		{
+			name: "unknown expansion", t: organization, expand: "owner",
+			wantParam: "expand", wantError: `unknown expansion "owner"`,
+		},
+		{
+			name: "too many fields", t: organization, fields: strings.Repeat("id,", maxFields+1),
+			wantParam: "fields", wantError: "at most 100 fields",
+		},
+		{
+			name: "response without fields", t: reflect.TypeFor[[]string](), fields: "id",
+			wantParam: "fields", wantError: "no fields to select",
+		},
+	}
+
+	r := newTestRegistry()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := r.Parse(tt.t, tt.fields, tt.expand)
+			if tt.wantError == "" {
+				if err != nil {
+					t.Fatalf("Parse() error = %v", err)
+				}
+				if s == nil {
+					t.Fatal("Parse() returned no selection")
+				}
+				return
+			}
+
+			var invalid *InvalidError
+			if !errors.As(err, &invalid) {
+				t.Fatalf("Parse() error = %v, want an *InvalidError", err)
+			}
+			if invalid.Param != tt.wantParam || !strings.Contains(invalid.Message, tt.wantError) {
+				t.Errorf("Parse() error = %v, want %s: %s", err, tt.wantParam, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestParseEmpty(t *testing.T) {
+	s, err := newTestRegistry().Parse(reflect.TypeFor[testOrganization](), " ", "")
+	if s != nil || err != nil {
+		t.Errorf("Parse() = %v, %v, want no selection", s, err)
+	}
+}
+
+func TestShapeOf(t *testing.T) {
+	organization := reflect.TypeFor[testOrganization]()
+
+	tests := []struct {
+		name string
+		t    reflect.Type
+		want shape
+	}{
+		{"resource", organization, shape{resource: organization}},
+		{"pointer", reflect.TypeFor[*testOrganization](), shape{resource: organization}},
+		{"list", reflect.TypeFor[[]testOrganization](), shape{resource: organization, list: true}},
+		{"list of pointers", reflect.TypeFor[[]*testOrganization](), shape{resource: organization, list: true}},
+		{"array", reflect.TypeFor[[2]testOrganization](), shape{resource: organization, list: true}},
+		{"envelope", reflect.TypeFor[testPage](), shape{resource: organization, list: true, itemsField: "data"}},
+		{"pointer to envelope", reflect.TypeFor[*testPage](), shape{resource: organization, list: true, itemsField: "data"}},
+		{
+			"envelope with pointer receiver", reflect.TypeFor[testPointerPage](),
+			shape{resource: organization, list: true, itemsField: "items"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shapeOf(tt.t); got != tt.want {
+				t.Errorf("shapeOf() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandersFor(t *testing.T) {
+	r := newTestRegistry()
+	// Registered on the organization too, so it wins over the base's
+	Register(r, "creator", func(ctx context.Context, items []testOrganization) ([]string, error) {
+		return make([]string, len(items)), nil
+	})
+
+	expanders := r.expandersFor(reflect.TypeFor[testOrganization]())
+	if creator := expanders["creator"]; creator.index != nil || creator.result != reflect.TypeFor[string]() {
+		t.Errorf("creator = index %v, result %v, want the organization's own expander", creator.index, creator.result)
+	}
+	if count := expanders["memberCount"]; count.index != nil {
+		t.Errorf("memberCount index = %v, want nil", count.index)
+	}
+
+	base := r.expandersFor(reflect.TypeFor[Base]())
+	if _, ok := base["memberCount"]; ok {
+		t.Error("an expander of the embedding type is available on the embedded one")
+	}
+
+	type hidden struct{ ID string }
+	Register(r, "hidden", func(ctx context.Context, items []hidden) ([]int, error) {
+		return make([]int, len(items)), nil
+	})
+	if _, ok := r.expandersFor(reflect.TypeFor[struct{ hidden }]())["hidden"]; ok {
+		t.Error("an expander of an unexported embedded struct is available")
+	}
+
+	// Through an embedded struct, the expander gets the embedded value
+	embedding := r.expandersFor(reflect.TypeFor[struct{ Base }]())
+	if creator := embedding["creator"]; !reflect.DeepEqual(creator.index, []int{0}) {
+		t.Errorf("creator index through embedding = %v, want [0]", creator.index)
+	}
+}
+
+func TestRegisterDuplicatePanics(t *testing.T) {
+	r := newTestRegistry()
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering an expander twice did not panic")
+		}
+	}()
+	Register(r, "memberCount", func(ctx context.Context, items []testOrganization) ([]int, error) {
+		return nil, nil
+	})
+}
+
+func TestNodeAdd(t *testing.T) {
+	tests := []struct {
+		name  string
+		paths []string
+		want  node
+	}{
+		{"fields", []string{"id", "name"}, node{"id": nil, "name": nil}},
+		{"nested fields", []string{"owners.id", "owners.role"}, node{"owners": node{"id": nil, "role": nil}}},
+		{"whole field after part of it", []string{"owners.role", "owners"}, node{"owners": nil}},
+		{"part of a field after all of it", []string{"owners", "owners.role"}, node{"owners": nil}},
+		{"deep", []string{"a.b.c", "a.b", "a.d.e"}, node{"a": node{"b": nil, "d": node{"e": nil}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := make(node)
+			for _, path := range tt.paths {
+				n.add(strings.Split(path, "."))
+			}
+			if !reflect.DeepEqual(n, tt.want) {
+				t.Errorf("node = %v, want %v", n, tt.want)
+			}
+		})
+	}
+}
+
+func newTestOrganization(id string, owners int) *testOrganization {
+	o := &testOrganization{
+		Base: Base{ID: id, CreatedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)},
+		Name: "Org " + id,
+		Meta: json.RawMessage(`{"plan":"pro"}`),
+	}
+	for i := range owners {
+		o.Owners = append(o.Owners, testOwner{ID: id + "-owner", Role: []string{"owner", "admin"}[i%2]})
+	}
+	return o
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name   string
+		result any
+		fields string
+		expand string
+		want   string
+	}{
+		{
+			name:   "resource",
+			result: newTestOrganization("a", 2),
+			fields: "name,owners.role",
+			want:   `{"name":"Org a","owners":[{"role":"owner"},{"role":"admin"}]}`,
+		},
+		{
+			name:   "below raw JSON",
+			result: newTestOrganization("a", 0),
+			fields: "meta.plan",
+			want:   `{"meta":{"plan":"pro"}}`,
+		},
+		{
+			name:   "expansion without fields keeps the resource whole",
+			result: newTestOrganization("a", 1),
+			expand: "memberCount",
+			want: `{"createdAt":"2026-01-02T03:04:05Z","id":"a","labels":null,"memberCount":1,"meta":{"plan":"pro"},` +
+				`"name":"Org a","owners":[{"id":"a-owner","role":"owner"}]}`,
+		},
+		{
+			name:   "expansion of an embedded struct",
+			result: newTestOrganization("a", 0),
+			fields: "id,creator",
+			expand: "creator",
+			want:   `{"creator":{"name":"creator of a"},"id":"a"}`,
+		},
+		{
+			name:   "list",
+			result: []*testOrganization{newTestOrganization("a", 1), newTestOrganization("b", 3)},
+			fields: "id,memberCount",
+			expand: "memberCount",
+			want:   `[{"id":"a","memberCount":1},{"id":"b","memberCount":3}]`,
+		},
+		{
+			name:   "nil resources keep the expansions aligned",
+			result: []*testOrganization{newTestOrganization("a", 1), nil, newTestOrganization("b", 2), nil},
+			fields: "id,memberCount,creator.name",
+			expand: "memberCount,creator",
+			want: `[{"creator":{"name":"creator of a"},"id":"a","memberCount":1},null,` +
+				`{"creator":{"name":"creator of b"},"id":"b","memberCount":2},null]`,
+		},
+		{
+			name:   "only nil resources",
+			result: []*testOrganization{nil},
+			fields: "id",
+			expand: "memberCount",
+			want:   `[null]`,
+		},
+		{
+			name: "envelope",
+			result: &testPage{
+				Data:  []testOrganization{*newTestOrganization("a", 0), *newTestOrganization("b", 1)},
+				Total: 12,
+			},
+			fields: "id,memberCount",
+			expand: "memberCount",
+			want:   `{"data":[{"id":"a","memberCount":0},{"id":"b","memberCount":1}],"total":12}`,
+		},
+		{
+			name:   "envelope of pointers with a nil resource",
+			result: &testPointerPage{Items: []*testOrganization{nil, newTestOrganization("b", 2)}},
+			fields: "id,memberCount",
+			expand: "memberCount",
+			want:   `{"items":[null,{"id":"b","memberCount":2}]}`,
+		},
+		{
+			name:   "empty envelope",
+			result: &testPage{Data: []testOrganization{}},
+			fields: "id",
+			expand: "memberCount",
+			want:   `{"data":[],"total":0}`,
+		},
+	}
+
+	r := newTestRegistry()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := r.Parse(reflect.TypeOf(tt.result), tt.fields, tt.expand)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+
+			applied, err := s.Apply(context.Background(), tt.result)
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+
+			got, err := json.Marshal(applied)
+			if err != nil {
+				t.Fatalf("failed to encode the applied result: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Apply() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyExpanderErrors(t *testing.T) {
+	unavailable := errors.New("unavailable")
+
+	r := NewRegistry()
+	Register(r, "failing", func(ctx context.Context, items []testOrganization) ([]int, error) {
+		return nil, unavailable
+	})
+	Register(r, "short", func(ctx context.Context, items []testOrganization) ([]int, error) {
+		return make([]int, len(items)-1), nil
+	})
+
+	result := []*testOrganization{newTestOrganization("a", 0), newTestOrganization("b", 0)}
+	for _, name := range []string{"failing", "short"} {
+		t.Run(name, func(t *testing.T) {
+			s, err := r.Parse(reflect.TypeOf(result), "", name)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if _, err := s.Apply(context.Background(), result); err == nil || !strings.Contains(err.Error(), "failed to expand "+name) {
+				t.Errorf("Apply() error = %v, want the expansion to fail", err)
+			}
+		})
+	}
+}
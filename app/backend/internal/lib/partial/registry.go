@@ -0,0 +1,105 @@
+package partial
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// expander loads a related resource for a batch of resources of one type
+type expander struct {
+	// result is the type of the value loaded per resource, for validating
+	// fields below the expansion
+	result reflect.Type
+	load   func(ctx context.Context, items []reflect.Value) ([]any, error)
+}
+
+// Registry holds the expanders ?expand= can load, per resource type
+type Registry struct {
+	mu        sync.RWMutex
+	expanders map[reflect.Type]map[string]expander
+}
+
+func NewRegistry() *Registry {
+	return &Registry{expanders: make(map[reflect.Type]map[string]expander)}
+}
+
+// Register adds the expander name to responses containing T, including types
+// that embed T if T is exported. fn is called once per response with every T
+// in it and returns the related value of each, in order, so that loading is
+// one query instead of one per resource. Registering a name twice for T panics.
+func Register[T any, R any](r *Registry, name string, fn func(ctx context.Context, items []T) ([]R, error)) {
+	t := reflect.TypeFor[T]()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.expanders[t][name]; ok {
+		panic(fmt.Sprintf("partial: expander %q already registered for %s", name, t))
+	}
+	if r.expanders[t] == nil {
+		r.expanders[t] = make(map[string]expander)
+	}
+
+	r.expanders[t][name] = expander{
+		result: reflect.TypeFor[R](),
+		load: func(ctx context.Context, values []reflect.Value) ([]any, error) {
+			items := make([]T, len(values))
+			for i, v := range values {
+				items[i] = v.Interface().(T)
+			}
+
+			related, err := fn(ctx, items)
+			if err != nil {
+				return nil, err
+			}
+			if len(related) != len(items) {
+				return nil, fmt.Errorf("partial: expander %q returned %d values for %d items", name, len(related), len(items))
+			}
+
+			out := make([]any, len(related))
+			for i := range related {
+				out[i] = related[i]
+			}
+			return out, nil
+		},
+	}
+}
+
+// boundExpander is an expander of t or of a struct t embeds, found at index
+type boundExpander struct {
+	expander
+	index []int
+}
+
+// expandersFor returns the expanders available on t. Expanders of t win
+// over those of embedded structs with the same name.
+func (r *Registry) expandersFor(t reflect.Type) map[string]boundExpander {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	found := make(map[string]boundExpander)
+	r.collect(t, nil, found)
+	return found
+}
+
+func (r *Registry) collect(t reflect.Type, index []int, found map[string]boundExpander) {
+	for name, e := range r.expanders[t] {
+		if _, ok := found[name]; !ok {
+			found[name] = boundExpander{expander: e, index: index}
+		}
+	}
+
+	if t.Kind() != reflect.Struct {
+		return
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		// The value of an unexported embedded struct cannot be passed to
+		// its expanders through reflection
+		if field.Anonymous && field.IsExported() && field.Type.Kind() == reflect.Struct {
+			r.collect(field.Type, append(append([]int{}, index...), i), found)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package partial
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+var (
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+	rawMessageType    = reflect.TypeFor[json.RawMessage]()
+)
+
+// Envelope is implemented by responses that wrap a list of resources, such as
+// paginated responses. Fields and expansions apply to the wrapped resources.
+type Envelope interface {
+	// ItemsField is the JSON name of the field holding the resources
+	ItemsField() string
+}
+
+var envelopeType = reflect.TypeFor[Envelope]()
+
+// shape describes where the resources are in a response of some type
+type shape struct {
+	// resource is the type of a single resource
+	resource reflect.Type
+	// list is set when the response, or its envelope items, is a list
+	list bool
+	// itemsField is the JSON field holding the resources of an envelope
+	itemsField string
+}
+
+func shapeOf(t reflect.Type) shape {
+	t = deref(t)
+
+	if t.Implements(envelopeType) || reflect.PointerTo(t).Implements(envelopeType) {
+		itemsField := reflect.New(t).Interface().(Envelope).ItemsField()
+		if field, ok := jsonFields(t)[itemsField]; ok {
+			s := shapeOf(field)
+			s.itemsField = itemsField
+			return s
+		}
+	}
+
+	if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		return shape{resource: deref(t.Elem()), list: true}
+	}
+
+	return shape{resource: t}
+}
+
+func deref(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+// element returns the type fields below t are looked up in: the element of
+// lists and the target of pointers
+func element(t reflect.Type) reflect.Type {
+	t = deref(t)
+	for (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && t != rawMessageType {
+		t = deref(t.Elem())
+	}
+	return t
+}
+
+// opaque reports whether t encodes to JSON whose fields cannot be known from
+// its type, so any field below it is accepted
+func opaque(t reflect.Type) bool {
+	return t == rawMessageType || t.Kind() == reflect.Interface || t.Kind() == reflect.Map
+}
+
+// leaf reports whether t encodes to a JSON value without fields
+func leaf(t reflect.Type) bool {
+	if t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType) ||
+		reflect.PointerTo(t).Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType) {
+		return true
+	}
+	return t.Kind() != reflect.Struct
+}
+
+// jsonFields returns the JSON field names of struct type t and their types,
+// following encoding/json: embedded structs without a tag are flattened and
+// fields tagged "-" or unexported are skipped
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	collectJSONFields(t, fields)
+	return fields
+}
+
+func collectJSONFields(t reflect.Type, fields map[string]reflect.Type) {
+	var embedded []reflect.Type
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" && deref(field.Type).Kind() == reflect.Struct {
+			embedded = append(embedded, deref(field.Type))
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		if _, ok := fields[name]; !ok {
+			fields[name] = field.Type
+		}
+	}
+
+	// Fields of the outer struct shadow those of embedded structs
+	for _, e := range embedded {
+		collectJSONFields(e, fields)
+	}
+}
@@ -0,0 +1,312 @@
+package partial
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// maxFields bounds the paths of one fields parameter
+const maxFields = 100
+
+// InvalidError reports a fields or expand parameter that does not match the
+// response type
+type InvalidError struct {
+	// Param is the query parameter, fields or expand
+	Param   string
+	Message string
+}
+
+func (e *InvalidError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Param, e.Message)
+}
+
+// node is a set of selected fields. A nil child selects the whole field.
+type node map[string]node
+
+// Selection is a validated fields and expand request for one response type
+type Selection struct {
+	shape  shape
+	fields node
+	expand []string
+	loads  map[string]boundExpander
+}
+
+// Parse validates fields and expand against the response type t. fields is
+// a comma separated list of dotted JSON paths, e.g. "id,name,owners.role",
+// and expand a comma separated list of expanders registered for the resource
+// type. It returns nil when both are empty.
+func (r *Registry) Parse(t reflect.Type, fields, expand string) (*Selection, error) {
+	if strings.TrimSpace(fields) == "" && strings.TrimSpace(expand) == "" {
+		return nil, nil
+	}
+
+	s := &Selection{
+		shape: shapeOf(t),
+		loads: make(map[string]boundExpander),
+	}
+	if s.shape.resource.Kind() != reflect.Struct {
+		return nil, &InvalidError{Param: "fields", Message: "the response has no fields to select"}
+	}
+
+	available := r.expandersFor(s.shape.resource)
+	for _, name := range split(expand) {
+		e, ok := available[name]
+		if !ok {
+			return nil, &InvalidError{Param: "expand", Message: fmt.Sprintf("unknown expansion %q", name)}
+		}
+		if _, dup := s.loads[name]; !dup {
+			s.expand = append(s.expand, name)
+			s.loads[name] = e
+		}
+	}
+
+	paths := split(fields)
+	if len(paths) > maxFields {
+		return nil, &InvalidError{Param: "fields", Message: fmt.Sprintf("at most %d fields can be selected", maxFields)}
+	}
+
+	resourceFields := jsonFields(s.shape.resource)
+	for _, path := range paths {
+		segments := strings.Split(path, ".")
+		if err := s.validatePath(segments, resourceFields, available); err != nil {
+			return nil, err
+		}
+		if s.fields == nil {
+			s.fields = make(node)
+		}
+		s.fields.add(segments)
+	}
+
+	return s, nil
+}
+
+func (s *Selection) validatePath(segments []string, resourceFields map[string]reflect.Type, available map[string]boundExpander) error {
+	path := strings.Join(segments, ".")
+
+	var t reflect.Type
+	if e, ok := s.loads[segments[0]]; ok {
+		t = e.result
+	} else if field, ok := resourceFields[segments[0]]; ok {
+		t = field
+	} else if _, ok := available[segments[0]]; ok {
+		return &InvalidError{Param: "fields", Message: fmt.Sprintf("%q requires expand=%s", path, segments[0])}
+	} else {
+		return &InvalidError{Param: "fields", Message: fmt.Sprintf("unknown field %q", path)}
+	}
+
+	for _, segment := range segments[1:] {
+		t = element(t)
+		if opaque(t) {
+			return nil
+		}
+		if leaf(t) {
+			return &InvalidError{Param: "fields", Message: fmt.Sprintf("unknown field %q", path)}
+		}
+
+		field, ok := jsonFields(t)[segment]
+		if !ok || segment == "" {
+			return &InvalidError{Param: "fields", Message: fmt.Sprintf("unknown field %q", path)}
+		}
+		t = field
+	}
+
+	return nil
+}
+
+// add selects path. Selecting a field whole wins over selecting part of it.
+func (n node) add(path []string) {
+	child, exists := n[path[0]]
+	if len(path) == 1 {
+		n[path[0]] = nil
+		return
+	}
+	if exists && child == nil {
+		return
+	}
+	if child == nil {
+		child = make(node)
+		n[path[0]] = child
+	}
+	child.add(path[1:])
+}
+
+// Apply loads the expansions of result and prunes it to the selected fields.
+// The returned value encodes to the pruned JSON.
+func (s *Selection) Apply(ctx context.Context, result any) (any, error) {
+	resources := s.resources(reflect.ValueOf(result))
+
+	related := make(map[string][]any, len(s.expand))
+	for _, name := range s.expand {
+		e := s.loads[name]
+
+		values := make([]reflect.Value, 0, len(resources))
+		for _, resource := range resources {
+			if resource.IsValid() {
+				values = append(values, resource.FieldByIndex(e.index))
+			}
+		}
+		if len(values) == 0 {
+			continue
+		}
+
+		loaded, err := e.load(ctx, values)
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand %s: %w", name, err)
+		}
+		related[name] = loaded
+	}
+
+	generic, err := toGeneric(result)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []any
+	switch {
+	case s.shape.itemsField != "":
+		if envelope, ok := generic.(map[string]any); ok {
+			items, _ = envelope[s.shape.itemsField].([]any)
+		}
+	case s.shape.list:
+		items, _ = generic.([]any)
+	default:
+		items = []any{generic}
+	}
+
+	next := 0
+	for i, item := range items {
+		object, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		for _, name := range s.expand {
+			value, err := toGeneric(related[name][next])
+			if err != nil {
+				return nil, err
+			}
+			object[name] = value
+		}
+		next++
+
+		items[i] = s.fields.prune(object)
+	}
+
+	if s.shape.itemsField == "" && !s.shape.list {
+		return items[0], nil
+	}
+	return generic, nil
+}
+
+// resources returns the resources in result, in JSON order. Nil resources
+// are returned as invalid values.
+func (s *Selection) resources(v reflect.Value) []reflect.Value {
+	v = derefValue(v)
+	if !v.IsValid() {
+		return nil
+	}
+
+	if s.shape.itemsField != "" {
+		index, ok := jsonFieldIndex(v.Type(), s.shape.itemsField)
+		if !ok {
+			return nil
+		}
+		v = derefValue(v.FieldByIndex(index))
+		if !v.IsValid() {
+			return nil
+		}
+	}
+
+	if !s.shape.list {
+		return []reflect.Value{v}
+	}
+
+	resources := make([]reflect.Value, v.Len())
+	for i := range resources {
+		resources[i] = derefValue(v.Index(i))
+	}
+	return resources
+}
+
+// prune removes the fields of value that are not selected
+func (n node) prune(value any) any {
+	if n == nil {
+		return value
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		pruned := make(map[string]any, len(n))
+		for name, child := range n {
+			if field, ok := v[name]; ok {
+				pruned[name] = child.prune(field)
+			}
+		}
+		return pruned
+	case []any:
+		for i := range v {
+			v[i] = n.prune(v[i])
+		}
+		return v
+	default:
+		return value
+	}
+}
+
+func derefValue(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// jsonFieldIndex finds the field of struct type t encoded as name
+func jsonFieldIndex(t reflect.Type, name string) ([]int, bool) {
+	var found []int
+	for _, field := range reflect.VisibleFields(t) {
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+		tag, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if tag == name || (tag == "" && field.Name == name) {
+			if found == nil || len(field.Index) < len(found) {
+				found = field.Index
+			}
+		}
+	}
+	return found, found != nil
+}
+
+// toGeneric converts v to the maps, slices and scalars its JSON decodes to
+func toGeneric(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode partial response: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var generic any
+	if err := decoder.Decode(&generic); err != nil {
+		return nil, fmt.Errorf("failed to decode partial response: %w", err)
+	}
+	return generic, nil
+}
+
+func split(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,87 @@
+package patch
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Change is a top-level field of a resource that a patch changed. From is
+// absent when the field was added and To when it was removed.
+type Change struct {
+	Field string          `json:"field"`
+	From  json.RawMessage `json:"from,omitempty"`
+	To    json.RawMessage `json:"to,omitempty"`
+}
+
+// Changes are sorted by field
+type Changes []Change
+
+// Fields returns the names of the changed fields
+func (c Changes) Fields() []string {
+	fields := make([]string, len(c))
+	for i, change := range c {
+		fields[i] = change.Field
+	}
+	return fields
+}
+
+// Has reports whether field changed
+func (c Changes) Has(field string) bool {
+	for _, change := range c {
+		if change.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Diff compares the top-level fields of two JSON objects
+func Diff(before, after []byte) (Changes, error) {
+	b, err := decode(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := decode(after)
+	if err != nil {
+		return nil, err
+	}
+
+	bObject, ok := b.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: the document is not an object", ErrInvalidPatch)
+	}
+	aObject, ok := a.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: the patched document must stay an object", ErrInvalidPatch)
+	}
+
+	fields := make(map[string]struct{}, len(bObject))
+	for field := range bObject {
+		fields[field] = struct{}{}
+	}
+	for field := range aObject {
+		fields[field] = struct{}{}
+	}
+
+	var changes Changes
+	for field := range fields {
+		from, hadField := bObject[field]
+		to, hasField := aObject[field]
+		if hadField && hasField && equal(from, to) {
+			continue
+		}
+
+		change := Change{Field: field}
+		if hadField {
+			change.From, _ = json.Marshal(from)
+		}
+		if hasField {
+			change.To, _ = json.Marshal(to)
+		}
+		changes = append(changes, change)
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes, nil
+}
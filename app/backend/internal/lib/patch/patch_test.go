@@ -0,0 +1,305 @@
+package patch
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestJSONPatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+	}{
+		// RFC 6902 Appendix A
+		{
+			name:  "A.1 adding an object member",
+			doc:   `{"foo":"bar"}`,
+			patch: `[{"op":"add","path":"/baz","value":"qux"}]`,
+			want:  `{"baz":"qux","foo":"bar"}`,
+		},
+		{
+			name:  "A.2 adding an array element",
+			doc:   `{"foo":["bar","baz"]}`,
+			patch: `[{"op":"add","path":"/foo/1","value":"qux"}]`,
+			want:  `{"foo":["bar","qux","baz"]}`,
+		},
+		{
+			name:  "A.3 removing an object member",
+			doc:   `{"baz":"qux","foo":"bar"}`,
+			patch: `[{"op":"remove","path":"/baz"}]`,
+			want:  `{"foo":"bar"}`,
+		},
+		{
+			name:  "A.4 removing an array element",
+			doc:   `{"foo":["bar","qux","baz"]}`,
+			patch: `[{"op":"remove","path":"/foo/1"}]`,
+			want:  `{"foo":["bar","baz"]}`,
+		},
+		{
+			name:  "A.5 replacing a value",
+			doc:   `{"baz":"qux","foo":"bar"}`,
+			patch: `[{"op":"replace","path":"/baz","value":"boo"}]`,
+			want:  `{"baz":"boo","foo":"bar"}`,
+		},
+		{
+			name:  "A.6 moving a value",
+			doc:   `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+			patch: `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			want:  `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`,
+		},
+		{
+			name:  "A.7 moving an array element",
+			doc:   `{"foo":["all","grass","cows","eat"]}`,
+			patch: `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`,
+			want:  `{"foo":["all","cows","eat","grass"]}`,
+		},
+		{
+			name:  "A.8 testing a value: success",
+			doc:   `{"baz":"qux","foo":["a",2,"c"]}`,
+			patch: `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`,
+			want:  `{"baz":"qux","foo":["a",2,"c"]}`,
+		},
+		{
+			name:  "A.10 adding a nested member object",
+			doc:   `{"foo":"bar"}`,
+			patch: `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`,
+			want:  `{"foo":"bar","child":{"grandchild":{}}}`,
+		},
+		{
+			name:  "A.11 ignoring unrecognized elements",
+			doc:   `{"foo":"bar"}`,
+			patch: `[{"op":"add","path":"/baz","value":"qux","xyz":123}]`,
+			want:  `{"foo":"bar","baz":"qux"}`,
+		},
+		{
+			name:  "A.14 ~ escape ordering",
+			doc:   `{"/":9,"~1":10}`,
+			patch: `[{"op":"test","path":"/~01","value":10}]`,
+			want:  `{"/":9,"~1":10}`,
+		},
+		{
+			name:  "A.16 adding an array value",
+			doc:   `{"foo":["bar"]}`,
+			patch: `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`,
+			want:  `{"foo":["bar",["abc","def"]]}`,
+		},
+
+		{
+			name:  "replacing an array element",
+			doc:   `{"a":[1,2,3]}`,
+			patch: `[{"op":"replace","path":"/a/0","value":9}]`,
+			want:  `{"a":[9,2,3]}`,
+		},
+		{
+			name:  "replacing the last array element",
+			doc:   `{"a":[1,2,3]}`,
+			patch: `[{"op":"replace","path":"/a/2","value":9}]`,
+			want:  `{"a":[1,2,9]}`,
+		},
+		{
+			name:  "replacing the whole document",
+			doc:   `{"a":1}`,
+			patch: `[{"op":"replace","path":"","value":[1]}]`,
+			want:  `[1]`,
+		},
+		{
+			name:  "copying a value",
+			doc:   `{"foo":{"bar":1}}`,
+			patch: `[{"op":"copy","from":"/foo","path":"/baz"}]`,
+			want:  `{"foo":{"bar":1},"baz":{"bar":1}}`,
+		},
+		{
+			name:  "changing a copy leaves the original",
+			doc:   `{"foo":{"bar":1}}`,
+			patch: `[{"op":"copy","from":"/foo","path":"/baz"},{"op":"replace","path":"/baz/bar","value":2}]`,
+			want:  `{"foo":{"bar":1},"baz":{"bar":2}}`,
+		},
+		{
+			name:  "copying an array element",
+			doc:   `{"a":[1,2]}`,
+			patch: `[{"op":"copy","from":"/a/0","path":"/a/-"}]`,
+			want:  `{"a":[1,2,1]}`,
+		},
+		{
+			name:  "testing numbers by value",
+			doc:   `{"n":1.0}`,
+			patch: `[{"op":"test","path":"/n","value":1}]`,
+			want:  `{"n":1.0}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := JSONPatch([]byte(tt.doc), []byte(tt.patch))
+			if err != nil {
+				t.Fatalf("JSONPatch() error = %v", err)
+			}
+			assertJSONEqual(t, got, tt.want)
+		})
+	}
+}
+
+func TestJSONPatchErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  error
+	}{
+		// RFC 6902 Appendix A
+		{
+			name:  "A.9 testing a value: error",
+			doc:   `{"baz":"qux"}`,
+			patch: `[{"op":"test","path":"/baz","value":"bar"}]`,
+			want:  ErrTestFailed,
+		},
+		{
+			name:  "A.12 adding to a nonexistent target",
+			doc:   `{"foo":"bar"}`,
+			patch: `[{"op":"add","path":"/baz/bat","value":"qux"}]`,
+			want:  ErrInvalidPatch,
+		},
+		{
+			name:  "A.13 invalid JSON Patch document",
+			doc:   `{"foo":"bar"}`,
+			patch: `[{"op":"add","path":"/baz","value":"qux","op":"remove"}]`,
+			want:  ErrInvalidPatch,
+		},
+		{
+			name:  "A.15 comparing strings and numbers",
+			doc:   `{"/":9,"~1":10}`,
+			patch: `[{"op":"test","path":"/~01","value":"10"}]`,
+			want:  ErrTestFailed,
+		},
+
+		{
+			name:  "not an array of operations",
+			doc:   `{}`,
+			patch: `{"op":"add","path":"/a","value":1}`,
+			want:  ErrInvalidPatch,
+		},
+		{
+			name:  "unknown operation",
+			doc:   `{}`,
+			patch: `[{"op":"append","path":"/a","value":1}]`,
+			want:  ErrInvalidPatch,
+		},
+		{
+			name:  "missing path",
+			doc:   `{}`,
+			patch: `[{"op":"add","value":1}]`,
+			want:  ErrInvalidPatch,
+		},
+		{
+			name:  "path without a leading slash",
+			doc:   `{"a":1}`,
+			patch: `[{"op":"remove","path":"a"}]`,
+			want:  ErrInvalidPatch,
+		},
+		{
+			name:  "add without a value",
+			doc:   `{}`,
+			patch: `[{"op":"add","path":"/a"}]`,
+			want:  ErrInvalidPatch,
+		},
+		{
+			name:  "adding past the end of an array",
+			doc:   `{"a":[1]}`,
+			patch: `[{"op":"add","path":"/a/2","value":2}]`,
+			want:  ErrInvalidPatch,
+		},
+		{
+			name:  "array index with a leading zero",
+			doc:   `{"a":[1,2]}`,
+			patch: `[{"op":"remove","path":"/a/01"}]`,
+			want:  ErrInvalidPatch,
+		},
+		{
+			name:  "removing a missing member",
+			doc:   `{"a":1}`,
+			patch: `[{"op":"remove","path":"/b"}]`,
+			want:  ErrInvalidPatch,
+		},
+		{
+			name:  "removing the whole document",
+			doc:   `{"a":1}`,
+			patch: `[{"op":"remove","path":""}]`,
+			want:  ErrInvalidPatch,
+		},
+		{
+			name:  "replacing a missing member",
+			doc:   `{"a":1}`,
+			patch: `[{"op":"replace","path":"/b","value":2}]`,
+			want:  ErrInvalidPatch,
+		},
+		{
+			name:  "replacing past the end of an array",
+			doc:   `{"a":[1,2,3]}`,
+			patch: `[{"op":"replace","path":"/a/3","value":4}]`,
+			want:  ErrInvalidPatch,
+		},
+		{
+			name:  "replacing the end of an array",
+			doc:   `{"a":[1,2,3]}`,
+			patch: `[{"op":"replace","path":"/a/-","value":4}]`,
+			want:  ErrInvalidPatch,
+		},
+		{
+			name:  "move without from",
+			doc:   `{"a":1}`,
+			patch: `[{"op":"move","path":"/b"}]`,
+			want:  ErrInvalidPatch,
+		},
+		{
+			name:  "moving from a missing member",
+			doc:   `{"a":1}`,
+			patch: `[{"op":"move","from":"/b","path":"/c"}]`,
+			want:  ErrInvalidPatch,
+		},
+		{
+			name:  "moving a value into itself",
+			doc:   `{"a":{"b":1}}`,
+			patch: `[{"op":"move","from":"/a","path":"/a/c"}]`,
+			want:  ErrInvalidPatch,
+		},
+		{
+			name:  "copying from a missing member",
+			doc:   `{"a":1}`,
+			patch: `[{"op":"copy","from":"/b","path":"/c"}]`,
+			want:  ErrInvalidPatch,
+		},
+		{
+			name:  "testing a missing member",
+			doc:   `{"a":1}`,
+			patch: `[{"op":"test","path":"/b","value":1}]`,
+			want:  ErrInvalidPatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := JSONPatch([]byte(tt.doc), []byte(tt.patch))
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("JSONPatch() = %s, %v, want error %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func assertJSONEqual(t *testing.T, got []byte, want string) {
+	t.Helper()
+
+	g, err := decode(got)
+	if err != nil {
+		t.Fatalf("invalid result %s: %v", got, err)
+	}
+	w, err := decode([]byte(want))
+	if err != nil {
+		t.Fatalf("invalid expectation %s: %v", want, err)
+	}
+	if !equal(g, w) {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
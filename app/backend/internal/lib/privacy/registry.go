@@ -0,0 +1,139 @@
+package privacy
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Exporter returns everything a module stores about a user, marshalled to
+// JSON as the module's file in the export
+type Exporter func(ctx context.Context, userID uuid.UUID) (any, error)
+
+// Eraser deletes or anonymizes everything a module stores about a user. Erasure
+// is retried from the first module when one fails, so erasers must be
+// idempotent.
+type Eraser func(ctx context.Context, userID uuid.UUID) error
+
+// Module is a source of user-owned data. Either function may be nil when a
+// module only holds data of one kind, e.g. data that is erased by a foreign
+// key cascade but still needs exporting.
+type Module struct {
+	Name   string
+	Export Exporter
+	Erase  Eraser
+}
+
+// Registry holds the modules that own user data. Services register their
+// module while they are constructed.
+type Registry struct {
+	mu      sync.RWMutex
+	modules []Module
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds a module. Names become file names in exports and must be
+// unique; duplicates panic since modules are registered once at startup.
+func (r *Registry) Register(module Module) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, m := range r.modules {
+		if m.Name == module.Name {
+			panic("privacy: duplicate module " + module.Name)
+		}
+	}
+
+	r.modules = append(r.modules, module)
+}
+
+// Modules returns the registered modules in registration order
+func (r *Registry) Modules() []Module {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]Module(nil), r.modules...)
+}
+
+// ErasureOrder returns the modules with erasers in reverse registration
+// order. Services are constructed after the services they depend on, so
+// dependent modules are erased before the data they reference, with the user
+// account itself last.
+func (r *Registry) ErasureOrder() []Module {
+	modules := r.Modules()
+
+	ordered := make([]Module, 0, len(modules))
+	for i := len(modules) - 1; i >= 0; i-- {
+		if modules[i].Erase != nil {
+			ordered = append(ordered, modules[i])
+		}
+	}
+	return ordered
+}
+
+// Manifest is written to manifest.json in every export
+type Manifest struct {
+	UserID      uuid.UUID `json:"userId"`
+	GeneratedAt time.Time `json:"generatedAt"`
+	Modules     []string  `json:"modules"`
+}
+
+// Export writes a ZIP archive to w with a <module>.json file per module and a
+// manifest. It fails if any module fails, rather than producing an export
+// that silently misses data.
+func (r *Registry) Export(ctx context.Context, userID uuid.UUID, w io.Writer) error {
+	archive := zip.NewWriter(w)
+
+	manifest := Manifest{
+		UserID:      userID,
+		GeneratedAt: time.Now().UTC(),
+	}
+
+	for _, module := range r.Modules() {
+		if module.Export == nil {
+			continue
+		}
+
+		data, err := module.Export(ctx, userID)
+		if err != nil {
+			return fmt.Errorf("failed to export module %s: %w", module.Name, err)
+		}
+
+		if err := writeJSON(archive, module.Name+".json", data); err != nil {
+			return err
+		}
+		manifest.Modules = append(manifest.Modules, module.Name)
+	}
+
+	if err := writeJSON(archive, "manifest.json", manifest); err != nil {
+		return err
+	}
+
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("failed to finish export archive: %w", err)
+	}
+	return nil
+}
+
+func writeJSON(archive *zip.Writer, name string, data any) error {
+	file, err := archive.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to add %s to export archive: %w", name, err)
+	}
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(data); err != nil {
+		return fmt.Errorf("failed to write %s to export archive: %w", name, err)
+	}
+	return nil
+}
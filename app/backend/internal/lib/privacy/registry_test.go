@@ -0,0 +1,140 @@
+package privacy
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func noopEraser(ctx context.Context, userID uuid.UUID) error { return nil }
+
+func names(modules []Module) []string {
+	out := make([]string, len(modules))
+	for i, m := range modules {
+		out[i] = m.Name
+	}
+	return out
+}
+
+func TestErasureOrder(t *testing.T) {
+	r := NewRegistry()
+	r.Register(Module{Name: "profile", Erase: noopEraser})
+	r.Register(Module{Name: "organizations", Erase: noopEraser})
+	// Erased by a foreign key cascade, so only exported
+	r.Register(Module{Name: "notifications", Export: func(ctx context.Context, userID uuid.UUID) (any, error) {
+		return nil, nil
+	}})
+	r.Register(Module{Name: "privacy", Erase: noopEraser})
+
+	if got, want := names(r.ErasureOrder()), []string{"privacy", "organizations", "profile"}; !slices.Equal(got, want) {
+		t.Errorf("ErasureOrder() = %v, want %v", got, want)
+	}
+	if got := names(r.Modules()); !slices.Equal(got, []string{"profile", "organizations", "notifications", "privacy"}) {
+		t.Errorf("Modules() = %v, want registration order", got)
+	}
+}
+
+func TestRegisterDuplicatePanics(t *testing.T) {
+	r := NewRegistry()
+	r.Register(Module{Name: "profile"})
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a module twice did not panic")
+		}
+	}()
+	r.Register(Module{Name: "profile"})
+}
+
+// readArchive returns the files of a ZIP archive by name, in archive order
+func readArchive(t *testing.T, data []byte) ([]string, map[string][]byte) {
+	t.Helper()
+
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("export is not a ZIP archive: %v", err)
+	}
+
+	var order []string
+	files := map[string][]byte{}
+	for _, f := range archive.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("failed to open %s: %v", f.Name, err)
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", f.Name, err)
+		}
+		order = append(order, f.Name)
+		files[f.Name] = content
+	}
+	return order, files
+}
+
+func TestExport(t *testing.T) {
+	userID := uuid.New()
+
+	r := NewRegistry()
+	r.Register(Module{Name: "profile", Export: func(ctx context.Context, id uuid.UUID) (any, error) {
+		return map[string]string{"userId": id.String(), "locale": "en"}, nil
+	}})
+	r.Register(Module{Name: "audit", Erase: noopEraser})
+	r.Register(Module{Name: "notifications", Export: func(ctx context.Context, id uuid.UUID) (any, error) {
+		return []string{"welcome"}, nil
+	}})
+
+	var buf bytes.Buffer
+	if err := r.Export(context.Background(), userID, &buf); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	order, files := readArchive(t, buf.Bytes())
+	if want := []string{"profile.json", "notifications.json", "manifest.json"}; !slices.Equal(order, want) {
+		t.Fatalf("files = %v, want %v", order, want)
+	}
+
+	var profile map[string]string
+	if err := json.Unmarshal(files["profile.json"], &profile); err != nil {
+		t.Fatalf("profile.json: %v", err)
+	}
+	if profile["userId"] != userID.String() || profile["locale"] != "en" {
+		t.Errorf("profile.json = %v", profile)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(files["manifest.json"], &manifest); err != nil {
+		t.Fatalf("manifest.json: %v", err)
+	}
+	if manifest.UserID != userID || manifest.GeneratedAt.IsZero() {
+		t.Errorf("manifest = %+v, want user %s and a generation time", manifest, userID)
+	}
+	if !slices.Equal(manifest.Modules, []string{"profile", "notifications"}) {
+		t.Errorf("manifest modules = %v, want the exported modules only", manifest.Modules)
+	}
+}
+
+func TestExportFailsWithModule(t *testing.T) {
+	unavailable := errors.New("unavailable")
+
+	r := NewRegistry()
+	r.Register(Module{Name: "profile", Export: func(ctx context.Context, id uuid.UUID) (any, error) {
+		return map[string]string{}, nil
+	}})
+	r.Register(Module{Name: "webhooks", Export: func(ctx context.Context, id uuid.UUID) (any, error) {
+		return nil, unavailable
+	}})
+
+	err := r.Export(context.Background(), uuid.New(), io.Discard)
+	if !errors.Is(err, unavailable) {
+		t.Errorf("Export() error = %v, want the module's error", err)
+	}
+}
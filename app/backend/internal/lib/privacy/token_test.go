@@ -0,0 +1,69 @@
+package privacy
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestSignerVerify(t *testing.T) {
+	signer := NewSigner("test-key")
+	requestID := uuid.New()
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	token := signer.Sign(requestID, now.Add(time.Hour))
+
+	// tamper flips a byte of the decoded payload and re-encodes it with the
+	// original MAC
+	tamper := func(token string) string {
+		payload, mac, _ := strings.Cut(token, ".")
+		raw, _ := base64.RawURLEncoding.DecodeString(payload)
+		raw[0] ^= 0xff
+		return base64.RawURLEncoding.EncodeToString(raw) + "." + mac
+	}
+
+	tests := []struct {
+		name    string
+		signer  *Signer
+		token   string
+		now     time.Time
+		wantErr error
+	}{
+		{"valid", signer, token, now, nil},
+		{"just before expiry", signer, token, now.Add(time.Hour - time.Second), nil},
+		{"at expiry", signer, token, now.Add(time.Hour), ErrExpiredToken},
+		{"after expiry", signer, token, now.Add(2 * time.Hour), ErrExpiredToken},
+		{"tampered payload", signer, tamper(token), now, ErrInvalidToken},
+		{"other key", NewSigner("other-key"), token, now, ErrInvalidToken},
+		{"truncated MAC", signer, token[:len(token)-4], now, ErrInvalidToken},
+		{"no separator", signer, strings.ReplaceAll(token, ".", ""), now, ErrInvalidToken},
+		{"short payload", signer, "AAAA." + strings.SplitN(token, ".", 2)[1], now, ErrInvalidToken},
+		{"not base64", signer, "!!!.???", now, ErrInvalidToken},
+		{"empty", signer, "", now, ErrInvalidToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := tt.signer.Verify(tt.token, tt.now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && id != requestID {
+				t.Errorf("Verify() = %s, want %s", id, requestID)
+			}
+			if tt.wantErr != nil && id != uuid.Nil {
+				t.Errorf("Verify() = %s, want uuid.Nil on error", id)
+			}
+		})
+	}
+}
+
+func TestSignIsURLSafe(t *testing.T) {
+	token := NewSigner("test-key").Sign(uuid.New(), time.Now())
+	if strings.ContainsAny(token, "+/=") {
+		t.Errorf("token %q is not URL safe", token)
+	}
+}
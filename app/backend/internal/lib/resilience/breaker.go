@@ -0,0 +1,136 @@
+package resilience
+
+import (
+	"sync"
+	"time"
+)
+
+// State is the state of a circuit breaker
+type State string
+
+const (
+	// StateClosed lets calls through
+	StateClosed State = "closed"
+	// StateOpen rejects calls until the cooldown passed
+	StateOpen State = "open"
+	// StateHalfOpen lets one probe call through to decide whether to close
+	StateHalfOpen State = "half_open"
+)
+
+// Breaker trips after a number of failures in a row and rejects calls until a
+// cooldown passed. The first call after the cooldown is let through as a
+// probe: success closes the circuit, failure opens it again.
+type Breaker struct {
+	failures int
+	cooldown time.Duration
+	onChange func(from, to State)
+
+	mu          sync.Mutex
+	consecutive int
+	openUntil   time.Time
+	probing     bool
+}
+
+// NewBreaker returns a closed breaker. onChange, if not nil, is called after
+// every state change.
+func NewBreaker(failures int, cooldown time.Duration, onChange func(from, to State)) *Breaker {
+	return &Breaker{
+		failures: max(failures, 1),
+		cooldown: cooldown,
+		onChange: onChange,
+	}
+}
+
+// Allow reports whether a call may proceed. A caller that was allowed must
+// report the outcome with Record or Abandon.
+func (b *Breaker) Allow() bool {
+	b.mu.Lock()
+	from := b.state(time.Now())
+	allowed := true
+	switch {
+	case from == StateClosed:
+	case from == StateOpen || b.probing:
+		allowed = false
+	default:
+		b.probing = true
+	}
+	b.mu.Unlock()
+
+	if allowed && from == StateHalfOpen {
+		b.changed(StateOpen, StateHalfOpen)
+	}
+	return allowed
+}
+
+// Record reports the outcome of an allowed call
+func (b *Breaker) Record(failed bool) {
+	b.mu.Lock()
+	now := time.Now()
+	from := b.state(now)
+	if b.probing {
+		from = StateHalfOpen
+	}
+
+	b.probing = false
+	if failed {
+		b.consecutive++
+		if b.consecutive >= b.failures {
+			b.openUntil = now.Add(b.cooldown)
+		}
+	} else {
+		b.consecutive = 0
+	}
+	to := b.state(now)
+	b.mu.Unlock()
+
+	if from != to {
+		b.changed(from, to)
+	}
+}
+
+// Abandon ends an allowed call without an outcome, e.g. one the caller gave
+// up on, letting the next call probe
+func (b *Breaker) Abandon() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// State returns the current state
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.probing {
+		return StateHalfOpen
+	}
+	return b.state(time.Now())
+}
+
+// RetryAfter returns how long the circuit stays open, 0 when it is not
+func (b *Breaker) RetryAfter() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.consecutive < b.failures {
+		return 0
+	}
+	return max(time.Until(b.openUntil), 0)
+}
+
+func (b *Breaker) state(now time.Time) State {
+	switch {
+	case b.consecutive < b.failures:
+		return StateClosed
+	case now.Before(b.openUntil):
+		return StateOpen
+	default:
+		return StateHalfOpen
+	}
+}
+
+func (b *Breaker) changed(from, to State) {
+	if b.onChange != nil {
+		b.onChange(from, to)
+	}
+}
@@ -0,0 +1,65 @@
+package resilience
+
+import (
+	"context"
+	"time"
+)
+
+// Bulkhead bounds the calls in flight to a dependency, so a slow dependency
+// ties up a bounded number of goroutines and connections. A nil Bulkhead is
+// unbounded.
+type Bulkhead struct {
+	slots   chan struct{}
+	maxWait time.Duration
+}
+
+// NewBulkhead returns a bulkhead admitting maxConcurrent calls, where further
+// calls wait up to maxWait for a slot. It returns nil when maxConcurrent is 0.
+func NewBulkhead(maxConcurrent int, maxWait time.Duration) *Bulkhead {
+	if maxConcurrent <= 0 {
+		return nil
+	}
+	return &Bulkhead{
+		slots:   make(chan struct{}, maxConcurrent),
+		maxWait: maxWait,
+	}
+}
+
+// Acquire takes a slot, returning the func that releases it. It fails with
+// ErrFull when no slot frees up within the wait, or with the context error.
+func (b *Bulkhead) Acquire(ctx context.Context) (func(), error) {
+	if b == nil {
+		return func() {}, nil
+	}
+
+	release := func() { <-b.slots }
+
+	select {
+	case b.slots <- struct{}{}:
+		return release, nil
+	default:
+	}
+	if b.maxWait <= 0 {
+		return nil, ErrFull
+	}
+
+	timer := time.NewTimer(b.maxWait)
+	defer timer.Stop()
+
+	select {
+	case b.slots <- struct{}{}:
+		return release, nil
+	case <-timer.C:
+		return nil, ErrFull
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// InFlight returns the number of slots taken
+func (b *Bulkhead) InFlight() int {
+	if b == nil {
+		return 0
+	}
+	return len(b.slots)
+}
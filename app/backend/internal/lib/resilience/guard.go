@@ -0,0 +1,132 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+var (
+	// ErrOpen rejects calls to a dependency whose circuit is open
+	ErrOpen = errors.New("circuit open")
+	// ErrFull rejects calls to a dependency whose bulkhead has no free slot
+	ErrFull = errors.New("bulkhead full")
+)
+
+// Fallback is what callers do when a guard rejects a call
+type Fallback string
+
+const (
+	// FallbackSkip lets callers that can do without the dependency skip it
+	// silently, e.g. treat the cache as a miss
+	FallbackSkip Fallback = "skip"
+	// FallbackFail fails the call; API requests get 503 with Retry-After
+	FallbackFail Fallback = "fail"
+)
+
+// RejectedError is returned for calls a guard did not let through. It wraps
+// ErrOpen or ErrFull.
+type RejectedError struct {
+	Guard    string
+	Fallback Fallback
+	// RetryAfter is how long the circuit stays open, 0 when the bulkhead was full
+	RetryAfter time.Duration
+	err        error
+}
+
+func (e *RejectedError) Error() string {
+	return fmt.Sprintf("%s unavailable: %v", e.Guard, e.err)
+}
+
+func (e *RejectedError) Unwrap() error {
+	return e.err
+}
+
+// Skipped reports whether err is a rejection callers should skip the
+// dependency for
+func Skipped(err error) bool {
+	var rejected *RejectedError
+	return errors.As(err, &rejected) && rejected.Fallback == FallbackSkip
+}
+
+// Guard protects calls to one dependency with a circuit breaker and a
+// bulkhead. A nil Guard runs calls unprotected.
+type Guard struct {
+	name      string
+	fallback  Fallback
+	breaker   *Breaker
+	bulkhead  *Bulkhead
+	isFailure func(error) bool
+	nrApp     *newrelic.Application
+
+	rejected atomic.Int64
+}
+
+// Do runs fn unless the circuit is open or the bulkhead is full, in which case
+// it returns a *RejectedError. Errors of fn that isFailure accepts count
+// against the circuit; calls whose context ended do not count.
+func (g *Guard) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if g == nil {
+		return fn(ctx)
+	}
+
+	if !g.breaker.Allow() {
+		return g.reject(ErrOpen)
+	}
+
+	release, err := g.bulkhead.Acquire(ctx)
+	if err != nil {
+		g.breaker.Abandon()
+		if errors.Is(err, ErrFull) {
+			return g.reject(err)
+		}
+		return err
+	}
+	defer release()
+
+	err = fn(ctx)
+	if err != nil && ctx.Err() != nil {
+		g.breaker.Abandon()
+		return err
+	}
+	g.breaker.Record(err != nil && g.isFailure(err))
+	return err
+}
+
+// Name returns the name of the dependency
+func (g *Guard) Name() string {
+	return g.name
+}
+
+// Fallback returns what callers do when the guard rejects a call
+func (g *Guard) Fallback() Fallback {
+	return g.fallback
+}
+
+// Status returns the state of the guard
+func (g *Guard) Status() Status {
+	return Status{
+		Name:     g.name,
+		State:    g.breaker.State(),
+		InFlight: g.bulkhead.InFlight(),
+		Rejected: g.rejected.Load(),
+		Fallback: g.fallback,
+	}
+}
+
+func (g *Guard) reject(reason error) error {
+	g.rejected.Add(1)
+	if g.nrApp != nil {
+		g.nrApp.RecordCustomMetric("Resilience/"+g.name+"/Rejected", 1)
+	}
+
+	rejected := &RejectedError{Guard: g.name, Fallback: g.fallback, err: reason}
+	if errors.Is(reason, ErrOpen) {
+		rejected.RetryAfter = g.breaker.RetryAfter()
+	}
+	return rejected
+}
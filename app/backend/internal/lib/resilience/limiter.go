@@ -0,0 +1,129 @@
+package resilience
+
+import (
+	"sync"
+	"time"
+)
+
+// decreaseFactor is how much the limit shrinks when calls get slow
+const decreaseFactor = 0.9
+
+// Limiter bounds the calls in flight and adapts the bound to latency with
+// AIMD: calls slower than the target shrink the limit multiplicatively, at
+// most once per target interval, and fast calls grow it by one per limit's
+// worth of calls. A nil Limiter admits every call.
+type Limiter struct {
+	min    int
+	max    int
+	target time.Duration
+
+	mu           sync.Mutex
+	limit        float64
+	inFlight     int
+	lastDecrease time.Time
+}
+
+// NewLimiter returns a limiter starting at maxLimit calls in flight, adapting
+// between minLimit and maxLimit when target is positive. It returns nil when
+// maxLimit is 0.
+func NewLimiter(minLimit, maxLimit int, target time.Duration) *Limiter {
+	if maxLimit <= 0 {
+		return nil
+	}
+	return &Limiter{
+		min:    clamp(minLimit, 1, maxLimit),
+		max:    maxLimit,
+		target: target,
+		limit:  float64(maxLimit),
+	}
+}
+
+// Permit is a call admitted by a Limiter. It must be ended with Release or
+// Abandon.
+type Permit struct {
+	limiter *Limiter
+	start   time.Time
+}
+
+// Release ends the call and adapts the limit to its latency
+func (p Permit) Release() {
+	if p.limiter != nil {
+		p.limiter.release(time.Since(p.start), true)
+	}
+}
+
+// Abandon ends a call that did not run, e.g. because another limit rejected
+// it, without adapting the limit: its latency says nothing about load
+func (p Permit) Abandon() {
+	if p.limiter != nil {
+		p.limiter.release(0, false)
+	}
+}
+
+// Acquire admits a call when fewer than share of the limit are in flight,
+// so lower priority callers can be given a smaller share
+func (l *Limiter) Acquire(share float64) (Permit, bool) {
+	if l == nil {
+		return Permit{}, true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if float64(l.inFlight) >= max(l.limit*share, 1) {
+		return Permit{}, false
+	}
+	l.inFlight++
+
+	return Permit{limiter: l, start: time.Now()}, true
+}
+
+// Limit returns the current limit
+func (l *Limiter) Limit() int {
+	if l == nil {
+		return 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.limit)
+}
+
+// InFlight returns the number of calls in flight
+func (l *Limiter) InFlight() int {
+	if l == nil {
+		return 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inFlight
+}
+
+func (l *Limiter) release(latency time.Duration, adapt bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	inFlight := l.inFlight
+	l.inFlight--
+	if !adapt || l.target <= 0 {
+		return
+	}
+
+	now := time.Now()
+	switch {
+	case latency > l.target:
+		if now.Sub(l.lastDecrease) >= l.target {
+			l.limit = max(l.limit*decreaseFactor, float64(l.min))
+			l.lastDecrease = now
+		}
+	case float64(inFlight) >= l.limit/2:
+		// Only grow while the limit is in use, so an idle server does not
+		// drift back to max before it was tested under load
+		l.limit = min(l.limit+1/l.limit, float64(l.max))
+	}
+}
+
+func clamp(v, lo, hi int) int {
+	return min(max(v, lo), hi)
+}
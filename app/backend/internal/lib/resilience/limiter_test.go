@@ -0,0 +1,92 @@
+package resilience
+
+import (
+	"testing"
+	"time"
+)
+
+// shrunk returns a limiter whose limit a slow call lowered below its max, so
+// fast calls can raise it again
+func shrunk(t *testing.T) *Limiter {
+	t.Helper()
+
+	l := NewLimiter(1, 10, 50*time.Millisecond)
+	permit, ok := l.Acquire(1)
+	if !ok {
+		t.Fatal("an idle limiter rejected a call")
+	}
+	time.Sleep(60 * time.Millisecond)
+	permit.Release()
+
+	if l.Limit() != 9 {
+		t.Fatalf("limit after a slow call = %d, want 9", l.Limit())
+	}
+	return l
+}
+
+func TestLimiterReleaseAdaptsLimit(t *testing.T) {
+	l := shrunk(t)
+	before := l.limit
+
+	permits := make([]Permit, 5)
+	for i := range permits {
+		permits[i], _ = l.Acquire(1)
+	}
+	for _, permit := range permits {
+		permit.Release()
+	}
+
+	if l.limit <= before {
+		t.Errorf("limit = %v after fast calls, want above %v", l.limit, before)
+	}
+	if l.InFlight() != 0 {
+		t.Errorf("in flight = %d, want 0", l.InFlight())
+	}
+}
+
+func TestLimiterAbandonKeepsLimit(t *testing.T) {
+	l := shrunk(t)
+	before := l.limit
+
+	permits := make([]Permit, 5)
+	for i := range permits {
+		permits[i], _ = l.Acquire(1)
+	}
+	for _, permit := range permits {
+		permit.Abandon()
+	}
+
+	if l.limit != before {
+		t.Errorf("limit = %v after abandoned calls, want %v", l.limit, before)
+	}
+	if l.InFlight() != 0 {
+		t.Errorf("in flight = %d, want 0", l.InFlight())
+	}
+}
+
+func TestLimiterShare(t *testing.T) {
+	l := NewLimiter(1, 4, 0)
+
+	// Half the limit for lower priority callers
+	for range 2 {
+		if _, ok := l.Acquire(0.5); !ok {
+			t.Fatal("call within the share was rejected")
+		}
+	}
+	if _, ok := l.Acquire(0.5); ok {
+		t.Error("call beyond the share was admitted")
+	}
+	if _, ok := l.Acquire(1); !ok {
+		t.Error("call within the full limit was rejected")
+	}
+}
+
+func TestNilLimiterAdmitsEverything(t *testing.T) {
+	var l *Limiter
+	permit, ok := l.Acquire(1)
+	if !ok {
+		t.Fatal("nil limiter rejected a call")
+	}
+	permit.Release()
+	permit.Abandon()
+}
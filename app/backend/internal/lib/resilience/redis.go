@@ -0,0 +1,60 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisHook runs every command and pipeline of a go-redis client through g.
+// Rejected commands fail with the *RejectedError without reaching Redis.
+func RedisHook(g *Guard) redis.Hook {
+	return redisHook{guard: g}
+}
+
+type redisHook struct {
+	guard *Guard
+}
+
+func (h redisHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h redisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		err := h.guard.Do(ctx, func(ctx context.Context) error {
+			return next(ctx, cmd)
+		})
+		var rejected *RejectedError
+		if errors.As(err, &rejected) {
+			cmd.SetErr(err)
+		}
+		return err
+	}
+}
+
+func (h redisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		err := h.guard.Do(ctx, func(ctx context.Context) error {
+			return next(ctx, cmds)
+		})
+		var rejected *RejectedError
+		if errors.As(err, &rejected) {
+			for _, cmd := range cmds {
+				cmd.SetErr(err)
+			}
+		}
+		return err
+	}
+}
+
+// RedisFailure reports whether a Redis error means Redis is unavailable.
+// Replies such as a missing key or a wrong type come from a healthy server.
+func RedisFailure(err error) bool {
+	if errors.Is(err, redis.Nil) {
+		return false
+	}
+	var reply redis.Error
+	return !errors.As(err, &reply)
+}
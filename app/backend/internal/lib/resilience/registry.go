@@ -0,0 +1,114 @@
+package resilience
+
+import (
+	"sync"
+
+	"github.com/apk471/go-boilerplate/internal/config"
+	"github.com/newrelic/go-agent/v3/newrelic"
+	"github.com/rs/zerolog"
+)
+
+// Names of the guarded dependencies
+const (
+	Postgres = "postgres"
+	Redis    = "redis"
+	Jobs     = "jobs"
+	Email    = "email"
+)
+
+// Status is the state of a guard as reported by health checks
+type Status struct {
+	Name     string   `json:"name"`
+	State    State    `json:"state"`
+	InFlight int      `json:"in_flight"`
+	Rejected int64    `json:"rejected"`
+	Fallback Fallback `json:"fallback"`
+}
+
+// Registry holds the guards of the application's dependencies, so their state
+// can be reported in one place
+type Registry struct {
+	logger *zerolog.Logger
+	nrApp  *newrelic.Application
+
+	mu     sync.RWMutex
+	guards []*Guard
+}
+
+// NewRegistry returns an empty registry. nrApp may be nil.
+func NewRegistry(logger *zerolog.Logger, nrApp *newrelic.Application) *Registry {
+	return &Registry{logger: logger, nrApp: nrApp}
+}
+
+// Register adds a guard for the dependency name. isFailure decides which
+// errors count against the circuit, so errors that are answers of a healthy
+// dependency, such as a missing key, do not open it.
+func (r *Registry) Register(name string, cfg config.GuardConfig, isFailure func(error) bool) *Guard {
+	g := &Guard{
+		name:      name,
+		fallback:  Fallback(cfg.Fallback),
+		bulkhead:  NewBulkhead(cfg.MaxConcurrent, cfg.MaxWait),
+		isFailure: isFailure,
+		nrApp:     r.nrApp,
+	}
+	g.breaker = NewBreaker(cfg.BreakerFailures, cfg.BreakerCooldown, func(from, to State) {
+		r.stateChanged(g, from, to)
+	})
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.guards = append(r.guards, g)
+	return g
+}
+
+// Get returns the guard of the dependency name, or nil
+func (r *Registry) Get(name string) *Guard {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, g := range r.guards {
+		if g.name == name {
+			return g
+		}
+	}
+	return nil
+}
+
+// Statuses returns the state of every guard in registration order
+func (r *Registry) Statuses() []Status {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	statuses := make([]Status, len(r.guards))
+	for i, g := range r.guards {
+		statuses[i] = g.Status()
+	}
+	return statuses
+}
+
+func (r *Registry) stateChanged(g *Guard, from, to State) {
+	event := r.logger.Info()
+	if to == StateOpen {
+		event = r.logger.Warn()
+	}
+	event.
+		Str("dependency", g.name).
+		Str("from", string(from)).
+		Str("to", string(to)).
+		Msg("circuit state changed")
+
+	if r.nrApp == nil {
+		return
+	}
+
+	r.nrApp.RecordCustomEvent("CircuitStateChange", map[string]interface{}{
+		"dependency": g.name,
+		"from":       string(from),
+		"to":         string(to),
+	})
+	open := 0.0
+	if to == StateOpen {
+		open = 1
+	}
+	r.nrApp.RecordCustomMetric("Resilience/"+g.name+"/Open", open)
+}
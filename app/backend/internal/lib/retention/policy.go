@@ -0,0 +1,103 @@
+package retention
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type Mode string
+
+const (
+	// ModeDelete removes rows
+	ModeDelete Mode = "delete"
+	// ModeSoftDelete stamps rows with Policy.SoftDeleteColumn instead, for
+	// tables whose queries already skip soft-deleted rows
+	ModeSoftDelete Mode = "soft_delete"
+)
+
+// Policy declares how long rows of a table are kept. Rows whose AgeColumn is
+// older than MaxAge and that match Condition are purged.
+type Policy struct {
+	// Name identifies the policy in logs, metrics, archives and advisory locks
+	Name  string
+	Table string
+	// AgeColumn is the timestamp compared with MaxAge. Rows where it is NULL
+	// are never purged.
+	AgeColumn string
+	MaxAge    time.Duration
+	// Condition is an optional SQL boolean expression over the table's
+	// columns, e.g. only finished deliveries
+	Condition string
+	Mode      Mode
+	// SoftDeleteColumn is required with ModeSoftDelete. Rows where it is set
+	// are not purged again.
+	SoftDeleteColumn string
+	// Archive writes purged rows to a file before they are removed
+	Archive bool
+}
+
+func (p Policy) Validate() error {
+	switch {
+	case p.Name == "" || p.Table == "" || p.AgeColumn == "":
+		return fmt.Errorf("retention policy %q: name, table and age column are required", p.Name)
+	case p.MaxAge <= 0:
+		return fmt.Errorf("retention policy %q: max age must be positive", p.Name)
+	case p.Mode == ModeSoftDelete && p.SoftDeleteColumn == "":
+		return fmt.Errorf("retention policy %q: soft delete column is required", p.Name)
+	case p.Mode != ModeDelete && p.Mode != ModeSoftDelete:
+		return fmt.Errorf("retention policy %q: unknown mode %q", p.Name, p.Mode)
+	}
+	return nil
+}
+
+// where returns the condition selecting purgeable rows, with the cutoff as $1
+func (p Policy) where() string {
+	where := ident(p.AgeColumn) + ` < $1`
+	if p.Mode == ModeSoftDelete {
+		where += ` AND ` + ident(p.SoftDeleteColumn) + ` IS NULL`
+	}
+	if p.Condition != "" {
+		where += ` AND (` + p.Condition + `)`
+	}
+	return where
+}
+
+// purgeSQL purges up to $2 of the oldest rows before the cutoff $1. Rows
+// locked by a concurrent purge are skipped. Purged rows are returned as JSON
+// when the policy archives them.
+func (p Policy) purgeSQL() string {
+	batch := `WITH batch AS (
+		SELECT ctid FROM ` + ident(p.Table) + `
+		WHERE ` + p.where() + `
+		ORDER BY ` + ident(p.AgeColumn) + `
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	)
+	`
+
+	var statement string
+	if p.Mode == ModeSoftDelete {
+		statement = `UPDATE ` + ident(p.Table) + ` t SET ` + ident(p.SoftDeleteColumn) + ` = now()
+		FROM batch WHERE t.ctid = batch.ctid`
+	} else {
+		statement = `DELETE FROM ` + ident(p.Table) + ` t
+		USING batch WHERE t.ctid = batch.ctid`
+	}
+
+	if p.Archive {
+		statement += ` RETURNING row_to_json(t)::text`
+	}
+
+	return batch + statement
+}
+
+// countSQL counts the rows purgeable before the cutoff $1 and finds the oldest
+func (p Policy) countSQL() string {
+	return `SELECT count(*), min(` + ident(p.AgeColumn) + `) FROM ` + ident(p.Table) + ` WHERE ` + p.where()
+}
+
+func ident(name string) string {
+	return pgx.Identifier{name}.Sanitize()
+}
@@ -0,0 +1,120 @@
+package retention
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// squash collapses whitespace runs, so generated SQL compares regardless of
+// its indentation
+func squash(sql string) string {
+	return strings.Join(strings.Fields(sql), " ")
+}
+
+func TestPolicyValidate(t *testing.T) {
+	valid := Policy{Name: "deliveries", Table: "webhook_deliveries", AgeColumn: "created_at", MaxAge: time.Hour, Mode: ModeDelete}
+
+	tests := []struct {
+		name    string
+		modify  func(*Policy)
+		wantErr bool
+	}{
+		{"delete", func(p *Policy) {}, false},
+		{"soft delete", func(p *Policy) { p.Mode, p.SoftDeleteColumn = ModeSoftDelete, "deleted_at" }, false},
+		{"missing name", func(p *Policy) { p.Name = "" }, true},
+		{"missing table", func(p *Policy) { p.Table = "" }, true},
+		{"missing age column", func(p *Policy) { p.AgeColumn = "" }, true},
+		{"zero max age", func(p *Policy) { p.MaxAge = 0 }, true},
+		{"soft delete without column", func(p *Policy) { p.Mode = ModeSoftDelete }, true},
+		{"unknown mode", func(p *Policy) { p.Mode = "truncate" }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := valid
+			tt.modify(&p)
+			if err := p.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPolicySQL(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    Policy
+		wantWhere string
+		wantPurge string
+	}{
+		{
+			name:      "delete",
+			policy:    Policy{Table: "webhook_deliveries", AgeColumn: "created_at", Mode: ModeDelete},
+			wantWhere: `"created_at" < $1`,
+			wantPurge: `WITH batch AS ( SELECT ctid FROM "webhook_deliveries" WHERE "created_at" < $1 ` +
+				`ORDER BY "created_at" LIMIT $2 FOR UPDATE SKIP LOCKED ) ` +
+				`DELETE FROM "webhook_deliveries" t USING batch WHERE t.ctid = batch.ctid`,
+		},
+		{
+			name:      "soft delete",
+			policy:    Policy{Table: "notifications", AgeColumn: "created_at", Mode: ModeSoftDelete, SoftDeleteColumn: "deleted_at"},
+			wantWhere: `"created_at" < $1 AND "deleted_at" IS NULL`,
+			wantPurge: `WITH batch AS ( SELECT ctid FROM "notifications" WHERE "created_at" < $1 AND "deleted_at" IS NULL ` +
+				`ORDER BY "created_at" LIMIT $2 FOR UPDATE SKIP LOCKED ) ` +
+				`UPDATE "notifications" t SET "deleted_at" = now() FROM batch WHERE t.ctid = batch.ctid`,
+		},
+		{
+			name:      "archive",
+			policy:    Policy{Table: "audit_log", AgeColumn: "occurred_at", Mode: ModeDelete, Archive: true},
+			wantWhere: `"occurred_at" < $1`,
+			wantPurge: `WITH batch AS ( SELECT ctid FROM "audit_log" WHERE "occurred_at" < $1 ` +
+				`ORDER BY "occurred_at" LIMIT $2 FOR UPDATE SKIP LOCKED ) ` +
+				`DELETE FROM "audit_log" t USING batch WHERE t.ctid = batch.ctid RETURNING row_to_json(t)::text`,
+		},
+		{
+			name:      "soft delete with archive",
+			policy:    Policy{Table: "notifications", AgeColumn: "created_at", Mode: ModeSoftDelete, SoftDeleteColumn: "deleted_at", Archive: true},
+			wantWhere: `"created_at" < $1 AND "deleted_at" IS NULL`,
+			wantPurge: `WITH batch AS ( SELECT ctid FROM "notifications" WHERE "created_at" < $1 AND "deleted_at" IS NULL ` +
+				`ORDER BY "created_at" LIMIT $2 FOR UPDATE SKIP LOCKED ) ` +
+				`UPDATE "notifications" t SET "deleted_at" = now() FROM batch WHERE t.ctid = batch.ctid ` +
+				`RETURNING row_to_json(t)::text`,
+		},
+		{
+			name: "condition",
+			policy: Policy{Table: "webhook_deliveries", AgeColumn: "created_at", Mode: ModeDelete,
+				Condition: "status = 'succeeded' OR status = 'failed'"},
+			wantWhere: `"created_at" < $1 AND (status = 'succeeded' OR status = 'failed')`,
+			wantPurge: `WITH batch AS ( SELECT ctid FROM "webhook_deliveries" ` +
+				`WHERE "created_at" < $1 AND (status = 'succeeded' OR status = 'failed') ` +
+				`ORDER BY "created_at" LIMIT $2 FOR UPDATE SKIP LOCKED ) ` +
+				`DELETE FROM "webhook_deliveries" t USING batch WHERE t.ctid = batch.ctid`,
+		},
+		{
+			name:      "quoted identifiers",
+			policy:    Policy{Table: `odd"table`, AgeColumn: "Created At", Mode: ModeDelete},
+			wantWhere: `"Created At" < $1`,
+			wantPurge: `WITH batch AS ( SELECT ctid FROM "odd""table" WHERE "Created At" < $1 ` +
+				`ORDER BY "Created At" LIMIT $2 FOR UPDATE SKIP LOCKED ) ` +
+				`DELETE FROM "odd""table" t USING batch WHERE t.ctid = batch.ctid`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.where(); got != tt.wantWhere {
+				t.Errorf("where() =\n%s\nwant\n%s", got, tt.wantWhere)
+			}
+			if got := squash(tt.policy.purgeSQL()); got != tt.wantPurge {
+				t.Errorf("purgeSQL() =\n%s\nwant\n%s", got, tt.wantPurge)
+			}
+
+			wantCount := `SELECT count(*), min(` + ident(tt.policy.AgeColumn) + `) FROM ` + ident(tt.policy.Table) +
+				` WHERE ` + tt.wantWhere
+			if got := tt.policy.countSQL(); got != wantCount {
+				t.Errorf("countSQL() =\n%s\nwant\n%s", got, wantCount)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package retention
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/apk471/go-boilerplate/internal/database"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrLocked is returned when another transaction holds the policy's lock,
+// i.e. another instance is purging the same table
+var ErrLocked = errors.New("retention policy is locked by another purge")
+
+// Purger executes retention policies in batches. Each batch is a transaction
+// holding the policy's advisory lock, so batches never interleave across
+// instances and a long purge never holds locks for more than one batch.
+type Purger struct {
+	db         database.Querier
+	archiveDir string
+}
+
+func NewPurger(db database.Querier, archiveDir string) *Purger {
+	return &Purger{db: db, archiveDir: archiveDir}
+}
+
+// PurgeBatch purges up to limit of the oldest rows before cutoff and returns
+// how many it purged. Archived rows are written before the transaction
+// commits, so a failed commit archives them again with the next batch.
+func (p *Purger) PurgeBatch(ctx context.Context, policy Policy, cutoff time.Time, limit int) (int, error) {
+	var purged int
+	err := pgx.BeginFunc(ctx, p.db, func(tx pgx.Tx) error {
+		var locked bool
+		if err := tx.QueryRow(ctx, `SELECT pg_try_advisory_xact_lock($1)`, lockKey(policy.Name)).Scan(&locked); err != nil {
+			return fmt.Errorf("failed to lock retention policy: %w", err)
+		}
+		if !locked {
+			return ErrLocked
+		}
+
+		if !policy.Archive {
+			tag, err := tx.Exec(ctx, policy.purgeSQL(), cutoff, limit)
+			if err != nil {
+				return fmt.Errorf("failed to purge table:%s: %w", policy.Table, err)
+			}
+			purged = int(tag.RowsAffected())
+			return nil
+		}
+
+		rows, err := tx.Query(ctx, policy.purgeSQL(), cutoff, limit)
+		if err != nil {
+			return fmt.Errorf("failed to purge table:%s: %w", policy.Table, err)
+		}
+
+		records, err := pgx.CollectRows(rows, pgx.RowTo[string])
+		if err != nil {
+			return fmt.Errorf("failed to collect rows from table:%s: %w", policy.Table, err)
+		}
+		purged = len(records)
+
+		if purged == 0 {
+			return nil
+		}
+		return p.archive(policy, records)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return purged, nil
+}
+
+// Eligible is what a purge would remove right now
+type Eligible struct {
+	Rows   int64
+	Oldest *time.Time
+}
+
+// Count reports the rows a purge with cutoff would remove, without removing them
+func (p *Purger) Count(ctx context.Context, policy Policy, cutoff time.Time) (Eligible, error) {
+	var eligible Eligible
+	if err := p.db.QueryRow(ctx, policy.countSQL(), cutoff).Scan(&eligible.Rows, &eligible.Oldest); err != nil {
+		return Eligible{}, fmt.Errorf("failed to count table:%s: %w", policy.Table, err)
+	}
+	return eligible, nil
+}
+
+// archive appends records as JSON lines to the policy's file for the day,
+// <archive dir>/<policy>/<date>.jsonl.gz. Each batch is its own gzip member,
+// which gzip readers concatenate.
+func (p *Purger) archive(policy Policy, records []string) error {
+	dir := filepath.Join(p.archiveDir, policy.Name)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return fmt.Errorf("failed to create retention archive directory: %w", err)
+	}
+
+	path := filepath.Join(dir, time.Now().UTC().Format("2006-01-02")+".jsonl.gz")
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o640)
+	if err != nil {
+		return fmt.Errorf("failed to open retention archive: %w", err)
+	}
+	defer file.Close()
+
+	gz := gzip.NewWriter(file)
+	for _, record := range records {
+		if _, err := gz.Write([]byte(record + "\n")); err != nil {
+			return fmt.Errorf("failed to write retention archive: %w", err)
+		}
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to write retention archive: %w", err)
+	}
+
+	// The rows are deleted once the transaction commits, so the archive
+	// must be on disk first
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync retention archive: %w", err)
+	}
+	return nil
+}
+
+func lockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("retention:" + name))
+	return int64(h.Sum64())
+}
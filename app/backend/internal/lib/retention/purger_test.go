@@ -0,0 +1,222 @@
+package retention
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/apk471/go-boilerplate/internal/testutil"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// newPurgeTable creates a table of old rows, ids 1 to old, and of new rows
+// after them. Old rows alternate between the statuses done and open.
+func newPurgeTable(t *testing.T, pool *pgxpool.Pool, name string, old, recent int) {
+	t.Helper()
+	ctx := context.Background()
+
+	_, err := pool.Exec(ctx, `CREATE TABLE `+ident(name)+` (
+		id INT PRIMARY KEY,
+		status TEXT NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL,
+		deleted_at TIMESTAMPTZ
+	)`)
+	if err != nil {
+		t.Fatalf("failed to create table %s: %v", name, err)
+	}
+
+	_, err = pool.Exec(ctx, `
+		INSERT INTO `+ident(name)+` (id, status, created_at)
+		SELECT i, CASE WHEN i % 2 = 1 THEN 'done' ELSE 'open' END, now() - interval '30 days' + i * interval '1 minute'
+		FROM generate_series(1, $1) AS i
+		UNION ALL
+		SELECT i, 'done', now()
+		FROM generate_series($1 + 1, $1 + $2) AS i`, old, recent)
+	if err != nil {
+		t.Fatalf("failed to fill table %s: %v", name, err)
+	}
+}
+
+// remaining returns the ids of rows left in table that are not soft deleted
+func remaining(t *testing.T, pool *pgxpool.Pool, table string) []int {
+	t.Helper()
+
+	rows, err := pool.Query(context.Background(), `SELECT id FROM `+ident(table)+` WHERE deleted_at IS NULL ORDER BY id`)
+	if err != nil {
+		t.Fatalf("failed to query %s: %v", table, err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			t.Fatalf("failed to scan %s: %v", table, err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("failed to read %s: %v", table, err)
+	}
+	return ids
+}
+
+// purgeAll runs batches of limit rows until one purges nothing and returns
+// the rows each batch purged
+func purgeAll(t *testing.T, purger *Purger, policy Policy, cutoff time.Time, limit int) []int {
+	t.Helper()
+
+	var batches []int
+	for {
+		purged, err := purger.PurgeBatch(context.Background(), policy, cutoff, limit)
+		if err != nil {
+			t.Fatalf("PurgeBatch() error = %v", err)
+		}
+		batches = append(batches, purged)
+		if purged == 0 || len(batches) > 10 {
+			return batches
+		}
+	}
+}
+
+func TestPurgeBatch(t *testing.T) {
+	t.Parallel()
+
+	// The advisory lock is only contended across connections, so the test
+	// commits to a database of its own
+	pool := testutil.NewTestDatabase(t).Clone(t)
+	cutoff := time.Now().Add(-24 * time.Hour)
+
+	t.Run("deletes the oldest rows in batches", func(t *testing.T) {
+		newPurgeTable(t, pool, "purge_delete", 5, 2)
+		policy := Policy{Name: "delete", Table: "purge_delete", AgeColumn: "created_at", MaxAge: 24 * time.Hour, Mode: ModeDelete}
+
+		if got := purgeAll(t, NewPurger(pool, t.TempDir()), policy, cutoff, 2); !slices.Equal(got, []int{2, 2, 1, 0}) {
+			t.Errorf("batches = %v, want [2 2 1 0]", got)
+		}
+		if got := remaining(t, pool, "purge_delete"); !slices.Equal(got, []int{6, 7}) {
+			t.Errorf("remaining rows = %v, want the recent ones", got)
+		}
+	})
+
+	t.Run("soft deletes rows once", func(t *testing.T) {
+		newPurgeTable(t, pool, "purge_soft", 3, 1)
+		policy := Policy{Name: "soft", Table: "purge_soft", AgeColumn: "created_at", MaxAge: 24 * time.Hour,
+			Mode: ModeSoftDelete, SoftDeleteColumn: "deleted_at"}
+
+		if got := purgeAll(t, NewPurger(pool, t.TempDir()), policy, cutoff, 10); !slices.Equal(got, []int{3, 0}) {
+			t.Errorf("batches = %v, want [3 0]", got)
+		}
+
+		var rows int
+		if err := pool.QueryRow(context.Background(), `SELECT count(*) FROM purge_soft`).Scan(&rows); err != nil {
+			t.Fatal(err)
+		}
+		if rows != 4 {
+			t.Errorf("rows = %d, want soft deleted rows kept", rows)
+		}
+		if got := remaining(t, pool, "purge_soft"); !slices.Equal(got, []int{4}) {
+			t.Errorf("rows not soft deleted = %v, want [4]", got)
+		}
+	})
+
+	t.Run("only purges rows matching the condition", func(t *testing.T) {
+		newPurgeTable(t, pool, "purge_condition", 4, 1)
+		policy := Policy{Name: "condition", Table: "purge_condition", AgeColumn: "created_at", MaxAge: 24 * time.Hour,
+			Mode: ModeDelete, Condition: "status = 'done'"}
+
+		if got := purgeAll(t, NewPurger(pool, t.TempDir()), policy, cutoff, 10); !slices.Equal(got, []int{2, 0}) {
+			t.Errorf("batches = %v, want [2 0]", got)
+		}
+		if got := remaining(t, pool, "purge_condition"); !slices.Equal(got, []int{2, 4, 5}) {
+			t.Errorf("remaining rows = %v, want the open and recent ones", got)
+		}
+	})
+
+	t.Run("archives purged rows before removing them", func(t *testing.T) {
+		newPurgeTable(t, pool, "purge_archive", 3, 1)
+		policy := Policy{Name: "archive", Table: "purge_archive", AgeColumn: "created_at", MaxAge: 24 * time.Hour,
+			Mode: ModeDelete, Archive: true}
+		dir := t.TempDir()
+
+		if got := purgeAll(t, NewPurger(pool, dir), policy, cutoff, 2); !slices.Equal(got, []int{2, 1, 0}) {
+			t.Fatalf("batches = %v, want [2 1 0]", got)
+		}
+
+		// Each batch appended a gzip member to the file of the day
+		path := filepath.Join(dir, "archive", time.Now().UTC().Format("2006-01-02")+".jsonl.gz")
+		file, err := os.Open(path)
+		if err != nil {
+			t.Fatalf("failed to open archive: %v", err)
+		}
+		defer file.Close()
+
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			t.Fatalf("archive is not gzipped: %v", err)
+		}
+
+		var ids []int
+		scanner := bufio.NewScanner(gz)
+		for scanner.Scan() {
+			var row struct {
+				ID     int    `json:"id"`
+				Status string `json:"status"`
+			}
+			if err := json.Unmarshal(scanner.Bytes(), &row); err != nil {
+				t.Fatalf("archive line %q: %v", scanner.Text(), err)
+			}
+			ids = append(ids, row.ID)
+		}
+		if err := scanner.Err(); err != nil {
+			t.Fatalf("failed to read archive: %v", err)
+		}
+
+		if !slices.Equal(ids, []int{1, 2, 3}) {
+			t.Errorf("archived rows = %v, want [1 2 3]", ids)
+		}
+		if got := remaining(t, pool, "purge_archive"); !slices.Equal(got, []int{4}) {
+			t.Errorf("remaining rows = %v, want [4]", got)
+		}
+	})
+
+	t.Run("another purge of the policy holds the lock", func(t *testing.T) {
+		ctx := context.Background()
+		newPurgeTable(t, pool, "purge_locked", 2, 0)
+		policy := Policy{Name: "locked", Table: "purge_locked", AgeColumn: "created_at", MaxAge: 24 * time.Hour, Mode: ModeDelete}
+		purger := NewPurger(pool, t.TempDir())
+
+		tx, err := pool.Begin(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, lockKey(policy.Name)); err != nil {
+			t.Fatalf("failed to take the policy's lock: %v", err)
+		}
+
+		if _, err := purger.PurgeBatch(ctx, policy, cutoff, 10); !errors.Is(err, ErrLocked) {
+			t.Errorf("PurgeBatch() while locked: error = %v, want ErrLocked", err)
+		}
+
+		// Other policies are not blocked
+		other := policy
+		other.Name = "other"
+		if _, err := purger.PurgeBatch(ctx, other, cutoff, 1); err != nil {
+			t.Errorf("PurgeBatch() of another policy: error = %v", err)
+		}
+
+		if err := tx.Rollback(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if purged, err := purger.PurgeBatch(ctx, policy, cutoff, 10); err != nil || purged != 1 {
+			t.Errorf("PurgeBatch() after unlock = %d, %v, want the remaining row", purged, err)
+		}
+	})
+}
@@ -0,0 +1,236 @@
+package search
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Weight ranks matches in a field relative to the other fields of an index,
+// from A (highest) to D. ts_rank scores them 1.0, 0.4, 0.2 and 0.1.
+type Weight string
+
+const (
+	WeightA Weight = "A"
+	WeightB Weight = "B"
+	WeightC Weight = "C"
+	WeightD Weight = "D"
+)
+
+const (
+	// DefaultConfig is the text search configuration used by indexes that do
+	// not set one. It does not stem, which suits names and mixed languages.
+	DefaultConfig = "simple"
+
+	// FuzzyMaxLength is the longest single-word query that is also matched by
+	// trigram similarity, catching typos and partial words in short queries
+	// that full-text search misses
+	FuzzyMaxLength = 16
+
+	// Highlighted terms are wrapped in these markers. The surrounding text is
+	// HTML escaped, so highlights can be rendered as markup.
+	HighlightStart = "<mark>"
+	HighlightEnd   = "</mark>"
+)
+
+// Field is a source column of an index
+type Field struct {
+	// Name is the key of the field in highlights
+	Name   string
+	Column string
+	// Weight is applied with setweight when the tsvector is built, see
+	// Index.Document; fields without one get D
+	Weight Weight
+	// Fragments limits the highlight to that many excerpts around matches;
+	// zero highlights the whole value, which suits short fields like titles
+	Fragments int
+}
+
+// Index describes a tsvector column kept up to date by a generated column.
+// A migration creates the column from the expression Document returns for
+// Fields, for example
+//
+//	ALTER TABLE notifications ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+//	    setweight(to_tsvector('simple'::regconfig, coalesce(title, '')), 'A') ||
+//	    setweight(to_tsvector('simple'::regconfig, coalesce(body, '')), 'B')
+//	) STORED;
+//	CREATE INDEX idx_notifications_search_vector ON notifications USING GIN (search_vector);
+//
+// and, if Fuzzy is set, a pg_trgm index on that column:
+//
+//	CREATE INDEX idx_notifications_title_trgm ON notifications USING GIN (title gin_trgm_ops);
+type Index struct {
+	// Config is the text search configuration the column was built with
+	Config string
+	// Vector is the tsvector column, qualified if the query joins tables
+	Vector string
+	Fields []Field
+	// Fuzzy is the column short queries are matched against by trigram
+	// similarity. Leave it empty to use full-text search only.
+	Fuzzy string
+}
+
+func (i Index) config() string {
+	if i.Config == "" {
+		return DefaultConfig
+	}
+	return i.Config
+}
+
+// Document returns the weighted tsvector of Fields, the expression of the
+// generated column. Columns are unqualified, since a generated column can
+// only refer to its own table.
+func (i Index) Document() string {
+	parts := make([]string, 0, len(i.Fields))
+	for _, field := range i.Fields {
+		weight := field.Weight
+		if weight == "" {
+			weight = WeightD
+		}
+
+		column := field.Column
+		if dot := strings.LastIndexByte(column, '.'); dot >= 0 {
+			column = column[dot+1:]
+		}
+
+		parts = append(parts, fmt.Sprintf("setweight(to_tsvector('%s'::regconfig, coalesce(%s, '')), '%s')",
+			i.config(), column, weight))
+	}
+	return strings.Join(parts, " || ")
+}
+
+// Args collects positional arguments while a query is assembled, so search
+// conditions compose with filters and pagination written against the same
+// list
+type Args []any
+
+// Add appends v and returns its placeholder
+func (a *Args) Add(v any) string {
+	*a = append(*a, v)
+	return "$" + strconv.Itoa(len(*a))
+}
+
+// Query is a search against an index. A nil Query matches every row, so
+// repositories can build one statement whether or not a search was given.
+type Query struct {
+	index   Index
+	text    string
+	tsquery string
+	fuzzy   bool
+}
+
+// Query parses text with websearch_to_tsquery, which accepts quoted phrases,
+// OR and -exclusions and never fails on user input. It returns nil if text is
+// blank. The text is added to args once and referenced by every expression.
+func (i Index) Query(text string, args *Args) *Query {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+
+	placeholder := args.Add(text)
+	return &Query{
+		index:   i,
+		text:    placeholder + "::text",
+		tsquery: fmt.Sprintf("websearch_to_tsquery('%s', %s::text)", i.config(), placeholder),
+		fuzzy:   i.Fuzzy != "" && isShort(text),
+	}
+}
+
+// Where returns the condition matching rows, to be ANDed with other filters
+func (q *Query) Where() string {
+	if q == nil {
+		return "TRUE"
+	}
+
+	match := fmt.Sprintf("%s @@ %s", q.index.Vector, q.tsquery)
+	if q.fuzzy {
+		// <% is the indexable form of word_similarity above
+		// pg_trgm.word_similarity_threshold
+		match = fmt.Sprintf("(%s OR %s <%% %s)", match, q.text, q.index.Fuzzy)
+	}
+	return match
+}
+
+// Rank returns the relevance of a row, higher first. Fuzzy matches rank by
+// their trigram similarity when it beats the full-text rank.
+func (q *Query) Rank() string {
+	if q == nil {
+		return "0"
+	}
+
+	rank := fmt.Sprintf("ts_rank(%s, %s)", q.index.Vector, q.tsquery)
+	if q.fuzzy {
+		rank = fmt.Sprintf("greatest(%s, word_similarity(%s, %s))", rank, q.text, q.index.Fuzzy)
+	}
+	return rank
+}
+
+// OrderBy returns an ORDER BY list ranking matches first and falling back to
+// then for ties and when there is no search
+func (q *Query) OrderBy(then string) string {
+	if q == nil {
+		return then
+	}
+	return q.Rank() + " DESC, " + then
+}
+
+// Highlights returns a jsonb object of each field's value, HTML escaped, with
+// the matched terms marked, keyed by field name, or NULL if there is no search
+func (q *Query) Highlights() string {
+	if q == nil {
+		return "NULL::jsonb"
+	}
+
+	pairs := make([]string, 0, len(q.index.Fields))
+	for _, field := range q.index.Fields {
+		options := fmt.Sprintf("StartSel=%s, StopSel=%s", HighlightStart, HighlightEnd)
+		if field.Fragments > 0 {
+			options += fmt.Sprintf(", MaxFragments=%d, FragmentDelimiter=\" … \"", field.Fragments)
+		} else {
+			options += ", HighlightAll=true"
+		}
+
+		// The value is escaped before ts_headline adds the markers, since
+		// escaping its output would escape them too. The parser reads
+		// escapes such as &lt; as entities rather than words, so they are
+		// never split by a marker.
+		pairs = append(pairs, fmt.Sprintf("'%s', ts_headline('%s', %s, %s, '%s')",
+			field.Name, q.index.config(), htmlEscaped("coalesce("+field.Column+", '')"), q.tsquery, options))
+	}
+	return "jsonb_build_object(" + strings.Join(pairs, ", ") + ")"
+}
+
+// htmlEscapes are the replacements of html.EscapeString, ampersand first
+var htmlEscapes = [][2]string{
+	{"&", "&amp;"},
+	{"<", "&lt;"},
+	{">", "&gt;"},
+	{`"`, "&#34;"},
+	{"'", "&#39;"},
+}
+
+// htmlEscaped wraps the SQL expression expr in the replacements of htmlEscapes
+func htmlEscaped(expr string) string {
+	for _, escape := range htmlEscapes {
+		expr = fmt.Sprintf("replace(%s, '%s', '%s')", expr,
+			strings.ReplaceAll(escape[0], "'", "''"), escape[1])
+	}
+	return expr
+}
+
+// isShort reports whether text is a single plain word short enough for
+// trigram matching to be meaningful
+func isShort(text string) bool {
+	if utf8.RuneCountInString(text) > FuzzyMaxLength {
+		return false
+	}
+	for _, r := range text {
+		if unicode.IsSpace(r) || r == '"' {
+			return false
+		}
+	}
+	return !strings.HasPrefix(text, "-")
+}
@@ -0,0 +1,115 @@
+package search
+
+import (
+	"strings"
+	"testing"
+)
+
+var testIndex = Index{
+	Vector: "search_vector",
+	Fields: []Field{
+		{Name: "title", Column: "title"},
+		{Name: "body", Column: "body", Fragments: 2},
+	},
+	Fuzzy: "title",
+}
+
+func TestNilQuery(t *testing.T) {
+	var args Args
+	q := testIndex.Query("   ", &args)
+	if q != nil {
+		t.Fatal("blank text returned a query")
+	}
+	if len(args) != 0 {
+		t.Errorf("blank text added %d args", len(args))
+	}
+	if got := q.Where(); got != "TRUE" {
+		t.Errorf("Where() = %q, want TRUE", got)
+	}
+	if got := q.OrderBy("created_at DESC"); got != "created_at DESC" {
+		t.Errorf("OrderBy() = %q", got)
+	}
+	if got := q.Highlights(); got != "NULL::jsonb" {
+		t.Errorf("Highlights() = %q", got)
+	}
+}
+
+func TestQueryFuzzy(t *testing.T) {
+	tests := []struct {
+		text  string
+		fuzzy bool
+	}{
+		{"alice", true},
+		{"alice smith", false},
+		{`"alice"`, false},
+		{"-alice", false},
+		{strings.Repeat("a", FuzzyMaxLength+1), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			var args Args
+			q := testIndex.Query(tt.text, &args)
+			if got := strings.Contains(q.Where(), "<%"); got != tt.fuzzy {
+				t.Errorf("Where() fuzzy = %v, want %v: %s", got, tt.fuzzy, q.Where())
+			}
+		})
+	}
+}
+
+func TestHighlightsEscapeSourceText(t *testing.T) {
+	var args Args
+	args.Add("earlier argument")
+	q := testIndex.Query("alice", &args)
+
+	highlights := q.Highlights()
+	if len(args) != 2 {
+		t.Fatalf("args = %v, want the search text added once", args)
+	}
+
+	// Every field is escaped, ampersand first, before ts_headline marks it
+	want := "ts_headline('simple', replace(replace(replace(replace(replace(coalesce(title, ''), '&', '&amp;'), " +
+		"'<', '&lt;'), '>', '&gt;'), '\"', '&#34;'), '''', '&#39;'), websearch_to_tsquery('simple', $2::text)"
+	if !strings.Contains(highlights, want) {
+		t.Errorf("Highlights() does not escape title:\n%s", highlights)
+	}
+	if strings.Count(highlights, "'&amp;'") != len(testIndex.Fields) {
+		t.Errorf("Highlights() does not escape every field:\n%s", highlights)
+	}
+}
+
+func TestDocument(t *testing.T) {
+	tests := []struct {
+		name  string
+		index Index
+		want  string
+	}{
+		{
+			name: "weighted fields",
+			index: Index{Fields: []Field{
+				{Name: "title", Column: "title", Weight: WeightA},
+				{Name: "body", Column: "body", Weight: WeightB},
+			}},
+			want: "setweight(to_tsvector('simple'::regconfig, coalesce(title, '')), 'A') || " +
+				"setweight(to_tsvector('simple'::regconfig, coalesce(body, '')), 'B')",
+		},
+		{
+			name:  "unweighted field",
+			index: Index{Fields: []Field{{Name: "note", Column: "note"}}},
+			want:  "setweight(to_tsvector('simple'::regconfig, coalesce(note, '')), 'D')",
+		},
+		{
+			name:  "qualified column and config",
+			index: Index{Config: "english", Fields: []Field{{Name: "displayName", Column: "u.display_name", Weight: WeightC}}},
+			want:  "setweight(to_tsvector('english'::regconfig, coalesce(display_name, '')), 'C')",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.index.Document(); got != tt.want {
+				t.Errorf("Document() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package timing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Span is the time a request spent in one layer, such as the database
+type Span struct {
+	Name     string
+	Duration time.Duration
+	// Count is the number of calls the duration adds up
+	Count int
+}
+
+// Timings collects the spans of a request. Spans of the same name are added
+// up. It is safe for concurrent use, and a nil Timings discards spans.
+type Timings struct {
+	mu    sync.Mutex
+	spans []Span
+}
+
+type timingsKey struct{}
+
+// WithTimings returns a context collecting spans in the returned Timings
+func WithTimings(ctx context.Context) (context.Context, *Timings) {
+	timings := &Timings{}
+	return context.WithValue(ctx, timingsKey{}, timings), timings
+}
+
+// FromContext returns the Timings set by WithTimings, or nil
+func FromContext(ctx context.Context) *Timings {
+	timings, _ := ctx.Value(timingsKey{}).(*Timings)
+	return timings
+}
+
+// Start starts a span of the context's Timings, ended by calling the returned
+// func:
+//
+//	defer timing.Start(ctx, "cache")()
+func Start(ctx context.Context, name string) func() {
+	timings := FromContext(ctx)
+	if timings == nil {
+		return func() {}
+	}
+
+	start := time.Now()
+	return func() { timings.Add(name, time.Since(start)) }
+}
+
+// Add adds d to the span name. Names must be valid Server-Timing tokens,
+// such as db or http.clerk.
+func (t *Timings) Add(name string, d time.Duration) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for i := range t.spans {
+		if t.spans[i].Name == name {
+			t.spans[i].Duration += d
+			t.spans[i].Count++
+			return
+		}
+	}
+	t.spans = append(t.spans, Span{Name: name, Duration: d, Count: 1})
+}
+
+// Spans returns the spans in the order they were first added
+func (t *Timings) Spans() []Span {
+	if t == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Span(nil), t.spans...)
+}
+
+// Header formats spans as a Server-Timing header value
+func Header(spans []Span) string {
+	metrics := make([]string, len(spans))
+	for i, span := range spans {
+		metrics[i] = fmt.Sprintf(`%s;dur=%.2f`, span.Name, float64(span.Duration.Microseconds())/1000)
+		if span.Count > 1 {
+			metrics[i] += fmt.Sprintf(`;desc="%d calls"`, span.Count)
+		}
+	}
+	return strings.Join(metrics, ", ")
+}
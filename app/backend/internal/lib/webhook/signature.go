@@ -0,0 +1,55 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers sent with every delivery. Receivers verify HeaderSignature by
+// computing HMAC-SHA256 over "<timestamp>.<body>" with the endpoint secret and
+// should reject timestamps too far from their own clock to prevent replays.
+const (
+	HeaderID        = "X-Webhook-Id"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+
+	// SignatureVersion prefixes each signature so the scheme can change later
+	SignatureVersion = "v1"
+
+	secretPrefix = "whsec_"
+	secretBytes  = 32
+)
+
+// NewSecret returns a random signing secret
+func NewSecret() (string, error) {
+	b := make([]byte, secretBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return secretPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Sign returns the value of HeaderSignature for body sent at timestamp. While a
+// secret is being rotated, every given secret signs the body and the
+// signatures are joined by commas, so receivers holding either secret accept it.
+func Sign(timestamp time.Time, body []byte, secrets ...string) string {
+	signed := strconv.FormatInt(timestamp.Unix(), 10) + "."
+
+	signatures := make([]string, 0, len(secrets))
+	for _, secret := range secrets {
+		h := hmac.New(sha256.New, []byte(secret))
+		h.Write([]byte(signed))
+		h.Write(body)
+		signatures = append(signatures, SignatureVersion+"="+hex.EncodeToString(h.Sum(nil)))
+	}
+
+	return strings.Join(signatures, ",")
+}
@@ -0,0 +1,61 @@
+package logger
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/rs/zerolog"
+)
+
+// contextKey is unexported so no other package can read or overwrite the logger
+type contextKey struct{}
+
+type requestIDKey struct{}
+
+var defaultLogger atomic.Pointer[zerolog.Logger]
+
+// SetDefault sets the logger FromContext returns when the context carries none
+func SetDefault(logger *zerolog.Logger) {
+	defaultLogger.Store(logger)
+}
+
+// WithContext returns a copy of ctx carrying logger
+func WithContext(ctx context.Context, logger *zerolog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the logger stored in ctx, falling back to the default logger.
+// Loggers stored by the request middleware carry request_id, user and trace fields.
+func FromContext(ctx context.Context) *zerolog.Logger {
+	if ctx != nil {
+		if logger, ok := ctx.Value(contextKey{}).(*zerolog.Logger); ok && logger != nil {
+			return logger
+		}
+	}
+	if logger := defaultLogger.Load(); logger != nil {
+		return logger
+	}
+	logger := zerolog.Nop()
+	return &logger
+}
+
+// WithFields stores a child of the context logger with extra fields added by fields
+func WithFields(ctx context.Context, fields func(zerolog.Context) zerolog.Context) context.Context {
+	logger := fields(FromContext(ctx).With()).Logger()
+	return WithContext(ctx, &logger)
+}
+
+// WithRequestID returns a copy of ctx carrying the ID of the request it serves,
+// which outbound HTTP calls forward as X-Request-ID
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext returns the request ID stored in ctx, or ""
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
@@ -0,0 +1,125 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// maxDedupeEntries bounds the number of distinct messages tracked before expired ones are pruned
+const maxDedupeEntries = 1024
+
+// dedupeWriter drops identical error lines once more than burst of them are written within window.
+// The first line written after a window closes carries the number of lines dropped in it.
+type dedupeWriter struct {
+	next   zerolog.LevelWriter
+	window time.Duration
+	burst  int
+
+	mu      sync.Mutex
+	entries map[string]*dedupeEntry
+}
+
+type dedupeEntry struct {
+	windowStart time.Time
+	count       int
+	suppressed  int
+}
+
+func newDedupeWriter(next io.Writer, window time.Duration, burst int) *dedupeWriter {
+	levelWriter, ok := next.(zerolog.LevelWriter)
+	if !ok {
+		levelWriter = zerolog.LevelWriterAdapter{Writer: next}
+	}
+
+	return &dedupeWriter{
+		next:    levelWriter,
+		window:  window,
+		burst:   burst,
+		entries: make(map[string]*dedupeEntry),
+	}
+}
+
+func (w *dedupeWriter) Write(p []byte) (int, error) {
+	return w.next.Write(p)
+}
+
+func (w *dedupeWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	if level < zerolog.ErrorLevel {
+		return w.next.WriteLevel(level, p)
+	}
+
+	var event map[string]any
+	if err := json.Unmarshal(p, &event); err != nil {
+		return w.next.WriteLevel(level, p)
+	}
+	key := fmt.Sprintf("%s|%v|%v", level, event[zerolog.MessageFieldName], event[zerolog.ErrorFieldName])
+
+	n := len(p)
+	now := time.Now()
+
+	w.mu.Lock()
+	entry, ok := w.entries[key]
+	if !ok || now.Sub(entry.windowStart) >= w.window {
+		if entry != nil && entry.suppressed > 0 {
+			p = appendSuppressed(p, entry.suppressed)
+		}
+		entry = &dedupeEntry{windowStart: now}
+		w.entries[key] = entry
+		w.prune(now)
+	}
+	entry.count++
+	if entry.count > w.burst {
+		entry.suppressed++
+		w.mu.Unlock()
+		return n, nil
+	}
+	w.mu.Unlock()
+
+	if _, err := w.next.WriteLevel(level, p); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// Close closes the sinks beneath, flushing their async buffers. zerolog calls
+// it on Fatal before exiting, so the fatal line is not lost.
+func (w *dedupeWriter) Close() error {
+	if closer, ok := w.next.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// prune drops entries whose window has closed once too many are tracked. Must hold w.mu.
+func (w *dedupeWriter) prune(now time.Time) {
+	if len(w.entries) <= maxDedupeEntries {
+		return
+	}
+	for key, entry := range w.entries {
+		if now.Sub(entry.windowStart) >= w.window {
+			delete(w.entries, key)
+		}
+	}
+}
+
+// appendSuppressed adds a suppressed_duplicates field to a serialized JSON event
+func appendSuppressed(p []byte, suppressed int) []byte {
+	end := bytes.LastIndexByte(p, '}')
+	if end < 0 {
+		return p
+	}
+
+	out := make([]byte, 0, len(p)+32)
+	out = append(out, p[:end]...)
+	out = append(out, `,"suppressed_duplicates":`...)
+	out = strconv.AppendInt(out, int64(suppressed), 10)
+	out = append(out, p[end:]...)
+	return out
+}
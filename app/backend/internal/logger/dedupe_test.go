@@ -0,0 +1,66 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/diode"
+)
+
+// lockedBuffer is written by the diode's poller while the test reads it
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestDedupeWriterCloseFlushesAsyncSink(t *testing.T) {
+	var out lockedBuffer
+	// A poll interval far longer than the test keeps the line buffered until Close
+	async := diode.NewWriter(&out, 100, time.Hour, nil)
+	w := newDedupeWriter(zerolog.MultiLevelWriter(async), time.Minute, 5)
+
+	log := zerolog.New(w)
+	log.Error().Msg("fatal startup error")
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if !strings.Contains(out.String(), "fatal startup error") {
+		t.Fatalf("buffered line was not flushed by Close, got %q", out.String())
+	}
+}
+
+func TestDedupeWriterSuppressesBursts(t *testing.T) {
+	var out bytes.Buffer
+	w := newDedupeWriter(&out, time.Minute, 2)
+	log := zerolog.New(w)
+
+	for range 5 {
+		log.Error().Msg("database unreachable")
+	}
+	log.Info().Msg("not deduplicated")
+	log.Info().Msg("not deduplicated")
+
+	if got := strings.Count(out.String(), "database unreachable"); got != 2 {
+		t.Errorf("wrote %d error lines, want 2", got)
+	}
+	if got := strings.Count(out.String(), "not deduplicated"); got != 2 {
+		t.Errorf("wrote %d info lines, want 2", got)
+	}
+}
@@ -7,8 +7,8 @@ import (
 	"os"
 	"time"
 
+	"github.com/apk471/go-boilerplate/internal/buildinfo"
 	"github.com/apk471/go-boilerplate/internal/config"
-	"github.com/newrelic/go-agent/v3/integrations/logcontext-v2/zerologWriter"
 	"github.com/newrelic/go-agent/v3/newrelic"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/pkgerrors"
@@ -16,12 +16,16 @@ import (
 
 // LoggerService manages New Relic integration and logger creation
 type LoggerService struct {
-	nrApp *newrelic.Application
+	nrApp  *newrelic.Application
+	policy *LogPolicy
+	sinks  *sinkSet
 }
 
 // NewLoggerService creates a new logger service with New Relic integration
 func NewLoggerService(cfg *config.ObservabilityConfig) *LoggerService {
-	service := &LoggerService{}
+	service := &LoggerService{
+		policy: NewLogPolicy(&cfg.Logging),
+	}
 
 	if cfg.NewRelic.LicenseKey == "" {
 		return service
@@ -33,6 +37,13 @@ func NewLoggerService(cfg *config.ObservabilityConfig) *LoggerService {
 		newrelic.ConfigLicense(cfg.NewRelic.LicenseKey),
 		newrelic.ConfigAppLogForwardingEnabled(cfg.NewRelic.AppLogForwardingEnabled),
 		newrelic.ConfigDistributedTracerEnabled(cfg.NewRelic.DistributedTracingEnabled),
+		func(c *newrelic.Config) {
+			build := buildinfo.Get()
+			c.Labels = map[string]string{
+				"version": build.Version,
+				"commit":  build.ShortCommit(),
+			}
+		},
 	)
 
 	// Add debug logging only if explicitly enabled
@@ -49,8 +60,11 @@ func NewLoggerService(cfg *config.ObservabilityConfig) *LoggerService {
 	return service
 }
 
-// Shutdown shuts down New Relic
+// Shutdown flushes log sinks and shuts down New Relic
 func (ls *LoggerService) Shutdown() {
+	if ls.sinks != nil {
+		_ = ls.sinks.Close()
+	}
 	if ls.nrApp != nil {
 		ls.nrApp.Shutdown(10 * time.Second)
 	}
@@ -61,51 +75,63 @@ func (ls *LoggerService) GetApplication() *newrelic.Application {
 	return ls.nrApp
 }
 
+// DroppedLogs returns how many log lines async sinks dropped because their buffer was full
+func (ls *LoggerService) DroppedLogs() uint64 {
+	if ls == nil || ls.sinks == nil {
+		return 0
+	}
+	return ls.sinks.dropped.Load()
+}
 
-// NewLoggerWithService creates a logger with full config and logger service
-func NewLoggerWithService(cfg *config.ObservabilityConfig, loggerService *LoggerService) zerolog.Logger {
-	var logLevel zerolog.Level
-	level := cfg.GetLogLevel()
+// Policy returns the sampling and level override policy
+func (ls *LoggerService) Policy() *LogPolicy {
+	if ls == nil {
+		return nil
+	}
+	return ls.policy
+}
 
+// ParseLevel converts a configured level name to a zerolog level, defaulting to info
+func ParseLevel(level string) zerolog.Level {
 	switch level {
 	case "debug":
-		logLevel = zerolog.DebugLevel
+		return zerolog.DebugLevel
 	case "info":
-		logLevel = zerolog.InfoLevel
+		return zerolog.InfoLevel
 	case "warn":
-		logLevel = zerolog.WarnLevel
+		return zerolog.WarnLevel
 	case "error":
-		logLevel = zerolog.ErrorLevel
+		return zerolog.ErrorLevel
 	default:
-		logLevel = zerolog.InfoLevel
+		return zerolog.InfoLevel
 	}
+}
+
+
+// NewLoggerWithService creates a logger with full config and logger service
+func NewLoggerWithService(cfg *config.ObservabilityConfig, loggerService *LoggerService) zerolog.Logger {
+	logLevel := ParseLevel(cfg.GetLogLevel())
 
 	// Don't set global level - let each logger have its own level
 	zerolog.TimeFieldFormat = "2006-01-02 15:04:05"
 	zerolog.ErrorStackMarshaler = pkgerrors.MarshalStack
 
-	var writer io.Writer
-
-	// Setup base writer
-	var baseWriter io.Writer
-	if cfg.IsProduction() && cfg.Logging.Format == "json" {
-		// In production, write to stdout
-		baseWriter = os.Stdout
-
-		// Wrap with New Relic zerologWriter for log forwarding in production
-		if loggerService != nil && loggerService.nrApp != nil {
-			nrWriter := zerologWriter.New(baseWriter, loggerService.nrApp)
-			writer = nrWriter
-		} else {
-			writer = baseWriter
-		}
-	} else {
-		// Development mode - use console writer
-		consoleWriter := zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: "2006-01-02 15:04:05"}
-		writer = consoleWriter
+	// Fan out to the configured sinks, each honouring the configured format
+	sinks := newSinkSet(cfg, loggerService)
+	if loggerService != nil {
+		loggerService.sinks = sinks
+	}
+
+	var writer io.Writer = sinks.writer
+
+	// Note: New Relic log forwarding is handled by the zerologWriter wrapping the stdout sink
+
+	// Drop bursts of identical error lines before they reach stdout or New Relic
+	if cfg.Logging.Dedupe.Enabled {
+		writer = newDedupeWriter(writer, cfg.Logging.Dedupe.Window, cfg.Logging.Dedupe.Burst)
 	}
 
-	// Note: New Relic log forwarding is now handled automatically by zerologWriter integration
+	build := buildinfo.Get()
 
 	logger := zerolog.New(writer).
 		Level(logLevel).
@@ -113,6 +139,8 @@ func NewLoggerWithService(cfg *config.ObservabilityConfig, loggerService *Logger
 		Timestamp().
 		Str("service", cfg.ServiceName).
 		Str("environment", cfg.Environment).
+		Str("version", build.Version).
+		Str("commit", build.ShortCommit()).
 		Logger()
 
 	// Include stack traces for errors in development
@@ -0,0 +1,85 @@
+package logger
+
+import (
+	"sync/atomic"
+
+	"github.com/apk471/go-boilerplate/internal/config"
+	"github.com/rs/zerolog"
+)
+
+// LogPolicy applies per-route and per-component level overrides and route sampling
+type LogPolicy struct {
+	routeLevels     map[string]zerolog.Level
+	componentLevels map[string]zerolog.Level
+	samplers        map[string]*routeSampler
+}
+
+type routeSampler struct {
+	rate    uint64
+	counter atomic.Uint64
+}
+
+// NewLogPolicy builds a policy from validated logging config
+func NewLogPolicy(cfg *config.LoggingConfig) *LogPolicy {
+	policy := &LogPolicy{
+		routeLevels:     make(map[string]zerolog.Level),
+		componentLevels: make(map[string]zerolog.Level),
+		samplers:        make(map[string]*routeSampler),
+	}
+
+	for route, level := range cfg.RouteLevelOverrides() {
+		policy.routeLevels[route] = ParseLevel(level)
+	}
+
+	for component, level := range cfg.ComponentLevelOverrides() {
+		policy.componentLevels[component] = ParseLevel(level)
+	}
+
+	rates, _ := cfg.RouteSampleRates()
+	for route, rate := range rates {
+		policy.samplers[route] = &routeSampler{rate: uint64(rate)}
+	}
+
+	return policy
+}
+
+// RouteLogger returns the logger to use for a single request on route.
+// Requests that are sampled out are raised to warn level, so successful
+// requests are dropped while warnings and errors are still logged.
+func (p *LogPolicy) RouteLogger(logger zerolog.Logger, route string) zerolog.Logger {
+	if p == nil {
+		return logger
+	}
+
+	if level, ok := p.routeLevels[route]; ok {
+		logger = logger.Level(level)
+	}
+
+	if sampler, ok := p.samplers[route]; ok && !sampler.sample() && logger.GetLevel() < zerolog.WarnLevel {
+		logger = logger.Level(zerolog.WarnLevel)
+	}
+
+	return logger
+}
+
+// ComponentLogger tags logger with the component name and applies its level override
+func (p *LogPolicy) ComponentLogger(logger zerolog.Logger, component string) zerolog.Logger {
+	logger = logger.With().Str("component", component).Logger()
+	return logger.Level(p.ComponentLevel(component, logger.GetLevel()))
+}
+
+// ComponentLevel returns the override for component, or fallback if there is none
+func (p *LogPolicy) ComponentLevel(component string, fallback zerolog.Level) zerolog.Level {
+	if p == nil {
+		return fallback
+	}
+	if level, ok := p.componentLevels[component]; ok {
+		return level
+	}
+	return fallback
+}
+
+// sample reports whether this request is one of the 1 in rate that get logged
+func (s *routeSampler) sample() bool {
+	return (s.counter.Add(1)-1)%s.rate == 0
+}
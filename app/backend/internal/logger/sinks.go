@@ -0,0 +1,166 @@
+package logger
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/apk471/go-boilerplate/internal/config"
+	"github.com/newrelic/go-agent/v3/integrations/logcontext-v2/zerologWriter"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/diode"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+const networkDialTimeout = 2 * time.Second
+
+// sinkSet is the fan-out writer built from the configured sinks
+type sinkSet struct {
+	writer  zerolog.LevelWriter
+	closers []io.Closer
+	dropped *atomic.Uint64
+}
+
+// newSinkSet builds a writer for every enabled sink, each filtered by its own minimum level
+func newSinkSet(cfg *config.ObservabilityConfig, loggerService *LoggerService) *sinkSet {
+	sinks := cfg.Logging.Sinks
+	set := &sinkSet{dropped: &atomic.Uint64{}}
+
+	if !sinks.Stdout.Enabled && !sinks.File.Enabled && !sinks.Network.Enabled {
+		sinks.Stdout = config.LogSinkConfig{Enabled: true}
+	}
+
+	var writers []io.Writer
+
+	if sinks.Stdout.Enabled {
+		// Hide os.Stdout's Close so shutting the sink down never closes the process stdout
+		var out io.Writer = struct{ io.Writer }{os.Stdout}
+		format := sinkFormat(cfg, sinks.Stdout)
+
+		// Wrap with New Relic zerologWriter for log forwarding, which needs JSON input
+		if format == "json" && loggerService != nil && loggerService.nrApp != nil {
+			out = zerologWriter.New(out, loggerService.nrApp)
+		}
+
+		writers = append(writers, set.add(cfg, sinks.Stdout, out, format, loggerService))
+	}
+
+	if sinks.File.Enabled {
+		out := &lumberjack.Logger{
+			Filename:   sinks.File.Path,
+			MaxSize:    sinks.File.MaxSizeMB,
+			MaxAge:     sinks.File.MaxAgeDays,
+			MaxBackups: sinks.File.MaxBackups,
+			Compress:   sinks.File.Compress,
+		}
+		writers = append(writers, set.add(cfg, sinks.File.LogSinkConfig, out, sinkFormat(cfg, sinks.File.LogSinkConfig), loggerService))
+	}
+
+	if sinks.Network.Enabled {
+		out := &networkWriter{network: sinks.Network.Network, address: sinks.Network.Address}
+		writers = append(writers, set.add(cfg, sinks.Network.LogSinkConfig, out, sinkFormat(cfg, sinks.Network.LogSinkConfig), loggerService))
+	}
+
+	set.writer = zerolog.MultiLevelWriter(writers...)
+	return set
+}
+
+// add wraps out with formatting, async buffering and level filtering for a single sink
+func (s *sinkSet) add(
+	cfg *config.ObservabilityConfig,
+	sink config.LogSinkConfig,
+	out io.Writer,
+	format string,
+	loggerService *LoggerService,
+) io.Writer {
+	if format == "console" {
+		out = zerolog.ConsoleWriter{Out: out, TimeFormat: "2006-01-02 15:04:05"}
+	}
+
+	if cfg.Logging.Sinks.Async.Enabled {
+		async := diode.NewWriter(out, cfg.Logging.Sinks.Async.BufferSize, 0, func(missed int) {
+			s.dropped.Add(uint64(missed))
+			if loggerService != nil && loggerService.nrApp != nil {
+				loggerService.nrApp.RecordCustomMetric("Logging/Dropped", float64(missed))
+			}
+		})
+		out = async
+	}
+
+	// Closing the outermost writer flushes async buffers and closes the sink beneath
+	if closer, ok := out.(io.Closer); ok {
+		s.closers = append(s.closers, closer)
+	}
+
+	if sink.MinLevel == "" {
+		return out
+	}
+
+	return &zerolog.FilteredLevelWriter{
+		Writer: zerolog.LevelWriterAdapter{Writer: out},
+		Level:  ParseLevel(sink.MinLevel),
+	}
+}
+
+// Close flushes async buffers and closes file and network sinks
+func (s *sinkSet) Close() error {
+	var errs []error
+	for _, closer := range s.closers {
+		if err := closer.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func sinkFormat(cfg *config.ObservabilityConfig, sink config.LogSinkConfig) string {
+	if sink.Format != "" {
+		return sink.Format
+	}
+	return cfg.Logging.Format
+}
+
+// networkWriter writes each log line to a tcp or udp endpoint, reconnecting after failures
+type networkWriter struct {
+	network string
+	address string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+func (w *networkWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.conn == nil {
+		conn, err := net.DialTimeout(w.network, w.address, networkDialTimeout)
+		if err != nil {
+			return 0, err
+		}
+		w.conn = conn
+	}
+
+	n, err := w.conn.Write(p)
+	if err != nil {
+		_ = w.conn.Close()
+		w.conn = nil
+	}
+	return n, err
+}
+
+func (w *networkWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
@@ -1,25 +1,30 @@
 package middleware
 
 import (
+	"crypto/subtle"
 	"encoding/json"
 	"net/http"
 	"time"
 
 	"github.com/apk471/go-boilerplate/internal/errs"
 	"github.com/apk471/go-boilerplate/internal/server"
+	"github.com/apk471/go-boilerplate/internal/service"
 	"github.com/clerk/clerk-sdk-go/v2"
 	clerkhttp "github.com/clerk/clerk-sdk-go/v2/http"
 	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
 )
 
 
 type AuthMiddleware struct {
 	server *server.Server
+	users  *service.UserService
 }
 
-func NewAuthMiddleware(s *server.Server) *AuthMiddleware {
+func NewAuthMiddleware(s *server.Server, users *service.UserService) *AuthMiddleware {
 	return &AuthMiddleware{
 		server: s,
+		users:  users,
 	}
 }
 
@@ -64,6 +69,23 @@ func (auth *AuthMiddleware) RequireAuth(next echo.HandlerFunc) echo.HandlerFunc
 		c.Set("user_role", claims.ActiveOrganizationRole)
 		c.Set("permissions", claims.Claims.ActiveOrganizationPermissions)
 
+		// EnhanceContext runs before auth, so add the user to the request logger here
+		addLoggerFields(c, func(ctx zerolog.Context) zerolog.Context {
+			ctx = ctx.Str("user_id", claims.Subject)
+			if claims.ActiveOrganizationRole != "" {
+				ctx = ctx.Str("user_role", claims.ActiveOrganizationRole)
+			}
+			return ctx
+		})
+
+		// The local user is created on the first authenticated request, and
+		// handlers and services read it from the context from then on
+		user, err := auth.users.Load(c.Request().Context(), claims.Subject)
+		if err != nil {
+			return err
+		}
+		SetUser(c, user)
+
 		auth.server.Logger.Info().
 			Str("function", "RequireAuth").
 			Str("user_id", claims.Subject).
@@ -73,4 +95,27 @@ func (auth *AuthMiddleware) RequireAuth(next echo.HandlerFunc) echo.HandlerFunc
 
 		return next(c)
 	})
+}
+
+// RequireServiceToken admits requests whose x-service-token header matches
+// the configured service token, for operational endpoints. They are not
+// found while no token is configured.
+func (auth *AuthMiddleware) RequireServiceToken(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		expected := auth.server.Config.Auth.ServiceToken
+		if expected == "" {
+			return errs.NewNotFoundError("Route not found", false, nil)
+		}
+
+		token := c.Request().Header.Get("x-service-token")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+			auth.server.Logger.Warn().
+				Str("function", "RequireServiceToken").
+				Str("request_id", GetRequestID(c)).
+				Msg("invalid service token")
+			return errs.NewUnauthorizedError("Unauthorized", false)
+		}
+
+		return next(c)
+	}
 }
\ No newline at end of file
@@ -1,10 +1,10 @@
 package middleware
 
 import (
-	"context"
-
 	"github.com/apk471/go-boilerplate/internal/logger"
+	"github.com/apk471/go-boilerplate/internal/model"
 	"github.com/apk471/go-boilerplate/internal/server"
+	"github.com/apk471/go-boilerplate/internal/service"
 	"github.com/labstack/echo/v4"
 	"github.com/newrelic/go-agent/v3/newrelic"
 	"github.com/rs/zerolog"
@@ -13,6 +13,7 @@ import (
 const (
 	UserIDKey   = "user_id"
 	UserRoleKey = "user_role"
+	UserKey     = "user"
 	LoggerKey   = "logger"
 )
 
@@ -38,6 +39,9 @@ func (ce *ContextEnhancer) EnhanceContext() echo.MiddlewareFunc {
 				Str("ip", c.RealIP()).
 				Logger()
 
+			// Apply per-route level overrides and sampling
+			contextLogger = ce.server.LoggerService.Policy().RouteLogger(contextLogger, c.Path())
+
 			// Add trace context if available
 			if txn := newrelic.FromContext(c.Request().Context()); txn != nil {
 				contextLogger = logger.WithTraceContext(contextLogger, txn)
@@ -52,12 +56,7 @@ func (ce *ContextEnhancer) EnhanceContext() echo.MiddlewareFunc {
 				contextLogger = contextLogger.With().Str("user_role", userRole).Logger()
 			}
 
-			// Store the enhanced logger in context
-			c.Set(LoggerKey, &contextLogger)
-
-			// Create a new context with the logger
-			ctx := context.WithValue(c.Request().Context(), LoggerKey, &contextLogger)
-			c.SetRequest(c.Request().WithContext(ctx))
+			setLogger(c, &contextLogger)
 
 			return next(c)
 		}
@@ -87,11 +86,39 @@ func GetUserID(c echo.Context) string {
 	return ""
 }
 
+// GetUser returns the local user of the authenticated caller, nil on routes
+// without RequireAuth
+func GetUser(c echo.Context) *model.User {
+	if user, ok := c.Get(UserKey).(*model.User); ok {
+		return user
+	}
+	return nil
+}
+
+// SetUser stores the caller on the echo context and in the request context,
+// where UserService.GetOrCreate finds it
+func SetUser(c echo.Context, user *model.User) {
+	c.Set(UserKey, user)
+	c.SetRequest(c.Request().WithContext(service.WithUser(c.Request().Context(), user)))
+}
+
 func GetLogger(c echo.Context) *zerolog.Logger {
 	if logger, ok := c.Get(LoggerKey).(*zerolog.Logger); ok {
 		return logger
 	}
-	// Fallback to a basic logger if not found
-	logger := zerolog.Nop()
-	return &logger
+	// Fallback to the request context, then the default logger
+	return logger.FromContext(c.Request().Context())
+}
+
+// setLogger stores the logger on the echo context and in the request context,
+// so layers below handlers can get it through logger.FromContext
+func setLogger(c echo.Context, l *zerolog.Logger) {
+	c.Set(LoggerKey, l)
+	c.SetRequest(c.Request().WithContext(logger.WithContext(c.Request().Context(), l)))
+}
+
+// addLoggerFields enriches the request logger with fields known only after EnhanceContext ran
+func addLoggerFields(c echo.Context, fields func(zerolog.Context) zerolog.Context) {
+	l := fields(GetLogger(c).With()).Logger()
+	setLogger(c, &l)
 }
\ No newline at end of file
@@ -2,9 +2,15 @@ package middleware
 
 import (
 	"errors"
+	"math"
 	"net/http"
+	"strconv"
 
+	"github.com/apk471/go-boilerplate/internal/database"
 	"github.com/apk471/go-boilerplate/internal/errs"
+	"github.com/apk471/go-boilerplate/internal/lib/httpclient"
+	"github.com/apk471/go-boilerplate/internal/lib/resilience"
+	"github.com/apk471/go-boilerplate/internal/lib/timing"
 	"github.com/apk471/go-boilerplate/internal/server"
 	"github.com/apk471/go-boilerplate/internal/sqlerr"
 	"github.com/labstack/echo/v4"
@@ -30,7 +36,6 @@ func (global *GlobalMiddlewares) CORS() echo.MiddlewareFunc {
 
 func (global *GlobalMiddlewares) RequestLogger() echo.MiddlewareFunc {
 	return middleware.RequestLoggerWithConfig(middleware.RequestLoggerConfig{
-		LogURI:     true,
 		LogStatus:  true,
 		LogError:   true,
 		LogLatency: true,
@@ -76,11 +81,26 @@ func (global *GlobalMiddlewares) RequestLogger() echo.MiddlewareFunc {
 				e = e.Str("user_id", userID)
 			}
 
+			// Add the request's database queries, see CollectQueryStats
+			if stats, ok := database.QueryStatsFromContext(c.Request().Context()); ok {
+				summary := stats.Summary()
+				e = e.Int("db_queries", summary.Queries).Dur("db_duration", summary.Duration)
+			}
+
+			// Add the time spent per layer, see CollectTimings
+			if spans := timing.FromContext(c.Request().Context()).Spans(); len(spans) > 0 {
+				timings := zerolog.Dict()
+				for _, span := range spans {
+					timings = timings.Dur(span.Name, span.Duration)
+				}
+				e = e.Dict("timings", timings)
+			}
+
 			e.
 				Dur("latency", v.Latency).
 				Int("status", statusCode).
 				Str("method", v.Method).
-				Str("uri", v.URI).
+				Str("uri", redactedURI(c)).
 				Str("host", v.Host).
 				Str("ip", c.RealIP()).
 				Str("user_agent", c.Request().UserAgent()).
@@ -91,6 +111,13 @@ func (global *GlobalMiddlewares) RequestLogger() echo.MiddlewareFunc {
 	})
 }
 
+// redactedURI returns the request URI with secret query parameters, such as
+// the data export download token, replaced. New Relic's request.uri attribute
+// already leaves out the query string.
+func redactedURI(c echo.Context) string {
+	return httpclient.RedactURL(c.Request().URL)
+}
+
 func (global *GlobalMiddlewares) Recover() echo.MiddlewareFunc {
 	return middleware.Recover()
 }
@@ -108,10 +135,19 @@ func (global *GlobalMiddlewares) GlobalErrorHandler(err error, c echo.Context) {
 	var httpErr *errs.HTTPError
 	if !errors.As(err, &httpErr) {
 		var echoErr *echo.HTTPError
+		var rejected *resilience.RejectedError
 		if errors.As(err, &echoErr) {
 			if echoErr.Code == http.StatusNotFound {
 				err = errs.NewNotFoundError("Route not found", false, nil)
 			}
+		} else if errors.As(err, &rejected) {
+			// A dependency's circuit is open or its bulkhead full, see
+			// internal/lib/resilience
+			if rejected.RetryAfter > 0 {
+				c.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(rejected.RetryAfter.Seconds()))))
+			}
+			code := "DEPENDENCY_UNAVAILABLE"
+			err = errs.NewServiceUnavailableError("The service is temporarily unavailable, retry later", true, &code)
 		} else {
 			// Here we call our sqlerr handler which will convert database errors
 			// to appropriate application errors
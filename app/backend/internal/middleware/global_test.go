@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apk471/go-boilerplate/internal/errs"
+	"github.com/apk471/go-boilerplate/internal/lib/resilience"
+	"github.com/apk471/go-boilerplate/internal/testutil"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/labstack/echo/v4"
+)
+
+func TestRedactedURIHidesExportToken(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/privacy/exports/download?token=secret-token&format=zip", nil)
+	c := e.NewContext(req, httptest.NewRecorder())
+
+	uri := redactedURI(c)
+	if strings.Contains(uri, "secret-token") {
+		t.Fatalf("uri %q contains the download token", uri)
+	}
+	if !strings.HasPrefix(uri, "/api/v1/privacy/exports/download?") || !strings.Contains(uri, "format=zip") {
+		t.Errorf("uri = %q, want the path and the other query parameters kept", uri)
+	}
+}
+
+func TestGlobalErrorHandlerShapes(t *testing.T) {
+	missing := "ORGANIZATION_NOT_FOUND"
+
+	tests := []struct {
+		name string
+		err  error
+		opts []testutil.GoldenOption
+	}{
+		{
+			name: "validation",
+			err: errs.NewBadRequestError("Validation failed", true, nil,
+				[]errs.FieldError{{Field: "name", Error: "is required"}}, nil),
+		},
+		{
+			name: "not_found",
+			err:  errs.NewNotFoundError("Organization not found", true, &missing),
+		},
+		{
+			name: "route_not_found",
+			err:  echo.ErrNotFound,
+		},
+		{
+			name: "echo_http_error",
+			err:  echo.NewHTTPError(http.StatusUnsupportedMediaType),
+		},
+		{
+			name: "echo_http_error_message",
+			err:  echo.NewHTTPError(http.StatusBadRequest, "Syntax error: unexpected data after the JSON value"),
+		},
+		{
+			name: "dependency_unavailable",
+			err:  &resilience.RejectedError{Guard: resilience.Postgres, RetryAfter: 1500 * time.Millisecond},
+			opts: []testutil.GoldenOption{testutil.WithHeaders("Content-Type", "Retry-After")},
+		},
+		{
+			name: "no_rows",
+			err:  fmt.Errorf("failed to collect row from table:organizations: %w", pgx.ErrNoRows),
+		},
+		{
+			name: "unique_violation",
+			err: &pgconn.PgError{
+				Code:           "23505",
+				Message:        `duplicate key value violates unique constraint "unique_organizations_slug"`,
+				TableName:      "organizations",
+				ConstraintName: "unique_organizations_slug",
+			},
+		},
+		{
+			name: "internal",
+			err:  errors.New("connection reset by peer"),
+		},
+	}
+
+	global := NewGlobalMiddlewares(nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.HTTPErrorHandler = global.GlobalErrorHandler
+			e.GET("/", func(c echo.Context) error { return tt.err })
+
+			rec := testutil.Record(e, httptest.NewRequest(http.MethodGet, "/", nil))
+			testutil.AssertGolden(t, "error_"+tt.name, rec, tt.opts...)
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/apk471/go-boilerplate/internal/errs"
+	"github.com/apk471/go-boilerplate/internal/lib/resilience"
+	"github.com/apk471/go-boilerplate/internal/server"
+	"github.com/labstack/echo/v4"
+	echoMiddleware "github.com/labstack/echo/v4/middleware"
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+// Route classes with their own in-flight limit
+const (
+	RouteClassRead  = "read"
+	RouteClassWrite = "write"
+	RouteClassBatch = "batch"
+)
+
+// LoadShedMiddleware rejects requests with 503 while the server has more in
+// flight than it can handle, see resilience.Limiter
+type LoadShedMiddleware struct {
+	server *server.Server
+	nrApp  *newrelic.Application
+
+	global  *resilience.Limiter
+	classes map[string]*resilience.Limiter
+}
+
+func NewLoadShedMiddleware(s *server.Server, nrApp *newrelic.Application) *LoadShedMiddleware {
+	cfg := s.Config.LoadShed
+
+	return &LoadShedMiddleware{
+		server: s,
+		nrApp:  nrApp,
+		global: resilience.NewLimiter(cfg.MinInFlight, cfg.MaxInFlight, cfg.TargetLatency),
+		classes: map[string]*resilience.Limiter{
+			RouteClassRead:  resilience.NewLimiter(cfg.MinInFlight, cfg.ReadMaxInFlight, cfg.TargetLatency),
+			RouteClassWrite: resilience.NewLimiter(cfg.MinInFlight, cfg.WriteMaxInFlight, cfg.TargetLatency),
+			RouteClassBatch: resilience.NewLimiter(cfg.MinInFlight, cfg.BatchMaxInFlight, cfg.TargetLatency),
+		},
+	}
+}
+
+// Shed admits a request when both the global limit and the limit of its
+// route class have room. Health checks are always admitted, and requests
+// without credentials may only use part of each limit. Requests skipper
+// accepts, such as batch sub-requests admitted with their batch, pass.
+func (ls *LoadShedMiddleware) Shed(skipper echoMiddleware.Skipper) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		if ls.server.Config.LoadShed.MaxInFlight <= 0 {
+			return next
+		}
+
+		return func(c echo.Context) error {
+			if skipper(c) || isHealthCheck(c) {
+				return next(c)
+			}
+
+			share := 1.0
+			if !hasCredentials(c.Request()) {
+				share = float64(ls.server.Config.LoadShed.AnonymousShare) / 100
+			}
+
+			global, ok := ls.global.Acquire(share)
+			if !ok {
+				return ls.reject(c, "global", ls.global)
+			}
+
+			class := routeClass(c)
+			limiter := ls.classes[class]
+			permit, ok := limiter.Acquire(share)
+			if !ok {
+				// The rejection is near instant, which must not read as a
+				// fast request and raise the global limit
+				global.Abandon()
+				return ls.reject(c, class, limiter)
+			}
+			defer global.Release()
+			defer permit.Release()
+
+			return next(c)
+		}
+	}
+}
+
+func (ls *LoadShedMiddleware) reject(c echo.Context, class string, limiter *resilience.Limiter) error {
+	limit := limiter.Limit()
+
+	GetLogger(c).Warn().
+		Str("class", class).
+		Int("limit", limit).
+		Int("in_flight", limiter.InFlight()).
+		Bool("authenticated", hasCredentials(c.Request())).
+		Msg("request shed")
+
+	if ls.nrApp != nil {
+		ls.nrApp.RecordCustomMetric("LoadShed/"+class+"/Shed", 1)
+		ls.nrApp.RecordCustomMetric("LoadShed/"+class+"/Limit", float64(limit))
+	}
+
+	retryAfter := ls.server.Config.LoadShed.RetryAfter
+	c.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+
+	code := "OVERLOADED"
+	return errs.NewServiceUnavailableError("The server is overloaded, retry later", true, &code)
+}
+
+func isHealthCheck(c echo.Context) bool {
+	return c.Path() == "/status" || c.Path() == "/version"
+}
+
+// hasCredentials reports whether the request carries credentials. They are
+// verified later by the auth middleware, so forged credentials only gain
+// priority, not access.
+func hasCredentials(r *http.Request) bool {
+	return strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") || r.Header.Get("x-service-token") != ""
+}
+
+func routeClass(c echo.Context) string {
+	switch {
+	case strings.HasSuffix(c.Path(), "/batch"):
+		return RouteClassBatch
+	case c.Request().Method == http.MethodGet || c.Request().Method == http.MethodHead ||
+		c.Request().Method == http.MethodOptions:
+		return RouteClassRead
+	default:
+		return RouteClassWrite
+	}
+}
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"github.com/apk471/go-boilerplate/internal/server"
+	"github.com/apk471/go-boilerplate/internal/service"
 	"github.com/newrelic/go-agent/v3/newrelic"
 )
 
@@ -11,9 +12,12 @@ type Middlewares struct {
 	ContextEnhancer *ContextEnhancer
 	Tracing         *TracingMiddleware
 	RateLimit       *RateLimitMiddleware
+	LoadShed        *LoadShedMiddleware
+	QueryStats      *QueryStatsMiddleware
+	Timing          *TimingMiddleware
 }
 
-func NewMiddlewares(s *server.Server) *Middlewares {
+func NewMiddlewares(s *server.Server, services *service.Services) *Middlewares {
 	// Get New Relic application instance from server
 	var nrApp *newrelic.Application
 	if s.LoggerService != nil {
@@ -22,9 +26,12 @@ func NewMiddlewares(s *server.Server) *Middlewares {
 
 	return &Middlewares{
 		Global:          NewGlobalMiddlewares(s),
-		Auth:            NewAuthMiddleware(s),
+		Auth:            NewAuthMiddleware(s, services.User),
 		ContextEnhancer: NewContextEnhancer(s),
 		Tracing:         NewTracingMiddleware(s, nrApp),
 		RateLimit:       NewRateLimitMiddleware(s),
+		LoadShed:        NewLoadShedMiddleware(s, nrApp),
+		QueryStats:      NewQueryStatsMiddleware(s),
+		Timing:          NewTimingMiddleware(s),
 	}
 }
\ No newline at end of file
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"github.com/apk471/go-boilerplate/internal/database"
+	"github.com/apk471/go-boilerplate/internal/server"
+	"github.com/labstack/echo/v4"
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+// maxLoggedStatementLength bounds the repeated statement in warnings
+const maxLoggedStatementLength = 500
+
+type QueryStatsMiddleware struct {
+	server *server.Server
+}
+
+func NewQueryStatsMiddleware(s *server.Server) *QueryStatsMiddleware {
+	return &QueryStatsMiddleware{
+		server: s,
+	}
+}
+
+// CollectQueryStats counts the database queries of each request, see
+// database.WithQueryStats. The numbers are added to the New Relic
+// transaction, and the db span of the Server-Timing header shows their time.
+// A request running more queries or repeating a statement more often than
+// configured is logged as a likely N+1.
+func (qs *QueryStatsMiddleware) CollectQueryStats() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ctx, stats := database.WithQueryStats(c.Request().Context())
+			c.SetRequest(c.Request().WithContext(ctx))
+
+			err := next(c)
+
+			summary := stats.Summary()
+			if txn := newrelic.FromContext(ctx); txn != nil {
+				txn.AddAttribute("db.queries", summary.Queries)
+				txn.AddAttribute("db.duration_ms", summary.Duration.Milliseconds())
+				txn.AddAttribute("db.max_repeats", summary.Repeats)
+			}
+
+			cfg := qs.server.Config.Observability.QueryStats
+			if summary.Queries > cfg.MaxQueries || summary.Repeats > cfg.MaxRepeats {
+				statement := summary.Statement
+				if len(statement) > maxLoggedStatementLength {
+					statement = statement[:maxLoggedStatementLength] + "…"
+				}
+				GetLogger(c).Warn().
+					Int("db_queries", summary.Queries).
+					Dur("db_duration", summary.Duration).
+					Int("db_repeats", summary.Repeats).
+					Str("db_repeated_statement", statement).
+					Msg("request ran too many queries, likely an N+1")
+			}
+
+			return err
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/apk471/go-boilerplate/internal/lib/timing"
+	"github.com/apk471/go-boilerplate/internal/server"
+	"github.com/labstack/echo/v4"
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+type TimingMiddleware struct {
+	server *server.Server
+}
+
+func NewTimingMiddleware(s *server.Server) *TimingMiddleware {
+	return &TimingMiddleware{
+		server: s,
+	}
+}
+
+// CollectTimings puts a timing.Timings in the request context, which the
+// database, Redis, cache, outbound HTTP and handler layers add spans to.
+// With observability.server_timing the spans and the total are sent in a
+// Server-Timing header for browser devtools. They are also added to the
+// request log and the New Relic transaction.
+func (tm *TimingMiddleware) CollectTimings() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			ctx, timings := timing.WithTimings(c.Request().Context())
+			c.SetRequest(c.Request().WithContext(ctx))
+
+			if tm.server.Config.Observability.ServerTiming {
+				// Headers are sent with the first write, so spans of work
+				// after it, such as streamed responses, are not included
+				c.Response().Before(func() {
+					spans := append(timings.Spans(), timing.Span{Name: "total", Duration: time.Since(start), Count: 1})
+					c.Response().Header().Set("Server-Timing", timing.Header(spans))
+				})
+			}
+
+			err := next(c)
+
+			if txn := newrelic.FromContext(ctx); txn != nil {
+				for _, span := range timings.Spans() {
+					txn.AddAttribute("timing."+span.Name+".duration_ms", span.Duration.Milliseconds())
+				}
+			}
+
+			return err
+		}
+	}
+}
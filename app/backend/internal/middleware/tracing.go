@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"github.com/apk471/go-boilerplate/internal/buildinfo"
 	"github.com/apk471/go-boilerplate/internal/server"
 	"github.com/labstack/echo/v4"
 	"github.com/newrelic/go-agent/v3/integrations/nrecho-v4"
@@ -46,6 +47,10 @@ func (tm *TracingMiddleware) EnhanceTracing() echo.MiddlewareFunc {
 			txn.AddAttribute("http.real_ip", c.RealIP())
 			txn.AddAttribute("http.user_agent", c.Request().UserAgent())
 
+			build := buildinfo.Get()
+			txn.AddAttribute("service.version", build.Version)
+			txn.AddAttribute("service.commit", build.ShortCommit())
+
 			// Add request ID if available
 			if requestID := GetRequestID(c); requestID != "" {
 				txn.AddAttribute("request.id", requestID)
@@ -1,6 +1,8 @@
 package model
 
 import (
+	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/google/uuid"
@@ -18,6 +20,21 @@ type BaseWithUpdatedAt struct {
 	UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
 }
 
+// Version identifies the state of a resource for ETag and If-Match. Every
+// update sets updated_at, so it changes with each write.
+func (b BaseWithUpdatedAt) Version() string {
+	return strconv.FormatInt(b.UpdatedAt.UnixMicro(), 10)
+}
+
+// VersionTime returns the updated_at a Version was taken from
+func VersionTime(version string) (time.Time, error) {
+	micros, err := strconv.ParseInt(version, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid resource version %q: %w", version, err)
+	}
+	return time.UnixMicro(micros), nil
+}
+
 type Base struct {
 	BaseWithId
 	BaseWithCreatedAt
@@ -30,4 +47,9 @@ type PaginatedResponse[T interface{}] struct {
 	Limit      int `json:"limit"`
 	Total      int `json:"total"`
 	TotalPages int `json:"totalPages"`
+}
+
+// ItemsField points ?fields= and ?expand= at the paginated items
+func (PaginatedResponse[T]) ItemsField() string {
+	return "data"
 }
\ No newline at end of file
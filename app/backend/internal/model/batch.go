@@ -0,0 +1,47 @@
+package model
+
+import (
+	"encoding/json"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// BatchRequest is one sub-request of a batch, dispatched as if it had been
+// sent on its own
+type BatchRequest struct {
+	// ID is echoed in the result so clients can match results to requests
+	ID     string `json:"id" validate:"omitempty,max=64"`
+	Method string `json:"method" validate:"required,oneof=GET POST PUT PATCH DELETE"`
+	// Path is the API path including the query string, e.g. /api/v1/me
+	Path    string            `json:"path" validate:"required,startswith=/api/v1/,max=2048"`
+	Headers map[string]string `json:"headers"`
+	Body    json.RawMessage   `json:"body"`
+}
+
+type BatchResult struct {
+	ID     string          `json:"id,omitempty"`
+	Status int             `json:"status"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+type BatchResponse struct {
+	Atomic bool `json:"atomic"`
+	// RolledBack is set when a sub-request of an atomic batch failed and the
+	// changes of every sub-request were rolled back
+	RolledBack bool          `json:"rolledBack"`
+	Results    []BatchResult `json:"results"`
+}
+
+// ------------------------------------------------------------
+
+type ExecuteBatchPayload struct {
+	// Atomic runs every sub-request in one database transaction, committed
+	// only if all of them succeed
+	Atomic   bool           `json:"atomic"`
+	Requests []BatchRequest `json:"requests" validate:"required,min=1,dive"`
+}
+
+func (p *ExecuteBatchPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
@@ -0,0 +1,79 @@
+package model
+
+import (
+	"encoding/json"
+
+	"github.com/google/uuid"
+)
+
+// Domain event types. Organization events are also delivered to the
+// organization's webhooks under the same names.
+const (
+	EventOrganizationCreated           = "organization.created"
+	EventOrganizationMemberJoined      = "organization.member.joined"
+	EventOrganizationMemberRoleChanged = "organization.member.role_changed"
+	EventOrganizationMemberRemoved     = "organization.member.removed"
+)
+
+type OrganizationCreatedEvent struct {
+	OrganizationID uuid.UUID `json:"organizationId"`
+	Name           string    `json:"name"`
+	Slug           string    `json:"slug"`
+	CreatedBy      uuid.UUID `json:"createdBy"`
+}
+
+func (OrganizationCreatedEvent) EventType() string {
+	return EventOrganizationCreated
+}
+
+type OrganizationMemberJoinedEvent struct {
+	OrganizationID uuid.UUID        `json:"organizationId"`
+	UserID         uuid.UUID        `json:"userId"`
+	Role           OrganizationRole `json:"role"`
+	InvitationID   *uuid.UUID       `json:"invitationId"`
+}
+
+func (OrganizationMemberJoinedEvent) EventType() string {
+	return EventOrganizationMemberJoined
+}
+
+type OrganizationMemberRoleChangedEvent struct {
+	OrganizationID uuid.UUID        `json:"organizationId"`
+	UserID         uuid.UUID        `json:"userId"`
+	PreviousRole   OrganizationRole `json:"previousRole"`
+	Role           OrganizationRole `json:"role"`
+	ChangedBy      uuid.UUID        `json:"changedBy"`
+}
+
+func (OrganizationMemberRoleChangedEvent) EventType() string {
+	return EventOrganizationMemberRoleChanged
+}
+
+type OrganizationMemberRemovedEvent struct {
+	OrganizationID uuid.UUID        `json:"organizationId"`
+	UserID         uuid.UUID        `json:"userId"`
+	Role           OrganizationRole `json:"role"`
+	RemovedBy      uuid.UUID        `json:"removedBy"`
+}
+
+func (OrganizationMemberRemovedEvent) EventType() string {
+	return EventOrganizationMemberRemoved
+}
+
+const EventNotificationCreated = "notification.created"
+
+// NotificationCreatedEvent is published for notifications whose recipient
+// enabled the webhook channel, and delivered to the organization's webhooks
+type NotificationCreatedEvent struct {
+	NotificationID uuid.UUID        `json:"notificationId"`
+	UserID         uuid.UUID        `json:"userId"`
+	OrganizationID uuid.UUID        `json:"organizationId"`
+	Type           NotificationType `json:"type"`
+	Title          string           `json:"title"`
+	Body           string           `json:"body"`
+	Data           json.RawMessage  `json:"data"`
+}
+
+func (NotificationCreatedEvent) EventType() string {
+	return EventNotificationCreated
+}
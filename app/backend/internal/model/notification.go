@@ -0,0 +1,228 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/apk471/go-boilerplate/internal/validation"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+type NotificationType string
+
+const (
+	// Sent to owners and admins when someone joins their organization
+	NotificationTypeOrganizationMemberJoined NotificationType = "organization.member_joined"
+	// Sent to a member whose role was changed by someone else
+	NotificationTypeOrganizationRoleChanged NotificationType = "organization.role_changed"
+	// Sent to a member removed by someone else
+	NotificationTypeOrganizationRemoved NotificationType = "organization.removed"
+)
+
+// NotificationTypes lists every type users can set preferences for
+var NotificationTypes = []NotificationType{
+	NotificationTypeOrganizationMemberJoined,
+	NotificationTypeOrganizationRoleChanged,
+	NotificationTypeOrganizationRemoved,
+}
+
+type NotificationEmailStatus string
+
+const (
+	NotificationEmailPending NotificationEmailStatus = "pending"
+	NotificationEmailDigest  NotificationEmailStatus = "digest"
+	NotificationEmailSent    NotificationEmailStatus = "sent"
+	NotificationEmailSkipped NotificationEmailStatus = "skipped"
+	NotificationEmailFailed  NotificationEmailStatus = "failed"
+)
+
+type Notification struct {
+	Base
+	UserID         uuid.UUID                `json:"userId" db:"user_id"`
+	OrganizationID *uuid.UUID               `json:"organizationId" db:"organization_id"`
+	Type           NotificationType         `json:"type" db:"type"`
+	Title          string                   `json:"title" db:"title"`
+	Body           string                   `json:"body" db:"body"`
+	Data           json.RawMessage          `json:"data" db:"data"`
+	InApp          bool                     `json:"-" db:"in_app"`
+	EmailStatus    *NotificationEmailStatus `json:"-" db:"email_status"`
+	EmailedAt      *time.Time               `json:"-" db:"emailed_at"`
+	ReadAt         *time.Time               `json:"readAt" db:"read_at"`
+}
+
+// NotificationWithHighlights is a notification in a list. When the list was
+// searched, Highlights holds its title and body with the matched terms marked.
+type NotificationWithHighlights struct {
+	Notification
+	Highlights map[string]string `json:"highlights,omitempty" db:"highlights"`
+}
+
+// NewNotification is a notification for one user, before channels are
+// resolved from their preferences
+type NewNotification struct {
+	UserID         uuid.UUID
+	OrganizationID *uuid.UUID
+	Type           NotificationType
+	Title          string
+	Body           string
+	Data           any
+	// DedupeKey makes the notification unique for its recipient; creating it
+	// again does nothing
+	DedupeKey *string
+}
+
+type NotificationPreference struct {
+	Type        NotificationType `json:"type" db:"type" validate:"required,oneof=organization.member_joined organization.role_changed organization.removed"`
+	InApp       bool             `json:"inApp" db:"in_app"`
+	Email       bool             `json:"email" db:"email"`
+	Webhook     bool             `json:"webhook" db:"webhook"`
+	EmailDigest bool             `json:"emailDigest" db:"email_digest"`
+}
+
+// DefaultNotificationPreference is used for types the user has not configured.
+// Webhooks are opt-in; member joins are batched into the digest.
+func DefaultNotificationPreference(t NotificationType) NotificationPreference {
+	return NotificationPreference{
+		Type:        t,
+		InApp:       true,
+		Email:       true,
+		EmailDigest: t == NotificationTypeOrganizationMemberJoined,
+	}
+}
+
+// QuietHours holds back emails between Start and End, as HH:MM in the user's
+// timezone. A Start after End spans midnight.
+type QuietHours struct {
+	Start string `json:"start" db:"quiet_hours_start" validate:"required,datetime=15:04"`
+	End   string `json:"end" db:"quiet_hours_end" validate:"required,datetime=15:04"`
+}
+
+// Until reports whether t falls within the quiet hours and, if so, when they
+// end. t should be in the user's location.
+func (q QuietHours) Until(t time.Time) (time.Time, bool) {
+	start, err := time.Parse("15:04", q.Start)
+	if err != nil {
+		return time.Time{}, false
+	}
+	end, err := time.Parse("15:04", q.End)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	now := t.Hour()*60 + t.Minute()
+	from := start.Hour()*60 + start.Minute()
+	to := end.Hour()*60 + end.Minute()
+
+	var quiet bool
+	if from < to {
+		quiet = now >= from && now < to
+	} else {
+		quiet = now >= from || now < to
+	}
+	if !quiet {
+		return time.Time{}, false
+	}
+
+	endsAt := time.Date(t.Year(), t.Month(), t.Day(), end.Hour(), end.Minute(), 0, 0, t.Location())
+	if !endsAt.After(t) {
+		endsAt = endsAt.AddDate(0, 0, 1)
+	}
+	return endsAt, true
+}
+
+type NotificationPreferences struct {
+	QuietHours *QuietHours              `json:"quietHours"`
+	Types      []NotificationPreference `json:"types"`
+}
+
+type NotificationUnreadCount struct {
+	Count int `json:"count"`
+}
+
+// NotificationStreamMessage is pushed to a user's open notification streams.
+// Type is also the SSE event name.
+type NotificationStreamMessage struct {
+	Type         string        `json:"type"`
+	Notification *Notification `json:"notification,omitempty"`
+	UnreadCount  int           `json:"unreadCount"`
+}
+
+const (
+	NotificationStreamCreated = "notification.created"
+	NotificationStreamRead    = "notification.read"
+	NotificationStreamReadAll = "notification.read_all"
+)
+
+// ------------------------------------------------------------
+
+type ListNotificationsPayload struct {
+	Page   int    `query:"page" validate:"omitempty,min=1"`
+	Limit  int    `query:"limit" validate:"omitempty,min=1,max=100"`
+	Unread bool   `query:"unread"`
+	Q      string `query:"q" validate:"omitempty,max=200"`
+}
+
+func (p *ListNotificationsPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type GetUnreadNotificationCountPayload struct{}
+
+func (p *GetUnreadNotificationCountPayload) Validate() error {
+	return nil
+}
+
+// ------------------------------------------------------------
+
+type MarkNotificationReadPayload struct {
+	ID uuid.UUID `param:"id" validate:"required"`
+}
+
+func (p *MarkNotificationReadPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type MarkAllNotificationsReadPayload struct{}
+
+func (p *MarkAllNotificationsReadPayload) Validate() error {
+	return nil
+}
+
+// ------------------------------------------------------------
+
+type GetNotificationPreferencesPayload struct{}
+
+func (p *GetNotificationPreferencesPayload) Validate() error {
+	return nil
+}
+
+// ------------------------------------------------------------
+
+// UpdateNotificationPreferencesPayload replaces the user's preferences. Types
+// left out return to their defaults and a null quietHours turns them off.
+type UpdateNotificationPreferencesPayload struct {
+	QuietHours *QuietHours              `json:"quietHours"`
+	Types      []NotificationPreference `json:"types" validate:"omitempty,unique=Type,dive"`
+}
+
+func (p *UpdateNotificationPreferencesPayload) Validate() error {
+	validate := validator.New()
+	if err := validate.Struct(p); err != nil {
+		return err
+	}
+
+	if p.QuietHours != nil && p.QuietHours.Start == p.QuietHours.End {
+		return validation.CustomValidationErrors{
+			{Field: "quietHours", Message: "start and end must differ"},
+		}
+	}
+
+	return nil
+}
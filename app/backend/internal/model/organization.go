@@ -0,0 +1,198 @@
+package model
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/apk471/go-boilerplate/internal/validation"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+type OrganizationRole string
+
+const (
+	OrganizationRoleOwner  OrganizationRole = "owner"
+	OrganizationRoleAdmin  OrganizationRole = "admin"
+	OrganizationRoleMember OrganizationRole = "member"
+)
+
+// AtLeast reports whether r grants every permission of other
+func (r OrganizationRole) AtLeast(other OrganizationRole) bool {
+	return r.rank() >= other.rank()
+}
+
+func (r OrganizationRole) rank() int {
+	switch r {
+	case OrganizationRoleOwner:
+		return 3
+	case OrganizationRoleAdmin:
+		return 2
+	case OrganizationRoleMember:
+		return 1
+	default:
+		return 0
+	}
+}
+
+var slugRegex = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+
+type Organization struct {
+	Base
+	ClerkOrgID *string    `json:"clerkOrgId" db:"clerk_org_id"`
+	Name       string     `json:"name" db:"name"`
+	Slug       string     `json:"slug" db:"slug"`
+	CreatedBy  *uuid.UUID `json:"createdBy" db:"created_by"`
+}
+
+// OrganizationWithRole is an organization as seen by one of its members
+type OrganizationWithRole struct {
+	Organization
+	Role OrganizationRole `json:"role" db:"role"`
+}
+
+type OrganizationMember struct {
+	Base
+	OrganizationID uuid.UUID        `json:"organizationId" db:"organization_id"`
+	UserID         uuid.UUID        `json:"userId" db:"user_id"`
+	Role           OrganizationRole `json:"role" db:"role"`
+	DisplayName    *string          `json:"displayName" db:"display_name"`
+}
+
+type OrganizationInvitation struct {
+	Base
+	OrganizationID uuid.UUID        `json:"organizationId" db:"organization_id"`
+	Email          string           `json:"email" db:"email"`
+	Role           OrganizationRole `json:"role" db:"role"`
+	InvitedBy      *uuid.UUID       `json:"invitedBy" db:"invited_by"`
+	ExpiresAt      time.Time        `json:"expiresAt" db:"expires_at"`
+	AcceptedAt     *time.Time       `json:"acceptedAt" db:"accepted_at"`
+	AcceptedBy     *uuid.UUID       `json:"acceptedBy" db:"accepted_by"`
+	RevokedAt      *time.Time       `json:"revokedAt" db:"revoked_at"`
+}
+
+// ------------------------------------------------------------
+
+type ListOrganizationsPayload struct{}
+
+func (p *ListOrganizationsPayload) Validate() error {
+	return nil
+}
+
+// ------------------------------------------------------------
+
+type CreateOrganizationPayload struct {
+	Name       string  `json:"name" validate:"required,min=1,max=100"`
+	Slug       string  `json:"slug" validate:"required,min=2,max=64"`
+	ClerkOrgID *string `json:"clerkOrgId" validate:"omitempty,min=1,max=255"`
+}
+
+func (p *CreateOrganizationPayload) Validate() error {
+	validate := validator.New()
+	if err := validate.Struct(p); err != nil {
+		return err
+	}
+
+	if !slugRegex.MatchString(p.Slug) {
+		return validation.CustomValidationErrors{
+			{Field: "slug", Message: "must contain only lowercase letters, digits and single hyphens"},
+		}
+	}
+
+	return nil
+}
+
+// ------------------------------------------------------------
+
+type GetOrganizationPayload struct {
+	ID uuid.UUID `param:"id" validate:"required"`
+}
+
+func (p *GetOrganizationPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type ListOrganizationMembersPayload struct {
+	ID uuid.UUID `param:"id" validate:"required"`
+	Q  string    `query:"q" validate:"omitempty,max=200"`
+}
+
+func (p *ListOrganizationMembersPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type UpdateOrganizationMemberPayload struct {
+	ID     uuid.UUID        `param:"id" validate:"required"`
+	UserID uuid.UUID        `param:"userId" validate:"required"`
+	Role   OrganizationRole `json:"role" validate:"required,oneof=owner admin member"`
+}
+
+func (p *UpdateOrganizationMemberPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type RemoveOrganizationMemberPayload struct {
+	ID     uuid.UUID `param:"id" validate:"required"`
+	UserID uuid.UUID `param:"userId" validate:"required"`
+}
+
+func (p *RemoveOrganizationMemberPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type ListOrganizationInvitationsPayload struct {
+	ID uuid.UUID `param:"id" validate:"required"`
+}
+
+func (p *ListOrganizationInvitationsPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type CreateOrganizationInvitationPayload struct {
+	ID    uuid.UUID        `param:"id" validate:"required"`
+	Email string           `json:"email" validate:"required,email,max=320"`
+	Role  OrganizationRole `json:"role" validate:"required,oneof=admin member"`
+}
+
+func (p *CreateOrganizationInvitationPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type RevokeOrganizationInvitationPayload struct {
+	ID           uuid.UUID `param:"id" validate:"required"`
+	InvitationID uuid.UUID `param:"invitationId" validate:"required"`
+}
+
+func (p *RevokeOrganizationInvitationPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type AcceptInvitationPayload struct {
+	Token string `json:"token" validate:"required,max=512"`
+}
+
+func (p *AcceptInvitationPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
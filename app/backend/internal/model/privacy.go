@@ -0,0 +1,80 @@
+package model
+
+import (
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+type PrivacyRequestKind string
+
+const (
+	PrivacyRequestExport  PrivacyRequestKind = "export"
+	PrivacyRequestErasure PrivacyRequestKind = "erasure"
+)
+
+type PrivacyRequestStatus string
+
+const (
+	PrivacyRequestPending    PrivacyRequestStatus = "pending"
+	PrivacyRequestProcessing PrivacyRequestStatus = "processing"
+	PrivacyRequestCompleted  PrivacyRequestStatus = "completed"
+	PrivacyRequestFailed     PrivacyRequestStatus = "failed"
+	// Blocked erasures were stopped by a legal hold
+	PrivacyRequestBlocked PrivacyRequestStatus = "blocked"
+)
+
+// PrivacyRequest is a data export or erasure. The export archive itself is
+// only loaded for downloads.
+type PrivacyRequest struct {
+	Base
+	UserID      uuid.UUID            `json:"userId" db:"user_id"`
+	Kind        PrivacyRequestKind   `json:"kind" db:"kind"`
+	Status      PrivacyRequestStatus `json:"status" db:"status"`
+	ArchiveSize *int64               `json:"archiveSize" db:"archive_size"`
+	ExpiresAt   *time.Time           `json:"expiresAt" db:"expires_at"`
+	Error       *string              `json:"-" db:"error"`
+	CompletedAt *time.Time           `json:"completedAt" db:"completed_at"`
+}
+
+// Actions recorded in the privacy audit log
+const (
+	PrivacyAuditExportRequested  = "export.requested"
+	PrivacyAuditExportCompleted  = "export.completed"
+	PrivacyAuditExportFailed     = "export.failed"
+	PrivacyAuditExportDownloaded = "export.downloaded"
+	PrivacyAuditErasureRequested = "erasure.requested"
+	PrivacyAuditErasureStarted   = "erasure.started"
+	PrivacyAuditErasureBlocked   = "erasure.blocked"
+	PrivacyAuditModuleErased     = "erasure.module_erased"
+	PrivacyAuditModuleFailed     = "erasure.module_failed"
+	PrivacyAuditErasureCompleted = "erasure.completed"
+)
+
+// ------------------------------------------------------------
+
+type RequestDataExportPayload struct{}
+
+func (p *RequestDataExportPayload) Validate() error {
+	return nil
+}
+
+// ------------------------------------------------------------
+
+type ListDataExportsPayload struct{}
+
+func (p *ListDataExportsPayload) Validate() error {
+	return nil
+}
+
+// ------------------------------------------------------------
+
+type DownloadDataExportPayload struct {
+	Token string `query:"token" validate:"required,max=512"`
+}
+
+func (p *DownloadDataExportPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
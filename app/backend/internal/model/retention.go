@@ -0,0 +1,31 @@
+package model
+
+import "time"
+
+// RetentionPolicyReport is what a retention policy would purge if it ran now
+type RetentionPolicyReport struct {
+	Name          string `json:"name"`
+	Table         string `json:"table"`
+	Mode          string `json:"mode"`
+	MaxAgeSeconds int64  `json:"maxAgeSeconds"`
+	Archive       bool   `json:"archive"`
+	// Cutoff is the age column value rows must be older than
+	Cutoff           time.Time  `json:"cutoff"`
+	EligibleRows     int64      `json:"eligibleRows"`
+	OldestEligibleAt *time.Time `json:"oldestEligibleAt"`
+	// Runs is the number of scheduled runs needed to purge every eligible row
+	Runs int `json:"runs"`
+}
+
+type RetentionReport struct {
+	GeneratedAt time.Time               `json:"generatedAt"`
+	Policies    []RetentionPolicyReport `json:"policies"`
+}
+
+// ------------------------------------------------------------
+
+type GetRetentionReportPayload struct{}
+
+func (p *GetRetentionReportPayload) Validate() error {
+	return nil
+}
@@ -0,0 +1,42 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type User struct {
+	Base
+	ClerkUserID         string          `json:"clerkUserId" db:"clerk_user_id"`
+	DisplayName         *string         `json:"displayName" db:"display_name"`
+	Locale              string          `json:"locale" db:"locale"`
+	Timezone            string          `json:"timezone" db:"timezone"`
+	Preferences         json.RawMessage `json:"preferences" db:"preferences"`
+	DeletionScheduledAt *time.Time      `json:"deletionScheduledAt" db:"deletion_scheduled_at"`
+}
+
+type GetMePayload struct{}
+
+func (p *GetMePayload) Validate() error {
+	return nil
+}
+
+type UpdateMePayload struct {
+	DisplayName *string          `json:"displayName" validate:"omitempty,min=1,max=100"`
+	Locale      *string          `json:"locale" validate:"omitempty,bcp47_language_tag"`
+	Timezone    *string          `json:"timezone" validate:"omitempty,timezone"`
+	Preferences *json.RawMessage `json:"preferences" validate:"omitempty,json"`
+}
+
+func (p *UpdateMePayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+type DeleteMePayload struct{}
+
+func (p *DeleteMePayload) Validate() error {
+	return nil
+}
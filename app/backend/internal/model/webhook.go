@@ -0,0 +1,185 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryStatusPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliveryStatusRetrying  WebhookDeliveryStatus = "retrying"
+	WebhookDeliveryStatusSucceeded WebhookDeliveryStatus = "succeeded"
+	WebhookDeliveryStatusFailed    WebhookDeliveryStatus = "failed"
+)
+
+// WebhookEventPing is sent by the ping endpoint regardless of an endpoint's event filter
+const WebhookEventPing = "webhook.ping"
+
+type WebhookEndpoint struct {
+	Base
+	OrganizationID          uuid.UUID  `json:"organizationId" db:"organization_id"`
+	URL                     string     `json:"url" db:"url"`
+	Description             *string    `json:"description" db:"description"`
+	EventTypes              []string   `json:"eventTypes" db:"event_types"`
+	Secret                  string     `json:"-" db:"secret"`
+	PreviousSecret          *string    `json:"-" db:"previous_secret"`
+	PreviousSecretExpiresAt *time.Time `json:"-" db:"previous_secret_expires_at"`
+	Enabled                 bool       `json:"enabled" db:"enabled"`
+	ConsecutiveFailures     int        `json:"consecutiveFailures" db:"consecutive_failures"`
+	DisabledAt              *time.Time `json:"disabledAt" db:"disabled_at"`
+	DisabledReason          *string    `json:"disabledReason" db:"disabled_reason"`
+	CreatedBy               *uuid.UUID `json:"createdBy" db:"created_by"`
+}
+
+// WebhookEndpointWithSecret exposes the signing secret, which is only returned
+// when an endpoint is created or its secret is rotated
+type WebhookEndpointWithSecret struct {
+	WebhookEndpoint
+	Secret string `json:"secret"`
+}
+
+type WebhookDelivery struct {
+	Base
+	EndpointID     uuid.UUID             `json:"endpointId" db:"endpoint_id"`
+	EventID        uuid.UUID             `json:"eventId" db:"event_id"`
+	EventType      string                `json:"eventType" db:"event_type"`
+	Payload        json.RawMessage       `json:"payload" db:"payload"`
+	Status         WebhookDeliveryStatus `json:"status" db:"status"`
+	Attempts       int                   `json:"attempts" db:"attempts"`
+	ResponseStatus *int                  `json:"responseStatus" db:"response_status"`
+	LatencyMs      *int                  `json:"latencyMs" db:"latency_ms"`
+	Error          *string               `json:"error" db:"error"`
+	DeliveredAt    *time.Time            `json:"deliveredAt" db:"delivered_at"`
+	RedeliveryOf   *uuid.UUID            `json:"redeliveryOf" db:"redelivery_of"`
+}
+
+// WebhookEvent is the JSON body posted to endpoints
+type WebhookEvent struct {
+	ID             uuid.UUID `json:"id"`
+	Type           string    `json:"type"`
+	OrganizationID uuid.UUID `json:"organizationId"`
+	CreatedAt      time.Time `json:"createdAt"`
+	Data           any       `json:"data"`
+}
+
+// ------------------------------------------------------------
+
+type ListWebhookEndpointsPayload struct {
+	ID uuid.UUID `param:"id" validate:"required"`
+}
+
+func (p *ListWebhookEndpointsPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type CreateWebhookEndpointPayload struct {
+	ID          uuid.UUID `param:"id" validate:"required"`
+	URL         string    `json:"url" validate:"required,url,max=2048"`
+	Description *string   `json:"description" validate:"omitempty,max=500"`
+	EventTypes  []string  `json:"eventTypes" validate:"omitempty,max=50,dive,min=1,max=100"`
+}
+
+func (p *CreateWebhookEndpointPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type GetWebhookEndpointPayload struct {
+	ID         uuid.UUID `param:"id" validate:"required"`
+	EndpointID uuid.UUID `param:"endpointId" validate:"required"`
+}
+
+func (p *GetWebhookEndpointPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type UpdateWebhookEndpointPayload struct {
+	ID          uuid.UUID `param:"id" validate:"required"`
+	EndpointID  uuid.UUID `param:"endpointId" validate:"required"`
+	URL         *string   `json:"url" validate:"omitempty,url,max=2048"`
+	Description *string   `json:"description" validate:"omitempty,max=500"`
+	EventTypes  *[]string `json:"eventTypes" validate:"omitempty,max=50,dive,min=1,max=100"`
+	// Enabled re-enables an endpoint, which also resets its failure count
+	Enabled *bool `json:"enabled"`
+}
+
+func (p *UpdateWebhookEndpointPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type DeleteWebhookEndpointPayload struct {
+	ID         uuid.UUID `param:"id" validate:"required"`
+	EndpointID uuid.UUID `param:"endpointId" validate:"required"`
+}
+
+func (p *DeleteWebhookEndpointPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type RotateWebhookSecretPayload struct {
+	ID         uuid.UUID `param:"id" validate:"required"`
+	EndpointID uuid.UUID `param:"endpointId" validate:"required"`
+}
+
+func (p *RotateWebhookSecretPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type PingWebhookEndpointPayload struct {
+	ID         uuid.UUID `param:"id" validate:"required"`
+	EndpointID uuid.UUID `param:"endpointId" validate:"required"`
+}
+
+func (p *PingWebhookEndpointPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type ListWebhookDeliveriesPayload struct {
+	ID         uuid.UUID `param:"id" validate:"required"`
+	EndpointID uuid.UUID `param:"endpointId" validate:"required"`
+	Page       int       `query:"page" validate:"omitempty,min=1"`
+	Limit      int       `query:"limit" validate:"omitempty,min=1,max=100"`
+}
+
+func (p *ListWebhookDeliveriesPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type RedeliverWebhookPayload struct {
+	ID         uuid.UUID `param:"id" validate:"required"`
+	EndpointID uuid.UUID `param:"endpointId" validate:"required"`
+	DeliveryID uuid.UUID `param:"deliveryId" validate:"required"`
+}
+
+func (p *RedeliverWebhookPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
@@ -0,0 +1,361 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/apk471/go-boilerplate/internal/database"
+	"github.com/apk471/go-boilerplate/internal/lib/search"
+	"github.com/apk471/go-boilerplate/internal/model"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const notificationColumns = `id, user_id, organization_id, type, title, body, data, in_app, email_status,
+	emailed_at, read_at, created_at, updated_at`
+
+type NotificationRepository struct {
+	db database.Querier
+}
+
+func NewNotificationRepository(db database.Querier) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+// WithinTx runs fn with a repository bound to a new transaction, committing
+// if fn returns nil and rolling back otherwise
+func (r *NotificationRepository) WithinTx(ctx context.Context, fn func(*NotificationRepository) error) error {
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		return fn(NewNotificationRepository(tx))
+	})
+}
+
+// Querier returns the pool or transaction the repository runs on, for writes
+// such as outbox events that must share its transaction
+func (r *NotificationRepository) Querier() database.Querier {
+	return r.db
+}
+
+// Create inserts the notification. A notification whose DedupeKey the
+// recipient already has is not inserted again and returns pgx.ErrNoRows.
+func (r *NotificationRepository) Create(
+	ctx context.Context,
+	notification *model.NewNotification,
+	data []byte,
+	inApp bool,
+	emailStatus *model.NotificationEmailStatus,
+) (*model.Notification, error) {
+	rows, err := r.db.Query(ctx, `
+		INSERT INTO notifications (user_id, organization_id, type, title, body, data, in_app, email_status, dedupe_key)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (user_id, dedupe_key) WHERE dedupe_key IS NOT NULL DO NOTHING
+		RETURNING `+notificationColumns,
+		notification.UserID, notification.OrganizationID, notification.Type, notification.Title,
+		notification.Body, data, inApp, emailStatus, notification.DedupeKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create notification: %w", err)
+	}
+
+	return collectNotification(rows)
+}
+
+func (r *NotificationRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.Notification, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+notificationColumns+` FROM notifications WHERE id = $1`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification: %w", err)
+	}
+
+	return collectNotification(rows)
+}
+
+// notificationSearch matches the search_vector column of migration 007_search.sql
+var notificationSearch = search.Index{
+	Vector: "search_vector",
+	Fields: []search.Field{
+		{Name: "title", Column: "title", Weight: search.WeightA},
+		{Name: "body", Column: "body", Weight: search.WeightB, Fragments: 2},
+	},
+	Fuzzy: "title",
+}
+
+// List returns the user's in-app notifications, newest first, or best
+// matches first with highlights if q is not empty
+func (r *NotificationRepository) List(
+	ctx context.Context,
+	userID uuid.UUID,
+	unreadOnly bool,
+	q string,
+	page, limit int,
+) (*model.PaginatedResponse[model.NotificationWithHighlights], error) {
+	args := search.Args{userID, unreadOnly}
+	query := notificationSearch.Query(q, &args)
+	where := `user_id = $1 AND in_app AND (NOT $2 OR read_at IS NULL) AND ` + query.Where()
+
+	var total int
+	err := r.db.QueryRow(ctx, `SELECT count(*) FROM notifications WHERE `+where, args...).Scan(&total)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count notifications: %w", err)
+	}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT `+notificationColumns+`, `+query.Highlights()+` AS highlights
+		FROM notifications
+		WHERE `+where+`
+		ORDER BY `+query.OrderBy("created_at DESC")+`
+		LIMIT `+args.Add(limit)+` OFFSET `+args.Add((page-1)*limit), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notifications: %w", err)
+	}
+
+	notifications, err := pgx.CollectRows(rows, pgx.RowToStructByName[model.NotificationWithHighlights])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:notifications: %w", err)
+	}
+
+	return &model.PaginatedResponse[model.NotificationWithHighlights]{
+		Data:       notifications,
+		Page:       page,
+		Limit:      limit,
+		Total:      total,
+		TotalPages: (total + limit - 1) / limit,
+	}, nil
+}
+
+func (r *NotificationRepository) CountUnread(ctx context.Context, userID uuid.UUID) (int, error) {
+	var count int
+	err := r.db.QueryRow(ctx, `
+		SELECT count(*) FROM notifications
+		WHERE user_id = $1 AND in_app AND read_at IS NULL`, userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count unread notifications: %w", err)
+	}
+	return count, nil
+}
+
+// MarkRead marks one of the user's in-app notifications read, keeping the
+// original read time if it already was
+func (r *NotificationRepository) MarkRead(ctx context.Context, userID, id uuid.UUID) (*model.Notification, error) {
+	rows, err := r.db.Query(ctx, `
+		UPDATE notifications SET read_at = coalesce(read_at, now())
+		WHERE id = $1 AND user_id = $2 AND in_app
+		RETURNING `+notificationColumns, id, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark notification read: %w", err)
+	}
+
+	return collectNotification(rows)
+}
+
+func (r *NotificationRepository) MarkAllRead(ctx context.Context, userID uuid.UUID) (int64, error) {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE notifications SET read_at = now()
+		WHERE user_id = $1 AND in_app AND read_at IS NULL`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark notifications read: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (r *NotificationRepository) SetEmailStatus(
+	ctx context.Context,
+	id uuid.UUID,
+	status model.NotificationEmailStatus,
+) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE notifications
+		SET email_status = $2, emailed_at = CASE WHEN $2 = 'sent' THEN now() ELSE emailed_at END
+		WHERE id = $1`, id, status)
+	if err != nil {
+		return fmt.Errorf("failed to update notification email status: %w", err)
+	}
+	return nil
+}
+
+// ClaimDigest marks the user's digest notifications as emailed and returns
+// those still unread. Read ones are skipped since the user has seen them.
+func (r *NotificationRepository) ClaimDigest(ctx context.Context, userID uuid.UUID) ([]model.Notification, error) {
+	rows, err := r.db.Query(ctx, `
+		UPDATE notifications
+		SET email_status = CASE WHEN read_at IS NULL THEN 'sent' ELSE 'skipped' END,
+			emailed_at = CASE WHEN read_at IS NULL THEN now() END
+		WHERE user_id = $1 AND email_status = 'digest'
+		RETURNING `+notificationColumns, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim notification digest: %w", err)
+	}
+
+	notifications, err := pgx.CollectRows(rows, pgx.RowToStructByName[model.Notification])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:notifications: %w", err)
+	}
+
+	unread := notifications[:0]
+	for _, notification := range notifications {
+		if notification.ReadAt == nil {
+			unread = append(unread, notification)
+		}
+	}
+	return unread, nil
+}
+
+// GetPreference returns the user's stored preference for a type, or the
+// default if they have not configured it
+func (r *NotificationRepository) GetPreference(
+	ctx context.Context,
+	userID uuid.UUID,
+	notificationType model.NotificationType,
+) (model.NotificationPreference, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT type, in_app, email, webhook, email_digest FROM notification_preferences
+		WHERE user_id = $1 AND type = $2`, userID, notificationType)
+	if err != nil {
+		return model.NotificationPreference{}, fmt.Errorf("failed to get notification preference: %w", err)
+	}
+
+	preference, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[model.NotificationPreference])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.DefaultNotificationPreference(notificationType), nil
+		}
+		return model.NotificationPreference{}, fmt.Errorf("failed to collect row from table:notification_preferences: %w", err)
+	}
+
+	return preference, nil
+}
+
+// GetPreferences returns a preference for every notification type, filling in
+// defaults, and the user's quiet hours
+func (r *NotificationRepository) GetPreferences(ctx context.Context, userID uuid.UUID) (*model.NotificationPreferences, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT type, in_app, email, webhook, email_digest FROM notification_preferences
+		WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notification preferences: %w", err)
+	}
+
+	stored, err := pgx.CollectRows(rows, pgx.RowToStructByName[model.NotificationPreference])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:notification_preferences: %w", err)
+	}
+
+	byType := make(map[model.NotificationType]model.NotificationPreference, len(stored))
+	for _, preference := range stored {
+		byType[preference.Type] = preference
+	}
+
+	preferences := &model.NotificationPreferences{
+		Types: make([]model.NotificationPreference, 0, len(model.NotificationTypes)),
+	}
+	for _, notificationType := range model.NotificationTypes {
+		preference, ok := byType[notificationType]
+		if !ok {
+			preference = model.DefaultNotificationPreference(notificationType)
+		}
+		preferences.Types = append(preferences.Types, preference)
+	}
+
+	preferences.QuietHours, err = r.GetQuietHours(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return preferences, nil
+}
+
+// GetQuietHours returns nil if the user has none
+func (r *NotificationRepository) GetQuietHours(ctx context.Context, userID uuid.UUID) (*model.QuietHours, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT quiet_hours_start, quiet_hours_end FROM notification_settings
+		WHERE user_id = $1 AND quiet_hours_start IS NOT NULL`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification settings: %w", err)
+	}
+
+	quietHours, err := pgx.CollectOneRow(rows, pgx.RowToAddrOfStructByName[model.QuietHours])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to collect row from table:notification_settings: %w", err)
+	}
+
+	return quietHours, nil
+}
+
+// ReplacePreferences stores payload as the user's complete preferences. It
+// should run in a transaction.
+func (r *NotificationRepository) ReplacePreferences(
+	ctx context.Context,
+	userID uuid.UUID,
+	payload *model.UpdateNotificationPreferencesPayload,
+) error {
+	if _, err := r.db.Exec(ctx, `DELETE FROM notification_preferences WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to clear notification preferences: %w", err)
+	}
+
+	_, err := database.ExecBatch(ctx, r.db, payload.Types, 0, func(preference model.NotificationPreference) (string, []any) {
+		return `
+			INSERT INTO notification_preferences (user_id, type, in_app, email, webhook, email_digest)
+			VALUES ($1, $2, $3, $4, $5, $6)`,
+			[]any{userID, preference.Type, preference.InApp, preference.Email, preference.Webhook, preference.EmailDigest}
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store notification preferences: %w", err)
+	}
+
+	var start, end *string
+	if payload.QuietHours != nil {
+		start, end = &payload.QuietHours.Start, &payload.QuietHours.End
+	}
+
+	_, err = r.db.Exec(ctx, `
+		INSERT INTO notification_settings (user_id, quiet_hours_start, quiet_hours_end)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET quiet_hours_start = excluded.quiet_hours_start, quiet_hours_end = excluded.quiet_hours_end`,
+		userID, start, end)
+	if err != nil {
+		return fmt.Errorf("failed to store notification settings: %w", err)
+	}
+
+	return nil
+}
+
+// ListAll returns every notification of the user, including email-only ones,
+// oldest first
+func (r *NotificationRepository) ListAll(ctx context.Context, userID uuid.UUID) ([]model.Notification, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+notificationColumns+` FROM notifications
+		WHERE user_id = $1
+		ORDER BY created_at`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notifications: %w", err)
+	}
+
+	notifications, err := pgx.CollectRows(rows, pgx.RowToStructByName[model.Notification])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:notifications: %w", err)
+	}
+
+	return notifications, nil
+}
+
+// DeleteForUser deletes the user's notifications, preferences and settings.
+// It should run in a transaction.
+func (r *NotificationRepository) DeleteForUser(ctx context.Context, userID uuid.UUID) error {
+	for _, table := range []string{"notifications", "notification_preferences", "notification_settings"} {
+		if _, err := r.db.Exec(ctx, `DELETE FROM `+table+` WHERE user_id = $1`, userID); err != nil {
+			return fmt.Errorf("failed to delete from table:%s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+func collectNotification(rows pgx.Rows) (*model.Notification, error) {
+	notification, err := pgx.CollectOneRow(rows, pgx.RowToAddrOfStructByName[model.Notification])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:notifications: %w", err)
+	}
+	return notification, nil
+}
@@ -0,0 +1,421 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/apk471/go-boilerplate/internal/database"
+	"github.com/apk471/go-boilerplate/internal/lib/search"
+	"github.com/apk471/go-boilerplate/internal/model"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	organizationColumns = `o.id, o.clerk_org_id, o.name, o.slug, o.created_by, o.created_at, o.updated_at`
+	memberColumns       = `m.id, m.organization_id, m.user_id, m.role, m.created_at, m.updated_at`
+	invitationColumns   = `id, organization_id, email, role, invited_by, expires_at, accepted_at,
+	accepted_by, revoked_at, created_at, updated_at`
+)
+
+type OrganizationRepository struct {
+	db database.Querier
+}
+
+func NewOrganizationRepository(db database.Querier) *OrganizationRepository {
+	return &OrganizationRepository{db: db}
+}
+
+// WithinTx runs fn with a repository bound to a new transaction, committing
+// if fn returns nil and rolling back otherwise
+func (r *OrganizationRepository) WithinTx(ctx context.Context, fn func(*OrganizationRepository) error) error {
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		return fn(NewOrganizationRepository(tx))
+	})
+}
+
+// Querier returns the pool or transaction the repository runs on, for writes
+// such as outbox events that must share its transaction
+func (r *OrganizationRepository) Querier() database.Querier {
+	return r.db
+}
+
+// CreateWithOwner creates the organization and makes ownerID its owner in one statement
+func (r *OrganizationRepository) CreateWithOwner(
+	ctx context.Context,
+	payload *model.CreateOrganizationPayload,
+	ownerID uuid.UUID,
+) (*model.OrganizationWithRole, error) {
+	rows, err := r.db.Query(ctx, `
+		WITH o AS (
+			INSERT INTO organizations (clerk_org_id, name, slug, created_by)
+			VALUES ($1, $2, $3, $4)
+			RETURNING *
+		), m AS (
+			INSERT INTO organization_memberships (organization_id, user_id, role)
+			SELECT id, $4, 'owner' FROM o
+		)
+		SELECT `+organizationColumns+`, 'owner' AS role FROM o`,
+		payload.ClerkOrgID, payload.Name, payload.Slug, ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create organization: %w", err)
+	}
+
+	return collectOrganization(rows)
+}
+
+func (r *OrganizationRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.Organization, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+organizationColumns+` FROM organizations o WHERE o.id = $1`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization: %w", err)
+	}
+
+	organization, err := pgx.CollectOneRow(rows, pgx.RowToAddrOfStructByName[model.Organization])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:organizations: %w", err)
+	}
+
+	return organization, nil
+}
+
+func (r *OrganizationRepository) ListForUser(ctx context.Context, userID uuid.UUID) ([]model.OrganizationWithRole, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+organizationColumns+`, m.role
+		FROM organizations o
+		JOIN organization_memberships m ON m.organization_id = o.id
+		WHERE m.user_id = $1
+		ORDER BY o.name`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organizations: %w", err)
+	}
+
+	organizations, err := pgx.CollectRows(rows, pgx.RowToStructByName[model.OrganizationWithRole])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:organizations: %w", err)
+	}
+
+	return organizations, nil
+}
+
+// GetForUser returns the organization if userID is a member of it. Non-members
+// get the same not found error as for a missing organization.
+func (r *OrganizationRepository) GetForUser(ctx context.Context, id, userID uuid.UUID) (*model.OrganizationWithRole, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+organizationColumns+`, m.role
+		FROM organizations o
+		JOIN organization_memberships m ON m.organization_id = o.id
+		WHERE o.id = $1 AND m.user_id = $2`, id, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization: %w", err)
+	}
+
+	return collectOrganization(rows)
+}
+
+// Lock takes a row lock on the organization for the rest of the transaction,
+// serializing membership changes that must keep at least one owner
+func (r *OrganizationRepository) Lock(ctx context.Context, id uuid.UUID) error {
+	var locked uuid.UUID
+	if err := r.db.QueryRow(ctx, `SELECT id FROM organizations WHERE id = $1 FOR UPDATE`, id).Scan(&locked); err != nil {
+		return fmt.Errorf("failed to lock row in table:organizations: %w", err)
+	}
+	return nil
+}
+
+// memberSearch matches the users.search_vector column of migration 007_search.sql
+var memberSearch = search.Index{
+	Vector: "u.search_vector",
+	Fields: []search.Field{
+		{Name: "displayName", Column: "u.display_name", Weight: search.WeightA},
+	},
+	Fuzzy: "u.display_name",
+}
+
+// ListMembers returns the organization's members in the order they joined, or
+// best matches first if q is not empty
+func (r *OrganizationRepository) ListMembers(
+	ctx context.Context,
+	organizationID uuid.UUID,
+	q string,
+) ([]model.OrganizationMember, error) {
+	args := search.Args{organizationID}
+	query := memberSearch.Query(q, &args)
+
+	rows, err := r.db.Query(ctx, `
+		SELECT `+memberColumns+`, u.display_name
+		FROM organization_memberships m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.organization_id = $1 AND `+query.Where()+`
+		ORDER BY `+query.OrderBy("m.created_at"), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organization members: %w", err)
+	}
+
+	members, err := pgx.CollectRows(rows, pgx.RowToStructByName[model.OrganizationMember])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:organization_memberships: %w", err)
+	}
+
+	return members, nil
+}
+
+// ListMembersOf lists the members of several organizations at once, oldest
+// first, optionally only those with one of roles
+func (r *OrganizationRepository) ListMembersOf(
+	ctx context.Context,
+	organizationIDs []uuid.UUID,
+	roles ...model.OrganizationRole,
+) ([]model.OrganizationMember, error) {
+	// Never nil, which would be NULL and match nothing
+	roleFilter := make([]string, len(roles))
+	for i, role := range roles {
+		roleFilter[i] = string(role)
+	}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT `+memberColumns+`, u.display_name
+		FROM organization_memberships m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.organization_id = ANY($1) AND (cardinality($2::text[]) = 0 OR m.role = ANY($2))
+		ORDER BY m.created_at`, organizationIDs, roleFilter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organization members: %w", err)
+	}
+
+	members, err := pgx.CollectRows(rows, pgx.RowToStructByName[model.OrganizationMember])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:organization_memberships: %w", err)
+	}
+
+	return members, nil
+}
+
+func (r *OrganizationRepository) GetMember(ctx context.Context, organizationID, userID uuid.UUID) (*model.OrganizationMember, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+memberColumns+`, u.display_name
+		FROM organization_memberships m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.organization_id = $1 AND m.user_id = $2`, organizationID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization member: %w", err)
+	}
+
+	return collectMember(rows)
+}
+
+func (r *OrganizationRepository) CountOwners(ctx context.Context, organizationID uuid.UUID) (int, error) {
+	var count int
+	err := r.db.QueryRow(ctx, `
+		SELECT count(*) FROM organization_memberships
+		WHERE organization_id = $1 AND role = 'owner'`, organizationID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count organization owners: %w", err)
+	}
+	return count, nil
+}
+
+func (r *OrganizationRepository) UpdateMemberRole(
+	ctx context.Context,
+	organizationID, userID uuid.UUID,
+	role model.OrganizationRole,
+) (*model.OrganizationMember, error) {
+	rows, err := r.db.Query(ctx, `
+		UPDATE organization_memberships m SET role = $3
+		FROM users u
+		WHERE u.id = m.user_id AND m.organization_id = $1 AND m.user_id = $2
+		RETURNING `+memberColumns+`, u.display_name`, organizationID, userID, role)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update organization member: %w", err)
+	}
+
+	return collectMember(rows)
+}
+
+// AddMember adds userID to the organization, keeping the existing role if the
+// user is already a member. It reports whether a membership was created.
+func (r *OrganizationRepository) AddMember(
+	ctx context.Context,
+	organizationID, userID uuid.UUID,
+	role model.OrganizationRole,
+) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+		INSERT INTO organization_memberships (organization_id, user_id, role)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (organization_id, user_id) DO NOTHING`, organizationID, userID, role)
+	if err != nil {
+		return false, fmt.Errorf("failed to add organization member: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *OrganizationRepository) RemoveMember(ctx context.Context, organizationID, userID uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `
+		DELETE FROM organization_memberships
+		WHERE organization_id = $1 AND user_id = $2`, organizationID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to remove organization member: %w", err)
+	}
+	return nil
+}
+
+// CloseExpiredInvitations revokes the expired open invitations of email,
+// which would otherwise keep it from being invited again. Expired invitations
+// are not listed, so they cannot be revoked through the API.
+func (r *OrganizationRepository) CloseExpiredInvitations(ctx context.Context, organizationID uuid.UUID, email string) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE organization_invitations SET revoked_at = now()
+		WHERE organization_id = $1 AND lower(email) = lower($2)
+			AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at <= now()`, organizationID, email)
+	if err != nil {
+		return fmt.Errorf("failed to close expired organization invitations: %w", err)
+	}
+	return nil
+}
+
+func (r *OrganizationRepository) CreateInvitation(
+	ctx context.Context,
+	organizationID uuid.UUID,
+	email string,
+	role model.OrganizationRole,
+	invitedBy uuid.UUID,
+	expiresAt time.Time,
+) (*model.OrganizationInvitation, error) {
+	rows, err := r.db.Query(ctx, `
+		INSERT INTO organization_invitations (organization_id, email, role, invited_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+invitationColumns, organizationID, email, role, invitedBy, expiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create organization invitation: %w", err)
+	}
+
+	return collectInvitation(rows)
+}
+
+// ListPendingInvitations returns invitations that can still be accepted
+func (r *OrganizationRepository) ListPendingInvitations(
+	ctx context.Context,
+	organizationID uuid.UUID,
+) ([]model.OrganizationInvitation, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+invitationColumns+` FROM organization_invitations
+		WHERE organization_id = $1
+			AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > now()
+		ORDER BY created_at DESC`, organizationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organization invitations: %w", err)
+	}
+
+	invitations, err := pgx.CollectRows(rows, pgx.RowToStructByName[model.OrganizationInvitation])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:organization_invitations: %w", err)
+	}
+
+	return invitations, nil
+}
+
+// GetInvitationForUpdate locks the invitation for the rest of the transaction
+func (r *OrganizationRepository) GetInvitationForUpdate(ctx context.Context, id uuid.UUID) (*model.OrganizationInvitation, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+invitationColumns+` FROM organization_invitations
+		WHERE id = $1
+		FOR UPDATE`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization invitation: %w", err)
+	}
+
+	return collectInvitation(rows)
+}
+
+func (r *OrganizationRepository) MarkInvitationAccepted(ctx context.Context, id, userID uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE organization_invitations SET accepted_at = now(), accepted_by = $2
+		WHERE id = $1`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to accept organization invitation: %w", err)
+	}
+	return nil
+}
+
+// RevokeInvitation revokes a pending invitation. Accepted or already revoked
+// invitations are reported as not found.
+func (r *OrganizationRepository) RevokeInvitation(
+	ctx context.Context,
+	organizationID, id uuid.UUID,
+) (*model.OrganizationInvitation, error) {
+	rows, err := r.db.Query(ctx, `
+		UPDATE organization_invitations SET revoked_at = now()
+		WHERE id = $1 AND organization_id = $2 AND accepted_at IS NULL AND revoked_at IS NULL
+		RETURNING `+invitationColumns, id, organizationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke organization invitation: %w", err)
+	}
+
+	return collectInvitation(rows)
+}
+
+// RemoveUserEverywhere takes the user out of all their organizations for
+// account erasure. Organizations the user is the only member of are deleted;
+// where they are the only owner, the longest-standing admin, or member if
+// there is none, becomes owner. It should run in a transaction.
+func (r *OrganizationRepository) RemoveUserEverywhere(ctx context.Context, userID uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `
+		DELETE FROM organizations o
+		WHERE EXISTS (
+			SELECT 1 FROM organization_memberships m WHERE m.organization_id = o.id AND m.user_id = $1
+		) AND NOT EXISTS (
+			SELECT 1 FROM organization_memberships m WHERE m.organization_id = o.id AND m.user_id <> $1
+		)`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete organizations: %w", err)
+	}
+
+	_, err = r.db.Exec(ctx, `
+		UPDATE organization_memberships m SET role = 'owner'
+		FROM (
+			SELECT DISTINCT ON (s.organization_id) s.id
+			FROM organization_memberships s
+			JOIN organization_memberships o ON o.organization_id = s.organization_id
+				AND o.user_id = $1 AND o.role = 'owner'
+			WHERE s.user_id <> $1 AND NOT EXISTS (
+				SELECT 1 FROM organization_memberships x
+				WHERE x.organization_id = s.organization_id AND x.role = 'owner' AND x.user_id <> $1
+			)
+			ORDER BY s.organization_id, s.role = 'admin' DESC, s.created_at
+		) successor
+		WHERE m.id = successor.id`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to transfer organization ownership: %w", err)
+	}
+
+	_, err = r.db.Exec(ctx, `DELETE FROM organization_memberships WHERE user_id = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete organization memberships: %w", err)
+	}
+
+	return nil
+}
+
+func collectOrganization(rows pgx.Rows) (*model.OrganizationWithRole, error) {
+	organization, err := pgx.CollectOneRow(rows, pgx.RowToAddrOfStructByName[model.OrganizationWithRole])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:organizations: %w", err)
+	}
+	return organization, nil
+}
+
+func collectMember(rows pgx.Rows) (*model.OrganizationMember, error) {
+	member, err := pgx.CollectOneRow(rows, pgx.RowToAddrOfStructByName[model.OrganizationMember])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:organization_memberships: %w", err)
+	}
+	return member, nil
+}
+
+func collectInvitation(rows pgx.Rows) (*model.OrganizationInvitation, error) {
+	invitation, err := pgx.CollectOneRow(rows, pgx.RowToAddrOfStructByName[model.OrganizationInvitation])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:organization_invitations: %w", err)
+	}
+	return invitation, nil
+}
@@ -0,0 +1,162 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apk471/go-boilerplate/internal/database"
+	"github.com/apk471/go-boilerplate/internal/model"
+	"github.com/apk471/go-boilerplate/internal/repository"
+	"github.com/apk471/go-boilerplate/internal/testutil"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var organizationFactory = testutil.NewFactory(func() model.CreateOrganizationPayload {
+	slug := "org-" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	return model.CreateOrganizationPayload{Name: "Acme", Slug: slug}
+})
+
+// newOrganization creates an organization owned by a new user and returns it
+// with the owner's ID
+func newOrganization(t *testing.T, db database.Querier) (*model.OrganizationWithRole, uuid.UUID) {
+	t.Helper()
+	ctx := context.Background()
+
+	owner, err := repository.NewUserRepository(db).GetOrCreateByClerkID(ctx, newClerkID())
+	if err != nil {
+		t.Fatalf("failed to create owner: %v", err)
+	}
+
+	payload := organizationFactory.Build()
+	organization, err := repository.NewOrganizationRepository(db).CreateWithOwner(ctx, &payload, owner.ID)
+	if err != nil {
+		t.Fatalf("failed to create organization: %v", err)
+	}
+
+	return organization, owner.ID
+}
+
+func TestOrganizationInvitations(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	tx := testutil.NewTestDatabase(t).Tx(t)
+
+	// Every subtest starts from this organization, in a savepoint of tx
+	organization, ownerID := newOrganization(t, tx)
+	now := testutil.Now()
+
+	invite := func(t *testing.T, organizations *repository.OrganizationRepository, email string, expiresAt time.Time) *model.OrganizationInvitation {
+		t.Helper()
+
+		invitation, err := organizations.CreateInvitation(ctx, organization.ID, email, model.OrganizationRoleMember, ownerID, expiresAt)
+		if err != nil {
+			t.Fatalf("CreateInvitation(%s) error = %v", email, err)
+		}
+		return invitation
+	}
+
+	t.Run("pending invitations leave out expired, accepted and revoked ones", func(t *testing.T) {
+		sp := testutil.Savepoint(t, tx)
+		organizations := repository.NewOrganizationRepository(sp)
+
+		pending := invite(t, organizations, "pending@example.com", now.Add(time.Hour))
+		invite(t, organizations, "expired@example.com", now.Add(-time.Hour))
+
+		accepted := invite(t, organizations, "accepted@example.com", now.Add(time.Hour))
+		if err := organizations.MarkInvitationAccepted(ctx, accepted.ID, ownerID); err != nil {
+			t.Fatalf("MarkInvitationAccepted() error = %v", err)
+		}
+
+		revoked := invite(t, organizations, "revoked@example.com", now.Add(time.Hour))
+		if _, err := organizations.RevokeInvitation(ctx, organization.ID, revoked.ID); err != nil {
+			t.Fatalf("RevokeInvitation() error = %v", err)
+		}
+
+		invitations, err := organizations.ListPendingInvitations(ctx, organization.ID)
+		if err != nil {
+			t.Fatalf("ListPendingInvitations() error = %v", err)
+		}
+		if len(invitations) != 1 || invitations[0].ID != pending.ID {
+			t.Errorf("pending invitations = %+v, want only %s", invitations, pending.ID)
+		}
+	})
+
+	t.Run("an address has one open invitation", func(t *testing.T) {
+		sp := testutil.Savepoint(t, tx)
+		invite(t, repository.NewOrganizationRepository(sp), "dup@example.com", now.Add(time.Hour))
+
+		// The failing insert aborts its savepoint, not the subtest's
+		nested := testutil.Savepoint(t, sp)
+		_, err := repository.NewOrganizationRepository(nested).CreateInvitation(
+			ctx, organization.ID, "DUP@example.com", model.OrganizationRoleAdmin, ownerID, now.Add(time.Hour))
+
+		var pgErr *pgconn.PgError
+		if !errors.As(err, &pgErr) || pgErr.ConstraintName != "unique_organization_invitations_email" {
+			t.Fatalf("CreateInvitation() error = %v, want a unique_organization_invitations_email violation", err)
+		}
+	})
+
+	t.Run("closing expired invitations frees the address", func(t *testing.T) {
+		sp := testutil.Savepoint(t, tx)
+		organizations := repository.NewOrganizationRepository(sp)
+
+		expired := invite(t, organizations, "again@example.com", now.Add(-time.Hour))
+		open := invite(t, organizations, "open@example.com", now.Add(time.Hour))
+
+		if err := organizations.CloseExpiredInvitations(ctx, organization.ID, "AGAIN@example.com"); err != nil {
+			t.Fatalf("CloseExpiredInvitations() error = %v", err)
+		}
+		if err := organizations.CloseExpiredInvitations(ctx, organization.ID, "open@example.com"); err != nil {
+			t.Fatalf("CloseExpiredInvitations() error = %v", err)
+		}
+
+		closed, err := organizations.GetInvitationForUpdate(ctx, expired.ID)
+		if err != nil {
+			t.Fatalf("GetInvitationForUpdate() error = %v", err)
+		}
+		if closed.RevokedAt == nil {
+			t.Error("the expired invitation was not revoked")
+		}
+		stillOpen, err := organizations.GetInvitationForUpdate(ctx, open.ID)
+		if err != nil {
+			t.Fatalf("GetInvitationForUpdate() error = %v", err)
+		}
+		if stillOpen.RevokedAt != nil {
+			t.Error("an invitation that has not expired was revoked")
+		}
+
+		invite(t, organizations, "again@example.com", now.Add(time.Hour))
+	})
+
+	t.Run("only pending invitations can be revoked", func(t *testing.T) {
+		sp := testutil.Savepoint(t, tx)
+		organizations := repository.NewOrganizationRepository(sp)
+
+		accepted := invite(t, organizations, "member@example.com", now.Add(time.Hour))
+		if err := organizations.MarkInvitationAccepted(ctx, accepted.ID, ownerID); err != nil {
+			t.Fatalf("MarkInvitationAccepted() error = %v", err)
+		}
+		if _, err := organizations.RevokeInvitation(ctx, organization.ID, accepted.ID); !errors.Is(err, pgx.ErrNoRows) {
+			t.Errorf("revoking an accepted invitation: error = %v, want pgx.ErrNoRows", err)
+		}
+
+		revoked := invite(t, organizations, "gone@example.com", now.Add(time.Hour))
+		if _, err := organizations.RevokeInvitation(ctx, organization.ID, revoked.ID); err != nil {
+			t.Fatalf("RevokeInvitation() error = %v", err)
+		}
+		if _, err := organizations.RevokeInvitation(ctx, organization.ID, revoked.ID); !errors.Is(err, pgx.ErrNoRows) {
+			t.Errorf("revoking twice: error = %v, want pgx.ErrNoRows", err)
+		}
+
+		other, _ := newOrganization(t, sp)
+		pending := invite(t, organizations, "other@example.com", now.Add(time.Hour))
+		if _, err := organizations.RevokeInvitation(ctx, other.ID, pending.ID); !errors.Is(err, pgx.ErrNoRows) {
+			t.Errorf("revoking through another organization: error = %v, want pgx.ErrNoRows", err)
+		}
+	})
+}
@@ -0,0 +1,212 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/apk471/go-boilerplate/internal/database"
+	"github.com/apk471/go-boilerplate/internal/model"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const privacyRequestColumns = `id, user_id, kind, status, archive_size, expires_at, error, completed_at,
+	created_at, updated_at`
+
+type PrivacyRepository struct {
+	db database.Querier
+}
+
+func NewPrivacyRepository(db database.Querier) *PrivacyRepository {
+	return &PrivacyRepository{db: db}
+}
+
+// WithinTx runs fn with a repository bound to a new transaction, committing
+// if fn returns nil and rolling back otherwise
+func (r *PrivacyRepository) WithinTx(ctx context.Context, fn func(*PrivacyRepository) error) error {
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		return fn(NewPrivacyRepository(tx))
+	})
+}
+
+// Open returns the user's pending or processing request of kind, creating one
+// if there is none. It reports whether the request was created.
+func (r *PrivacyRepository) Open(
+	ctx context.Context,
+	userID uuid.UUID,
+	kind model.PrivacyRequestKind,
+) (*model.PrivacyRequest, bool, error) {
+	rows, err := r.db.Query(ctx, `
+		INSERT INTO privacy_requests (user_id, kind)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id, kind) WHERE status IN ('pending', 'processing') DO NOTHING
+		RETURNING `+privacyRequestColumns, userID, kind)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create privacy request: %w", err)
+	}
+
+	request, err := pgx.CollectOneRow(rows, pgx.RowToAddrOfStructByName[model.PrivacyRequest])
+	if err == nil {
+		return request, true, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, fmt.Errorf("failed to collect row from table:privacy_requests: %w", err)
+	}
+
+	rows, err = r.db.Query(ctx, `
+		SELECT `+privacyRequestColumns+` FROM privacy_requests
+		WHERE user_id = $1 AND kind = $2 AND status IN ('pending', 'processing')`, userID, kind)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get privacy request: %w", err)
+	}
+
+	request, err = collectPrivacyRequest(rows)
+	return request, false, err
+}
+
+func (r *PrivacyRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.PrivacyRequest, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+privacyRequestColumns+` FROM privacy_requests WHERE id = $1`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get privacy request: %w", err)
+	}
+
+	return collectPrivacyRequest(rows)
+}
+
+// ListForUser returns the user's requests of kind, newest first
+func (r *PrivacyRepository) ListForUser(
+	ctx context.Context,
+	userID uuid.UUID,
+	kind model.PrivacyRequestKind,
+) ([]model.PrivacyRequest, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+privacyRequestColumns+` FROM privacy_requests
+		WHERE user_id = $1 AND kind = $2
+		ORDER BY created_at DESC`, userID, kind)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list privacy requests: %w", err)
+	}
+
+	requests, err := pgx.CollectRows(rows, pgx.RowToStructByName[model.PrivacyRequest])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:privacy_requests: %w", err)
+	}
+
+	return requests, nil
+}
+
+// SetStatus moves a request to status, recording failure as the error
+func (r *PrivacyRepository) SetStatus(
+	ctx context.Context,
+	id uuid.UUID,
+	status model.PrivacyRequestStatus,
+	failure error,
+) error {
+	var message *string
+	if failure != nil {
+		text := failure.Error()
+		message = &text
+	}
+
+	_, err := r.db.Exec(ctx, `
+		UPDATE privacy_requests
+		SET status = $2, error = $3,
+			completed_at = CASE WHEN $2 = 'completed' THEN now() ELSE completed_at END
+		WHERE id = $1`, id, status, message)
+	if err != nil {
+		return fmt.Errorf("failed to update privacy request: %w", err)
+	}
+	return nil
+}
+
+// CompleteExport stores the export archive, downloadable until expiresAt
+func (r *PrivacyRepository) CompleteExport(
+	ctx context.Context,
+	id uuid.UUID,
+	archive []byte,
+	expiresAt time.Time,
+) (*model.PrivacyRequest, error) {
+	rows, err := r.db.Query(ctx, `
+		UPDATE privacy_requests
+		SET status = 'completed', archive = $2, archive_size = $3, expires_at = $4,
+			error = NULL, completed_at = now()
+		WHERE id = $1
+		RETURNING `+privacyRequestColumns, id, archive, len(archive), expiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to complete data export: %w", err)
+	}
+
+	return collectPrivacyRequest(rows)
+}
+
+// GetArchive returns a completed, unexpired export with its archive
+func (r *PrivacyRepository) GetArchive(ctx context.Context, id uuid.UUID) (*model.PrivacyRequest, []byte, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+privacyRequestColumns+`, archive FROM privacy_requests
+		WHERE id = $1 AND kind = 'export' AND status = 'completed'
+			AND archive IS NOT NULL AND expires_at > now()`, id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get data export: %w", err)
+	}
+
+	type withArchive struct {
+		model.PrivacyRequest
+		Archive []byte `db:"archive"`
+	}
+
+	export, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[withArchive])
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to collect row from table:privacy_requests: %w", err)
+	}
+
+	return &export.PrivacyRequest, export.Archive, nil
+}
+
+// DeleteArchives drops the user's export archives, keeping the requests on record
+func (r *PrivacyRepository) DeleteArchives(ctx context.Context, userID uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE privacy_requests SET archive = NULL
+		WHERE user_id = $1 AND archive IS NOT NULL`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete data export archives: %w", err)
+	}
+	return nil
+}
+
+// Audit records an action taken on a request. Module is empty for actions on
+// the request as a whole.
+func (r *PrivacyRepository) Audit(
+	ctx context.Context,
+	request *model.PrivacyRequest,
+	action, module, detail string,
+) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO privacy_audit_log (request_id, user_id, action, module, detail)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''))`,
+		request.ID, request.UserID, action, module, detail)
+	if err != nil {
+		return fmt.Errorf("failed to insert into table:privacy_audit_log: %w", err)
+	}
+	return nil
+}
+
+func (r *PrivacyRepository) HasActiveLegalHold(ctx context.Context, userID uuid.UUID) (bool, error) {
+	var held bool
+	err := r.db.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM legal_holds WHERE user_id = $1 AND released_at IS NULL)`,
+		userID).Scan(&held)
+	if err != nil {
+		return false, fmt.Errorf("failed to check legal holds: %w", err)
+	}
+	return held, nil
+}
+
+func collectPrivacyRequest(rows pgx.Rows) (*model.PrivacyRequest, error) {
+	request, err := pgx.CollectOneRow(rows, pgx.RowToAddrOfStructByName[model.PrivacyRequest])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:privacy_requests: %w", err)
+	}
+	return request, nil
+}
@@ -2,8 +2,20 @@ package repository
 
 import "github.com/apk471/go-boilerplate/internal/server"
 
-type Repositories struct{}
+type Repositories struct {
+	User         *UserRepository
+	Organization *OrganizationRepository
+	Webhook      *WebhookRepository
+	Notification *NotificationRepository
+	Privacy      *PrivacyRepository
+}
 
 func NewRepositories(s *server.Server) *Repositories {
-	return &Repositories{}
-}
\ No newline at end of file
+	return &Repositories{
+		User:         NewUserRepository(s.DB.Querier()),
+		Organization: NewOrganizationRepository(s.DB.Querier()),
+		Webhook:      NewWebhookRepository(s.DB.Querier()),
+		Notification: NewNotificationRepository(s.DB.Querier()),
+		Privacy:      NewPrivacyRepository(s.DB.Querier()),
+	}
+}
@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/apk471/go-boilerplate/internal/database"
+	"github.com/apk471/go-boilerplate/internal/model"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const userColumns = `id, clerk_user_id, display_name, locale, timezone, preferences,
+	deletion_scheduled_at, created_at, updated_at`
+
+type UserRepository struct {
+	db database.Querier
+}
+
+func NewUserRepository(db database.Querier) *UserRepository {
+	return &UserRepository{db: db}
+}
+
+// GetOrCreateByClerkID returns the user for a Clerk subject, creating it with
+// default profile values the first time the subject is seen.
+//
+// When concurrent first requests race, the loser's insert does nothing and
+// its statement snapshot predates the winner's row, so it is read again. An
+// ON CONFLICT DO UPDATE would return it too, but would bump updated_at, the
+// user's ETag version, on every call.
+func (r *UserRepository) GetOrCreateByClerkID(ctx context.Context, clerkUserID string) (*model.User, error) {
+	rows, err := r.db.Query(ctx, `
+		WITH inserted AS (
+			INSERT INTO users (clerk_user_id)
+			VALUES ($1)
+			ON CONFLICT (clerk_user_id) DO NOTHING
+			RETURNING `+userColumns+`
+		)
+		SELECT `+userColumns+` FROM inserted
+		UNION ALL
+		SELECT `+userColumns+` FROM users WHERE clerk_user_id = $1
+		LIMIT 1`, clerkUserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get or create user: %w", err)
+	}
+
+	user, err := collectUser(rows)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return r.GetByClerkID(ctx, clerkUserID)
+	}
+	return user, err
+}
+
+func (r *UserRepository) GetByClerkID(ctx context.Context, clerkUserID string) (*model.User, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+userColumns+` FROM users WHERE clerk_user_id = $1`, clerkUserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user by clerk id: %w", err)
+	}
+
+	return collectUser(rows)
+}
+
+func (r *UserRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.User, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+userColumns+` FROM users WHERE id = $1`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return collectUser(rows)
+}
+
+// UpdateProfile applies the non-nil fields of payload. With a version it only
+// updates a user last updated at that time and returns pgx.ErrNoRows otherwise.
+func (r *UserRepository) UpdateProfile(ctx context.Context, id uuid.UUID, payload *model.UpdateMePayload, version *time.Time) (*model.User, error) {
+	var preferences []byte
+	if payload.Preferences != nil {
+		preferences = *payload.Preferences
+	}
+
+	rows, err := r.db.Query(ctx, `
+		UPDATE users SET
+			display_name = COALESCE($2, display_name),
+			locale = COALESCE($3, locale),
+			timezone = COALESCE($4, timezone),
+			preferences = COALESCE($5::jsonb, preferences)
+		WHERE id = $1 AND ($6::timestamptz IS NULL OR updated_at = $6)
+		RETURNING `+userColumns,
+		id, payload.DisplayName, payload.Locale, payload.Timezone, preferences, version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update user profile: %w", err)
+	}
+
+	return collectUser(rows)
+}
+
+// ScheduleDeletion marks the user for deletion at the given time, keeping an
+// earlier schedule if one exists
+func (r *UserRepository) ScheduleDeletion(ctx context.Context, id uuid.UUID, at time.Time) (*model.User, error) {
+	rows, err := r.db.Query(ctx, `
+		UPDATE users SET deletion_scheduled_at = COALESCE(deletion_scheduled_at, $2)
+		WHERE id = $1
+		RETURNING `+userColumns, id, at)
+	if err != nil {
+		return nil, fmt.Errorf("failed to schedule user deletion: %w", err)
+	}
+
+	return collectUser(rows)
+}
+
+func (r *UserRepository) CancelDeletion(ctx context.Context, id uuid.UUID) (*model.User, error) {
+	rows, err := r.db.Query(ctx, `
+		UPDATE users SET deletion_scheduled_at = NULL
+		WHERE id = $1
+		RETURNING `+userColumns, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel user deletion: %w", err)
+	}
+
+	return collectUser(rows)
+}
+
+func (r *UserRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	if _, err := r.db.Exec(ctx, `DELETE FROM users WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	return nil
+}
+
+func collectUser(rows pgx.Rows) (*model.User, error) {
+	user, err := pgx.CollectOneRow(rows, pgx.RowToAddrOfStructByName[model.User])
+	if err != nil {
+		// The table: prefix lets sqlerr.HandleError report "User not found"
+		return nil, fmt.Errorf("failed to collect row from table:users: %w", err)
+	}
+	return user, nil
+}
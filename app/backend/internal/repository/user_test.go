@@ -0,0 +1,113 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/apk471/go-boilerplate/internal/model"
+	"github.com/apk471/go-boilerplate/internal/repository"
+	"github.com/apk471/go-boilerplate/internal/testutil"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+func newClerkID() string {
+	return "user_" + uuid.NewString()
+}
+
+func TestUserGetOrCreateByClerkID(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	tx := testutil.NewTestDatabase(t).Tx(t)
+
+	// Subtests share tx, each in a savepoint of its own. They run one after
+	// the other since a transaction holds a single connection.
+	t.Run("creates the user with default profile values", func(t *testing.T) {
+		users := repository.NewUserRepository(testutil.Savepoint(t, tx))
+
+		clerkID := newClerkID()
+		user, err := users.GetOrCreateByClerkID(ctx, clerkID)
+		if err != nil {
+			t.Fatalf("GetOrCreateByClerkID() error = %v", err)
+		}
+		if user.ClerkUserID != clerkID || user.Locale != "en" || user.Timezone != "UTC" {
+			t.Errorf("user = %+v, want defaults for %s", user, clerkID)
+		}
+	})
+
+	t.Run("returns the existing user unchanged", func(t *testing.T) {
+		users := repository.NewUserRepository(testutil.Savepoint(t, tx))
+
+		clerkID := newClerkID()
+		created, err := users.GetOrCreateByClerkID(ctx, clerkID)
+		if err != nil {
+			t.Fatalf("first GetOrCreateByClerkID() error = %v", err)
+		}
+		existing, err := users.GetOrCreateByClerkID(ctx, clerkID)
+		if err != nil {
+			t.Fatalf("second GetOrCreateByClerkID() error = %v", err)
+		}
+		if existing.ID != created.ID {
+			t.Errorf("id = %s, want %s", existing.ID, created.ID)
+		}
+		if !existing.UpdatedAt.Equal(created.UpdatedAt) {
+			t.Errorf("updated_at = %s, want it unchanged at %s", existing.UpdatedAt, created.UpdatedAt)
+		}
+	})
+
+	t.Run("savepoints keep subtests apart", func(t *testing.T) {
+		clerkID := newClerkID()
+
+		t.Run("write", func(t *testing.T) {
+			users := repository.NewUserRepository(testutil.Savepoint(t, tx))
+			if _, err := users.GetOrCreateByClerkID(ctx, clerkID); err != nil {
+				t.Fatalf("GetOrCreateByClerkID() error = %v", err)
+			}
+		})
+
+		t.Run("read", func(t *testing.T) {
+			users := repository.NewUserRepository(testutil.Savepoint(t, tx))
+			if _, err := users.GetByClerkID(ctx, clerkID); !errors.Is(err, pgx.ErrNoRows) {
+				t.Errorf("GetByClerkID() error = %v, want pgx.ErrNoRows after the write rolled back", err)
+			}
+		})
+	})
+}
+
+func TestUserGetOrCreateByClerkIDConcurrent(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	// Concurrent first requests only race on committed rows, so this test
+	// runs on its own database instead of a rolled back transaction
+	users := repository.NewUserRepository(testutil.NewTestDatabase(t).Clone(t))
+
+	const callers = 8
+	clerkID := newClerkID()
+	results := make([]*model.User, callers)
+	errs := make([]error, callers)
+
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			results[i], errs[i] = users.GetOrCreateByClerkID(ctx, clerkID)
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	for i := range callers {
+		if errs[i] != nil {
+			t.Fatalf("caller %d: GetOrCreateByClerkID() error = %v", i, errs[i])
+		}
+		if results[i].ID != results[0].ID {
+			t.Errorf("caller %d: id = %s, want %s", i, results[i].ID, results[0].ID)
+		}
+	}
+}
@@ -0,0 +1,342 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/apk471/go-boilerplate/internal/database"
+	"github.com/apk471/go-boilerplate/internal/model"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	webhookEndpointColumns = `id, organization_id, url, description, event_types, secret, previous_secret,
+	previous_secret_expires_at, enabled, consecutive_failures, disabled_at, disabled_reason, created_by,
+	created_at, updated_at`
+	webhookDeliveryColumns = `id, endpoint_id, event_id, event_type, payload, status, attempts, response_status,
+	latency_ms, error, delivered_at, redelivery_of, created_at, updated_at`
+)
+
+type WebhookRepository struct {
+	db database.Querier
+}
+
+func NewWebhookRepository(db database.Querier) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+func (r *WebhookRepository) CreateEndpoint(
+	ctx context.Context,
+	payload *model.CreateWebhookEndpointPayload,
+	secret string,
+	createdBy uuid.UUID,
+) (*model.WebhookEndpoint, error) {
+	eventTypes := payload.EventTypes
+	if eventTypes == nil {
+		eventTypes = []string{}
+	}
+
+	rows, err := r.db.Query(ctx, `
+		INSERT INTO webhook_endpoints (organization_id, url, description, event_types, secret, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+webhookEndpointColumns,
+		payload.ID, payload.URL, payload.Description, eventTypes, secret, createdBy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook endpoint: %w", err)
+	}
+
+	return collectWebhookEndpoint(rows)
+}
+
+func (r *WebhookRepository) ListEndpoints(ctx context.Context, organizationID uuid.UUID) ([]model.WebhookEndpoint, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+webhookEndpointColumns+` FROM webhook_endpoints
+		WHERE organization_id = $1
+		ORDER BY created_at`, organizationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook endpoints: %w", err)
+	}
+
+	endpoints, err := pgx.CollectRows(rows, pgx.RowToStructByName[model.WebhookEndpoint])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:webhook_endpoints: %w", err)
+	}
+
+	return endpoints, nil
+}
+
+// ListSubscribedEndpoints returns the enabled endpoints of the organization
+// whose event filter matches eventType
+func (r *WebhookRepository) ListSubscribedEndpoints(
+	ctx context.Context,
+	organizationID uuid.UUID,
+	eventType string,
+) ([]model.WebhookEndpoint, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+webhookEndpointColumns+` FROM webhook_endpoints
+		WHERE organization_id = $1 AND enabled
+			AND (event_types = '{}' OR $2 = ANY (event_types))`, organizationID, eventType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list subscribed webhook endpoints: %w", err)
+	}
+
+	endpoints, err := pgx.CollectRows(rows, pgx.RowToStructByName[model.WebhookEndpoint])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:webhook_endpoints: %w", err)
+	}
+
+	return endpoints, nil
+}
+
+func (r *WebhookRepository) GetEndpoint(ctx context.Context, organizationID, id uuid.UUID) (*model.WebhookEndpoint, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+webhookEndpointColumns+` FROM webhook_endpoints
+		WHERE id = $1 AND organization_id = $2`, id, organizationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook endpoint: %w", err)
+	}
+
+	return collectWebhookEndpoint(rows)
+}
+
+func (r *WebhookRepository) GetEndpointByID(ctx context.Context, id uuid.UUID) (*model.WebhookEndpoint, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+webhookEndpointColumns+` FROM webhook_endpoints WHERE id = $1`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook endpoint: %w", err)
+	}
+
+	return collectWebhookEndpoint(rows)
+}
+
+// UpdateEndpoint applies the non-nil fields of payload. Re-enabling an
+// endpoint clears its failure count and disabled state.
+func (r *WebhookRepository) UpdateEndpoint(
+	ctx context.Context,
+	payload *model.UpdateWebhookEndpointPayload,
+) (*model.WebhookEndpoint, error) {
+	rows, err := r.db.Query(ctx, `
+		UPDATE webhook_endpoints SET
+			url = COALESCE($3, url),
+			description = COALESCE($4, description),
+			event_types = COALESCE($5, event_types),
+			enabled = COALESCE($6, enabled),
+			consecutive_failures = CASE WHEN $6 THEN 0 ELSE consecutive_failures END,
+			disabled_at = CASE
+				WHEN $6 THEN NULL
+				WHEN NOT $6 AND enabled THEN now()
+				ELSE disabled_at
+			END,
+			disabled_reason = CASE
+				WHEN $6 THEN NULL
+				WHEN NOT $6 AND enabled THEN 'disabled by user'
+				ELSE disabled_reason
+			END
+		WHERE id = $1 AND organization_id = $2
+		RETURNING `+webhookEndpointColumns,
+		payload.EndpointID, payload.ID, payload.URL, payload.Description, payload.EventTypes, payload.Enabled)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update webhook endpoint: %w", err)
+	}
+
+	return collectWebhookEndpoint(rows)
+}
+
+func (r *WebhookRepository) DeleteEndpoint(ctx context.Context, organizationID, id uuid.UUID) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM webhook_endpoints WHERE id = $1 AND organization_id = $2`, id, organizationID)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook endpoint: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("failed to delete row from table:webhook_endpoints: %w", pgx.ErrNoRows)
+	}
+	return nil
+}
+
+// RotateSecret replaces the signing secret, keeping the current one valid
+// until previousExpiresAt so receivers can roll over without dropping events
+func (r *WebhookRepository) RotateSecret(
+	ctx context.Context,
+	organizationID, id uuid.UUID,
+	secret string,
+	previousExpiresAt time.Time,
+) (*model.WebhookEndpoint, error) {
+	rows, err := r.db.Query(ctx, `
+		UPDATE webhook_endpoints SET
+			previous_secret = secret,
+			previous_secret_expires_at = $4,
+			secret = $3
+		WHERE id = $1 AND organization_id = $2
+		RETURNING `+webhookEndpointColumns, id, organizationID, secret, previousExpiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate webhook secret: %w", err)
+	}
+
+	return collectWebhookEndpoint(rows)
+}
+
+// RecordEndpointSuccess resets the consecutive failure count
+func (r *WebhookRepository) RecordEndpointSuccess(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE webhook_endpoints SET consecutive_failures = 0
+		WHERE id = $1 AND consecutive_failures <> 0`, id)
+	if err != nil {
+		return fmt.Errorf("failed to record webhook endpoint success: %w", err)
+	}
+	return nil
+}
+
+// RecordEndpointFailure counts a delivery that exhausted its retries and
+// disables the endpoint once disableAfter failures happen in a row
+func (r *WebhookRepository) RecordEndpointFailure(
+	ctx context.Context,
+	id uuid.UUID,
+	disableAfter int,
+) (*model.WebhookEndpoint, error) {
+	rows, err := r.db.Query(ctx, `
+		UPDATE webhook_endpoints SET
+			consecutive_failures = consecutive_failures + 1,
+			enabled = enabled AND consecutive_failures + 1 < $2,
+			disabled_at = CASE
+				WHEN enabled AND consecutive_failures + 1 >= $2 THEN now()
+				ELSE disabled_at
+			END,
+			disabled_reason = CASE
+				WHEN enabled AND consecutive_failures + 1 >= $2 THEN 'too many consecutive failed deliveries'
+				ELSE disabled_reason
+			END
+		WHERE id = $1
+		RETURNING `+webhookEndpointColumns, id, disableAfter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record webhook endpoint failure: %w", err)
+	}
+
+	return collectWebhookEndpoint(rows)
+}
+
+func (r *WebhookRepository) CreateDelivery(
+	ctx context.Context,
+	endpointID, eventID uuid.UUID,
+	eventType string,
+	payload json.RawMessage,
+	redeliveryOf *uuid.UUID,
+) (*model.WebhookDelivery, error) {
+	rows, err := r.db.Query(ctx, `
+		INSERT INTO webhook_deliveries (endpoint_id, event_id, event_type, payload, redelivery_of)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+webhookDeliveryColumns, endpointID, eventID, eventType, payload, redeliveryOf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook delivery: %w", err)
+	}
+
+	return collectWebhookDelivery(rows)
+}
+
+func (r *WebhookRepository) GetDelivery(ctx context.Context, endpointID, id uuid.UUID) (*model.WebhookDelivery, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+webhookDeliveryColumns+` FROM webhook_deliveries
+		WHERE id = $1 AND endpoint_id = $2`, id, endpointID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook delivery: %w", err)
+	}
+
+	return collectWebhookDelivery(rows)
+}
+
+func (r *WebhookRepository) GetDeliveryByID(ctx context.Context, id uuid.UUID) (*model.WebhookDelivery, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+webhookDeliveryColumns+` FROM webhook_deliveries WHERE id = $1`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook delivery: %w", err)
+	}
+
+	return collectWebhookDelivery(rows)
+}
+
+// ListDeliveries returns a page of the endpoint's deliveries, newest first
+func (r *WebhookRepository) ListDeliveries(
+	ctx context.Context,
+	endpointID uuid.UUID,
+	page, limit int,
+) (*model.PaginatedResponse[model.WebhookDelivery], error) {
+	var total int
+	err := r.db.QueryRow(ctx, `SELECT count(*) FROM webhook_deliveries WHERE endpoint_id = $1`, endpointID).Scan(&total)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count webhook deliveries: %w", err)
+	}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT `+webhookDeliveryColumns+` FROM webhook_deliveries
+		WHERE endpoint_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3`, endpointID, limit, (page-1)*limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+
+	deliveries, err := pgx.CollectRows(rows, pgx.RowToStructByName[model.WebhookDelivery])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:webhook_deliveries: %w", err)
+	}
+
+	return &model.PaginatedResponse[model.WebhookDelivery]{
+		Data:       deliveries,
+		Page:       page,
+		Limit:      limit,
+		Total:      total,
+		TotalPages: (total + limit - 1) / limit,
+	}, nil
+}
+
+// WebhookAttempt is the outcome of one delivery attempt
+type WebhookAttempt struct {
+	Status         model.WebhookDeliveryStatus
+	ResponseStatus *int
+	LatencyMs      int
+	Error          *string
+}
+
+func (r *WebhookRepository) RecordAttempt(ctx context.Context, id uuid.UUID, attempt WebhookAttempt) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE webhook_deliveries SET
+			status = $2,
+			attempts = attempts + 1,
+			response_status = $3,
+			latency_ms = $4,
+			error = $5,
+			delivered_at = CASE WHEN $2 = 'succeeded' THEN now() ELSE delivered_at END
+		WHERE id = $1`,
+		id, attempt.Status, attempt.ResponseStatus, attempt.LatencyMs, attempt.Error)
+	if err != nil {
+		return fmt.Errorf("failed to record webhook delivery attempt: %w", err)
+	}
+	return nil
+}
+
+// FailDelivery marks a delivery failed without attempting it
+func (r *WebhookRepository) FailDelivery(ctx context.Context, id uuid.UUID, reason string) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE webhook_deliveries SET status = 'failed', error = $2
+		WHERE id = $1`, id, reason)
+	if err != nil {
+		return fmt.Errorf("failed to fail webhook delivery: %w", err)
+	}
+	return nil
+}
+
+func collectWebhookEndpoint(rows pgx.Rows) (*model.WebhookEndpoint, error) {
+	endpoint, err := pgx.CollectOneRow(rows, pgx.RowToAddrOfStructByName[model.WebhookEndpoint])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:webhook_endpoints: %w", err)
+	}
+	return endpoint, nil
+}
+
+func collectWebhookDelivery(rows pgx.Rows) (*model.WebhookDelivery, error) {
+	delivery, err := pgx.CollectOneRow(rows, pgx.RowToAddrOfStructByName[model.WebhookDelivery])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:webhook_deliveries: %w", err)
+	}
+	return delivery, nil
+}
@@ -0,0 +1,21 @@
+package router
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/apk471/go-boilerplate/internal/handler"
+	"github.com/apk471/go-boilerplate/internal/middleware"
+	"github.com/apk471/go-boilerplate/internal/model"
+
+	"github.com/labstack/echo/v4"
+	echoMiddleware "github.com/labstack/echo/v4/middleware"
+)
+
+func registerBatchRoutes(router *echo.Echo, r *echo.Group, h *handler.Handlers, auth *middleware.AuthMiddleware, maxBodyBytes int64) {
+	h.Batch.SetRouter(router)
+
+	batch := r.Group("/batch", echoMiddleware.BodyLimit(strconv.FormatInt(maxBodyBytes, 10)), auth.RequireAuth)
+
+	batch.POST("", handler.Handle(h.Batch.Handler, h.Batch.Execute, http.StatusOK, &model.ExecuteBatchPayload{}))
+}
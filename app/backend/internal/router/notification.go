@@ -0,0 +1,33 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/apk471/go-boilerplate/internal/handler"
+	"github.com/apk471/go-boilerplate/internal/middleware"
+	"github.com/apk471/go-boilerplate/internal/model"
+
+	"github.com/labstack/echo/v4"
+)
+
+func registerNotificationRoutes(r *echo.Group, h *handler.Handlers, auth *middleware.AuthMiddleware) {
+	notifications := r.Group("/notifications", auth.RequireAuth)
+
+	notifications.GET("", handler.Handle(h.Notification.Handler, h.Notification.List,
+		http.StatusOK, &model.ListNotificationsPayload{}))
+	notifications.GET("/unread-count", handler.Handle(h.Notification.Handler, h.Notification.UnreadCount,
+		http.StatusOK, &model.GetUnreadNotificationCountPayload{}))
+	notifications.POST("/read-all", handler.HandleNoContent(h.Notification.Handler, h.Notification.MarkAllRead,
+		http.StatusNoContent, &model.MarkAllNotificationsReadPayload{}))
+	notifications.POST("/:id/read", handler.Handle(h.Notification.Handler, h.Notification.MarkRead,
+		http.StatusOK, &model.MarkNotificationReadPayload{}))
+
+	notifications.GET("/preferences", handler.Handle(h.Notification.Handler, h.Notification.GetPreferences,
+		http.StatusOK, &model.GetNotificationPreferencesPayload{}))
+	notifications.PUT("/preferences", handler.Handle(h.Notification.Handler, h.Notification.UpdatePreferences,
+		http.StatusOK, &model.UpdateNotificationPreferencesPayload{}))
+
+	// Server-sent events; clients must send the Authorization header, so use
+	// a fetch-based EventSource rather than the browser's built-in one
+	notifications.GET("/stream", h.Notification.Stream)
+}
@@ -0,0 +1,41 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/apk471/go-boilerplate/internal/handler"
+	"github.com/apk471/go-boilerplate/internal/middleware"
+	"github.com/apk471/go-boilerplate/internal/model"
+
+	"github.com/labstack/echo/v4"
+)
+
+func registerOrganizationRoutes(r *echo.Group, h *handler.Handlers, auth *middleware.AuthMiddleware) {
+	organizations := r.Group("/organizations", auth.RequireAuth)
+
+	organizations.GET("", handler.Handle(h.Organization.Handler, h.Organization.ListOrganizations,
+		http.StatusOK, &model.ListOrganizationsPayload{}))
+	organizations.POST("", handler.Handle(h.Organization.Handler, h.Organization.CreateOrganization,
+		http.StatusCreated, &model.CreateOrganizationPayload{}))
+	organizations.GET("/:id", handler.Handle(h.Organization.Handler, h.Organization.GetOrganization,
+		http.StatusOK, &model.GetOrganizationPayload{}))
+
+	organizations.GET("/:id/members", handler.Handle(h.Organization.Handler, h.Organization.ListMembers,
+		http.StatusOK, &model.ListOrganizationMembersPayload{}))
+	organizations.PATCH("/:id/members/:userId", handler.Handle(h.Organization.Handler, h.Organization.UpdateMember,
+		http.StatusOK, &model.UpdateOrganizationMemberPayload{}))
+	organizations.DELETE("/:id/members/:userId", handler.HandleNoContent(h.Organization.Handler,
+		h.Organization.RemoveMember, http.StatusNoContent, &model.RemoveOrganizationMemberPayload{}))
+
+	organizations.GET("/:id/invitations", handler.Handle(h.Organization.Handler, h.Organization.ListInvitations,
+		http.StatusOK, &model.ListOrganizationInvitationsPayload{}))
+	organizations.POST("/:id/invitations", handler.Handle(h.Organization.Handler, h.Organization.CreateInvitation,
+		http.StatusCreated, &model.CreateOrganizationInvitationPayload{}))
+	organizations.DELETE("/:id/invitations/:invitationId", handler.HandleNoContent(h.Organization.Handler,
+		h.Organization.RevokeInvitation, http.StatusNoContent, &model.RevokeOrganizationInvitationPayload{}))
+
+	invitations := r.Group("/invitations", auth.RequireAuth)
+
+	invitations.POST("/accept", handler.Handle(h.Organization.Handler, h.Organization.AcceptInvitation,
+		http.StatusOK, &model.AcceptInvitationPayload{}))
+}
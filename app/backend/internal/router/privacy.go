@@ -0,0 +1,25 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/apk471/go-boilerplate/internal/handler"
+	"github.com/apk471/go-boilerplate/internal/middleware"
+	"github.com/apk471/go-boilerplate/internal/model"
+
+	"github.com/labstack/echo/v4"
+)
+
+func registerPrivacyRoutes(r *echo.Group, h *handler.Handlers, auth *middleware.AuthMiddleware) {
+	exports := r.Group("/privacy/exports")
+
+	exports.POST("", handler.Handle(h.Privacy.Handler, h.Privacy.RequestExport,
+		http.StatusAccepted, &model.RequestDataExportPayload{}), auth.RequireAuth)
+	exports.GET("", handler.Handle(h.Privacy.Handler, h.Privacy.ListExports,
+		http.StatusOK, &model.ListDataExportsPayload{}), auth.RequireAuth)
+
+	// Opened from the emailed link, so the signed token authorizes it instead
+	// of a session
+	exports.GET("/download", handler.HandleFile(h.Privacy.Handler, h.Privacy.DownloadExport,
+		http.StatusOK, &model.DownloadDataExportPayload{}, "data-export.zip", "application/zip"))
+}
@@ -0,0 +1,19 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/apk471/go-boilerplate/internal/handler"
+	"github.com/apk471/go-boilerplate/internal/middleware"
+	"github.com/apk471/go-boilerplate/internal/model"
+
+	"github.com/labstack/echo/v4"
+)
+
+func registerRetentionRoutes(r *echo.Group, h *handler.Handlers, auth *middleware.AuthMiddleware) {
+	retention := r.Group("/retention", auth.RequireServiceToken)
+
+	// Dry run: counts what the next purge would remove
+	retention.GET("/report", handler.Handle(h.Retention.Handler, h.Retention.GetReport,
+		http.StatusOK, &model.GetRetentionReportPayload{}))
+}
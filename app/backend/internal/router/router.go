@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/apk471/go-boilerplate/internal/handler"
+	"github.com/apk471/go-boilerplate/internal/lib/jsoncodec"
 	"github.com/apk471/go-boilerplate/internal/middleware"
 	"github.com/apk471/go-boilerplate/internal/server"
 	"github.com/apk471/go-boilerplate/internal/service"
@@ -13,15 +14,20 @@ import (
 )
 
 func NewRouter(s *server.Server, h *handler.Handlers, services *service.Services) *echo.Echo {
-	middlewares := middleware.NewMiddlewares(s)
+	middlewares := middleware.NewMiddlewares(s, services)
 
 	router := echo.New()
 
 	router.HTTPErrorHandler = middlewares.Global.GlobalErrorHandler
+	router.JSONSerializer = jsoncodec.New(s.Config.Server.JSON)
 
 	// global middlewares
 	router.Use(
 		echoMiddleware.RateLimiterWithConfig(echoMiddleware.RateLimiterConfig{
+			// The batch request itself is rate limited, its sub-requests are not
+			Skipper: func(c echo.Context) bool {
+				return handler.IsBatchItem(c.Request())
+			},
 			Store: echoMiddleware.NewRateLimiterMemoryStore(rate.Limit(20)),
 			DenyHandler: func(c echo.Context, identifier string, err error) error {
 				// Record rate limit hit metrics
@@ -46,15 +52,30 @@ func NewRouter(s *server.Server, h *handler.Handlers, services *service.Services
 		middlewares.Tracing.NewRelicMiddleware(),
 		middlewares.Tracing.EnhanceTracing(),
 		middlewares.ContextEnhancer.EnhanceContext(),
+		middlewares.Timing.CollectTimings(),
+		middlewares.QueryStats.CollectQueryStats(),
 		middlewares.Global.RequestLogger(),
 		middlewares.Global.Recover(),
+		// Batch sub-requests were admitted with their batch, and event streams
+		// stay open for the whole session, which would hold slots and read as
+		// slow requests
+		middlewares.LoadShed.Shed(func(c echo.Context) bool {
+			return handler.IsBatchItem(c.Request()) || handler.IsStreamingPath(c.Path())
+		}),
 	)
 
 	// register system routes
 	registerSystemRoutes(router, h)
 
 	// register versioned routes
-	router.Group("/api/v1")
+	v1 := router.Group("/api/v1")
+	registerUserRoutes(v1, h, middlewares.Auth)
+	registerOrganizationRoutes(v1, h, middlewares.Auth)
+	registerWebhookRoutes(v1, h, middlewares.Auth)
+	registerNotificationRoutes(v1, h, middlewares.Auth)
+	registerPrivacyRoutes(v1, h, middlewares.Auth)
+	registerRetentionRoutes(v1, h, middlewares.Auth)
+	registerBatchRoutes(router, v1, h, middlewares.Auth, s.Config.Batch.MaxBodyBytes)
 
 	return router
 }
\ No newline at end of file
@@ -9,6 +9,8 @@ import (
 func registerSystemRoutes(r *echo.Echo, h *handler.Handlers) {
 	r.GET("/status", h.Health.CheckHealth)
 
+	r.GET("/version", h.Version.GetVersion)
+
 	r.Static("/static", "static")
 
 	r.GET("/docs", h.OpenAPI.ServeOpenAPIUI)
@@ -0,0 +1,22 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/apk471/go-boilerplate/internal/handler"
+	"github.com/apk471/go-boilerplate/internal/middleware"
+	"github.com/apk471/go-boilerplate/internal/model"
+
+	"github.com/labstack/echo/v4"
+)
+
+func registerUserRoutes(r *echo.Group, h *handler.Handlers, auth *middleware.AuthMiddleware) {
+	me := r.Group("/me", auth.RequireAuth)
+
+	me.GET("", handler.Handle(h.User.Handler, h.User.GetMe, http.StatusOK, &model.GetMePayload{}))
+	me.PATCH("", handler.HandlePatch(h.User.Handler, h.User.CurrentMe, h.User.UpdateMe, http.StatusOK,
+		&model.UpdateMePayload{}))
+	// Deleting the account erases all the user's data, see registerPrivacyRoutes
+	me.DELETE("", handler.HandleNoContent(h.Privacy.Handler, h.Privacy.RequestErasure, http.StatusAccepted,
+		&model.DeleteMePayload{}))
+}
@@ -0,0 +1,36 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/apk471/go-boilerplate/internal/handler"
+	"github.com/apk471/go-boilerplate/internal/middleware"
+	"github.com/apk471/go-boilerplate/internal/model"
+
+	"github.com/labstack/echo/v4"
+)
+
+func registerWebhookRoutes(r *echo.Group, h *handler.Handlers, auth *middleware.AuthMiddleware) {
+	webhooks := r.Group("/organizations/:id/webhooks", auth.RequireAuth)
+
+	webhooks.GET("", handler.Handle(h.Webhook.Handler, h.Webhook.ListEndpoints,
+		http.StatusOK, &model.ListWebhookEndpointsPayload{}))
+	webhooks.POST("", handler.Handle(h.Webhook.Handler, h.Webhook.CreateEndpoint,
+		http.StatusCreated, &model.CreateWebhookEndpointPayload{}))
+	webhooks.GET("/:endpointId", handler.Handle(h.Webhook.Handler, h.Webhook.GetEndpoint,
+		http.StatusOK, &model.GetWebhookEndpointPayload{}))
+	webhooks.PATCH("/:endpointId", handler.Handle(h.Webhook.Handler, h.Webhook.UpdateEndpoint,
+		http.StatusOK, &model.UpdateWebhookEndpointPayload{}))
+	webhooks.DELETE("/:endpointId", handler.HandleNoContent(h.Webhook.Handler, h.Webhook.DeleteEndpoint,
+		http.StatusNoContent, &model.DeleteWebhookEndpointPayload{}))
+
+	webhooks.POST("/:endpointId/rotate-secret", handler.Handle(h.Webhook.Handler, h.Webhook.RotateSecret,
+		http.StatusOK, &model.RotateWebhookSecretPayload{}))
+	webhooks.POST("/:endpointId/ping", handler.Handle(h.Webhook.Handler, h.Webhook.PingEndpoint,
+		http.StatusAccepted, &model.PingWebhookEndpointPayload{}))
+
+	webhooks.GET("/:endpointId/deliveries", handler.Handle(h.Webhook.Handler, h.Webhook.ListDeliveries,
+		http.StatusOK, &model.ListWebhookDeliveriesPayload{}))
+	webhooks.POST("/:endpointId/deliveries/:deliveryId/redeliver", handler.Handle(h.Webhook.Handler,
+		h.Webhook.Redeliver, http.StatusAccepted, &model.RedeliverWebhookPayload{}))
+}
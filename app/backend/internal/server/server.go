@@ -9,7 +9,12 @@ import (
 
 	"github.com/apk471/go-boilerplate/internal/config"
 	"github.com/apk471/go-boilerplate/internal/database"
+	"github.com/apk471/go-boilerplate/internal/lib/events"
 	job "github.com/apk471/go-boilerplate/internal/lib/jobs"
+	"github.com/apk471/go-boilerplate/internal/lib/partial"
+	"github.com/apk471/go-boilerplate/internal/lib/privacy"
+	"github.com/apk471/go-boilerplate/internal/lib/resilience"
+	"github.com/apk471/go-boilerplate/internal/lib/timing"
 	loggerPkg "github.com/apk471/go-boilerplate/internal/logger"
 	"github.com/newrelic/go-agent/v3/integrations/nrredis-v9"
 	"github.com/redis/go-redis/v9"
@@ -24,6 +29,10 @@ type Server struct {
 	Redis         *redis.Client
 	httpServer    *http.Server
 	Job           *job.JobService
+	Events        *events.Bus
+	Privacy       *privacy.Registry
+	Partial       *partial.Registry
+	Resilience    *resilience.Registry
 }
 
 func New(cfg *config.Config, logger *zerolog.Logger, loggerService *loggerPkg.LoggerService) (*Server, error) {
@@ -32,10 +41,17 @@ func New(cfg *config.Config, logger *zerolog.Logger, loggerService *loggerPkg.Lo
 		return nil, fmt.Errorf("failed to initialize database: %w", err)
 	}
 
+	// Circuit breakers and bulkheads of the dependencies, reported by the
+	// health check
+	guards := resilience.NewRegistry(logger, loggerService.GetApplication())
+	guards.Register(resilience.Postgres, cfg.Resilience.Postgres, database.Unavailable)
+
 	// Redis client with New Relic integration
 	redisClient := redis.NewClient(&redis.Options{
 		Addr: cfg.Redis.Address,
 	})
+	redisClient.AddHook(timing.RedisHook())
+	redisClient.AddHook(resilience.RedisHook(guards.Register(resilience.Redis, cfg.Resilience.Redis, resilience.RedisFailure)))
 
 	// Add New Relic Redis hooks if available
 	if loggerService != nil && loggerService.GetApplication() != nil {
@@ -52,14 +68,20 @@ func New(cfg *config.Config, logger *zerolog.Logger, loggerService *loggerPkg.Lo
 	}
 
 	// job service
-	jobService := job.NewJobService(logger, cfg)
-	jobService.InitHandlers(cfg, logger)
+	jobLogger := loggerService.Policy().ComponentLogger(*logger, "jobs")
+	jobService := job.NewJobService(&jobLogger, cfg, guards)
+	jobService.InitHandlers(cfg, &jobLogger, loggerService.GetApplication(), guards)
 
 	// Start job server
 	if err := jobService.Start(); err != nil {
 		return nil, err
 	}
 
+	// Services subscribe to the bus while they are constructed; the outbox
+	// relay starts with the HTTP server once they all have
+	eventsLogger := loggerService.Policy().ComponentLogger(*logger, "events")
+	eventBus := events.NewBus(&eventsLogger, jobService, db.Querier(), loggerService.GetApplication())
+
 	server := &Server{
 		Config:        cfg,
 		Logger:        logger,
@@ -67,6 +89,10 @@ func New(cfg *config.Config, logger *zerolog.Logger, loggerService *loggerPkg.Lo
 		DB:            db,
 		Redis:         redisClient,
 		Job:           jobService,
+		Events:        eventBus,
+		Privacy:       privacy.NewRegistry(),
+		Partial:       partial.NewRegistry(),
+		Resilience:    guards,
 	}
 
 	// Start metrics collection
@@ -95,6 +121,10 @@ func (s *Server) Start() error {
 		Str("env", s.Config.Primary.Env).
 		Msg("starting server")
 
+	if s.Events != nil {
+		s.Events.StartRelay()
+	}
+
 	return s.httpServer.ListenAndServe()
 }
 
@@ -103,6 +133,10 @@ func (s *Server) Shutdown(ctx context.Context) error {
 		return fmt.Errorf("failed to shutdown HTTP server: %w", err)
 	}
 
+	if s.Events != nil {
+		s.Events.StopRelay()
+	}
+
 	if err := s.DB.Close(); err != nil {
 		return fmt.Errorf("failed to close database connection: %w", err)
 	}
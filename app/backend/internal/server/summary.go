@@ -0,0 +1,76 @@
+package server
+
+import (
+	"strings"
+
+	"github.com/apk471/go-boilerplate/internal/buildinfo"
+	"github.com/apk471/go-boilerplate/internal/config"
+	"github.com/rs/zerolog"
+)
+
+// LogStartupSummary logs the build and the enabled subsystems with their key
+// settings. Secrets are redacted.
+func (s *Server) LogStartupSummary() {
+	cfg := s.Config
+	build := buildinfo.Get()
+
+	s.Logger.Info().
+		Str("version", build.Version).
+		Str("commit", build.Commit).
+		Str("build_date", build.BuildDate).
+		Bool("dirty", build.Dirty).
+		Str("go_version", build.GoVersion).
+		Str("env", cfg.Primary.Env).
+		Msg("build info")
+
+	s.Logger.Info().
+		Dict("server", zerolog.Dict().
+			Str("port", cfg.Server.Port).
+			Int("read_timeout_s", cfg.Server.ReadTimeout).
+			Int("write_timeout_s", cfg.Server.WriteTimeout).
+			Int("idle_timeout_s", cfg.Server.IdleTimeout).
+			Str("cors_allowed_origins", strings.Join(cfg.Server.CORSAllowedOrigins, ","))).
+		Dict("database", zerolog.Dict().
+			Str("host", cfg.Database.Host).
+			Int("port", cfg.Database.Port).
+			Str("name", cfg.Database.Name).
+			Str("user", cfg.Database.User).
+			Str("password", config.Redact(cfg.Database.Password)).
+			Str("ssl_mode", cfg.Database.SSLMode).
+			Int("max_open_conns", cfg.Database.MaxOpenConns)).
+		Dict("redis", zerolog.Dict().
+			Str("address", cfg.Redis.Address).
+			Bool("connected", s.Redis != nil)).
+		Dict("jobs", zerolog.Dict().
+			Bool("enabled", s.Job != nil)).
+		Dict("auth", zerolog.Dict().
+			Str("provider", "clerk").
+			Str("secret_key", config.Redact(cfg.Auth.SecretKey)).
+			Str("service_token", config.Redact(cfg.Auth.ServiceToken))).
+		Dict("retention", zerolog.Dict().
+			Str("schedule", cfg.Retention.Schedule).
+			Int("batch_size", cfg.Retention.BatchSize).
+			Str("archive_dir", cfg.Retention.ArchiveDir)).
+		Dict("email", zerolog.Dict().
+			Str("provider", "resend").
+			Str("api_key", config.Redact(cfg.Integration.ResendAPIKey))).
+		Dict("observability", s.observabilitySummary()).
+		Msg("startup summary")
+}
+
+func (s *Server) observabilitySummary() *zerolog.Event {
+	obs := s.Config.Observability
+	sinks := obs.Logging.Sinks
+
+	return zerolog.Dict().
+		Str("log_level", obs.GetLogLevel()).
+		Str("log_format", obs.Logging.Format).
+		Bool("log_stdout", sinks.Stdout.Enabled).
+		Bool("log_file", sinks.File.Enabled).
+		Bool("log_network", sinks.Network.Enabled).
+		Bool("log_async", sinks.Async.Enabled).
+		Bool("log_dedupe", obs.Logging.Dedupe.Enabled).
+		Bool("new_relic", s.LoggerService != nil && s.LoggerService.GetApplication() != nil).
+		Str("new_relic_license_key", config.Redact(obs.NewRelic.LicenseKey)).
+		Bool("health_checks", obs.HealthChecks.Enabled)
+}
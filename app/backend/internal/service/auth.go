@@ -1,6 +1,7 @@
 package service
 
 import (
+	"github.com/apk471/go-boilerplate/internal/lib/httpclient"
 	"github.com/apk471/go-boilerplate/internal/server"
 
 	"github.com/clerk/clerk-sdk-go/v2"
@@ -12,6 +13,9 @@ type AuthService struct {
 
 func NewAuthService(s *server.Server) *AuthService {
 	clerk.SetKey(s.Config.Auth.SecretKey)
+	clerk.SetBackend(clerk.NewBackend(&clerk.BackendConfig{
+		HTTPClient: httpclient.New("clerk", s.Config.Outbound, s.LoggerService.GetApplication()),
+	}))
 	return &AuthService{
 		server: s,
 	}
@@ -0,0 +1,679 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/apk471/go-boilerplate/internal/database"
+	"github.com/apk471/go-boilerplate/internal/lib/email"
+	"github.com/apk471/go-boilerplate/internal/lib/events"
+	job "github.com/apk471/go-boilerplate/internal/lib/jobs"
+	"github.com/apk471/go-boilerplate/internal/lib/privacy"
+	"github.com/apk471/go-boilerplate/internal/lib/resilience"
+	"github.com/apk471/go-boilerplate/internal/logger"
+	"github.com/apk471/go-boilerplate/internal/model"
+	"github.com/apk471/go-boilerplate/internal/repository"
+	"github.com/apk471/go-boilerplate/internal/server"
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	defaultNotificationsLimit = 20
+	// notificationChannelPrefix prefixes the Redis pub/sub channel carrying a
+	// user's realtime updates, so every instance can serve their streams
+	notificationChannelPrefix = "notifications:user:"
+)
+
+type NotificationService struct {
+	server        *server.Server
+	repo          *repository.NotificationRepository
+	users         *UserService
+	organizations *OrganizationService
+}
+
+func NewNotificationService(
+	s *server.Server,
+	repo *repository.NotificationRepository,
+	users *UserService,
+	organizations *OrganizationService,
+) *NotificationService {
+	svc := &NotificationService{
+		server:        s,
+		repo:          repo,
+		users:         users,
+		organizations: organizations,
+	}
+
+	if s.Job != nil {
+		s.Job.RegisterHandler(job.TaskNotificationDispatch, svc.handleDispatchTask)
+		s.Job.RegisterHandler(job.TaskNotificationDigest, svc.handleDigestTask)
+	}
+
+	if s.Events != nil {
+		svc.subscribeOrganizationEvents(s.Events)
+	}
+
+	if s.Privacy != nil {
+		s.Privacy.Register(privacy.Module{
+			Name:   "notifications",
+			Export: svc.export,
+			Erase: func(ctx context.Context, userID uuid.UUID) error {
+				return svc.repo.WithinTx(ctx, func(repo *repository.NotificationRepository) error {
+					return repo.DeleteForUser(ctx, userID)
+				})
+			},
+		})
+	}
+
+	return svc
+}
+
+func (s *NotificationService) List(
+	ctx context.Context,
+	clerkUserID string,
+	payload *model.ListNotificationsPayload,
+) (*model.PaginatedResponse[model.NotificationWithHighlights], error) {
+	user, err := s.users.GetOrCreate(ctx, clerkUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	page, limit := payload.Page, payload.Limit
+	if page == 0 {
+		page = 1
+	}
+	if limit == 0 {
+		limit = defaultNotificationsLimit
+	}
+
+	return s.repo.List(ctx, user.ID, payload.Unread, payload.Q, page, limit)
+}
+
+func (s *NotificationService) UnreadCount(ctx context.Context, clerkUserID string) (*model.NotificationUnreadCount, error) {
+	user, err := s.users.GetOrCreate(ctx, clerkUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := s.repo.CountUnread(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.NotificationUnreadCount{Count: count}, nil
+}
+
+func (s *NotificationService) MarkRead(ctx context.Context, clerkUserID string, id uuid.UUID) (*model.Notification, error) {
+	user, err := s.users.GetOrCreate(ctx, clerkUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	notification, err := s.repo.MarkRead(ctx, user.ID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.push(ctx, user.ID, model.NotificationStreamRead, notification)
+	return notification, nil
+}
+
+func (s *NotificationService) MarkAllRead(ctx context.Context, clerkUserID string) error {
+	user, err := s.users.GetOrCreate(ctx, clerkUserID)
+	if err != nil {
+		return err
+	}
+
+	updated, err := s.repo.MarkAllRead(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	if updated > 0 {
+		s.push(ctx, user.ID, model.NotificationStreamReadAll, nil)
+	}
+	return nil
+}
+
+func (s *NotificationService) GetPreferences(ctx context.Context, clerkUserID string) (*model.NotificationPreferences, error) {
+	user, err := s.users.GetOrCreate(ctx, clerkUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetPreferences(ctx, user.ID)
+}
+
+// UpdatePreferences replaces the caller's preferences. Emails already
+// scheduled keep the channel and time they were scheduled with.
+func (s *NotificationService) UpdatePreferences(
+	ctx context.Context,
+	clerkUserID string,
+	payload *model.UpdateNotificationPreferencesPayload,
+) (*model.NotificationPreferences, error) {
+	user, err := s.users.GetOrCreate(ctx, clerkUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repo.WithinTx(ctx, func(repo *repository.NotificationRepository) error {
+		return repo.ReplacePreferences(ctx, user.ID, payload)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetPreferences(ctx, user.ID)
+}
+
+// Stream delivers the caller's realtime updates until ctx is done, then
+// closes the returned channel
+func (s *NotificationService) Stream(ctx context.Context, clerkUserID string) (<-chan model.NotificationStreamMessage, error) {
+	if s.server.Redis == nil {
+		return nil, errors.New("redis is not available")
+	}
+
+	user, err := s.users.GetOrCreate(ctx, clerkUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	pubsub := s.server.Redis.Subscribe(ctx, notificationChannel(user.ID))
+	// Wait for the subscription so updates published once Stream returns are not missed
+	if _, err := pubsub.Receive(ctx); err != nil {
+		_ = pubsub.Close()
+		return nil, fmt.Errorf("failed to subscribe to notifications: %w", err)
+	}
+
+	messages := make(chan model.NotificationStreamMessage)
+	go func() {
+		defer close(messages)
+		defer func() { _ = pubsub.Close() }()
+
+		incoming := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-incoming:
+				if !ok {
+					return
+				}
+
+				var message model.NotificationStreamMessage
+				if err := json.Unmarshal([]byte(msg.Payload), &message); err != nil {
+					logger.FromContext(ctx).Warn().Err(err).Msg("failed to decode notification stream message")
+					continue
+				}
+
+				select {
+				case messages <- message:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return messages, nil
+}
+
+// Notify delivers a notification over the channels the recipient enabled for
+// its type. It returns nil if every channel is disabled, or if the recipient
+// already has a notification with the same DedupeKey.
+func (s *NotificationService) Notify(ctx context.Context, n *model.NewNotification) (*model.Notification, error) {
+	preference, err := s.repo.GetPreference(ctx, n.UserID, n.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	webhook := preference.Webhook && n.OrganizationID != nil
+	if !preference.InApp && !preference.Email && !webhook {
+		return nil, nil
+	}
+
+	data := []byte("{}")
+	if n.Data != nil {
+		if data, err = json.Marshal(n.Data); err != nil {
+			return nil, fmt.Errorf("failed to marshal notification data: %w", err)
+		}
+	}
+
+	var emailStatus *model.NotificationEmailStatus
+	if preference.Email {
+		status := model.NotificationEmailPending
+		if preference.EmailDigest {
+			status = model.NotificationEmailDigest
+		}
+		emailStatus = &status
+	}
+
+	var notification *model.Notification
+	err = s.repo.WithinTx(ctx, func(repo *repository.NotificationRepository) error {
+		created, err := repo.Create(ctx, n, data, preference.InApp, emailStatus)
+		if err != nil {
+			return err
+		}
+		notification = created
+
+		if webhook {
+			err := s.server.Events.PublishTx(ctx, repo.Querier(), model.NotificationCreatedEvent{
+				NotificationID: created.ID,
+				UserID:         created.UserID,
+				OrganizationID: *created.OrganizationID,
+				Type:           created.Type,
+				Title:          created.Title,
+				Body:           created.Body,
+				Data:           created.Data,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if n.DedupeKey != nil && errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Enqueued once the notification is committed, so the dispatch task
+	// cannot run before it can read the notification
+	if emailStatus != nil {
+		digest := *emailStatus == model.NotificationEmailDigest
+		_ = database.AfterCommit(ctx, func(ctx context.Context) error {
+			if err := s.scheduleEmail(ctx, notification, digest); err != nil {
+				s.failEmail(ctx, notification.ID, err)
+			}
+			return nil
+		})
+	}
+
+	if preference.InApp {
+		s.push(ctx, n.UserID, model.NotificationStreamCreated, notification)
+	}
+
+	return notification, nil
+}
+
+// scheduleEmail enqueues the notification's email, or the recipient's next
+// digest, for the first moment outside their quiet hours
+func (s *NotificationService) scheduleEmail(ctx context.Context, notification *model.Notification, digest bool) error {
+	if s.server.Job == nil {
+		return errors.New("job service is not available")
+	}
+
+	user, err := s.users.GetByID(ctx, notification.UserID)
+	if err != nil {
+		return err
+	}
+
+	quietHours, err := s.repo.GetQuietHours(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	at := time.Now().In(userLocation(user))
+	if digest {
+		at = nextDigestAt(at, s.server.Config.Notifications.DigestHour)
+	}
+	if quietHours != nil {
+		if end, quiet := quietHours.Until(at); quiet {
+			at = end
+		}
+	}
+
+	var task *asynq.Task
+	if digest {
+		task, err = job.NewNotificationDigestTask(user.ID, at)
+	} else {
+		task, err = job.NewNotificationDispatchTask(notification.ID, at)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create notification task: %w", err)
+	}
+
+	// A conflict means the digest for this slot is already scheduled
+	if err := s.server.Job.Enqueue(ctx, task); err != nil && !errors.Is(err, asynq.ErrTaskIDConflict) {
+		return fmt.Errorf("failed to enqueue notification task: %w", err)
+	}
+
+	return nil
+}
+
+// failEmail marks the email of a committed notification failed when it could
+// not be scheduled, since no task will ever send it
+func (s *NotificationService) failEmail(ctx context.Context, id uuid.UUID, cause error) {
+	log := logger.FromContext(ctx).With().Str("notification_id", id.String()).Logger()
+	log.Error().Err(cause).Msg("failed to schedule notification email")
+
+	if err := s.repo.SetEmailStatus(ctx, id, model.NotificationEmailFailed); err != nil {
+		log.Error().Err(err).Msg("failed to mark notification email failed")
+	}
+}
+
+func (s *NotificationService) handleDispatchTask(ctx context.Context, t *asynq.Task) error {
+	var p job.NotificationDispatchPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("failed to unmarshal notification dispatch payload: %w", err)
+	}
+
+	log := logger.FromContext(ctx).With().Str("notification_id", p.NotificationID.String()).Logger()
+
+	notification, err := s.repo.GetByID(ctx, p.NotificationID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// The recipient, and their notifications with them, was deleted
+			log.Info().Msg("Skipping notification that no longer exists")
+			return nil
+		}
+		return err
+	}
+
+	if notification.EmailStatus == nil || *notification.EmailStatus != model.NotificationEmailPending {
+		return nil
+	}
+
+	// Seen in the app while the email was held back for quiet hours
+	if notification.ReadAt != nil {
+		return s.repo.SetEmailStatus(ctx, notification.ID, model.NotificationEmailSkipped)
+	}
+
+	to, err := s.users.PrimaryEmail(ctx, notification.UserID)
+	if err != nil {
+		return err
+	}
+	if to == "" {
+		log.Info().Msg("Skipping notification email for user without a primary email address")
+		return s.repo.SetEmailStatus(ctx, notification.ID, model.NotificationEmailSkipped)
+	}
+
+	return s.repo.WithinTx(ctx, func(repo *repository.NotificationRepository) error {
+		if err := repo.SetEmailStatus(ctx, notification.ID, model.NotificationEmailSent); err != nil {
+			return err
+		}
+
+		task, err := job.NewNotificationEmailTask(notification.ID, to, notification.Title, notification.Body,
+			s.server.Config.Notifications.AppURL)
+		if err != nil {
+			return fmt.Errorf("failed to create notification email task: %w", err)
+		}
+
+		if err := s.server.Job.Enqueue(ctx, task); err != nil && !errors.Is(err, asynq.ErrTaskIDConflict) {
+			return fmt.Errorf("failed to enqueue notification email task: %w", err)
+		}
+		return nil
+	})
+}
+
+func (s *NotificationService) handleDigestTask(ctx context.Context, t *asynq.Task) error {
+	var p job.NotificationDigestPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("failed to unmarshal notification digest payload: %w", err)
+	}
+
+	log := logger.FromContext(ctx).With().Str("user_id", p.UserID.String()).Logger()
+
+	to, err := s.users.PrimaryEmail(ctx, p.UserID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			log.Info().Msg("Skipping notification digest for deleted user")
+			return nil
+		}
+		return err
+	}
+	if to == "" {
+		log.Info().Msg("Skipping notification digest for user without a primary email address")
+		return nil
+	}
+
+	return s.repo.WithinTx(ctx, func(repo *repository.NotificationRepository) error {
+		notifications, err := repo.ClaimDigest(ctx, p.UserID)
+		if err != nil {
+			return err
+		}
+		if len(notifications) == 0 {
+			return nil
+		}
+
+		items := make([]email.NotificationDigestItem, 0, len(notifications))
+		for _, notification := range notifications {
+			items = append(items, email.NotificationDigestItem{Title: notification.Title, Body: notification.Body})
+		}
+
+		// The email task is named after this task, so a retry after a failed
+		// commit does not send the digest twice
+		digestID, _ := asynq.GetTaskID(ctx)
+		task, err := job.NewNotificationDigestEmailTask(digestID, to, items, s.server.Config.Notifications.AppURL)
+		if err != nil {
+			return fmt.Errorf("failed to create notification digest email task: %w", err)
+		}
+
+		if err := s.server.Job.Enqueue(ctx, task); err != nil && !errors.Is(err, asynq.ErrTaskIDConflict) {
+			return fmt.Errorf("failed to enqueue notification digest email task: %w", err)
+		}
+
+		log.Info().Int("notifications", len(items)).Msg("Enqueued notification digest")
+		return nil
+	})
+}
+
+// notificationExport is the notifications file of a data export
+type notificationExport struct {
+	Notifications []model.Notification           `json:"notifications"`
+	Preferences   *model.NotificationPreferences `json:"preferences"`
+}
+
+func (s *NotificationService) export(ctx context.Context, userID uuid.UUID) (any, error) {
+	notifications, err := s.repo.ListAll(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	preferences, err := s.repo.GetPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return notificationExport{Notifications: notifications, Preferences: preferences}, nil
+}
+
+// push publishes a realtime update to the user's open streams once the change
+// commits. Failures are logged only; clients catch up from the list endpoint.
+func (s *NotificationService) push(
+	ctx context.Context,
+	userID uuid.UUID,
+	messageType string,
+	notification *model.Notification,
+) {
+	if s.server.Redis == nil {
+		return
+	}
+
+	_ = database.AfterCommit(ctx, func(ctx context.Context) error {
+		s.publishUpdate(ctx, userID, messageType, notification)
+		return nil
+	})
+}
+
+func (s *NotificationService) publishUpdate(
+	ctx context.Context,
+	userID uuid.UUID,
+	messageType string,
+	notification *model.Notification,
+) {
+	// The update is best effort, so the count is skipped while Postgres is
+	// failing rather than adding to its load
+	var count int
+	err := s.server.Resilience.Get(resilience.Postgres).Do(ctx, func(ctx context.Context) error {
+		var err error
+		count, err = s.repo.CountUnread(ctx, userID)
+		return err
+	})
+	if err != nil {
+		if !resilience.Skipped(err) {
+			logger.FromContext(ctx).Warn().Err(err).Msg("failed to count unread notifications")
+		}
+		return
+	}
+
+	payload, err := json.Marshal(model.NotificationStreamMessage{
+		Type:         messageType,
+		Notification: notification,
+		UnreadCount:  count,
+	})
+	if err != nil {
+		return
+	}
+
+	if err := s.server.Redis.Publish(ctx, notificationChannel(userID), payload).Err(); err != nil && !resilience.Skipped(err) {
+		logger.FromContext(ctx).Warn().Err(err).Msg("failed to publish notification update")
+	}
+}
+
+// subscribeOrganizationEvents turns organization membership changes into
+// notifications. Changes people make to themselves notify nobody.
+func (s *NotificationService) subscribeOrganizationEvents(bus *events.Bus) {
+	events.SubscribeAsync(bus, "notifications.organization_member_joined", s.notifyMemberJoined)
+	events.SubscribeAsync(bus, "notifications.organization_member_role_changed", s.notifyRoleChanged)
+	events.SubscribeAsync(bus, "notifications.organization_member_removed", s.notifyMemberRemoved)
+}
+
+func (s *NotificationService) notifyMemberJoined(ctx context.Context, e model.OrganizationMemberJoinedEvent) error {
+	organization, err := s.organizations.GetByID(ctx, e.OrganizationID)
+	if err != nil {
+		return err
+	}
+
+	joined, err := s.users.GetByID(ctx, e.UserID)
+	if err != nil {
+		return err
+	}
+	name := "A new member"
+	if joined.DisplayName != nil {
+		name = *joined.DisplayName
+	}
+
+	managers, err := s.organizations.Managers(ctx, e.OrganizationID)
+	if err != nil {
+		return err
+	}
+
+	// A retry only notifies the managers that were not notified yet, see
+	// eventDedupeKey
+	var failures []error
+	for _, manager := range managers {
+		if manager.UserID == e.UserID {
+			continue
+		}
+
+		_, err := s.Notify(ctx, &model.NewNotification{
+			UserID:         manager.UserID,
+			OrganizationID: &e.OrganizationID,
+			Type:           model.NotificationTypeOrganizationMemberJoined,
+			DedupeKey:      eventDedupeKey(ctx, model.NotificationTypeOrganizationMemberJoined),
+			Title:          name + " joined " + organization.Name,
+			Body:           "They joined as " + roleWithArticle(e.Role) + ".",
+			Data:           e,
+		})
+		if err != nil {
+			failures = append(failures, err)
+		}
+	}
+
+	return errors.Join(failures...)
+}
+
+func (s *NotificationService) notifyRoleChanged(ctx context.Context, e model.OrganizationMemberRoleChangedEvent) error {
+	if e.ChangedBy == e.UserID {
+		return nil
+	}
+
+	organization, err := s.organizations.GetByID(ctx, e.OrganizationID)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.Notify(ctx, &model.NewNotification{
+		UserID:         e.UserID,
+		OrganizationID: &e.OrganizationID,
+		Type:           model.NotificationTypeOrganizationRoleChanged,
+		DedupeKey:      eventDedupeKey(ctx, model.NotificationTypeOrganizationRoleChanged),
+		Title:          "Your role in " + organization.Name + " changed",
+		Body:           "You are now " + roleWithArticle(e.Role) + ".",
+		Data:           e,
+	})
+	return err
+}
+
+func (s *NotificationService) notifyMemberRemoved(ctx context.Context, e model.OrganizationMemberRemovedEvent) error {
+	if e.RemovedBy == e.UserID {
+		return nil
+	}
+
+	organization, err := s.organizations.GetByID(ctx, e.OrganizationID)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.Notify(ctx, &model.NewNotification{
+		UserID:         e.UserID,
+		OrganizationID: &e.OrganizationID,
+		Type:           model.NotificationTypeOrganizationRemoved,
+		DedupeKey:      eventDedupeKey(ctx, model.NotificationTypeOrganizationRemoved),
+		Title:          "You were removed from " + organization.Name,
+		Body:           "You no longer have access to " + organization.Name + ".",
+		Data:           e,
+	})
+	return err
+}
+
+// eventDedupeKey identifies the notification of a type created for the event
+// being handled, so a retried subscriber does not notify a recipient twice
+func eventDedupeKey(ctx context.Context, notificationType model.NotificationType) *string {
+	envelope, ok := events.EnvelopeFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	key := "event:" + envelope.ID.String() + ":" + string(notificationType)
+	return &key
+}
+
+func notificationChannel(userID uuid.UUID) string {
+	return notificationChannelPrefix + userID.String()
+}
+
+func userLocation(user *model.User) *time.Location {
+	location, err := time.LoadLocation(user.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// nextDigestAt returns the next digest time after now, in now's location
+func nextDigestAt(now time.Time, hour int) time.Time {
+	at := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, now.Location())
+	if !at.After(now) {
+		at = at.AddDate(0, 0, 1)
+	}
+	return at
+}
+
+func roleWithArticle(role model.OrganizationRole) string {
+	switch role {
+	case model.OrganizationRoleOwner:
+		return "an owner"
+	case model.OrganizationRoleAdmin:
+		return "an admin"
+	default:
+		return "a member"
+	}
+}
@@ -0,0 +1,595 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/apk471/go-boilerplate/internal/database"
+	"github.com/apk471/go-boilerplate/internal/errs"
+	"github.com/apk471/go-boilerplate/internal/lib/invite"
+	job "github.com/apk471/go-boilerplate/internal/lib/jobs"
+	"github.com/apk471/go-boilerplate/internal/lib/partial"
+	"github.com/apk471/go-boilerplate/internal/lib/privacy"
+	"github.com/apk471/go-boilerplate/internal/logger"
+	"github.com/apk471/go-boilerplate/internal/model"
+	"github.com/apk471/go-boilerplate/internal/repository"
+	"github.com/apk471/go-boilerplate/internal/server"
+	clerkuser "github.com/clerk/clerk-sdk-go/v2/user"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const invitationExpiryFormat = "January 2, 2006"
+
+var (
+	errInvitationInvalid = errs.NewBadRequestError("Invitation is invalid or no longer available", true,
+		errCode("INVITATION_INVALID"), nil, nil)
+	errInvitationExpired = errs.NewBadRequestError("Invitation has expired", true,
+		errCode("INVITATION_EXPIRED"), nil, nil)
+	errLastOwner = errs.NewBadRequestError("Organization must keep at least one owner", true,
+		errCode("ORGANIZATION_OWNER_REQUIRED"), nil, nil)
+)
+
+type OrganizationService struct {
+	server *server.Server
+	repo   *repository.OrganizationRepository
+	users  *UserService
+	signer *invite.Signer
+}
+
+func NewOrganizationService(
+	s *server.Server,
+	repo *repository.OrganizationRepository,
+	users *UserService,
+) *OrganizationService {
+	svc := &OrganizationService{
+		server: s,
+		repo:   repo,
+		users:  users,
+		signer: invite.NewSigner(s.Config.Auth.Invitations.SigningKey),
+	}
+
+	if s.Privacy != nil {
+		s.Privacy.Register(privacy.Module{
+			Name: "organizations",
+			Export: func(ctx context.Context, userID uuid.UUID) (any, error) {
+				return repo.ListForUser(ctx, userID)
+			},
+			Erase: func(ctx context.Context, userID uuid.UUID) error {
+				return repo.WithinTx(ctx, func(repo *repository.OrganizationRepository) error {
+					return repo.RemoveUserEverywhere(ctx, userID)
+				})
+			},
+		})
+	}
+
+	// Organizations are only returned to their members, who can see the members
+	partial.Register(s.Partial, "members", func(ctx context.Context, orgs []model.Organization) ([][]model.OrganizationMember, error) {
+		return svc.membersOf(ctx, orgs)
+	})
+	partial.Register(s.Partial, "owners", func(ctx context.Context, orgs []model.Organization) ([][]model.OrganizationMember, error) {
+		return svc.membersOf(ctx, orgs, model.OrganizationRoleOwner)
+	})
+
+	return svc
+}
+
+// membersOf loads the members of orgs in one query and groups them per organization
+func (s *OrganizationService) membersOf(
+	ctx context.Context,
+	orgs []model.Organization,
+	roles ...model.OrganizationRole,
+) ([][]model.OrganizationMember, error) {
+	ids := make([]uuid.UUID, len(orgs))
+	for i, org := range orgs {
+		ids[i] = org.ID
+	}
+
+	members, err := s.repo.ListMembersOf(ctx, ids, roles...)
+	if err != nil {
+		return nil, err
+	}
+
+	byOrg := make(map[uuid.UUID][]model.OrganizationMember, len(orgs))
+	for _, member := range members {
+		byOrg[member.OrganizationID] = append(byOrg[member.OrganizationID], member)
+	}
+
+	grouped := make([][]model.OrganizationMember, len(orgs))
+	for i, org := range orgs {
+		grouped[i] = byOrg[org.ID]
+		if grouped[i] == nil {
+			grouped[i] = []model.OrganizationMember{}
+		}
+	}
+	return grouped, nil
+}
+
+func (s *OrganizationService) List(ctx context.Context, clerkUserID string) ([]model.OrganizationWithRole, error) {
+	user, err := s.users.GetOrCreate(ctx, clerkUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.ListForUser(ctx, user.ID)
+}
+
+// Create creates an organization owned by the caller
+func (s *OrganizationService) Create(
+	ctx context.Context,
+	clerkUserID string,
+	payload *model.CreateOrganizationPayload,
+) (*model.OrganizationWithRole, error) {
+	user, err := s.users.GetOrCreate(ctx, clerkUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	var organization *model.OrganizationWithRole
+	err = s.repo.WithinTx(ctx, func(repo *repository.OrganizationRepository) error {
+		created, err := repo.CreateWithOwner(ctx, payload, user.ID)
+		if err != nil {
+			return err
+		}
+		organization = created
+
+		return s.server.Events.PublishTx(ctx, repo.Querier(), model.OrganizationCreatedEvent{
+			OrganizationID: created.ID,
+			Name:           created.Name,
+			Slug:           created.Slug,
+			CreatedBy:      user.ID,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.FromContext(ctx).Info().
+		Str("organization_id", organization.ID.String()).
+		Msg("created organization")
+
+	return organization, nil
+}
+
+func (s *OrganizationService) Get(ctx context.Context, clerkUserID string, id uuid.UUID) (*model.OrganizationWithRole, error) {
+	user, err := s.users.GetOrCreate(ctx, clerkUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetForUser(ctx, id, user.ID)
+}
+
+// ListMembers returns the organization's members, filtered and ranked by q if
+// it is not empty
+func (s *OrganizationService) ListMembers(
+	ctx context.Context,
+	clerkUserID string,
+	id uuid.UUID,
+	q string,
+) ([]model.OrganizationMember, error) {
+	if _, err := s.Get(ctx, clerkUserID, id); err != nil {
+		return nil, err
+	}
+
+	return s.repo.ListMembers(ctx, id, q)
+}
+
+// UpdateMemberRole changes a member's role. Admins manage admins and members;
+// only owners can grant or revoke the owner role.
+func (s *OrganizationService) UpdateMemberRole(
+	ctx context.Context,
+	clerkUserID string,
+	payload *model.UpdateOrganizationMemberPayload,
+) (*model.OrganizationMember, error) {
+	user, err := s.users.GetOrCreate(ctx, clerkUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	var member *model.OrganizationMember
+	err = s.repo.WithinTx(ctx, func(repo *repository.OrganizationRepository) error {
+		actorRole, err := lockAsRole(ctx, repo, payload.ID, user.ID, model.OrganizationRoleAdmin)
+		if err != nil {
+			return err
+		}
+
+		target, err := repo.GetMember(ctx, payload.ID, payload.UserID)
+		if err != nil {
+			return err
+		}
+
+		changesOwner := target.Role == model.OrganizationRoleOwner || payload.Role == model.OrganizationRoleOwner
+		if changesOwner && actorRole != model.OrganizationRoleOwner {
+			return errs.NewForbiddenError("Only owners can grant or revoke the owner role", true)
+		}
+
+		if target.Role == model.OrganizationRoleOwner && payload.Role != model.OrganizationRoleOwner {
+			if err := ensureAnotherOwner(ctx, repo, payload.ID); err != nil {
+				return err
+			}
+		}
+
+		member, err = repo.UpdateMemberRole(ctx, payload.ID, payload.UserID, payload.Role)
+		if err != nil || target.Role == payload.Role {
+			return err
+		}
+
+		return s.server.Events.PublishTx(ctx, repo.Querier(), model.OrganizationMemberRoleChangedEvent{
+			OrganizationID: payload.ID,
+			UserID:         payload.UserID,
+			PreviousRole:   target.Role,
+			Role:           payload.Role,
+			ChangedBy:      user.ID,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return member, nil
+}
+
+// RemoveMember removes a member from the organization. Members may always
+// remove themselves, as long as the organization keeps an owner.
+func (s *OrganizationService) RemoveMember(ctx context.Context, clerkUserID string, id, memberUserID uuid.UUID) error {
+	user, err := s.users.GetOrCreate(ctx, clerkUserID)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.WithinTx(ctx, func(repo *repository.OrganizationRepository) error {
+		minRole := model.OrganizationRoleAdmin
+		if memberUserID == user.ID {
+			minRole = model.OrganizationRoleMember
+		}
+
+		actorRole, err := lockAsRole(ctx, repo, id, user.ID, minRole)
+		if err != nil {
+			return err
+		}
+
+		target, err := repo.GetMember(ctx, id, memberUserID)
+		if err != nil {
+			return err
+		}
+
+		if target.Role == model.OrganizationRoleOwner {
+			if memberUserID != user.ID && actorRole != model.OrganizationRoleOwner {
+				return errs.NewForbiddenError("Only owners can remove an owner", true)
+			}
+			if err := ensureAnotherOwner(ctx, repo, id); err != nil {
+				return err
+			}
+		}
+
+		if err := repo.RemoveMember(ctx, id, memberUserID); err != nil {
+			return err
+		}
+
+		return s.server.Events.PublishTx(ctx, repo.Querier(), model.OrganizationMemberRemovedEvent{
+			OrganizationID: id,
+			UserID:         memberUserID,
+			Role:           target.Role,
+			RemovedBy:      user.ID,
+		})
+	})
+}
+
+func (s *OrganizationService) ListInvitations(
+	ctx context.Context,
+	clerkUserID string,
+	id uuid.UUID,
+) ([]model.OrganizationInvitation, error) {
+	if _, err := s.RequireRole(ctx, clerkUserID, id, model.OrganizationRoleAdmin); err != nil {
+		return nil, err
+	}
+
+	return s.repo.ListPendingInvitations(ctx, id)
+}
+
+// Invite creates an invitation and enqueues the email carrying its signed
+// token once the invitation is committed. The invitation is revoked again if
+// the email cannot be enqueued.
+func (s *OrganizationService) Invite(
+	ctx context.Context,
+	clerkUserID string,
+	payload *model.CreateOrganizationInvitationPayload,
+) (*model.OrganizationInvitation, error) {
+	if s.server.Job == nil {
+		return nil, errors.New("job service is not available")
+	}
+
+	user, err := s.users.GetOrCreate(ctx, clerkUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	organization, err := s.repo.GetForUser(ctx, payload.ID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !organization.Role.AtLeast(model.OrganizationRoleAdmin) {
+		return nil, errs.NewForbiddenError("Only admins can invite members", true)
+	}
+
+	cfg := s.server.Config.Auth.Invitations
+	email := strings.ToLower(strings.TrimSpace(payload.Email))
+	expiresAt := time.Now().Add(cfg.TTL).Truncate(time.Second)
+
+	inviterName := "A teammate"
+	if user.DisplayName != nil {
+		inviterName = *user.DisplayName
+	}
+
+	var invitation *model.OrganizationInvitation
+	err = s.repo.WithinTx(ctx, func(repo *repository.OrganizationRepository) error {
+		if err := repo.CloseExpiredInvitations(ctx, payload.ID, email); err != nil {
+			return err
+		}
+
+		created, err := repo.CreateInvitation(ctx, payload.ID, email, payload.Role, user.ID, expiresAt)
+		if err != nil {
+			return err
+		}
+		invitation = created
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = database.AfterCommit(ctx, func(ctx context.Context) error {
+		if err := s.enqueueInvitationEmail(ctx, invitation, organization.Name, inviterName); err != nil {
+			// Revoke the invitation so it does not keep the address from being invited again
+			if _, revokeErr := s.repo.RevokeInvitation(ctx, payload.ID, invitation.ID); revokeErr != nil {
+				logger.FromContext(ctx).Error().Err(revokeErr).
+					Str("invitation_id", invitation.ID.String()).
+					Msg("failed to revoke invitation without email")
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.FromContext(ctx).Info().
+		Str("organization_id", payload.ID.String()).
+		Str("invitation_id", invitation.ID.String()).
+		Msg("created organization invitation")
+
+	return invitation, nil
+}
+
+func (s *OrganizationService) enqueueInvitationEmail(
+	ctx context.Context,
+	invitation *model.OrganizationInvitation,
+	organizationName, inviterName string,
+) error {
+	acceptURL, err := invitationAcceptURL(s.server.Config.Auth.Invitations.AcceptURL,
+		s.signer.Sign(invitation.ID, invitation.ExpiresAt))
+	if err != nil {
+		return err
+	}
+
+	task, err := job.NewInvitationEmailTask(invitation.Email, organizationName, inviterName, acceptURL,
+		invitation.ExpiresAt.UTC().Format(invitationExpiryFormat))
+	if err != nil {
+		return fmt.Errorf("failed to create invitation email task: %w", err)
+	}
+
+	if err := s.server.Job.Enqueue(ctx, task); err != nil {
+		return fmt.Errorf("failed to enqueue invitation email task: %w", err)
+	}
+
+	return nil
+}
+
+func (s *OrganizationService) RevokeInvitation(ctx context.Context, clerkUserID string, id, invitationID uuid.UUID) error {
+	if _, err := s.RequireRole(ctx, clerkUserID, id, model.OrganizationRoleAdmin); err != nil {
+		return err
+	}
+
+	_, err := s.repo.RevokeInvitation(ctx, id, invitationID)
+	return err
+}
+
+// AcceptInvitation adds the caller to the organization the token was issued
+// for. The invitation must have been sent to one of the caller's verified
+// email addresses.
+func (s *OrganizationService) AcceptInvitation(
+	ctx context.Context,
+	clerkUserID string,
+	token string,
+) (*model.OrganizationWithRole, error) {
+	invitationID, err := s.signer.Verify(token, time.Now())
+	if err != nil {
+		if errors.Is(err, invite.ErrExpiredToken) {
+			return nil, errInvitationExpired
+		}
+		return nil, errInvitationInvalid
+	}
+
+	user, err := s.users.GetOrCreate(ctx, clerkUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	emails, err := verifiedEmails(ctx, clerkUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	var organization *model.OrganizationWithRole
+	err = s.repo.WithinTx(ctx, func(repo *repository.OrganizationRepository) error {
+		invitation, err := repo.GetInvitationForUpdate(ctx, invitationID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return errInvitationInvalid
+			}
+			return err
+		}
+
+		switch {
+		case invitation.AcceptedAt != nil:
+			// Accepting twice is harmless for the user who accepted
+			if invitation.AcceptedBy == nil || *invitation.AcceptedBy != user.ID {
+				return errInvitationInvalid
+			}
+		case invitation.RevokedAt != nil:
+			return errInvitationInvalid
+		case !time.Now().Before(invitation.ExpiresAt):
+			return errInvitationExpired
+		case !emails[strings.ToLower(invitation.Email)]:
+			return errs.NewForbiddenError("Invitation was sent to a different email address", true)
+		default:
+			added, err := repo.AddMember(ctx, invitation.OrganizationID, user.ID, invitation.Role)
+			if err != nil {
+				return err
+			}
+			if err := repo.MarkInvitationAccepted(ctx, invitation.ID, user.ID); err != nil {
+				return err
+			}
+			if added {
+				err := s.server.Events.PublishTx(ctx, repo.Querier(), model.OrganizationMemberJoinedEvent{
+					OrganizationID: invitation.OrganizationID,
+					UserID:         user.ID,
+					Role:           invitation.Role,
+					InvitationID:   &invitation.ID,
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		organization, err = repo.GetForUser(ctx, invitation.OrganizationID, user.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.FromContext(ctx).Info().
+		Str("organization_id", organization.ID.String()).
+		Str("invitation_id", invitationID.String()).
+		Msg("accepted organization invitation")
+
+	return organization, nil
+}
+
+// GetByID returns an organization without checking membership, for internal
+// callers such as event subscribers
+func (s *OrganizationService) GetByID(ctx context.Context, id uuid.UUID) (*model.Organization, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+// Managers returns the owners and admins of an organization without checking
+// the caller's membership, for internal callers such as event subscribers
+func (s *OrganizationService) Managers(ctx context.Context, id uuid.UUID) ([]model.OrganizationMember, error) {
+	members, err := s.repo.ListMembers(ctx, id, "")
+	if err != nil {
+		return nil, err
+	}
+
+	managers := members[:0]
+	for _, member := range members {
+		if member.Role.AtLeast(model.OrganizationRoleAdmin) {
+			managers = append(managers, member)
+		}
+	}
+	return managers, nil
+}
+
+// RequireRole returns the caller's organization if their role is at least minRole
+func (s *OrganizationService) RequireRole(
+	ctx context.Context,
+	clerkUserID string,
+	id uuid.UUID,
+	minRole model.OrganizationRole,
+) (*model.OrganizationWithRole, error) {
+	organization, err := s.Get(ctx, clerkUserID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !organization.Role.AtLeast(minRole) {
+		return nil, errs.NewForbiddenError("Insufficient organization role", true)
+	}
+
+	return organization, nil
+}
+
+// lockAsRole locks the organization and returns the caller's role if it is at least minRole
+func lockAsRole(
+	ctx context.Context,
+	repo *repository.OrganizationRepository,
+	id, userID uuid.UUID,
+	minRole model.OrganizationRole,
+) (model.OrganizationRole, error) {
+	if err := repo.Lock(ctx, id); err != nil {
+		return "", err
+	}
+
+	organization, err := repo.GetForUser(ctx, id, userID)
+	if err != nil {
+		return "", err
+	}
+
+	if !organization.Role.AtLeast(minRole) {
+		return "", errs.NewForbiddenError("Insufficient organization role", true)
+	}
+
+	return organization.Role, nil
+}
+
+func ensureAnotherOwner(ctx context.Context, repo *repository.OrganizationRepository, id uuid.UUID) error {
+	owners, err := repo.CountOwners(ctx, id)
+	if err != nil {
+		return err
+	}
+	if owners <= 1 {
+		return errLastOwner
+	}
+	return nil
+}
+
+// verifiedEmails returns the lowercased verified email addresses of a Clerk user
+func verifiedEmails(ctx context.Context, clerkUserID string) (map[string]bool, error) {
+	clerkUser, err := clerkuser.Get(ctx, clerkUserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get clerk user: %w", err)
+	}
+
+	emails := make(map[string]bool, len(clerkUser.EmailAddresses))
+	for _, address := range clerkUser.EmailAddresses {
+		if address.Verification != nil && address.Verification.Status == "verified" {
+			emails[strings.ToLower(address.EmailAddress)] = true
+		}
+	}
+
+	return emails, nil
+}
+
+func invitationAcceptURL(base, token string) (string, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", fmt.Errorf("invalid invitation accept url: %w", err)
+	}
+
+	query := u.Query()
+	query.Set("token", token)
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
+
+func errCode(code string) *string {
+	return &code
+}
@@ -0,0 +1,413 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/apk471/go-boilerplate/internal/database"
+	"github.com/apk471/go-boilerplate/internal/errs"
+	job "github.com/apk471/go-boilerplate/internal/lib/jobs"
+	"github.com/apk471/go-boilerplate/internal/lib/privacy"
+	"github.com/apk471/go-boilerplate/internal/lib/resilience"
+	"github.com/apk471/go-boilerplate/internal/logger"
+	"github.com/apk471/go-boilerplate/internal/model"
+	"github.com/apk471/go-boilerplate/internal/repository"
+	"github.com/apk471/go-boilerplate/internal/server"
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	errExportLinkInvalid = errs.NewBadRequestError("Download link is invalid", true,
+		errCode("EXPORT_LINK_INVALID"), nil, nil)
+	errExportLinkExpired = errs.NewBadRequestError("Download link has expired", true,
+		errCode("EXPORT_LINK_EXPIRED"), nil, nil)
+	errExportUnavailable = errs.NewNotFoundError("Data export is no longer available", true,
+		errCode("EXPORT_UNAVAILABLE"))
+)
+
+// PrivacyService exports and erases everything the modules registered with
+// the server's privacy registry store about a user
+type PrivacyService struct {
+	server *server.Server
+	repo   *repository.PrivacyRepository
+	users  *UserService
+	signer *privacy.Signer
+}
+
+func NewPrivacyService(s *server.Server, repo *repository.PrivacyRepository, users *UserService) *PrivacyService {
+	svc := &PrivacyService{
+		server: s,
+		repo:   repo,
+		users:  users,
+		signer: privacy.NewSigner(s.Config.Privacy.SigningKey),
+	}
+
+	if s.Job != nil {
+		s.Job.RegisterHandler(job.TaskDataExport, svc.handleExportTask)
+		s.Job.RegisterHandler(job.TaskDataErasure, svc.handleErasureTask)
+	}
+
+	if s.Privacy != nil {
+		// Registered last, so archives are dropped before anything else is erased
+		s.Privacy.Register(privacy.Module{
+			Name:  "privacy",
+			Erase: repo.DeleteArchives,
+		})
+	}
+
+	return svc
+}
+
+// RequestExport starts an export of the caller's data, or returns the one
+// already in progress. The download link is emailed when it is ready.
+func (s *PrivacyService) RequestExport(ctx context.Context, clerkUserID string) (*model.PrivacyRequest, error) {
+	user, err := s.users.GetOrCreate(ctx, clerkUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := s.open(ctx, user.ID, model.PrivacyRequestExport)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.FromContext(ctx).Info().
+		Str("privacy_request_id", request.ID.String()).
+		Msg("requested data export")
+
+	return request, nil
+}
+
+func (s *PrivacyService) ListExports(ctx context.Context, clerkUserID string) ([]model.PrivacyRequest, error) {
+	user, err := s.users.GetOrCreate(ctx, clerkUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.ListForUser(ctx, user.ID, model.PrivacyRequestExport)
+}
+
+// DownloadExport returns the ZIP archive of the export a download token was
+// issued for. The token is the only authorization, so links work from the
+// email without a session.
+func (s *PrivacyService) DownloadExport(ctx context.Context, token string) ([]byte, error) {
+	id, err := s.signer.Verify(token, time.Now())
+	if err != nil {
+		if errors.Is(err, privacy.ErrExpiredToken) {
+			return nil, errExportLinkExpired
+		}
+		return nil, errExportLinkInvalid
+	}
+
+	request, archive, err := s.repo.GetArchive(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errExportUnavailable
+		}
+		return nil, err
+	}
+
+	if err := s.repo.Audit(ctx, request, model.PrivacyAuditExportDownloaded, "", ""); err != nil {
+		return nil, err
+	}
+
+	return archive, nil
+}
+
+// RequestErasure schedules the caller's account for deletion and starts the
+// erasure of all their data. An active legal hold blocks the erasure when it
+// runs.
+func (s *PrivacyService) RequestErasure(ctx context.Context, clerkUserID string) error {
+	user, err := s.users.GetOrCreate(ctx, clerkUserID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.users.ScheduleDeletion(ctx, user.ID); err != nil {
+		return err
+	}
+
+	request, err := s.open(ctx, user.ID, model.PrivacyRequestErasure)
+	if err != nil {
+		return err
+	}
+
+	logger.FromContext(ctx).Info().
+		Str("user_id", user.ID.String()).
+		Str("privacy_request_id", request.ID.String()).
+		Msg("requested data erasure")
+
+	return nil
+}
+
+// open returns the user's open request of kind, creating it and enqueueing
+// its job if there is none
+func (s *PrivacyService) open(
+	ctx context.Context,
+	userID uuid.UUID,
+	kind model.PrivacyRequestKind,
+) (*model.PrivacyRequest, error) {
+	if s.server.Job == nil {
+		return nil, errors.New("job service is not available")
+	}
+
+	var (
+		request *model.PrivacyRequest
+		task    *asynq.Task
+	)
+	err := s.repo.WithinTx(ctx, func(repo *repository.PrivacyRepository) error {
+		opened, created, err := repo.Open(ctx, userID, kind)
+		if err != nil {
+			return err
+		}
+		request = opened
+
+		if !created {
+			return nil
+		}
+
+		action, newTask := model.PrivacyAuditExportRequested, job.NewDataExportTask
+		if kind == model.PrivacyRequestErasure {
+			action, newTask = model.PrivacyAuditErasureRequested, job.NewDataErasureTask
+		}
+
+		if err := repo.Audit(ctx, opened, action, "", ""); err != nil {
+			return err
+		}
+
+		task, err = newTask(opened.ID)
+		if err != nil {
+			return fmt.Errorf("failed to create privacy task: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if task == nil {
+		return request, nil
+	}
+
+	// The job is enqueued once the request is committed. If that fails, the
+	// request is marked failed so that it does not block a new one.
+	err = database.AfterCommit(ctx, func(ctx context.Context) error {
+		err := s.server.Job.Enqueue(ctx, task)
+		if err == nil || errors.Is(err, asynq.ErrTaskIDConflict) {
+			return nil
+		}
+
+		err = fmt.Errorf("failed to enqueue privacy task: %w", err)
+		if statusErr := s.repo.SetStatus(ctx, request.ID, model.PrivacyRequestFailed, err); statusErr != nil {
+			logger.FromContext(ctx).Error().Err(statusErr).
+				Str("privacy_request_id", request.ID.String()).
+				Msg("failed to mark privacy request without job failed")
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return request, nil
+}
+
+func (s *PrivacyService) handleExportTask(ctx context.Context, t *asynq.Task) error {
+	var p job.DataExportPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("failed to unmarshal data export payload: %w", err)
+	}
+
+	log := logger.FromContext(ctx).With().Str("privacy_request_id", p.RequestID.String()).Logger()
+
+	request, err := s.claim(ctx, p.RequestID)
+	if err != nil || request == nil {
+		return err
+	}
+
+	var archive bytes.Buffer
+	if err := s.server.Privacy.Export(ctx, request.UserID, &archive); err != nil {
+		return s.fail(ctx, request, model.PrivacyAuditExportFailed, "", err)
+	}
+
+	to, err := s.users.PrimaryEmail(ctx, request.UserID)
+	if err != nil {
+		return s.fail(ctx, request, model.PrivacyAuditExportFailed, "", err)
+	}
+
+	expiresAt := time.Now().Add(s.server.Config.Privacy.ExportTTL)
+
+	err = s.repo.WithinTx(ctx, func(repo *repository.PrivacyRepository) error {
+		completed, err := repo.CompleteExport(ctx, request.ID, archive.Bytes(), expiresAt)
+		if err != nil {
+			return err
+		}
+
+		detail := strconv.Itoa(archive.Len()) + " bytes"
+		if err := repo.Audit(ctx, completed, model.PrivacyAuditExportCompleted, "", detail); err != nil {
+			return err
+		}
+
+		if to == "" {
+			log.Info().Msg("Skipping data export email for user without a primary email address")
+			return nil
+		}
+
+		downloadURL, err := exportDownloadURL(s.server.Config.Privacy.DownloadURL, s.signer.Sign(request.ID, expiresAt))
+		if err != nil {
+			return err
+		}
+
+		task, err := job.NewDataExportEmailTask(request.ID, to, downloadURL, expiresAt.Format(invitationExpiryFormat))
+		if err != nil {
+			return fmt.Errorf("failed to create data export email task: %w", err)
+		}
+
+		if err := s.server.Job.Enqueue(ctx, task); err != nil && !errors.Is(err, asynq.ErrTaskIDConflict) {
+			return fmt.Errorf("failed to enqueue data export email task: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return s.fail(ctx, request, model.PrivacyAuditExportFailed, "", err)
+	}
+
+	log.Info().Int("bytes", archive.Len()).Msg("Completed data export")
+	return nil
+}
+
+func (s *PrivacyService) handleErasureTask(ctx context.Context, t *asynq.Task) error {
+	var p job.DataErasurePayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("failed to unmarshal data erasure payload: %w", err)
+	}
+
+	log := logger.FromContext(ctx).With().Str("privacy_request_id", p.RequestID.String()).Logger()
+
+	request, err := s.claim(ctx, p.RequestID)
+	if err != nil || request == nil {
+		return err
+	}
+
+	// Holds go with the user row, so they are checked before anything is
+	// erased and again on every retry
+	held, err := s.repo.HasActiveLegalHold(ctx, request.UserID)
+	if err != nil {
+		return err
+	}
+	if held {
+		if err := s.repo.SetStatus(ctx, request.ID, model.PrivacyRequestBlocked, nil); err != nil {
+			return err
+		}
+		if err := s.repo.Audit(ctx, request, model.PrivacyAuditErasureBlocked, "", "active legal hold"); err != nil {
+			return err
+		}
+		if err := s.users.CancelDeletion(ctx, request.UserID); err != nil {
+			return err
+		}
+
+		log.Warn().Str("user_id", request.UserID.String()).Msg("Data erasure blocked by legal hold")
+		return nil
+	}
+
+	if err := s.repo.Audit(ctx, request, model.PrivacyAuditErasureStarted, "", ""); err != nil {
+		return err
+	}
+
+	for _, module := range s.server.Privacy.ErasureOrder() {
+		if err := module.Erase(ctx, request.UserID); err != nil {
+			return s.fail(ctx, request, model.PrivacyAuditModuleFailed, module.Name, err)
+		}
+
+		if err := s.repo.Audit(ctx, request, model.PrivacyAuditModuleErased, module.Name, ""); err != nil {
+			return err
+		}
+	}
+
+	if err := s.repo.SetStatus(ctx, request.ID, model.PrivacyRequestCompleted, nil); err != nil {
+		return err
+	}
+	if err := s.repo.Audit(ctx, request, model.PrivacyAuditErasureCompleted, "", ""); err != nil {
+		return err
+	}
+
+	log.Info().Str("user_id", request.UserID.String()).Msg("Completed data erasure")
+	return nil
+}
+
+// claim moves an open request to processing. It returns nil if the request
+// no longer exists or was already finished.
+func (s *PrivacyService) claim(ctx context.Context, id uuid.UUID) (*model.PrivacyRequest, error) {
+	request, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			logger.FromContext(ctx).Info().Msg("Skipping privacy request that no longer exists")
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if request.Status != model.PrivacyRequestPending && request.Status != model.PrivacyRequestProcessing {
+		return nil, nil
+	}
+
+	if err := s.repo.SetStatus(ctx, request.ID, model.PrivacyRequestProcessing, nil); err != nil {
+		return nil, err
+	}
+	request.Status = model.PrivacyRequestProcessing
+
+	return request, nil
+}
+
+// fail audits a failed attempt and returns err so the task is retried. The
+// request is marked failed once retries are exhausted, which lets the user
+// request it again.
+func (s *PrivacyService) fail(
+	ctx context.Context,
+	request *model.PrivacyRequest,
+	action, module string,
+	err error,
+) error {
+	log := logger.FromContext(ctx)
+
+	// The audit of a failed attempt is best effort, so it is skipped while
+	// Postgres is failing
+	auditErr := s.server.Resilience.Get(resilience.Postgres).Do(ctx, func(ctx context.Context) error {
+		return s.repo.Audit(ctx, request, action, module, err.Error())
+	})
+	if auditErr != nil && !resilience.Skipped(auditErr) {
+		log.Warn().Err(auditErr).Msg("failed to audit privacy request failure")
+	}
+
+	retried, _ := asynq.GetRetryCount(ctx)
+	maxRetry, ok := asynq.GetMaxRetry(ctx)
+	if ok && retried >= maxRetry {
+		if statusErr := s.repo.SetStatus(ctx, request.ID, model.PrivacyRequestFailed, err); statusErr != nil {
+			log.Warn().Err(statusErr).Msg("failed to mark privacy request failed")
+		}
+	}
+
+	log.Error().Err(err).Str("module", module).Msg("Privacy request attempt failed")
+	return err
+}
+
+func exportDownloadURL(base, token string) (string, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", fmt.Errorf("invalid data export download url: %w", err)
+	}
+
+	query := u.Query()
+	query.Set("token", token)
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
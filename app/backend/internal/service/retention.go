@@ -0,0 +1,202 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	job "github.com/apk471/go-boilerplate/internal/lib/jobs"
+	"github.com/apk471/go-boilerplate/internal/lib/retention"
+	"github.com/apk471/go-boilerplate/internal/logger"
+	"github.com/apk471/go-boilerplate/internal/model"
+	"github.com/apk471/go-boilerplate/internal/server"
+	"github.com/hibiken/asynq"
+)
+
+const day = 24 * time.Hour
+
+// retentionPolicies are purged in this order by every scheduled run
+var retentionPolicies = []retention.Policy{
+	{
+		Name:      "webhook_deliveries",
+		Table:     "webhook_deliveries",
+		AgeColumn: "created_at",
+		MaxAge:    30 * day,
+		Condition: "status IN ('succeeded', 'failed')",
+		Mode:      retention.ModeDelete,
+	},
+	{
+		Name:      "outbox_events",
+		Table:     "outbox_events",
+		AgeColumn: "dispatched_at",
+		MaxAge:    7 * day,
+		Mode:      retention.ModeDelete,
+	},
+	{
+		// Unread in-app notifications are kept until they are read
+		Name:      "notifications",
+		Table:     "notifications",
+		AgeColumn: "created_at",
+		MaxAge:    180 * day,
+		Condition: "read_at IS NOT NULL OR NOT in_app",
+		Mode:      retention.ModeDelete,
+	},
+	{
+		Name:      "organization_invitations",
+		Table:     "organization_invitations",
+		AgeColumn: "expires_at",
+		MaxAge:    30 * day,
+		Condition: "accepted_at IS NULL",
+		Mode:      retention.ModeDelete,
+	},
+	{
+		Name:      "privacy_audit_log",
+		Table:     "privacy_audit_log",
+		AgeColumn: "created_at",
+		MaxAge:    3 * 365 * day,
+		Mode:      retention.ModeDelete,
+		Archive:   true,
+	},
+}
+
+// RetentionService purges rows that outlived their retention policy on the
+// configured schedule
+type RetentionService struct {
+	server   *server.Server
+	purger   *retention.Purger
+	policies []retention.Policy
+}
+
+func NewRetentionService(s *server.Server) (*RetentionService, error) {
+	for _, policy := range retentionPolicies {
+		if err := policy.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	svc := &RetentionService{
+		server:   s,
+		purger:   retention.NewPurger(s.DB.Querier(), s.Config.Retention.ArchiveDir),
+		policies: retentionPolicies,
+	}
+
+	if s.Job != nil {
+		s.Job.RegisterHandler(job.TaskRetentionPurge, svc.handlePurgeTask)
+		if err := s.Job.RegisterPeriodicTask(s.Config.Retention.Schedule, job.NewRetentionPurgeTask()); err != nil {
+			return nil, err
+		}
+	}
+
+	return svc, nil
+}
+
+// Report returns what each policy would purge if it ran now, without purging
+func (s *RetentionService) Report(ctx context.Context) (*model.RetentionReport, error) {
+	cfg := s.server.Config.Retention
+	now := time.Now()
+
+	report := &model.RetentionReport{
+		GeneratedAt: now,
+		Policies:    make([]model.RetentionPolicyReport, 0, len(s.policies)),
+	}
+
+	perRun := int64(cfg.BatchSize * cfg.MaxBatches)
+	for _, policy := range s.policies {
+		cutoff := now.Add(-policy.MaxAge)
+
+		eligible, err := s.purger.Count(ctx, policy, cutoff)
+		if err != nil {
+			return nil, err
+		}
+
+		report.Policies = append(report.Policies, model.RetentionPolicyReport{
+			Name:             policy.Name,
+			Table:            policy.Table,
+			Mode:             string(policy.Mode),
+			MaxAgeSeconds:    int64(policy.MaxAge / time.Second),
+			Archive:          policy.Archive,
+			Cutoff:           cutoff,
+			EligibleRows:     eligible.Rows,
+			OldestEligibleAt: eligible.Oldest,
+			Runs:             int((eligible.Rows + perRun - 1) / perRun),
+		})
+	}
+
+	return report, nil
+}
+
+func (s *RetentionService) handlePurgeTask(ctx context.Context, t *asynq.Task) error {
+	var failures []error
+	for _, policy := range s.policies {
+		if err := s.purge(ctx, policy); err != nil {
+			failures = append(failures, err)
+		}
+	}
+
+	return errors.Join(failures...)
+}
+
+// purge runs batches of policy until it is caught up or MaxBatches ran
+func (s *RetentionService) purge(ctx context.Context, policy retention.Policy) error {
+	cfg := s.server.Config.Retention
+	log := logger.FromContext(ctx).With().Str("policy", policy.Name).Logger()
+
+	cutoff := time.Now().Add(-policy.MaxAge)
+	start := time.Now()
+
+	var (
+		purged  int
+		batches int
+		err     error
+	)
+	for batches < cfg.MaxBatches {
+		var n int
+		n, err = s.purger.PurgeBatch(ctx, policy, cutoff, cfg.BatchSize)
+		if err != nil {
+			break
+		}
+		purged += n
+		batches++
+
+		if n < cfg.BatchSize {
+			break
+		}
+		log.Debug().Int("purged", purged).Int("batches", batches).Msg("Retention purge in progress")
+	}
+	duration := time.Since(start)
+
+	if errors.Is(err, retention.ErrLocked) {
+		log.Info().Msg("Skipping retention policy purged by another instance")
+		return nil
+	}
+
+	s.recordMetrics(policy, purged, batches, duration, err)
+
+	if err != nil {
+		log.Error().Err(err).Int("purged", purged).Msg("Retention purge failed")
+		return fmt.Errorf("retention policy %s: %w", policy.Name, err)
+	}
+
+	if batches == cfg.MaxBatches {
+		log.Warn().Int("purged", purged).Msg("Retention purge stopped at the batch limit, the rest waits for the next run")
+	} else if purged > 0 {
+		log.Info().Int("purged", purged).Dur("duration", duration).Msg("Retention purge completed")
+	}
+	return nil
+}
+
+func (s *RetentionService) recordMetrics(policy retention.Policy, purged, batches int, duration time.Duration, err error) {
+	app := s.server.LoggerService.GetApplication()
+	if app == nil {
+		return
+	}
+
+	prefix := "Retention/" + policy.Name + "/"
+	app.RecordCustomMetric(prefix+"Purged", float64(purged))
+	app.RecordCustomMetric(prefix+"Batches", float64(batches))
+	app.RecordCustomMetric(prefix+"Duration", float64(duration.Milliseconds()))
+	if err != nil {
+		app.RecordCustomMetric(prefix+"Errors", 1)
+	}
+}
@@ -7,15 +7,39 @@ import (
 )
 
 type Services struct {
-	Auth *AuthService
-	Job  *job.JobService
+	Auth         *AuthService
+	User         *UserService
+	Organization *OrganizationService
+	Webhook      *WebhookService
+	Notification *NotificationService
+	Privacy      *PrivacyService
+	Retention    *RetentionService
+	Job          *job.JobService
 }
 
 func NewServices(s *server.Server, repos *repository.Repositories) (*Services, error) {
 	authService := NewAuthService(s)
+	userService := NewUserService(s, repos.User)
+	organizationService := NewOrganizationService(s, repos.Organization, userService)
+	webhookService := NewWebhookService(s, repos.Webhook, userService, organizationService)
+	notificationService := NewNotificationService(s, repos.Notification, userService, organizationService)
+	// Constructed after the services owning user data: they register their
+	// privacy modules as they are built, and erasure runs the modules in reverse
+	privacyService := NewPrivacyService(s, repos.Privacy, userService)
+
+	retentionService, err := NewRetentionService(s)
+	if err != nil {
+		return nil, err
+	}
 
 	return &Services{
-		Job:  s.Job,
-		Auth: authService,
+		Job:          s.Job,
+		Auth:         authService,
+		User:         userService,
+		Organization: organizationService,
+		Webhook:      webhookService,
+		Notification: notificationService,
+		Privacy:      privacyService,
+		Retention:    retentionService,
 	}, nil
 }
\ No newline at end of file
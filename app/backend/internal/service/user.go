@@ -0,0 +1,266 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/apk471/go-boilerplate/internal/database"
+	"github.com/apk471/go-boilerplate/internal/errs"
+	"github.com/apk471/go-boilerplate/internal/lib/patch"
+	"github.com/apk471/go-boilerplate/internal/lib/privacy"
+	"github.com/apk471/go-boilerplate/internal/lib/resilience"
+	"github.com/apk471/go-boilerplate/internal/lib/timing"
+	"github.com/apk471/go-boilerplate/internal/logger"
+	"github.com/apk471/go-boilerplate/internal/model"
+	"github.com/apk471/go-boilerplate/internal/repository"
+	"github.com/apk471/go-boilerplate/internal/server"
+	"github.com/clerk/clerk-sdk-go/v2"
+	clerkuser "github.com/clerk/clerk-sdk-go/v2/user"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+const userCacheTTL = 5 * time.Minute
+
+type UserService struct {
+	server *server.Server
+	repo   *repository.UserRepository
+}
+
+func NewUserService(s *server.Server, repo *repository.UserRepository) *UserService {
+	svc := &UserService{
+		server: s,
+		repo:   repo,
+	}
+
+	if s.Privacy != nil {
+		s.Privacy.Register(privacy.Module{
+			Name: "profile",
+			Export: func(ctx context.Context, userID uuid.UUID) (any, error) {
+				return svc.repo.GetByID(ctx, userID)
+			},
+			Erase: svc.erase,
+		})
+	}
+
+	return svc
+}
+
+type userKey struct{}
+
+// WithUser returns a context in which GetOrCreate returns user for its Clerk
+// subject without a lookup. The auth middleware stores the caller this way.
+func WithUser(ctx context.Context, user *model.User) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+// GetOrCreate returns the local user for a Clerk subject, creating it on first
+// use. The caller of the request is read from the context, see WithUser.
+func (s *UserService) GetOrCreate(ctx context.Context, clerkUserID string) (*model.User, error) {
+	if user, ok := ctx.Value(userKey{}).(*model.User); ok && user.ClerkUserID == clerkUserID {
+		return user, nil
+	}
+
+	return s.Load(ctx, clerkUserID)
+}
+
+// Load is GetOrCreate ignoring the user of the context, for the auth
+// middleware to look up the caller of every request. Lookups are cached in
+// Redis and fall back to the database if Redis fails.
+func (s *UserService) Load(ctx context.Context, clerkUserID string) (*model.User, error) {
+	if user := s.getCached(ctx, clerkUserID); user != nil {
+		return user, nil
+	}
+
+	user, err := s.repo.GetOrCreateByClerkID(ctx, clerkUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.setCached(ctx, user)
+	return user, nil
+}
+
+// GetByID returns a user by local ID, for callers such as jobs that have no
+// Clerk subject at hand
+func (s *UserService) GetByID(ctx context.Context, id uuid.UUID) (*model.User, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+// UpdateProfile applies the changed fields of a profile patch. The update
+// fails with 412 if the profile changed since the version the patch was
+// applied to.
+func (s *UserService) UpdateProfile(ctx context.Context, clerkUserID string, payload *model.UpdateMePayload, p patch.Patch) (*model.User, error) {
+	user, err := s.GetOrCreate(ctx, clerkUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	var version *time.Time
+	if p.Version != "" {
+		at, err := model.VersionTime(p.Version)
+		if err != nil {
+			return nil, err
+		}
+		version = &at
+	}
+
+	updated, err := s.repo.UpdateProfile(ctx, user.ID, payload, version)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) && version != nil {
+			s.invalidateCached(ctx, clerkUserID)
+			return nil, errs.NewPreconditionFailedError(
+				"The profile was modified since it was read, fetch it again and retry", true)
+		}
+		return nil, err
+	}
+
+	logger.FromContext(ctx).Info().
+		Str("user_id", updated.ID.String()).
+		Strs("changed", p.Changes.Fields()).
+		Msg("updated user profile")
+
+	s.setCached(ctx, updated)
+	return updated, nil
+}
+
+// ScheduleDeletion marks the account for deletion, keeping an earlier
+// schedule. The privacy erasure job removes it.
+func (s *UserService) ScheduleDeletion(ctx context.Context, id uuid.UUID) error {
+	user, err := s.repo.ScheduleDeletion(ctx, id, time.Now())
+	if err != nil {
+		return err
+	}
+
+	s.setCached(ctx, user)
+	return nil
+}
+
+// CancelDeletion clears the deletion schedule of an account whose erasure was
+// blocked
+func (s *UserService) CancelDeletion(ctx context.Context, id uuid.UUID) error {
+	user, err := s.repo.CancelDeletion(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	s.setCached(ctx, user)
+	return nil
+}
+
+// PrimaryEmail returns the user's primary Clerk email address, or "" if they
+// have none
+func (s *UserService) PrimaryEmail(ctx context.Context, id uuid.UUID) (string, error) {
+	user, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return "", err
+	}
+
+	clerkUser, err := clerkuser.Get(ctx, user.ClerkUserID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get clerk user: %w", err)
+	}
+
+	if clerkUser.PrimaryEmailAddressID == nil {
+		return "", nil
+	}
+	for _, address := range clerkUser.EmailAddresses {
+		if address.ID == *clerkUser.PrimaryEmailAddressID {
+			return address.EmailAddress, nil
+		}
+	}
+
+	return "", nil
+}
+
+// erase deletes the Clerk user and the local row. Data of other modules that
+// is not erased before goes with the row by foreign key cascade.
+func (s *UserService) erase(ctx context.Context, id uuid.UUID) error {
+	user, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Deleted by an earlier attempt
+			return nil
+		}
+		return err
+	}
+
+	if _, err := clerkuser.Delete(ctx, user.ClerkUserID); err != nil {
+		var apiErr *clerk.APIErrorResponse
+		if !errors.As(err, &apiErr) || apiErr.HTTPStatusCode != http.StatusNotFound {
+			return fmt.Errorf("failed to delete clerk user: %w", err)
+		}
+	}
+
+	if err := s.repo.Delete(ctx, user.ID); err != nil {
+		return err
+	}
+	s.invalidateCached(ctx, user.ClerkUserID)
+
+	return nil
+}
+
+func userCacheKey(clerkUserID string) string {
+	return "user:clerk:" + clerkUserID
+}
+
+func (s *UserService) getCached(ctx context.Context, clerkUserID string) *model.User {
+	if s.server.Redis == nil {
+		return nil
+	}
+	defer timing.Start(ctx, "cache")()
+
+	data, err := s.server.Redis.Get(ctx, userCacheKey(clerkUserID)).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) && !resilience.Skipped(err) {
+			logger.FromContext(ctx).Warn().Err(err).Msg("failed to read user from cache")
+		}
+		return nil
+	}
+
+	var user model.User
+	if err := json.Unmarshal(data, &user); err != nil {
+		logger.FromContext(ctx).Warn().Err(err).Msg("failed to decode cached user")
+		return nil
+	}
+
+	return &user
+}
+
+// setCached caches user once the transaction that read or wrote it commits,
+// so a rolled back batch does not leave its version in the cache
+func (s *UserService) setCached(ctx context.Context, user *model.User) {
+	if s.server.Redis == nil {
+		return
+	}
+
+	data, err := json.Marshal(user)
+	if err != nil {
+		return
+	}
+
+	_ = database.AfterCommit(ctx, func(ctx context.Context) error {
+		if err := s.server.Redis.Set(ctx, userCacheKey(user.ClerkUserID), data, userCacheTTL).Err(); err != nil && !resilience.Skipped(err) {
+			logger.FromContext(ctx).Warn().Err(err).Msg("failed to cache user")
+		}
+		return nil
+	})
+}
+
+func (s *UserService) invalidateCached(ctx context.Context, clerkUserID string) {
+	if s.server.Redis == nil {
+		return
+	}
+
+	_ = database.AfterCommit(ctx, func(ctx context.Context) error {
+		if err := s.server.Redis.Del(ctx, userCacheKey(clerkUserID)).Err(); err != nil && !resilience.Skipped(err) {
+			logger.FromContext(ctx).Warn().Err(err).Msg("failed to invalidate cached user")
+		}
+		return nil
+	})
+}
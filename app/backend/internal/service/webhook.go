@@ -0,0 +1,555 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apk471/go-boilerplate/internal/buildinfo"
+	"github.com/apk471/go-boilerplate/internal/database"
+	"github.com/apk471/go-boilerplate/internal/errs"
+	"github.com/apk471/go-boilerplate/internal/lib/events"
+	"github.com/apk471/go-boilerplate/internal/lib/httpclient"
+	job "github.com/apk471/go-boilerplate/internal/lib/jobs"
+	"github.com/apk471/go-boilerplate/internal/lib/webhook"
+	"github.com/apk471/go-boilerplate/internal/logger"
+	"github.com/apk471/go-boilerplate/internal/model"
+	"github.com/apk471/go-boilerplate/internal/repository"
+	"github.com/apk471/go-boilerplate/internal/server"
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// webhookSecretRotationGrace is how long the previous secret keeps signing deliveries after a rotation
+	webhookSecretRotationGrace = 24 * time.Hour
+	// webhookResponseDrainLimit bounds how much of a response body is read so
+	// the connection can be reused. Bodies are not stored, so receivers
+	// cannot be used to read responses back.
+	webhookResponseDrainLimit = 64 << 10
+
+	webhookRetryBaseDelay = 30 * time.Second
+	webhookRetryMaxDelay  = 6 * time.Hour
+
+	defaultWebhookDeliveriesLimit = 20
+)
+
+var errWebhookEndpointDisabled = errs.NewBadRequestError("Webhook endpoint is disabled", true,
+	errCode("WEBHOOK_ENDPOINT_DISABLED"), nil, nil)
+
+type WebhookService struct {
+	server        *server.Server
+	repo          *repository.WebhookRepository
+	users         *UserService
+	organizations *OrganizationService
+	client        *http.Client
+}
+
+func NewWebhookService(
+	s *server.Server,
+	repo *repository.WebhookRepository,
+	users *UserService,
+	organizations *OrganizationService,
+) *WebhookService {
+	clientOptions := []httpclient.Option{
+		httpclient.WithTimeout(s.Config.Webhooks.Timeout),
+		// Deliveries are retried by the job queue, recording each attempt
+		httpclient.WithRetries(0),
+		// Redirects are reported as failures rather than followed, so a
+		// delivery only ever reaches the registered URL
+		httpclient.WithCheckRedirect(func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}),
+	}
+	// Endpoint URLs are user input, so they must not reach internal services
+	if !s.Config.Webhooks.AllowPrivateAddresses {
+		clientOptions = append(clientOptions, httpclient.WithTransport(httpclient.PublicTransport()))
+	}
+
+	svc := &WebhookService{
+		server:        s,
+		repo:          repo,
+		users:         users,
+		organizations: organizations,
+		client:        httpclient.New("webhooks", s.Config.Outbound, s.LoggerService.GetApplication(), clientOptions...),
+	}
+
+	if s.Job != nil {
+		s.Job.RegisterHandler(job.TaskWebhookDelivery, svc.handleDeliveryTask)
+		s.Job.RegisterRetryDelay(job.TaskWebhookDelivery, webhookRetryDelay)
+	}
+
+	if s.Events != nil {
+		svc.subscribeOrganizationEvents(s.Events)
+	}
+
+	return svc
+}
+
+func (s *WebhookService) ListEndpoints(
+	ctx context.Context,
+	clerkUserID string,
+	organizationID uuid.UUID,
+) ([]model.WebhookEndpoint, error) {
+	if _, err := s.organizations.RequireRole(ctx, clerkUserID, organizationID, model.OrganizationRoleAdmin); err != nil {
+		return nil, err
+	}
+
+	return s.repo.ListEndpoints(ctx, organizationID)
+}
+
+// CreateEndpoint registers an endpoint and returns it with its signing secret,
+// which is not returned again until the secret is rotated
+func (s *WebhookService) CreateEndpoint(
+	ctx context.Context,
+	clerkUserID string,
+	payload *model.CreateWebhookEndpointPayload,
+) (*model.WebhookEndpointWithSecret, error) {
+	if _, err := s.organizations.RequireRole(ctx, clerkUserID, payload.ID, model.OrganizationRoleAdmin); err != nil {
+		return nil, err
+	}
+
+	if err := s.validateURL(payload.URL); err != nil {
+		return nil, err
+	}
+
+	user, err := s.users.GetOrCreate(ctx, clerkUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint, err := s.repo.CreateEndpoint(ctx, payload, secret, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.FromContext(ctx).Info().
+		Str("organization_id", payload.ID.String()).
+		Str("webhook_endpoint_id", endpoint.ID.String()).
+		Msg("created webhook endpoint")
+
+	return &model.WebhookEndpointWithSecret{WebhookEndpoint: *endpoint, Secret: secret}, nil
+}
+
+func (s *WebhookService) GetEndpoint(
+	ctx context.Context,
+	clerkUserID string,
+	organizationID, id uuid.UUID,
+) (*model.WebhookEndpoint, error) {
+	if _, err := s.organizations.RequireRole(ctx, clerkUserID, organizationID, model.OrganizationRoleAdmin); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetEndpoint(ctx, organizationID, id)
+}
+
+func (s *WebhookService) UpdateEndpoint(
+	ctx context.Context,
+	clerkUserID string,
+	payload *model.UpdateWebhookEndpointPayload,
+) (*model.WebhookEndpoint, error) {
+	if _, err := s.organizations.RequireRole(ctx, clerkUserID, payload.ID, model.OrganizationRoleAdmin); err != nil {
+		return nil, err
+	}
+
+	if payload.URL != nil {
+		if err := s.validateURL(*payload.URL); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.repo.UpdateEndpoint(ctx, payload)
+}
+
+func (s *WebhookService) DeleteEndpoint(ctx context.Context, clerkUserID string, organizationID, id uuid.UUID) error {
+	if _, err := s.organizations.RequireRole(ctx, clerkUserID, organizationID, model.OrganizationRoleAdmin); err != nil {
+		return err
+	}
+
+	return s.repo.DeleteEndpoint(ctx, organizationID, id)
+}
+
+// RotateSecret issues a new signing secret. Deliveries are signed with both
+// the new and the previous secret for webhookSecretRotationGrace.
+func (s *WebhookService) RotateSecret(
+	ctx context.Context,
+	clerkUserID string,
+	organizationID, id uuid.UUID,
+) (*model.WebhookEndpointWithSecret, error) {
+	if _, err := s.organizations.RequireRole(ctx, clerkUserID, organizationID, model.OrganizationRoleAdmin); err != nil {
+		return nil, err
+	}
+
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint, err := s.repo.RotateSecret(ctx, organizationID, id, secret, time.Now().Add(webhookSecretRotationGrace))
+	if err != nil {
+		return nil, err
+	}
+
+	logger.FromContext(ctx).Info().
+		Str("webhook_endpoint_id", id.String()).
+		Msg("rotated webhook secret")
+
+	return &model.WebhookEndpointWithSecret{WebhookEndpoint: *endpoint, Secret: secret}, nil
+}
+
+// Ping sends a webhook.ping event to the endpoint, ignoring its event filter
+func (s *WebhookService) Ping(
+	ctx context.Context,
+	clerkUserID string,
+	organizationID, id uuid.UUID,
+) (*model.WebhookDelivery, error) {
+	endpoint, err := s.GetEndpoint(ctx, clerkUserID, organizationID, id)
+	if err != nil {
+		return nil, err
+	}
+	if !endpoint.Enabled {
+		return nil, errWebhookEndpointDisabled
+	}
+
+	event := newWebhookEvent(organizationID, model.WebhookEventPing, map[string]string{"endpointId": id.String()})
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal webhook event: %w", err)
+	}
+
+	return s.enqueueDelivery(ctx, endpoint.ID, event.ID, event.Type, payload, nil)
+}
+
+func (s *WebhookService) ListDeliveries(
+	ctx context.Context,
+	clerkUserID string,
+	payload *model.ListWebhookDeliveriesPayload,
+) (*model.PaginatedResponse[model.WebhookDelivery], error) {
+	if _, err := s.GetEndpoint(ctx, clerkUserID, payload.ID, payload.EndpointID); err != nil {
+		return nil, err
+	}
+
+	page, limit := payload.Page, payload.Limit
+	if page == 0 {
+		page = 1
+	}
+	if limit == 0 {
+		limit = defaultWebhookDeliveriesLimit
+	}
+
+	return s.repo.ListDeliveries(ctx, payload.EndpointID, page, limit)
+}
+
+// Redeliver sends a past delivery's event again as a new delivery
+func (s *WebhookService) Redeliver(
+	ctx context.Context,
+	clerkUserID string,
+	payload *model.RedeliverWebhookPayload,
+) (*model.WebhookDelivery, error) {
+	endpoint, err := s.GetEndpoint(ctx, clerkUserID, payload.ID, payload.EndpointID)
+	if err != nil {
+		return nil, err
+	}
+	if !endpoint.Enabled {
+		return nil, errWebhookEndpointDisabled
+	}
+
+	original, err := s.repo.GetDelivery(ctx, endpoint.ID, payload.DeliveryID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.enqueueDelivery(ctx, endpoint.ID, original.EventID, original.EventType, original.Payload, &original.ID)
+}
+
+// Publish delivers an event to every enabled endpoint of the organization
+// subscribed to eventType
+func (s *WebhookService) Publish(ctx context.Context, organizationID uuid.UUID, eventType string, data any) error {
+	endpoints, err := s.repo.ListSubscribedEndpoints(ctx, organizationID, eventType)
+	if err != nil {
+		return err
+	}
+	if len(endpoints) == 0 {
+		return nil
+	}
+
+	return s.publish(ctx, endpoints, newWebhookEvent(organizationID, eventType, data))
+}
+
+func (s *WebhookService) publish(ctx context.Context, endpoints []model.WebhookEndpoint, event model.WebhookEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook event: %w", err)
+	}
+
+	var failures []error
+	for _, endpoint := range endpoints {
+		if _, err := s.enqueueDelivery(ctx, endpoint.ID, event.ID, event.Type, payload, nil); err != nil {
+			failures = append(failures, err)
+		}
+	}
+
+	return errors.Join(failures...)
+}
+
+func (s *WebhookService) enqueueDelivery(
+	ctx context.Context,
+	endpointID, eventID uuid.UUID,
+	eventType string,
+	payload json.RawMessage,
+	redeliveryOf *uuid.UUID,
+) (*model.WebhookDelivery, error) {
+	if s.server.Job == nil {
+		return nil, errors.New("job service is not available")
+	}
+
+	delivery, err := s.repo.CreateDelivery(ctx, endpointID, eventID, eventType, payload, redeliveryOf)
+	if err != nil {
+		return nil, err
+	}
+
+	task, err := job.NewWebhookDeliveryTask(delivery.ID, s.server.Config.Webhooks.MaxRetries)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook delivery task: %w", err)
+	}
+
+	err = database.AfterCommit(ctx, func(ctx context.Context) error {
+		if err := s.server.Job.Enqueue(ctx, task); err != nil {
+			if failErr := s.repo.FailDelivery(ctx, delivery.ID, "failed to enqueue delivery"); failErr != nil {
+				logger.FromContext(ctx).Error().Err(failErr).Msg("failed to mark webhook delivery failed")
+			}
+			return fmt.Errorf("failed to enqueue webhook delivery task: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+func (s *WebhookService) handleDeliveryTask(ctx context.Context, t *asynq.Task) error {
+	var p job.WebhookDeliveryPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("failed to unmarshal webhook delivery payload: %w", err)
+	}
+
+	log := logger.FromContext(ctx).With().Str("webhook_delivery_id", p.DeliveryID.String()).Logger()
+
+	delivery, err := s.repo.GetDeliveryByID(ctx, p.DeliveryID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// The endpoint, and its deliveries with it, was deleted
+			log.Info().Msg("Skipping webhook delivery that no longer exists")
+			return nil
+		}
+		return err
+	}
+
+	endpoint, err := s.repo.GetEndpointByID(ctx, delivery.EndpointID)
+	if err != nil {
+		return err
+	}
+
+	log = log.With().Str("webhook_endpoint_id", endpoint.ID.String()).Logger()
+
+	if !endpoint.Enabled {
+		log.Info().Msg("Skipping webhook delivery to disabled endpoint")
+		return s.repo.FailDelivery(ctx, delivery.ID, "endpoint disabled")
+	}
+
+	attempt := s.attempt(ctx, endpoint, delivery)
+
+	retryCount, _ := asynq.GetRetryCount(ctx)
+	maxRetry, _ := asynq.GetMaxRetry(ctx)
+	final := retryCount >= maxRetry
+
+	if attempt.Status != model.WebhookDeliveryStatusSucceeded {
+		attempt.Status = model.WebhookDeliveryStatusRetrying
+		if final {
+			attempt.Status = model.WebhookDeliveryStatusFailed
+		}
+	}
+
+	if err := s.repo.RecordAttempt(ctx, delivery.ID, attempt); err != nil {
+		return err
+	}
+
+	if attempt.Status == model.WebhookDeliveryStatusSucceeded {
+		log.Info().Int("latency_ms", attempt.LatencyMs).Msg("Delivered webhook")
+		return s.repo.RecordEndpointSuccess(ctx, endpoint.ID)
+	}
+
+	log.Warn().
+		Int("retry", retryCount).
+		Str("error", *attempt.Error).
+		Msg("Webhook delivery attempt failed")
+
+	if final {
+		updated, err := s.repo.RecordEndpointFailure(ctx, endpoint.ID, s.server.Config.Webhooks.DisableAfterFailures)
+		if err != nil {
+			return err
+		}
+		if !updated.Enabled {
+			log.Warn().
+				Int("consecutive_failures", updated.ConsecutiveFailures).
+				Msg("Disabled webhook endpoint after repeated failures")
+		}
+	}
+
+	return fmt.Errorf("webhook delivery failed: %s", *attempt.Error)
+}
+
+// attempt posts the delivery payload to the endpoint and reports the outcome
+func (s *WebhookService) attempt(
+	ctx context.Context,
+	endpoint *model.WebhookEndpoint,
+	delivery *model.WebhookDelivery,
+) repository.WebhookAttempt {
+	now := time.Now()
+	secrets := []string{endpoint.Secret}
+	if endpoint.PreviousSecret != nil && endpoint.PreviousSecretExpiresAt != nil &&
+		now.Before(*endpoint.PreviousSecretExpiresAt) {
+		secrets = append(secrets, *endpoint.PreviousSecret)
+	}
+
+	failed := func(attempt repository.WebhookAttempt, err string) repository.WebhookAttempt {
+		attempt.Status = model.WebhookDeliveryStatusFailed
+		attempt.Error = &err
+		return attempt
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return failed(repository.WebhookAttempt{}, err.Error())
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "boilerplate-webhooks/"+buildinfo.Get().Version)
+	req.Header.Set(webhook.HeaderID, delivery.EventID.String())
+	req.Header.Set(webhook.HeaderEvent, delivery.EventType)
+	req.Header.Set(webhook.HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(webhook.HeaderSignature, webhook.Sign(now, delivery.Payload, secrets...))
+
+	resp, err := s.client.Do(req)
+	attempt := repository.WebhookAttempt{LatencyMs: int(time.Since(now).Milliseconds())}
+	if err != nil {
+		return failed(attempt, err.Error())
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, webhookResponseDrainLimit))
+	attempt.ResponseStatus = &resp.StatusCode
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return failed(attempt, "unexpected response status "+strconv.Itoa(resp.StatusCode))
+	}
+
+	attempt.Status = model.WebhookDeliveryStatusSucceeded
+	return attempt
+}
+
+// validateURL requires https in production so payloads and signatures are not
+// sent in clear text, and rejects literal private and local addresses
+func (s *WebhookService) validateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+		return errs.NewBadRequestError("Validation failed", true, nil,
+			[]errs.FieldError{{Field: "url", Error: "must be an absolute http or https URL"}}, nil)
+	}
+
+	if u.Scheme != "https" && s.server.Config.Observability.IsProduction() {
+		return errs.NewBadRequestError("Validation failed", true, nil,
+			[]errs.FieldError{{Field: "url", Error: "must use https"}}, nil)
+	}
+
+	// Hostnames are checked when deliveries connect, after they are resolved
+	if ip, err := netip.ParseAddr(strings.Trim(u.Hostname(), "[]")); err == nil &&
+		httpclient.BlockedAddress(ip) && !s.server.Config.Webhooks.AllowPrivateAddresses {
+		return errs.NewBadRequestError("Validation failed", true, nil,
+			[]errs.FieldError{{Field: "url", Error: "must not point at a private or local address"}}, nil)
+	}
+
+	return nil
+}
+
+// subscribeOrganizationEvents forwards organization domain events, and
+// notifications whose recipient enabled the webhook channel, to the
+// organization's webhooks
+func (s *WebhookService) subscribeOrganizationEvents(bus *events.Bus) {
+	events.SubscribeAsync(bus, "webhooks.organization_created",
+		func(ctx context.Context, e model.OrganizationCreatedEvent) error {
+			return s.forward(ctx, e.OrganizationID, e)
+		})
+	events.SubscribeAsync(bus, "webhooks.organization_member_joined",
+		func(ctx context.Context, e model.OrganizationMemberJoinedEvent) error {
+			return s.forward(ctx, e.OrganizationID, e)
+		})
+	events.SubscribeAsync(bus, "webhooks.organization_member_role_changed",
+		func(ctx context.Context, e model.OrganizationMemberRoleChangedEvent) error {
+			return s.forward(ctx, e.OrganizationID, e)
+		})
+	events.SubscribeAsync(bus, "webhooks.organization_member_removed",
+		func(ctx context.Context, e model.OrganizationMemberRemovedEvent) error {
+			return s.forward(ctx, e.OrganizationID, e)
+		})
+	events.SubscribeAsync(bus, "webhooks.notification_created",
+		func(ctx context.Context, e model.NotificationCreatedEvent) error {
+			return s.forward(ctx, e.OrganizationID, e)
+		})
+}
+
+// forward publishes a domain event under its own ID and time, so receivers
+// can deduplicate deliveries repeated when the subscriber is retried
+func (s *WebhookService) forward(ctx context.Context, organizationID uuid.UUID, event events.Event) error {
+	endpoints, err := s.repo.ListSubscribedEndpoints(ctx, organizationID, event.EventType())
+	if err != nil || len(endpoints) == 0 {
+		return err
+	}
+
+	webhookEvent := newWebhookEvent(organizationID, event.EventType(), event)
+	if envelope, ok := events.EnvelopeFromContext(ctx); ok {
+		webhookEvent.ID = envelope.ID
+		webhookEvent.CreatedAt = envelope.OccurredAt
+	}
+
+	return s.publish(ctx, endpoints, webhookEvent)
+}
+
+func newWebhookEvent(organizationID uuid.UUID, eventType string, data any) model.WebhookEvent {
+	return model.WebhookEvent{
+		ID:             uuid.New(),
+		Type:           eventType,
+		OrganizationID: organizationID,
+		CreatedAt:      time.Now().UTC(),
+		Data:           data,
+	}
+}
+
+// webhookRetryDelay backs off exponentially from webhookRetryBaseDelay up to
+// webhookRetryMaxDelay, with up to 10% jitter so retries to one endpoint spread out
+func webhookRetryDelay(n int, _ error, _ *asynq.Task) time.Duration {
+	delay := webhookRetryMaxDelay
+	if n < 20 {
+		delay = min(webhookRetryBaseDelay<<n, webhookRetryMaxDelay)
+	}
+
+	return delay + rand.N(delay/10+1)
+}
@@ -0,0 +1,64 @@
+package sqlerr
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// RowError is the error of one item of a bulk operation
+type RowError struct {
+	// Index is the position of the item in the slice passed to the operation
+	Index int
+	// Err is an *Error for database errors, so ErrCode works on it
+	Err error
+}
+
+// NewRowError converts database errors of err to *Error
+func NewRowError(index int, err error) RowError {
+	var pgerr *pgconn.PgError
+	if errors.As(err, &pgerr) {
+		err = ConvertPgError(pgerr)
+	}
+	return RowError{Index: index, Err: err}
+}
+
+func (e RowError) Error() string {
+	return fmt.Sprintf("row %d: %v", e.Index, e.Err)
+}
+
+func (e RowError) Unwrap() error {
+	return e.Err
+}
+
+// BulkError lists the items a bulk operation could not write, in order. The
+// other items were written. HandleError maps it like the error of its first
+// row.
+type BulkError struct {
+	Rows []RowError
+}
+
+func (e *BulkError) Error() string {
+	if len(e.Rows) == 1 {
+		return e.Rows[0].Error()
+	}
+	return fmt.Sprintf("%d rows failed, first %v", len(e.Rows), e.Rows[0])
+}
+
+func (e *BulkError) Unwrap() []error {
+	errs := make([]error, len(e.Rows))
+	for i, row := range e.Rows {
+		errs[i] = row
+	}
+	return errs
+}
+
+// Indexes returns the positions of the items that failed
+func (e *BulkError) Indexes() []int {
+	indexes := make([]int, len(e.Rows))
+	for i, row := range e.Rows {
+		indexes[i] = row.Index
+	}
+	return indexes
+}
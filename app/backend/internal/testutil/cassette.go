@@ -0,0 +1,176 @@
+package testutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/apk471/go-boilerplate/internal/lib/httpclient"
+)
+
+// recordCassettes sends outbound requests to the real services and rewrites
+// cassettes with their responses:
+//
+//	BOILERPLATE_INTEGRATION_RESEND_API_KEY=re_... go test ./internal/lib/email/ -record
+//	go test ./internal/lib/httpclient/ -record
+var recordCassettes = flag.Bool("record", false, "record outbound HTTP cassettes")
+
+// Recording reports whether cassettes are being recorded, e.g. for tests to
+// use real credentials
+func Recording() bool {
+	return *recordCassettes
+}
+
+// Interaction is a recorded outbound request and its response
+type Interaction struct {
+	Method          string      `json:"method"`
+	URL             string      `json:"url"`
+	RequestHeaders  http.Header `json:"requestHeaders,omitempty"`
+	RequestBody     string      `json:"requestBody,omitempty"`
+	Status          int         `json:"status"`
+	ResponseHeaders http.Header `json:"responseHeaders,omitempty"`
+	ResponseBody    string      `json:"responseBody,omitempty"`
+}
+
+// Cassette is an http.RoundTripper that replays the interactions stored in
+// testdata/cassettes/<name>.json of the calling package, in order. With
+// -record it sends requests with the default transport instead and writes
+// them to that file when the test ends, with secrets redacted. Pass it to
+// httpclient.WithTransport.
+type Cassette struct {
+	t      testing.TB
+	path   string
+	record bool
+
+	mu           sync.Mutex
+	interactions []Interaction
+	next         int
+}
+
+// NewCassette loads the cassette name, or starts recording it with -record
+func NewCassette(t testing.TB, name string) *Cassette {
+	t.Helper()
+
+	c := &Cassette{
+		t:      t,
+		path:   filepath.Join("testdata", "cassettes", name+".json"),
+		record: *recordCassettes,
+	}
+
+	if c.record {
+		t.Cleanup(c.save)
+		return c
+	}
+
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		t.Fatalf("failed to read cassette %s (run with -record to create it): %v", c.path, err)
+	}
+	if err := json.Unmarshal(data, &c.interactions); err != nil {
+		t.Fatalf("failed to decode cassette %s: %v", c.path, err)
+	}
+	t.Cleanup(func() {
+		if c.next < len(c.interactions) {
+			t.Errorf("cassette %s has %d unplayed interactions", c.path, len(c.interactions)-c.next)
+		}
+	})
+
+	return c
+}
+
+func (c *Cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	var requestBody []byte
+	if req.Body != nil {
+		var err error
+		if requestBody, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		_ = req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(requestBody))
+	}
+
+	if c.record {
+		return c.recordInteraction(req, requestBody)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.next >= len(c.interactions) {
+		return nil, fmt.Errorf("cassette %s has no interaction left for %s %s", c.path, req.Method, req.URL)
+	}
+	interaction := c.interactions[c.next]
+	url := httpclient.RedactURL(req.URL)
+	if interaction.Method != req.Method || interaction.URL != url || interaction.RequestBody != string(requestBody) {
+		return nil, fmt.Errorf("cassette %s expected %s %s as interaction %d, got %s %s",
+			c.path, interaction.Method, interaction.URL, c.next, req.Method, url)
+	}
+	c.next++
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", interaction.Status, http.StatusText(interaction.Status)),
+		StatusCode:    interaction.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        interaction.ResponseHeaders.Clone(),
+		Body:          io.NopCloser(bytes.NewReader([]byte(interaction.ResponseBody))),
+		ContentLength: int64(len(interaction.ResponseBody)),
+		Request:       req,
+	}, nil
+}
+
+func (c *Cassette) recordInteraction(req *http.Request, requestBody []byte) (*http.Response, error) {
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	responseBody, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(responseBody))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.interactions = append(c.interactions, Interaction{
+		Method:          req.Method,
+		URL:             httpclient.RedactURL(req.URL),
+		RequestHeaders:  httpclient.RedactHeaders(req.Header),
+		RequestBody:     string(requestBody),
+		Status:          resp.StatusCode,
+		ResponseHeaders: httpclient.RedactHeaders(resp.Header),
+		ResponseBody:    string(responseBody),
+	})
+	return resp, nil
+}
+
+func (c *Cassette) save() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, err := json.MarshalIndent(c.interactions, "", "  ")
+	if err != nil {
+		c.t.Errorf("failed to encode cassette %s: %v", c.path, err)
+		return
+	}
+	data = append(data, '\n')
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		c.t.Errorf("failed to create cassette directory: %v", err)
+		return
+	}
+	if err := os.WriteFile(c.path, data, 0o600); err != nil {
+		c.t.Errorf("failed to write cassette %s: %v", c.path, err)
+	}
+}
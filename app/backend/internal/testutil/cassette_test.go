@@ -0,0 +1,73 @@
+package testutil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCassetteRecordsWithoutSecrets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", "session=secret-cookie")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	c := &Cassette{t: t, path: filepath.Join(t.TempDir(), "recorded.json"), record: true}
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/items?page=2&token=secret-token", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer secret-bearer")
+
+	resp, err := c.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("RoundTrip() error = %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("status = %d, want the server's %d", resp.StatusCode, http.StatusNoContent)
+	}
+
+	c.save()
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		t.Fatalf("cassette was not written: %v", err)
+	}
+
+	recorded := string(data)
+	for _, secret := range []string{"secret-token", "secret-bearer", "secret-cookie"} {
+		if strings.Contains(recorded, secret) {
+			t.Errorf("cassette contains %q:\n%s", secret, recorded)
+		}
+	}
+	if !strings.Contains(recorded, "page=2") {
+		t.Errorf("cassette lost the query parameters that are not secret:\n%s", recorded)
+	}
+}
+
+func TestCassetteReplaysInOrder(t *testing.T) {
+	c := &Cassette{t: t, path: "inline", interactions: []Interaction{
+		{Method: http.MethodGet, URL: "https://api.example.com/a?key=REDACTED", Status: http.StatusOK, ResponseBody: "first"},
+		{Method: http.MethodGet, URL: "https://api.example.com/b", Status: http.StatusNotFound},
+	}}
+
+	// The secret is matched against its redacted form
+	req, _ := http.NewRequest(http.MethodGet, "https://api.example.com/a?key=secret", nil)
+	resp, err := c.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("RoundTrip() error = %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	req, _ = http.NewRequest(http.MethodGet, "https://api.example.com/a", nil)
+	if _, err := c.RoundTrip(req); err == nil {
+		t.Error("a request out of order was replayed")
+	}
+}
@@ -0,0 +1,120 @@
+package testutil
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/legacy"
+)
+
+// OpenAPISpecPath is the spec served at /docs, relative to the module root.
+const OpenAPISpecPath = "static/openapi.json"
+
+// Contract validates recorded responses against the OpenAPI document.
+type Contract struct {
+	router routers.Router
+}
+
+// NewContract loads and validates the OpenAPI document at OpenAPISpecPath.
+func NewContract(t testing.TB) *Contract {
+	t.Helper()
+
+	root, err := moduleRoot()
+	if err != nil {
+		t.Fatalf("failed to locate module root: %v", err)
+	}
+
+	doc, err := openapi3.NewLoader().LoadFromFile(filepath.Join(root, OpenAPISpecPath))
+	if err != nil {
+		t.Fatalf("failed to load OpenAPI spec: %v", err)
+	}
+
+	if err := doc.Validate(context.Background()); err != nil {
+		t.Fatalf("invalid OpenAPI spec: %v", err)
+	}
+
+	// Match operations on path alone so test requests need not use the
+	// documented server host
+	doc.Servers = nil
+
+	router, err := legacy.NewRouter(doc)
+	if err != nil {
+		t.Fatalf("failed to build OpenAPI router: %v", err)
+	}
+
+	return &Contract{router: router}
+}
+
+// Validate checks that rec matches the response schema documented for the
+// operation serving req. The status must be documented too, either on its own
+// or through the operation's default response, which every operation points
+// at the shared error body.
+func (c *Contract) Validate(t testing.TB, req *http.Request, rec *httptest.ResponseRecorder) {
+	t.Helper()
+
+	route, pathParams, err := c.router.FindRoute(req)
+	if err != nil {
+		t.Fatalf("no OpenAPI operation for %s %s: %v", req.Method, req.URL.Path, err)
+	}
+
+	input := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: &openapi3filter.RequestValidationInput{
+			Request:    req,
+			PathParams: pathParams,
+			Route:      route,
+		},
+		Status:  rec.Code,
+		Header:  rec.Header(),
+		Options: &openapi3filter.Options{IncludeResponseStatus: true},
+	}
+	input.SetBodyBytes(rec.Body.Bytes())
+
+	if err := openapi3filter.ValidateResponse(req.Context(), input); err != nil {
+		t.Errorf("response for %s %s violates OpenAPI operation %s: %v",
+			req.Method, req.URL.Path, route.Operation.OperationID, err)
+	}
+}
+
+// AssertContract records req against h, validates the response against the
+// OpenAPI spec and compares it with the named golden file.
+func (c *Contract) AssertContract(
+	t testing.TB,
+	name string,
+	h http.Handler,
+	req *http.Request,
+	opts ...GoldenOption,
+) *httptest.ResponseRecorder {
+	t.Helper()
+
+	rec := Record(h, req)
+	c.Validate(t, req, rec)
+	AssertGolden(t, name, rec, opts...)
+
+	return rec
+}
+
+// moduleRoot walks up from the working directory to the nearest go.mod.
+func moduleRoot() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return dir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", os.ErrNotExist
+		}
+		dir = parent
+	}
+}
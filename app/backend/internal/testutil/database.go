@@ -0,0 +1,223 @@
+package testutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apk471/go-boilerplate/internal/database"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+)
+
+// TestDatabaseDSNEnv names the environment variable holding the DSN of the
+// database used by repository tests. Tests are skipped when it is not set.
+const TestDatabaseDSNEnv = "BOILERPLATE_TEST_DB_DSN"
+
+const (
+	setupTimeout = 30 * time.Second
+	// templateLockKey serializes template migration and cloning across test
+	// processes, since Postgres refuses to copy a template that has other sessions.
+	templateLockKey = 7_471_001
+)
+
+// TestDatabase is a migrated Postgres database shared by all tests in a process.
+type TestDatabase struct {
+	Pool *pgxpool.Pool
+
+	templateOnce sync.Once
+	templateName string
+	templateErr  error
+}
+
+var (
+	sharedOnce sync.Once
+	shared     *TestDatabase
+	errShared  error
+)
+
+// NewTestDatabase returns the process-wide test database, connecting and
+// running migrations on first use.
+func NewTestDatabase(t testing.TB) *TestDatabase {
+	t.Helper()
+
+	dsn := os.Getenv(TestDatabaseDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set, skipping database test", TestDatabaseDSNEnv)
+	}
+
+	sharedOnce.Do(func() {
+		shared, errShared = openTestDatabase(dsn)
+	})
+	if errShared != nil {
+		t.Fatalf("failed to set up test database: %v", errShared)
+	}
+
+	return shared
+}
+
+func openTestDatabase(dsn string) (*TestDatabase, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), setupTimeout)
+	defer cancel()
+
+	poolConfig, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse test database dsn: %w", err)
+	}
+
+	if err := migrate(ctx, poolConfig.ConnConfig); err != nil {
+		return nil, err
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create test database pool: %w", err)
+	}
+
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to ping test database: %w", err)
+	}
+
+	return &TestDatabase{
+		Pool:         pool,
+		templateName: poolConfig.ConnConfig.Database + "_template",
+	}, nil
+}
+
+func migrate(ctx context.Context, connConfig *pgx.ConnConfig) error {
+	conn, err := pgx.ConnectConfig(ctx, connConfig)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", connConfig.Database, err)
+	}
+	defer conn.Close(ctx)
+
+	logger := zerolog.Nop()
+	if err := database.MigrateConn(ctx, &logger, conn); err != nil {
+		return fmt.Errorf("failed to migrate %s: %w", connConfig.Database, err)
+	}
+
+	return nil
+}
+
+// Tx begins a transaction that is rolled back when the test finishes. Every
+// call holds its own pooled connection, so parallel tests can each use a Tx
+// without seeing one another's writes.
+func (d *TestDatabase) Tx(t testing.TB) pgx.Tx {
+	t.Helper()
+	return Savepoint(t, d.Pool)
+}
+
+// Savepoint begins a transaction on q and rolls it back when the test
+// finishes. When q is itself a transaction pgx issues a SAVEPOINT instead,
+// which lets subtests share their parent's Tx while keeping their writes apart.
+func Savepoint(t testing.TB, q database.Querier) pgx.Tx {
+	t.Helper()
+
+	tx, err := q.Begin(context.Background())
+	if err != nil {
+		t.Fatalf("failed to begin test transaction: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := tx.Rollback(context.Background()); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+			t.Errorf("failed to roll back test transaction: %v", err)
+		}
+	})
+
+	return tx
+}
+
+// Clone creates a fresh database from a migrated template and drops it when
+// the test finishes. Use it for tests that need to commit, or that rely on
+// behaviour across connections such as row locks.
+func (d *TestDatabase) Clone(t testing.TB) *pgxpool.Pool {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), setupTimeout)
+	defer cancel()
+
+	d.templateOnce.Do(func() {
+		d.templateErr = d.withTemplateLock(ctx, d.prepareTemplate)
+	})
+	if d.templateErr != nil {
+		t.Fatalf("failed to prepare template database: %v", d.templateErr)
+	}
+
+	name := "test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	err := d.withTemplateLock(ctx, func(conn *pgxpool.Conn) error {
+		_, err := conn.Exec(ctx, fmt.Sprintf("CREATE DATABASE %s TEMPLATE %s",
+			pgx.Identifier{name}.Sanitize(), pgx.Identifier{d.templateName}.Sanitize()))
+		return err
+	})
+	if err != nil {
+		t.Fatalf("failed to clone template database: %v", err)
+	}
+
+	poolConfig := d.Pool.Config().Copy()
+	poolConfig.ConnConfig.Database = name
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		t.Fatalf("failed to connect to cloned database: %v", err)
+	}
+
+	t.Cleanup(func() {
+		pool.Close()
+		_, err := d.Pool.Exec(context.Background(), fmt.Sprintf("DROP DATABASE IF EXISTS %s WITH (FORCE)",
+			pgx.Identifier{name}.Sanitize()))
+		if err != nil {
+			t.Errorf("failed to drop cloned database %s: %v", name, err)
+		}
+	})
+
+	return pool
+}
+
+// prepareTemplate creates the template database if needed and brings its
+// schema up to date. The template is kept between runs.
+func (d *TestDatabase) prepareTemplate(conn *pgxpool.Conn) error {
+	ctx := context.Background()
+
+	var exists bool
+	err := conn.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM pg_database WHERE datname = $1)",
+		d.templateName).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to look up template database: %w", err)
+	}
+
+	if !exists {
+		if _, err := conn.Exec(ctx, "CREATE DATABASE "+pgx.Identifier{d.templateName}.Sanitize()); err != nil {
+			return fmt.Errorf("failed to create template database: %w", err)
+		}
+	}
+
+	connConfig := d.Pool.Config().ConnConfig.Copy()
+	connConfig.Database = d.templateName
+
+	return migrate(ctx, connConfig)
+}
+
+func (d *TestDatabase) withTemplateLock(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := d.Pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", templateLockKey); err != nil {
+		return fmt.Errorf("failed to acquire template lock: %w", err)
+	}
+	defer func() {
+		_, _ = conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", templateLockKey)
+	}()
+
+	return fn(conn)
+}
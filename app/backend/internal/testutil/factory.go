@@ -0,0 +1,85 @@
+package testutil
+
+import (
+	"time"
+
+	"github.com/apk471/go-boilerplate/internal/model"
+	"github.com/google/uuid"
+)
+
+// Factory builds test entities from a set of defaults. Overrides are applied
+// in order after the defaults, so later overrides win.
+type Factory[T any] struct {
+	defaults func() T
+}
+
+// NewFactory creates a factory that starts every entity from defaults().
+// defaults is called once per entity, so it can generate unique values.
+func NewFactory[T any](defaults func() T) *Factory[T] {
+	return &Factory[T]{defaults: defaults}
+}
+
+// Build returns a single entity with overrides applied.
+func (f *Factory[T]) Build(overrides ...func(*T)) T {
+	entity := f.defaults()
+	for _, override := range overrides {
+		override(&entity)
+	}
+	return entity
+}
+
+// BuildN returns n entities, each built with the same overrides.
+func (f *Factory[T]) BuildN(n int, overrides ...func(*T)) []T {
+	entities := make([]T, 0, n)
+	for range n {
+		entities = append(entities, f.Build(overrides...))
+	}
+	return entities
+}
+
+// Now returns the current time truncated to the precision Postgres stores,
+// so values round-trip through timestamptz columns unchanged.
+func Now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// BaseFactory builds model.Base values with a random ID and matching
+// created/updated timestamps.
+var BaseFactory = NewFactory(func() model.Base {
+	now := Now()
+	return model.Base{
+		BaseWithId:        model.BaseWithId{ID: uuid.New()},
+		BaseWithCreatedAt: model.BaseWithCreatedAt{CreatedAt: now},
+		BaseWithUpdatedAt: model.BaseWithUpdatedAt{UpdatedAt: now},
+	}
+})
+
+// NewBase is shorthand for BaseFactory.Build.
+func NewBase(overrides ...func(*model.Base)) model.Base {
+	return BaseFactory.Build(overrides...)
+}
+
+// WithID overrides the entity ID.
+func WithID(id uuid.UUID) func(*model.Base) {
+	return func(b *model.Base) {
+		b.ID = id
+	}
+}
+
+// WithCreatedAt overrides the creation time and keeps UpdatedAt from
+// predating it.
+func WithCreatedAt(t time.Time) func(*model.Base) {
+	return func(b *model.Base) {
+		b.CreatedAt = t
+		if b.UpdatedAt.Before(t) {
+			b.UpdatedAt = t
+		}
+	}
+}
+
+// WithUpdatedAt overrides the last update time.
+func WithUpdatedAt(t time.Time) func(*model.Base) {
+	return func(b *model.Base) {
+		b.UpdatedAt = t
+	}
+}
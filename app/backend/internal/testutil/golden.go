@@ -0,0 +1,174 @@
+package testutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// updateGolden rewrites golden files instead of comparing against them:
+//
+//	go test ./internal/handler/... -update
+var updateGolden = flag.Bool("update", false, "update golden files")
+
+// NormalizedValue replaces volatile fields in recorded snapshots.
+const NormalizedValue = "<normalized>"
+
+// DefaultVolatileFields are JSON keys whose values change between runs.
+var DefaultVolatileFields = []string{
+	"id",
+	"request_id",
+	"requestId",
+	"timestamp",
+	"createdAt",
+	"updatedAt",
+	"response_time",
+}
+
+// DefaultSnapshotHeaders are the response headers recorded in snapshots.
+var DefaultSnapshotHeaders = []string{
+	"Content-Type",
+	"X-Request-ID",
+}
+
+// volatileHeaders are recorded as present but with their value normalized.
+var volatileHeaders = map[string]bool{
+	"X-Request-Id": true,
+}
+
+// Snapshot is the normalized form of an HTTP response stored in a golden file.
+type Snapshot struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    any               `json:"body"`
+}
+
+type goldenOptions struct {
+	headers        []string
+	volatileFields map[string]bool
+}
+
+// GoldenOption customizes how a response is snapshotted.
+type GoldenOption func(*goldenOptions)
+
+// WithHeaders replaces the set of recorded response headers.
+func WithHeaders(headers ...string) GoldenOption {
+	return func(o *goldenOptions) {
+		o.headers = headers
+	}
+}
+
+// WithVolatileFields adds JSON keys to normalize on top of DefaultVolatileFields.
+func WithVolatileFields(fields ...string) GoldenOption {
+	return func(o *goldenOptions) {
+		for _, field := range fields {
+			o.volatileFields[field] = true
+		}
+	}
+}
+
+// Record serves req with h and returns the recorded response.
+func Record(h http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+// AssertGolden compares rec against testdata/golden/<name>.golden.json in the
+// calling package, or rewrites that file when tests run with -update.
+func AssertGolden(t testing.TB, name string, rec *httptest.ResponseRecorder, opts ...GoldenOption) {
+	t.Helper()
+
+	options := goldenOptions{
+		headers:        DefaultSnapshotHeaders,
+		volatileFields: make(map[string]bool, len(DefaultVolatileFields)),
+	}
+	for _, field := range DefaultVolatileFields {
+		options.volatileFields[field] = true
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	got, err := json.MarshalIndent(snapshot(rec, options), "", "  ")
+	if err != nil {
+		t.Fatalf("failed to marshal snapshot: %v", err)
+	}
+	got = append(got, '\n')
+
+	path := filepath.Join("testdata", "golden", name+".golden.json")
+
+	if *updateGolden {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("failed to create golden directory: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0o600); err != nil {
+			t.Fatalf("failed to write golden file: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read golden file %s (run with -update to create it): %v", path, err)
+	}
+
+	if !bytes.Equal(want, got) {
+		t.Errorf("response does not match %s (run with -update to accept)\n--- want\n%s\n--- got\n%s", path, want, got)
+	}
+}
+
+func snapshot(rec *httptest.ResponseRecorder, options goldenOptions) Snapshot {
+	s := Snapshot{Status: rec.Code}
+
+	for _, name := range options.headers {
+		value := rec.Header().Get(name)
+		if value == "" {
+			continue
+		}
+		if s.Headers == nil {
+			s.Headers = make(map[string]string, len(options.headers))
+		}
+		if volatileHeaders[http.CanonicalHeaderKey(name)] {
+			value = NormalizedValue
+		}
+		s.Headers[http.CanonicalHeaderKey(name)] = value
+	}
+
+	body := rec.Body.Bytes()
+	var decoded any
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		// Not JSON, keep the raw body so the diff is still readable
+		s.Body = string(body)
+		return s
+	}
+	s.Body = normalize(decoded, options.volatileFields)
+
+	return s
+}
+
+func normalize(v any, volatileFields map[string]bool) any {
+	switch value := v.(type) {
+	case map[string]any:
+		for key, field := range value {
+			if volatileFields[key] && field != nil {
+				value[key] = NormalizedValue
+				continue
+			}
+			value[key] = normalize(field, volatileFields)
+		}
+		return value
+	case []any:
+		for i, item := range value {
+			value[i] = normalize(item, volatileFields)
+		}
+		return value
+	default:
+		return v
+	}
+}
@@ -0,0 +1,17 @@
+package testutil
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/apk471/go-boilerplate/internal/logger"
+	"github.com/rs/zerolog"
+)
+
+// CaptureLogs returns a context whose logger writes JSON lines at every level
+// to the returned buffer, so a test can assert on what code under test logged.
+func CaptureLogs(ctx context.Context) (context.Context, *bytes.Buffer) {
+	var buf bytes.Buffer
+	l := zerolog.New(&buf).Level(zerolog.TraceLevel)
+	return logger.WithContext(ctx, &l), &buf
+}
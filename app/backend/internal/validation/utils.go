@@ -39,6 +39,14 @@ func BindAndValidate(c echo.Context, payload Validatable) error {
 	return nil
 }
 
+// Validate validates a payload bound by other means than BindAndValidate
+func Validate(payload Validatable) error {
+	if msg, fieldErrors := validateStruct(payload); fieldErrors != nil {
+		return errs.NewBadRequestError(msg, true, nil, fieldErrors, nil)
+	}
+	return nil
+}
+
 func validateStruct(v Validatable) (string, []errs.FieldError) {
 	if err := v.Validate(); err != nil {
 		return extractValidationErrors(err)
@@ -0,0 +1,91 @@
+import {
+  Body,
+  Button,
+  Container,
+  Head,
+  Heading,
+  Hr,
+  Html,
+  Img,
+  Link,
+  Preview,
+  Section,
+  Text,
+  Tailwind,
+} from "@react-email/components";
+
+interface DataExportEmailProps {
+  downloadUrl: string;
+  expiresAt: string;
+}
+
+export const DataExportEmail = ({
+  downloadUrl = "{{.DownloadURL}}",
+  expiresAt = "{{.ExpiresAt}}",
+}: DataExportEmailProps) => {
+  return (
+    <Html>
+      <Head />
+      <Preview>Your data export is ready</Preview>
+      <Tailwind>
+        <Body className="bg-gray-100 font-sans">
+          <Container className="bg-white p-8 rounded-lg shadow-sm my-10 mx-auto max-w-[600px]">
+            <Heading className="text-2xl font-bold text-gray-800 mt-4">
+              Your data export is ready
+            </Heading>
+
+            <Section>
+              <Text className="text-gray-700 text-base">
+                The export of your Boilerplate data you requested is ready.
+                It contains everything we store about your account as JSON
+                files in a ZIP archive.
+              </Text>
+              <Text className="text-gray-700 text-base">
+                Anyone with this link can download your data, so do not
+                forward this email. The link expires on {expiresAt}.
+              </Text>
+            </Section>
+
+            <Section className="my-8 text-center">
+              <Button
+                className="bg-orange-600 hover:bg-orange-700 text-white font-medium rounded-md px-6 py-3"
+                href={downloadUrl}>
+                Download Export
+              </Button>
+            </Section>
+
+            <Hr className="border-gray-200 my-6" />
+
+            <Section>
+              <Text className="text-gray-600 text-sm">
+                If you have any questions, feel free to{" "}
+                <Link href={`/support`} className="text-orange-600 underline">
+                  contact our support team
+                </Link>
+                .
+              </Text>
+            </Section>
+
+            <Section className="mt-8 text-center">
+              <Text className="text-gray-500 text-xs">
+                © {new Date().getFullYear()} Go-BoilerPlate. All rights
+                reserved.
+              </Text>
+              <Text className="text-gray-500 text-xs">
+                123 Project Street, Suite 100, San Francisco, CA 94103
+              </Text>
+            </Section>
+          </Container>
+        </Body>
+      </Tailwind>
+    </Html>
+  );
+};
+
+DataExportEmail.PreviewProps = {
+  downloadUrl:
+    "http://localhost:8080/api/v1/privacy/exports/download?token=preview",
+  expiresAt: "January 2, 2026",
+};
+
+export default DataExportEmail;
@@ -0,0 +1,94 @@
+import {
+  Body,
+  Button,
+  Container,
+  Head,
+  Heading,
+  Hr,
+  Html,
+  Img,
+  Link,
+  Preview,
+  Section,
+  Text,
+  Tailwind,
+} from "@react-email/components";
+
+interface InvitationEmailProps {
+  organizationName: string;
+  inviterName: string;
+  acceptUrl: string;
+  expiresAt: string;
+}
+
+export const InvitationEmail = ({
+  organizationName = "{{.OrganizationName}}",
+  inviterName = "{{.InviterName}}",
+  acceptUrl = "{{.AcceptURL}}",
+  expiresAt = "{{.ExpiresAt}}",
+}: InvitationEmailProps) => {
+  return (
+    <Html>
+      <Head />
+      <Preview>Join {organizationName} on Boilerplate</Preview>
+      <Tailwind>
+        <Body className="bg-gray-100 font-sans">
+          <Container className="bg-white p-8 rounded-lg shadow-sm my-10 mx-auto max-w-[600px]">
+            <Heading className="text-2xl font-bold text-gray-800 mt-4">
+              Join {organizationName} on Boilerplate
+            </Heading>
+
+            <Section>
+              <Text className="text-gray-700 text-base">
+                {inviterName} has invited you to join {organizationName}.
+              </Text>
+              <Text className="text-gray-700 text-base">
+                Sign in with this email address to accept. The invitation
+                expires on {expiresAt}.
+              </Text>
+            </Section>
+
+            <Section className="my-8 text-center">
+              <Button
+                className="bg-orange-600 hover:bg-orange-700 text-white font-medium rounded-md px-6 py-3"
+                href={acceptUrl}>
+                Accept Invitation
+              </Button>
+            </Section>
+
+            <Hr className="border-gray-200 my-6" />
+
+            <Section>
+              <Text className="text-gray-600 text-sm">
+                If you have any questions, feel free to{" "}
+                <Link href={`/support`} className="text-orange-600 underline">
+                  contact our support team
+                </Link>
+                .
+              </Text>
+            </Section>
+
+            <Section className="mt-8 text-center">
+              <Text className="text-gray-500 text-xs">
+                © {new Date().getFullYear()} Go-BoilerPlate. All rights
+                reserved.
+              </Text>
+              <Text className="text-gray-500 text-xs">
+                123 Project Street, Suite 100, San Francisco, CA 94103
+              </Text>
+            </Section>
+          </Container>
+        </Body>
+      </Tailwind>
+    </Html>
+  );
+};
+
+InvitationEmail.PreviewProps = {
+  organizationName: "Acme",
+  inviterName: "Jane",
+  acceptUrl: "http://localhost:3000/invitations/accept?token=preview",
+  expiresAt: "January 2, 2026",
+};
+
+export default InvitationEmail;
@@ -0,0 +1,24 @@
+import { initContract } from "@ts-rest/core";
+import { ZBatchResponse, ZExecuteBatchPayload } from "@boilerplate/zod";
+import { getSecurityMetadata } from "@/utils.js";
+
+const c = initContract();
+
+export const batchContract = c.router(
+  {
+    executeBatch: {
+      summary: "Execute batch",
+      path: "/api/v1/batch",
+      method: "POST",
+      description:
+        "Run several API requests as the caller and return the status and body of each. With atomic set, all of them share one database transaction that is rolled back if any fails, and the requests after the failed one are not run (424)",
+      body: ZExecuteBatchPayload,
+      responses: {
+        200: ZBatchResponse,
+      },
+    },
+  },
+  {
+    metadata: getSecurityMetadata(),
+  }
+);
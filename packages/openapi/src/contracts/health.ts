@@ -1,6 +1,6 @@
 import { initContract } from "@ts-rest/core";
 import { z } from "zod";
-import { ZHealthResponse } from "@boilerplate/zod";
+import { ZBuildInfo, ZHealthResponse } from "@boilerplate/zod";
 import { getSecurityMetadata } from "@/utils.js";
 
 const c = initContract();
@@ -10,9 +10,19 @@ export const healthContract = c.router({
     summary: "Get health",
     path: "/status",
     method: "GET",
-    description: "Get health status",
+    description: "Get health status, 503 when a dependency is unhealthy",
     responses: {
       200: ZHealthResponse,
+      503: ZHealthResponse,
+    },
+  },
+  getVersion: {
+    summary: "Get version",
+    path: "/version",
+    method: "GET",
+    description: "Get build information of the running server",
+    responses: {
+      200: ZBuildInfo,
     },
   },
 });
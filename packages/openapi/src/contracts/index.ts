@@ -1,8 +1,22 @@
 import { initContract } from "@ts-rest/core";
 import { healthContract } from "./health.js";
+import { userContract } from "./user.js";
+import { organizationContract } from "./organization.js";
+import { webhookContract } from "./webhook.js";
+import { notificationContract } from "./notification.js";
+import { privacyContract } from "./privacy.js";
+import { retentionContract } from "./retention.js";
+import { batchContract } from "./batch.js";
 
 const c = initContract();
 
 export const apiContract = c.router({
   Health: healthContract,
+  User: userContract,
+  Organization: organizationContract,
+  Webhook: webhookContract,
+  Notification: notificationContract,
+  Privacy: privacyContract,
+  Retention: retentionContract,
+  Batch: batchContract,
 });
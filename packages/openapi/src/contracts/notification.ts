@@ -0,0 +1,88 @@
+import { initContract } from "@ts-rest/core";
+import { z } from "zod";
+import {
+  ZNotification,
+  ZNotificationPreferences,
+  ZNotificationUnreadCount,
+  ZPaginatedNotifications,
+  ZPartialResponseQuery,
+  ZUpdateNotificationPreferencesRequest,
+} from "@boilerplate/zod";
+import { getSecurityMetadata } from "@/utils.js";
+
+const c = initContract();
+
+export const notificationContract = c.router(
+  {
+    listNotifications: {
+      summary: "List notifications",
+      path: "/api/v1/notifications",
+      method: "GET",
+      description:
+        "List the caller's in-app notifications, newest first. With q, only matching notifications are returned, best matches first and with the matched terms highlighted",
+      query: ZPartialResponseQuery.extend({
+        page: z.coerce.number().int().min(1).optional(),
+        limit: z.coerce.number().int().min(1).max(100).optional(),
+        unread: z.coerce.boolean().optional(),
+        q: z.string().max(200).optional(),
+      }),
+      responses: {
+        200: ZPaginatedNotifications,
+      },
+    },
+    getUnreadCount: {
+      summary: "Count unread notifications",
+      path: "/api/v1/notifications/unread-count",
+      method: "GET",
+      description: "Count the caller's unread in-app notifications",
+      responses: {
+        200: ZNotificationUnreadCount,
+      },
+    },
+    markAllRead: {
+      summary: "Mark all notifications read",
+      path: "/api/v1/notifications/read-all",
+      method: "POST",
+      description: "Mark every unread notification of the caller read",
+      body: z.undefined(),
+      responses: {
+        204: z.undefined(),
+      },
+    },
+    markRead: {
+      summary: "Mark notification read",
+      path: "/api/v1/notifications/:id/read",
+      method: "POST",
+      description: "Mark a notification read",
+      pathParams: z.object({ id: z.string().uuid() }),
+      body: z.undefined(),
+      responses: {
+        200: ZNotification,
+      },
+    },
+    getPreferences: {
+      summary: "Get notification preferences",
+      path: "/api/v1/notifications/preferences",
+      method: "GET",
+      description:
+        "Get the caller's channels for every notification type and their quiet hours",
+      responses: {
+        200: ZNotificationPreferences,
+      },
+    },
+    updatePreferences: {
+      summary: "Replace notification preferences",
+      path: "/api/v1/notifications/preferences",
+      method: "PUT",
+      description:
+        "Replace the caller's preferences. Types left out return to their defaults and a missing or null quietHours turns them off",
+      body: ZUpdateNotificationPreferencesRequest,
+      responses: {
+        200: ZNotificationPreferences,
+      },
+    },
+  },
+  {
+    metadata: getSecurityMetadata(),
+  }
+);
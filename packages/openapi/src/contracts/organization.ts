@@ -0,0 +1,135 @@
+import { initContract } from "@ts-rest/core";
+import { z } from "zod";
+import {
+  ZAcceptInvitationRequest,
+  ZCreateOrganizationInvitationRequest,
+  ZCreateOrganizationRequest,
+  ZOrganization,
+  ZOrganizationExpansions,
+  ZOrganizationInvitation,
+  ZOrganizationMember,
+  ZPartialResponseQuery,
+  ZUpdateOrganizationMemberRequest,
+} from "@boilerplate/zod";
+import { getSecurityMetadata } from "@/utils.js";
+
+const c = initContract();
+
+export const organizationContract = c.router(
+  {
+    listOrganizations: {
+      summary: "List organizations",
+      path: "/api/v1/organizations",
+      method: "GET",
+      description:
+        "List the organizations the authenticated user belongs to. expand accepts owners and members",
+      query: ZPartialResponseQuery,
+      responses: {
+        200: z.array(ZOrganization.merge(ZOrganizationExpansions)),
+      },
+    },
+    createOrganization: {
+      summary: "Create organization",
+      path: "/api/v1/organizations",
+      method: "POST",
+      description: "Create an organization owned by the authenticated user",
+      body: ZCreateOrganizationRequest,
+      responses: {
+        201: ZOrganization,
+      },
+    },
+    getOrganization: {
+      summary: "Get organization",
+      path: "/api/v1/organizations/:id",
+      method: "GET",
+      description:
+        "Get an organization the authenticated user belongs to. expand accepts owners and members",
+      pathParams: z.object({ id: z.string().uuid() }),
+      query: ZPartialResponseQuery,
+      responses: {
+        200: ZOrganization.merge(ZOrganizationExpansions),
+      },
+    },
+    listMembers: {
+      summary: "List organization members",
+      path: "/api/v1/organizations/:id/members",
+      method: "GET",
+      description:
+        "List the members of an organization. With q, only members whose name matches are returned, best matches first",
+      pathParams: z.object({ id: z.string().uuid() }),
+      query: ZPartialResponseQuery.extend({
+        q: z.string().max(200).optional(),
+      }),
+      responses: {
+        200: z.array(ZOrganizationMember),
+      },
+    },
+    updateMember: {
+      summary: "Update organization member",
+      path: "/api/v1/organizations/:id/members/:userId",
+      method: "PATCH",
+      description: "Change a member's role. Only owners can grant or revoke the owner role",
+      pathParams: z.object({ id: z.string().uuid(), userId: z.string().uuid() }),
+      body: ZUpdateOrganizationMemberRequest,
+      responses: {
+        200: ZOrganizationMember,
+      },
+    },
+    removeMember: {
+      summary: "Remove organization member",
+      path: "/api/v1/organizations/:id/members/:userId",
+      method: "DELETE",
+      description: "Remove a member, or leave the organization when userId is the caller",
+      pathParams: z.object({ id: z.string().uuid(), userId: z.string().uuid() }),
+      body: z.undefined(),
+      responses: {
+        204: z.undefined(),
+      },
+    },
+    listInvitations: {
+      summary: "List organization invitations",
+      path: "/api/v1/organizations/:id/invitations",
+      method: "GET",
+      description: "List pending invitations. Requires the admin role",
+      pathParams: z.object({ id: z.string().uuid() }),
+      responses: {
+        200: z.array(ZOrganizationInvitation),
+      },
+    },
+    createInvitation: {
+      summary: "Invite to organization",
+      path: "/api/v1/organizations/:id/invitations",
+      method: "POST",
+      description: "Invite an email address to the organization. Requires the admin role",
+      pathParams: z.object({ id: z.string().uuid() }),
+      body: ZCreateOrganizationInvitationRequest,
+      responses: {
+        201: ZOrganizationInvitation,
+      },
+    },
+    revokeInvitation: {
+      summary: "Revoke organization invitation",
+      path: "/api/v1/organizations/:id/invitations/:invitationId",
+      method: "DELETE",
+      description: "Revoke a pending invitation. Requires the admin role",
+      pathParams: z.object({ id: z.string().uuid(), invitationId: z.string().uuid() }),
+      body: z.undefined(),
+      responses: {
+        204: z.undefined(),
+      },
+    },
+    acceptInvitation: {
+      summary: "Accept invitation",
+      path: "/api/v1/invitations/accept",
+      method: "POST",
+      description: "Join the organization an invitation token was issued for",
+      body: ZAcceptInvitationRequest,
+      responses: {
+        200: ZOrganization,
+      },
+    },
+  },
+  {
+    metadata: getSecurityMetadata(),
+  }
+);
@@ -0,0 +1,52 @@
+import { initContract } from "@ts-rest/core";
+import { z } from "zod";
+import { ZDataExport } from "@boilerplate/zod";
+import { getSecurityMetadata } from "@/utils.js";
+
+const c = initContract();
+
+export const privacyContract = c.router({
+  ...c.router(
+    {
+      requestDataExport: {
+        summary: "Request data export",
+        path: "/api/v1/privacy/exports",
+        method: "POST",
+        description:
+          "Start an export of everything stored about the caller, or return the export already in progress. A download link is emailed when the ZIP archive is ready",
+        body: z.undefined(),
+        responses: {
+          202: ZDataExport,
+        },
+      },
+      listDataExports: {
+        summary: "List data exports",
+        path: "/api/v1/privacy/exports",
+        method: "GET",
+        description: "List the caller's data exports, newest first",
+        responses: {
+          200: z.array(ZDataExport),
+        },
+      },
+    },
+    {
+      metadata: getSecurityMetadata(),
+    }
+  ),
+  downloadDataExport: {
+    summary: "Download data export",
+    path: "/api/v1/privacy/exports/download",
+    method: "GET",
+    description:
+      "Download the ZIP archive of a finished export. The signed token from the email authorizes the download, which is available until the export expires",
+    query: z.object({
+      token: z.string().max(512),
+    }),
+    responses: {
+      200: c.otherResponse({
+        contentType: "application/zip",
+        body: z.object({ type: z.enum(["file"]) }),
+      }),
+    },
+  },
+});
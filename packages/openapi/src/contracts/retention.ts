@@ -0,0 +1,23 @@
+import { initContract } from "@ts-rest/core";
+import { ZRetentionReport } from "@boilerplate/zod";
+import { getSecurityMetadata } from "@/utils.js";
+
+const c = initContract();
+
+export const retentionContract = c.router(
+  {
+    getRetentionReport: {
+      summary: "Get retention report",
+      path: "/api/v1/retention/report",
+      method: "GET",
+      description:
+        "Dry run of the retention policies: how many rows each would purge now and how many scheduled runs that takes",
+      responses: {
+        200: ZRetentionReport,
+      },
+    },
+  },
+  {
+    metadata: getSecurityMetadata({ securityType: "service" }),
+  }
+);
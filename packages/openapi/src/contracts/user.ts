@@ -0,0 +1,54 @@
+import { initContract } from "@ts-rest/core";
+import { z } from "zod";
+import {
+  ZIfMatchHeaders,
+  ZJSONPatch,
+  ZPartialResponseQuery,
+  ZUpdateMeRequest,
+  ZUser,
+} from "@boilerplate/zod";
+import { getSecurityMetadata } from "@/utils.js";
+
+const c = initContract();
+
+export const userContract = c.router(
+  {
+    getMe: {
+      summary: "Get current user",
+      path: "/api/v1/me",
+      method: "GET",
+      description: "Get the profile of the authenticated user, creating it on first use",
+      query: ZPartialResponseQuery,
+      responses: {
+        200: ZUser,
+      },
+    },
+    updateMe: {
+      summary: "Update current user",
+      path: "/api/v1/me",
+      method: "PATCH",
+      description:
+        "Update profile fields of the authenticated user with a merge patch (application/merge-patch+json or application/json) or a JSON Patch (application/json-patch+json). Fields cannot be removed. Send the ETag of the profile as If-Match to fail with 412 if it changed since it was read",
+      headers: ZIfMatchHeaders,
+      query: ZPartialResponseQuery,
+      body: z.union([ZUpdateMeRequest, ZJSONPatch]),
+      responses: {
+        200: ZUser,
+      },
+    },
+    deleteMe: {
+      summary: "Delete current user",
+      path: "/api/v1/me",
+      method: "DELETE",
+      description:
+        "Schedule deletion of the authenticated user's account and erase all their data, unless a legal hold blocks it",
+      body: z.undefined(),
+      responses: {
+        202: z.undefined(),
+      },
+    },
+  },
+  {
+    metadata: getSecurityMetadata(),
+  }
+);
@@ -0,0 +1,129 @@
+import { initContract } from "@ts-rest/core";
+import { z } from "zod";
+import {
+  ZCreateWebhookEndpointRequest,
+  ZPaginatedWebhookDeliveries,
+  ZUpdateWebhookEndpointRequest,
+  ZWebhookDelivery,
+  ZWebhookEndpoint,
+  ZWebhookEndpointWithSecret,
+} from "@boilerplate/zod";
+import { getSecurityMetadata } from "@/utils.js";
+
+const c = initContract();
+
+const ZEndpointParams = z.object({
+  id: z.string().uuid(),
+  endpointId: z.string().uuid(),
+});
+
+export const webhookContract = c.router(
+  {
+    listEndpoints: {
+      summary: "List webhook endpoints",
+      path: "/api/v1/organizations/:id/webhooks",
+      method: "GET",
+      description: "List the organization's webhook endpoints. Requires the admin role",
+      pathParams: z.object({ id: z.string().uuid() }),
+      responses: {
+        200: z.array(ZWebhookEndpoint),
+      },
+    },
+    createEndpoint: {
+      summary: "Create webhook endpoint",
+      path: "/api/v1/organizations/:id/webhooks",
+      method: "POST",
+      description:
+        "Register an endpoint. The signing secret is only returned here and when it is rotated",
+      pathParams: z.object({ id: z.string().uuid() }),
+      body: ZCreateWebhookEndpointRequest,
+      responses: {
+        201: ZWebhookEndpointWithSecret,
+      },
+    },
+    getEndpoint: {
+      summary: "Get webhook endpoint",
+      path: "/api/v1/organizations/:id/webhooks/:endpointId",
+      method: "GET",
+      description: "Get a webhook endpoint",
+      pathParams: ZEndpointParams,
+      responses: {
+        200: ZWebhookEndpoint,
+      },
+    },
+    updateEndpoint: {
+      summary: "Update webhook endpoint",
+      path: "/api/v1/organizations/:id/webhooks/:endpointId",
+      method: "PATCH",
+      description:
+        "Update an endpoint. Re-enabling an endpoint resets its failure count",
+      pathParams: ZEndpointParams,
+      body: ZUpdateWebhookEndpointRequest,
+      responses: {
+        200: ZWebhookEndpoint,
+      },
+    },
+    deleteEndpoint: {
+      summary: "Delete webhook endpoint",
+      path: "/api/v1/organizations/:id/webhooks/:endpointId",
+      method: "DELETE",
+      description: "Delete an endpoint and its delivery log",
+      pathParams: ZEndpointParams,
+      body: z.undefined(),
+      responses: {
+        204: z.undefined(),
+      },
+    },
+    rotateSecret: {
+      summary: "Rotate webhook secret",
+      path: "/api/v1/organizations/:id/webhooks/:endpointId/rotate-secret",
+      method: "POST",
+      description:
+        "Issue a new signing secret. The previous secret also signs deliveries for 24 hours",
+      pathParams: ZEndpointParams,
+      body: z.undefined(),
+      responses: {
+        200: ZWebhookEndpointWithSecret,
+      },
+    },
+    pingEndpoint: {
+      summary: "Ping webhook endpoint",
+      path: "/api/v1/organizations/:id/webhooks/:endpointId/ping",
+      method: "POST",
+      description: "Send a webhook.ping event to the endpoint",
+      pathParams: ZEndpointParams,
+      body: z.undefined(),
+      responses: {
+        202: ZWebhookDelivery,
+      },
+    },
+    listDeliveries: {
+      summary: "List webhook deliveries",
+      path: "/api/v1/organizations/:id/webhooks/:endpointId/deliveries",
+      method: "GET",
+      description: "List the endpoint's deliveries, newest first",
+      pathParams: ZEndpointParams,
+      query: z.object({
+        page: z.coerce.number().int().min(1).optional(),
+        limit: z.coerce.number().int().min(1).max(100).optional(),
+      }),
+      responses: {
+        200: ZPaginatedWebhookDeliveries,
+      },
+    },
+    redeliver: {
+      summary: "Redeliver webhook",
+      path: "/api/v1/organizations/:id/webhooks/:endpointId/deliveries/:deliveryId/redeliver",
+      method: "POST",
+      description: "Send a past delivery's event again as a new delivery",
+      pathParams: ZEndpointParams.extend({ deliveryId: z.string().uuid() }),
+      body: z.undefined(),
+      responses: {
+        202: ZWebhookDelivery,
+      },
+    },
+  },
+  {
+    metadata: getSecurityMetadata(),
+  }
+);
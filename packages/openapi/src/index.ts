@@ -1,7 +1,8 @@
-import { extendZodWithOpenApi } from "@anatine/zod-openapi";
+import { extendZodWithOpenApi, generateSchema } from "@anatine/zod-openapi";
 import { z } from "zod";
 
 extendZodWithOpenApi(z);
+import { ZErrorResponse } from "@boilerplate/zod";
 import { generateOpenApi } from "@ts-rest/open-api";
 
 import { apiContract } from "./contracts/index.js";
@@ -22,8 +23,14 @@ const hasSecurity = (
   );
 };
 
+// Every operation can fail with the shared error body, see
+// components.responses.Error
 const operationMapper: OperationMapper = (operation, appRoute) => ({
   ...operation,
+  responses: {
+    ...operation.responses,
+    default: { $ref: "#/components/responses/Error" },
+  },
   ...(hasSecurity(appRoute.metadata)
     ? {
         security: appRoute.metadata.openApiSecurity,
@@ -67,6 +74,19 @@ export const OpenAPI = Object.assign(
           in: "header",
         },
       },
+      schemas: {
+        ErrorResponse: generateSchema(ZErrorResponse),
+      },
+      responses: {
+        Error: {
+          description: "Error",
+          content: {
+            "application/json": {
+              schema: { $ref: "#/components/schemas/ErrorResponse" },
+            },
+          },
+        },
+      },
     },
   }
 );
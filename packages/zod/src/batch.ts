@@ -0,0 +1,26 @@
+import { z } from "zod";
+
+export const ZBatchRequest = z.object({
+  id: z.string().max(64).optional(),
+  method: z.enum(["GET", "POST", "PUT", "PATCH", "DELETE"]),
+  path: z.string().startsWith("/api/v1/").max(2048),
+  headers: z.record(z.string()).optional(),
+  body: z.unknown().optional(),
+});
+
+export const ZExecuteBatchPayload = z.object({
+  atomic: z.boolean().optional(),
+  requests: z.array(ZBatchRequest).min(1),
+});
+
+export const ZBatchResult = z.object({
+  id: z.string().optional(),
+  status: z.number().int(),
+  body: z.unknown().optional(),
+});
+
+export const ZBatchResponse = z.object({
+  atomic: z.boolean(),
+  rolledBack: z.boolean(),
+  results: z.array(ZBatchResult),
+});
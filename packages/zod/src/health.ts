@@ -1,4 +1,5 @@
 import { z } from "zod";
+import { ZBuildInfo } from "./version.js";
 
 const ZHealthCheck = z.object({
   status: z.string(),
@@ -6,12 +7,22 @@ const ZHealthCheck = z.object({
   error: z.string().optional(),
 });
 
+const ZCircuitStatus = z.object({
+  name: z.string(),
+  state: z.enum(["closed", "open", "half_open"]),
+  in_flight: z.number().int(),
+  rejected: z.number().int(),
+  fallback: z.enum(["skip", "fail"]),
+});
+
 export const ZHealthResponse = z.object({
   status: z.enum(["healthy", "unhealthy"]),
   timestamp: z.string().datetime(),
   environment: z.string(),
+  build: ZBuildInfo,
   checks: z.object({
     database: ZHealthCheck,
     redis: ZHealthCheck.optional(),
+    circuits: z.record(ZCircuitStatus).optional(),
   }),
 });
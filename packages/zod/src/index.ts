@@ -5,3 +5,11 @@ extendZodWithOpenApi(z);
 
 export * from "./utils.js";
 export * from "./health.js";
+export * from "./version.js";
+export * from "./user.js";
+export * from "./organization.js";
+export * from "./webhook.js";
+export * from "./notification.js";
+export * from "./privacy.js";
+export * from "./retention.js";
+export * from "./batch.js";
@@ -0,0 +1,70 @@
+import { z } from "zod";
+import { schemaWithPagination } from "./utils.js";
+
+export const ZNotificationType = z.enum([
+  "organization.member_joined",
+  "organization.role_changed",
+  "organization.removed",
+]);
+
+export const ZNotification = z.object({
+  id: z.string().uuid(),
+  userId: z.string().uuid(),
+  organizationId: z.string().uuid().nullable(),
+  type: ZNotificationType,
+  title: z.string(),
+  body: z.string(),
+  data: z.record(z.unknown()),
+  readAt: z.string().datetime().nullable(),
+  createdAt: z.string().datetime(),
+  updatedAt: z.string().datetime(),
+});
+
+// Highlights are set when the list was searched. They are HTML escaped, with
+// matched terms wrapped in <mark>, so they can be rendered as markup.
+export const ZNotificationWithHighlights = ZNotification.extend({
+  highlights: z.object({ title: z.string(), body: z.string() }).optional(),
+});
+
+export const ZPaginatedNotifications = schemaWithPagination(
+  ZNotificationWithHighlights,
+);
+
+export const ZNotificationUnreadCount = z.object({
+  count: z.number().int(),
+});
+
+const ZTimeOfDay = z.string().regex(/^([01][0-9]|2[0-3]):[0-5][0-9]$/);
+
+export const ZQuietHours = z.object({
+  start: ZTimeOfDay,
+  end: ZTimeOfDay,
+});
+
+export const ZNotificationPreference = z.object({
+  type: ZNotificationType,
+  inApp: z.boolean(),
+  email: z.boolean(),
+  webhook: z.boolean(),
+  emailDigest: z.boolean(),
+});
+
+export const ZNotificationPreferences = z.object({
+  quietHours: ZQuietHours.nullable(),
+  types: z.array(ZNotificationPreference),
+});
+
+export const ZUpdateNotificationPreferencesRequest = z.object({
+  quietHours: ZQuietHours.nullable().optional(),
+  types: z.array(ZNotificationPreference).optional(),
+});
+
+export const ZNotificationStreamMessage = z.object({
+  type: z.enum([
+    "notification.created",
+    "notification.read",
+    "notification.read_all",
+  ]),
+  notification: ZNotification.optional(),
+  unreadCount: z.number().int(),
+});
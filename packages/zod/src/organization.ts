@@ -0,0 +1,67 @@
+import { z } from "zod";
+
+export const ZOrganizationRole = z.enum(["owner", "admin", "member"]);
+
+export const ZOrganization = z.object({
+  id: z.string().uuid(),
+  clerkOrgId: z.string().nullable(),
+  name: z.string(),
+  slug: z.string(),
+  createdBy: z.string().uuid().nullable(),
+  role: ZOrganizationRole,
+  createdAt: z.string().datetime(),
+  updatedAt: z.string().datetime(),
+});
+
+export const ZCreateOrganizationRequest = z.object({
+  name: z.string().min(1).max(100),
+  slug: z
+    .string()
+    .min(2)
+    .max(64)
+    .regex(/^[a-z0-9]+(?:-[a-z0-9]+)*$/),
+  clerkOrgId: z.string().min(1).max(255).optional(),
+});
+
+export const ZOrganizationMember = z.object({
+  id: z.string().uuid(),
+  organizationId: z.string().uuid(),
+  userId: z.string().uuid(),
+  role: ZOrganizationRole,
+  displayName: z.string().nullable(),
+  createdAt: z.string().datetime(),
+  updatedAt: z.string().datetime(),
+});
+
+// Related resources an organization includes with ?expand=
+export const ZOrganizationExpansions = z.object({
+  owners: z.array(ZOrganizationMember).optional(),
+  members: z.array(ZOrganizationMember).optional(),
+});
+
+export const ZUpdateOrganizationMemberRequest = z.object({
+  role: ZOrganizationRole,
+});
+
+export const ZOrganizationInvitation = z.object({
+  id: z.string().uuid(),
+  organizationId: z.string().uuid(),
+  email: z.string().email(),
+  role: ZOrganizationRole,
+  invitedBy: z.string().uuid().nullable(),
+  expiresAt: z.string().datetime(),
+  acceptedAt: z.string().datetime().nullable(),
+  acceptedBy: z.string().uuid().nullable(),
+  revokedAt: z.string().datetime().nullable(),
+  createdAt: z.string().datetime(),
+  updatedAt: z.string().datetime(),
+});
+
+export const ZCreateOrganizationInvitationRequest = z.object({
+  email: z.string().email().max(320),
+  role: z.enum(["admin", "member"]),
+});
+
+export const ZAcceptInvitationRequest = z.object({
+  token: z.string().max(512),
+});
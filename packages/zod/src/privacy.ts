@@ -0,0 +1,21 @@
+import { z } from "zod";
+
+export const ZPrivacyRequestStatus = z.enum([
+  "pending",
+  "processing",
+  "completed",
+  "failed",
+  "blocked",
+]);
+
+export const ZDataExport = z.object({
+  id: z.string().uuid(),
+  userId: z.string().uuid(),
+  kind: z.literal("export"),
+  status: ZPrivacyRequestStatus,
+  archiveSize: z.number().int().nullable(),
+  expiresAt: z.string().datetime().nullable(),
+  completedAt: z.string().datetime().nullable(),
+  createdAt: z.string().datetime(),
+  updatedAt: z.string().datetime(),
+});
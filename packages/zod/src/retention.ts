@@ -0,0 +1,18 @@
+import { z } from "zod";
+
+export const ZRetentionPolicyReport = z.object({
+  name: z.string(),
+  table: z.string(),
+  mode: z.enum(["delete", "soft_delete"]),
+  maxAgeSeconds: z.number().int(),
+  archive: z.boolean(),
+  cutoff: z.string().datetime(),
+  eligibleRows: z.number().int(),
+  oldestEligibleAt: z.string().datetime().nullable(),
+  runs: z.number().int(),
+});
+
+export const ZRetentionReport = z.object({
+  generatedAt: z.string().datetime(),
+  policies: z.array(ZRetentionPolicyReport),
+});
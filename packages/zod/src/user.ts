@@ -0,0 +1,20 @@
+import { z } from "zod";
+
+export const ZUser = z.object({
+  id: z.string().uuid(),
+  clerkUserId: z.string(),
+  displayName: z.string().nullable(),
+  locale: z.string(),
+  timezone: z.string(),
+  preferences: z.record(z.unknown()),
+  deletionScheduledAt: z.string().datetime().nullable(),
+  createdAt: z.string().datetime(),
+  updatedAt: z.string().datetime(),
+});
+
+export const ZUpdateMeRequest = z.object({
+  displayName: z.string().min(1).max(100).optional(),
+  locale: z.string().optional(),
+  timezone: z.string().optional(),
+  preferences: z.record(z.unknown()).optional(),
+});
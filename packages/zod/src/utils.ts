@@ -18,3 +18,57 @@ export const schemaWithPagination = <T>(
     limit: z.number(),
     totalPages: z.number(),
   });
+
+// Query parameters accepted by every JSON endpoint to select fields and, where
+// the resource has expanders, include related resources
+export const ZPartialResponseQuery = z.object({
+  fields: z
+    .string()
+    .optional()
+    .describe(
+      "Comma separated JSON paths to return, e.g. id,name,owners.role. Unknown fields are rejected"
+    ),
+  expand: z
+    .string()
+    .optional()
+    .describe("Comma separated related resources to include, e.g. owners,members"),
+});
+
+// An RFC 6902 JSON Patch, sent as application/json-patch+json to endpoints that
+// also accept a merge patch
+export const ZJSONPatch = z.array(
+  z.object({
+    op: z.enum(["add", "remove", "replace", "move", "copy", "test"]),
+    path: z.string().describe("JSON Pointer, e.g. /preferences/theme"),
+    from: z.string().optional().describe("JSON Pointer of the source of move and copy"),
+    value: z.unknown().optional(),
+  })
+);
+
+// Headers of endpoints that update a resource only if it is still at a known
+// version
+export const ZIfMatchHeaders = z.object({
+  "if-match": z
+    .string()
+    .optional()
+    .describe("ETag of the version the update is based on; 412 if the resource changed since"),
+});
+
+// Body of every error response, written by the server's global error handler
+export const ZErrorResponse = z.object({
+  code: z.string().describe("Machine readable error code, e.g. BAD_REQUEST or ORGANIZATION_NOT_FOUND"),
+  message: z.string(),
+  status: z.number().int(),
+  override: z.boolean().describe("Whether clients should show message as is"),
+  errors: z
+    .array(z.object({ field: z.string(), error: z.string() }))
+    .nullable()
+    .describe("Validation errors by field"),
+  action: z
+    .object({
+      type: z.enum(["redirect"]),
+      message: z.string(),
+      value: z.string(),
+    })
+    .nullable(),
+});
@@ -0,0 +1,9 @@
+import { z } from "zod";
+
+export const ZBuildInfo = z.object({
+  version: z.string(),
+  commit: z.string(),
+  buildDate: z.string(),
+  dirty: z.boolean(),
+  goVersion: z.string(),
+});
@@ -0,0 +1,61 @@
+import { z } from "zod";
+import { schemaWithPagination } from "./utils.js";
+
+export const ZWebhookEndpoint = z.object({
+  id: z.string().uuid(),
+  organizationId: z.string().uuid(),
+  url: z.string().url(),
+  description: z.string().nullable(),
+  eventTypes: z.array(z.string()),
+  enabled: z.boolean(),
+  consecutiveFailures: z.number().int(),
+  disabledAt: z.string().datetime().nullable(),
+  disabledReason: z.string().nullable(),
+  createdBy: z.string().uuid().nullable(),
+  createdAt: z.string().datetime(),
+  updatedAt: z.string().datetime(),
+});
+
+export const ZWebhookEndpointWithSecret = ZWebhookEndpoint.extend({
+  secret: z.string(),
+});
+
+export const ZCreateWebhookEndpointRequest = z.object({
+  url: z.string().url().max(2048),
+  description: z.string().max(500).optional(),
+  eventTypes: z.array(z.string().min(1).max(100)).max(50).optional(),
+});
+
+export const ZUpdateWebhookEndpointRequest = z.object({
+  url: z.string().url().max(2048).optional(),
+  description: z.string().max(500).optional(),
+  eventTypes: z.array(z.string().min(1).max(100)).max(50).optional(),
+  enabled: z.boolean().optional(),
+});
+
+export const ZWebhookDeliveryStatus = z.enum([
+  "pending",
+  "retrying",
+  "succeeded",
+  "failed",
+]);
+
+export const ZWebhookDelivery = z.object({
+  id: z.string().uuid(),
+  endpointId: z.string().uuid(),
+  eventId: z.string().uuid(),
+  eventType: z.string(),
+  payload: z.record(z.unknown()),
+  status: ZWebhookDeliveryStatus,
+  attempts: z.number().int(),
+  responseStatus: z.number().int().nullable(),
+  latencyMs: z.number().int().nullable(),
+  error: z.string().nullable(),
+  deliveredAt: z.string().datetime().nullable(),
+  redeliveryOf: z.string().uuid().nullable(),
+  createdAt: z.string().datetime(),
+  updatedAt: z.string().datetime(),
+});
+
+export const ZPaginatedWebhookDeliveries =
+  schemaWithPagination(ZWebhookDelivery);